@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/txbuilder"
+	"github.com/rs/zerolog/log"
+)
+
+// Error codes of the error responses, see the Error schema of openapi.yaml
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeInsufficientFunds   = "insufficient_funds"
+	CodeOutputSpent         = "output_spent"
+	CodeNotFinalized        = "psbt_not_finalized"
+	CodeNotConfirmed        = "not_enough_confirmations"
+	CodeDepositMismatch     = "deposit_mismatch"
+	CodeIndexersDisagree    = "indexers_disagree"
+	CodeIndexerBehind       = "indexer_behind"
+	CodeUpstream            = "upstream_error"
+	CodeTimeout             = "timeout"
+	CodeInternal            = "internal_error"
+	CodeRequestTooLarge     = "request_too_large"
+	CodeUnsupportedAddress  = "unsupported_address"
+	CodeFeeUnavailable      = "fee_estimate_unavailable"
+	CodeTransactionRejected = "transaction_rejected"
+)
+
+// Body of every error response, {"error": {...}}
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"` // Request field which failed validation
+}
+
+func (e *Error) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return e.Message
+}
+
+// Validation error of a request field
+func invalid(field, format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: fmt.Sprintf(format, args...), Field: field}
+}
+
+// Errors of the library mapped to a status & code, checked in order
+var errorStatuses = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{txbuilder.ErrInvalidAmount, http.StatusBadRequest, CodeInvalidRequest},
+	{txbuilder.ErrUnsupportedInput, http.StatusBadRequest, CodeUnsupportedAddress},
+	{txbuilder.ErrNotFinalized, http.StatusBadRequest, CodeNotFinalized},
+	{txbuilder.ErrInsufficientFunds, http.StatusUnprocessableEntity, CodeInsufficientFunds},
+	{txbuilder.ErrOutputSpent, http.StatusConflict, CodeOutputSpent},
+	{runes.ErrRunesUtxoNotFound, http.StatusUnprocessableEntity, CodeInsufficientFunds},
+	{client.ErrUnknownFeePriority, http.StatusBadRequest, CodeInvalidRequest},
+	{client.ErrFeeEstimateUnavailable, http.StatusServiceUnavailable, CodeFeeUnavailable},
+	{client.ErrPackageRejected, http.StatusUnprocessableEntity, CodeTransactionRejected},
+	{brc20.ErrNotEnoughConfirmations, http.StatusConflict, CodeNotConfirmed},
+	{runes.ErrNotEnoughConfirmations, http.StatusConflict, CodeNotConfirmed},
+	{brc20.ErrTransferAheadOfIndexer, http.StatusServiceUnavailable, CodeIndexerBehind},
+	{client.ErrIndexerBehind, http.StatusServiceUnavailable, CodeIndexerBehind},
+	{brc20.ErrIndexersDisagree, http.StatusBadGateway, CodeIndexersDisagree},
+	{brc20.ErrNoQuorum, http.StatusBadGateway, CodeIndexersDisagree},
+	{brc20.ErrNoEventsFound, http.StatusNotFound, CodeNotFound},
+	{brc20.ErrInvalidBrc20Transfer, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{brc20.ErrTransferBelowMinHeight, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{runes.ErrRunesInputsNotFound, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{runes.ErrRunesBurned, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{runes.ErrRunesNotReceived, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{runes.ErrRunesAmountMismatch, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{runes.ErrRunesSenderMismatch, http.StatusUnprocessableEntity, CodeDepositMismatch},
+	{client.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{client.ErrRateLimited, http.StatusBadGateway, CodeUpstream},
+	{client.ErrApiBadGateway, http.StatusBadGateway, CodeUpstream},
+	{client.ErrRpcTimeout, http.StatusGatewayTimeout, CodeTimeout},
+	{client.ErrRpcUnauthorized, http.StatusBadGateway, CodeUpstream},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+}
+
+// Error response of an error, unknown errors are internal ones
+func ErrorOf(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	// Transactions the node refuses to relay
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case btcjson.ErrRPCVerify, btcjson.ErrRPCVerifyRejected, btcjson.ErrRPCVerifyAlreadyInChain, btcjson.ErrRPCDeserialization:
+			return &Error{Status: http.StatusUnprocessableEntity, Code: CodeTransactionRejected, Message: err.Error()}
+		}
+	}
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			return &Error{Status: e.status, Code: e.code, Message: err.Error()}
+		}
+	}
+	// The details stay in the server logs
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error"}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := ErrorOf(err)
+	if e.Status >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("method", r.Method).Str("path", r.URL.Path).Msg("api request failed")
+	}
+	if e.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="btc-service"`)
+	}
+	writeJSON(w, e.Status, map[string]*Error{"error": e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Warn().Err(err).Msg("writing api response")
+	}
+}
@@ -0,0 +1,595 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/inscriptions"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/taproot"
+	"github.com/ordinox/btc-service/txbuilder"
+)
+
+// Sats/vB as a number or a string, or a priority: auto, fast, slow, fastest, halfhour, hour or economy
+type FeeRate string
+
+func (f *FeeRate) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		return json.Unmarshal(data, (*string)(f))
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*f = FeeRate(n.String())
+	return nil
+}
+
+// Handler of the json request of an operation, answered with its json result
+func jsonOp[Req, Res any](op func(context.Context, Req) (Res, error)) handler {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		var req Req
+		if err := decode(r, &req); err != nil {
+			return err
+		}
+		res, err := op(r.Context(), req)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, res)
+		return nil
+	}
+}
+
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &tooLarge):
+			return &Error{Status: http.StatusRequestEntityTooLarge, Code: CodeRequestTooLarge, Message: fmt.Sprintf("body is over %d bytes", tooLarge.Limit)}
+		case errors.As(err, &typeErr):
+			return invalid(typeErr.Field, "should be a %s", typeErr.Type)
+		}
+		return invalid("", "invalid json body: %s", err)
+	}
+	return nil
+}
+
+func (s *Server) address(field, value string) (btcutil.Address, error) {
+	if value == "" {
+		return nil, invalid(field, "required")
+	}
+	addr, err := btcutil.DecodeAddress(value, s.Params)
+	if err != nil || !addr.IsForNet(s.Params) {
+		return nil, invalid(field, "%q isn't a %s address", value, s.Params.Name)
+	}
+	return addr, nil
+}
+
+func (s *Server) feeRate(rate FeeRate) (uint64, error) {
+	if n, err := strconv.ParseUint(string(rate), 10, 64); err == nil {
+		if n == 0 {
+			return 0, invalid("fee_rate", "should be positive")
+		}
+		return n, nil
+	}
+	priority := s.FeePriority
+	if rate != "" && rate != "auto" {
+		var err error
+		if priority, err = client.ParseFeePriority(string(rate)); err != nil {
+			return 0, invalid("fee_rate", "%q should be sats/vB or one of auto, fast, slow, fastest, halfhour, hour or economy", rate)
+		}
+	}
+	return s.Fees.EstimateFeeRate(priority)
+}
+
+func runeId(field, value string) (*runes.Rune, error) {
+	// ParseRune expects block:tx
+	if !strings.Contains(value, ":") {
+		return nil, invalid(field, "%q should be BLOCK:TX", value)
+	}
+	rune, err := runes.ParseRune(value)
+	if err != nil {
+		return nil, invalid(field, "%q should be BLOCK:TX", value)
+	}
+	return rune, nil
+}
+
+func txId(field, value string) error {
+	if len(value) != 2*chainhash.HashSize {
+		return invalid(field, "%q should be a 64 characters hex tx id", value)
+	}
+	if _, err := chainhash.NewHashFromStr(value); err != nil {
+		return invalid(field, "%q should be a 64 characters hex tx id", value)
+	}
+	return nil
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	return nil
+}
+
+func (s *Server) openApi(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, err := w.Write(OpenApiSpec)
+	return err
+}
+
+type FeesResponse struct {
+	Fastest  uint64 `json:"fastest"`
+	HalfHour uint64 `json:"halfhour"`
+	Hour     uint64 `json:"hour"`
+	Economy  uint64 `json:"economy"`
+	Default  string `json:"default"` // Priority of fee_rate auto
+}
+
+func (s *Server) fees(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	res, err := s.FeeRates(r.Context())
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, res)
+	return nil
+}
+
+// Fee rate of every priority
+func (s *Server) FeeRates(ctx context.Context) (*FeesResponse, error) {
+	res := FeesResponse{Default: string(s.FeePriority)}
+	for priority, rate := range map[client.FeePriority]*uint64{
+		client.FeePriorityFastest:  &res.Fastest,
+		client.FeePriorityHalfHour: &res.HalfHour,
+		client.FeePriorityHour:     &res.Hour,
+		client.FeePriorityEconomy:  &res.Economy,
+	} {
+		var err error
+		if *rate, err = s.Fees.EstimateFeeRate(priority); err != nil {
+			return nil, err
+		}
+	}
+	return &res, nil
+}
+
+type Utxo struct {
+	TxId   string `json:"tx_id"`
+	Vout   uint32 `json:"vout"`
+	Value  uint64 `json:"value"`
+	Height int    `json:"height"` // 0 when unconfirmed
+}
+
+func (s *Server) utxos(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	res, err := s.Utxos(r.Context(), params["address"])
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, res)
+	return nil
+}
+
+// Unspent outputs of an address, including the ones holding inscriptions or runes
+func (s *Server) Utxos(ctx context.Context, address string) ([]Utxo, error) {
+	addr, err := s.address("address", address)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.Builder.Utxos.GetUtxos(ctx, addr.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Utxo, len(utxos))
+	for i, u := range utxos {
+		res[i] = Utxo{TxId: u.TxHash, Vout: u.Vout, Value: u.Value, Height: u.Height}
+	}
+	return res, nil
+}
+
+type Brc20BalanceResponse struct {
+	Ticker string `json:"ticker"`
+	client.Brc20Balance
+}
+
+func (s *Server) brc20Balance(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	res, err := s.Brc20Balance(r.Context(), params["address"], params["ticker"])
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, res)
+	return nil
+}
+
+// BRC-20 balance of an address, agreed on by the brc20 sources
+func (s *Server) Brc20Balance(ctx context.Context, address, ticker string) (*Brc20BalanceResponse, error) {
+	addr, err := s.address("address", address)
+	if err != nil {
+		return nil, err
+	}
+	ticker = strings.ToLower(ticker)
+	if err := brc20.ValidateTicker(ticker); err != nil {
+		return nil, invalid("ticker", "%s", err)
+	}
+	balance, err := s.Brc20.GetBrc20Balance(ctx, addr.EncodeAddress(), ticker)
+	if err != nil {
+		return nil, err
+	}
+	return &Brc20BalanceResponse{Ticker: ticker, Brc20Balance: *balance}, nil
+}
+
+type RuneBalance struct {
+	RuneId   string             `json:"rune_id"`
+	RuneName string             `json:"rune_name,omitempty"`
+	Amount   common.TokenAmount `json:"amount"` // Base units
+}
+
+func (s *Server) runesBalances(w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	res, err := s.RunesBalances(r.Context(), params["address"])
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, res)
+	return nil
+}
+
+// Runes held by an address, ordered by rune id
+func (s *Server) RunesBalances(ctx context.Context, address string) ([]RuneBalance, error) {
+	addr, err := s.address("address", address)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.Builder.Runes.GetRunesUtxos(ctx, addr.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	balances := runes.SumBalances(utxos)
+	res := make([]RuneBalance, len(balances))
+	for i, b := range balances {
+		res[i] = RuneBalance{RuneId: b.RuneId, RuneName: b.RuneName, Amount: b.Amount}
+	}
+	return res, nil
+}
+
+// Unsigned tx for the wallet of the sender to sign
+type PsbtResponse struct {
+	Psbt    string `json:"psbt"` // Base64
+	Fee     int64  `json:"fee"`
+	VSize   int64  `json:"vsize"` // Estimated, once signed
+	FeeRate uint64 `json:"fee_rate"`
+}
+
+func psbtResponse(u *txbuilder.Unsigned, feeRate uint64) (*PsbtResponse, error) {
+	b64, err := u.Base64()
+	if err != nil {
+		return nil, err
+	}
+	return &PsbtResponse{Psbt: b64, Fee: u.Fee, VSize: u.VSize, FeeRate: feeRate}, nil
+}
+
+// Sender & receiver of the txs built, the sender funding the tx and getting the change
+type Transfer struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	FeeRate FeeRate `json:"fee_rate"`
+}
+
+func (s *Server) parseTransfer(t Transfer) (from, to btcutil.Address, feeRate uint64, err error) {
+	if from, err = s.address("from", t.From); err != nil {
+		return
+	}
+	if to, err = s.address("to", t.To); err != nil {
+		return
+	}
+	feeRate, err = s.feeRate(t.FeeRate)
+	return
+}
+
+type BtcSendRequest struct {
+	Transfer
+	Amount int64 `json:"amount"` // Sats
+}
+
+// Unsigned BTC send
+func (s *Server) BuildBtcSend(ctx context.Context, req BtcSendRequest) (*PsbtResponse, error) {
+	from, to, feeRate, err := s.parseTransfer(req.Transfer)
+	if err != nil {
+		return nil, err
+	}
+	if req.Amount <= 0 {
+		return nil, invalid("amount", "should be positive")
+	}
+	u, err := s.Builder.SendBtc(ctx, from, to, req.Amount, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	return psbtResponse(u, feeRate)
+}
+
+type Brc20TransferRequest struct {
+	Transfer
+	InscriptionId string `json:"inscription_id"` // Transfer inscription, in the output it was revealed into
+}
+
+// Unsigned BRC-20 transfer, sending the transfer inscription
+func (s *Server) BuildBrc20Transfer(ctx context.Context, req Brc20TransferRequest) (*PsbtResponse, error) {
+	from, to, feeRate, err := s.parseTransfer(req.Transfer)
+	if err != nil {
+		return nil, err
+	}
+	outpoint, err := inscriptions.GenesisOutPoint(req.InscriptionId)
+	if err != nil {
+		return nil, invalid("inscription_id", "%s", err)
+	}
+	u, err := s.Builder.TransferInscription(ctx, from, to, *outpoint, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	return psbtResponse(u, feeRate)
+}
+
+type RunesTransferRequest struct {
+	Transfer
+	RuneId string `json:"rune_id"` // BLOCK:TX
+	Amount string `json:"amount"`  // Base units
+}
+
+// Unsigned runes transfer
+func (s *Server) BuildRunesTransfer(ctx context.Context, req RunesTransferRequest) (*PsbtResponse, error) {
+	from, to, feeRate, err := s.parseTransfer(req.Transfer)
+	if err != nil {
+		return nil, err
+	}
+	rune, err := runeId("rune_id", req.RuneId)
+	if err != nil {
+		return nil, err
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, invalid("amount", "%q should be a positive integer of base units", req.Amount)
+	}
+	u, err := s.Builder.TransferRune(ctx, from, to, *rune, amount, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	return psbtResponse(u, feeRate)
+}
+
+type Inscription struct {
+	ContentType   string `json:"content_type"`
+	Content       string `json:"content,omitempty"`
+	ContentBase64 string `json:"content_base64,omitempty"` // Instead of content, for binary data
+}
+
+type InscriptionRequest struct {
+	Transfer
+	Inscriptions []Inscription `json:"inscriptions"`
+}
+
+// Commit to sign, along with the reveal to submit with it
+type InscriptionResponse struct {
+	PsbtResponse
+	RevealTx       string   `json:"reveal_tx"` // Hex, signed
+	RevealTxId     string   `json:"reveal_tx_id"`
+	InscriptionIds []string `json:"inscription_ids"`
+}
+
+// Unsigned inscription commit, along with its reveal signed by a one-time key
+func (s *Server) BuildInscription(ctx context.Context, req InscriptionRequest) (*InscriptionResponse, error) {
+	from, to, feeRate, err := s.parseTransfer(req.Transfer)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Inscriptions) == 0 {
+		return nil, invalid("inscriptions", "required")
+	}
+	data := make([]taproot.InscriptionData, len(req.Inscriptions))
+	for i, ins := range req.Inscriptions {
+		field := fmt.Sprintf("inscriptions[%d]", i)
+		if ins.ContentType == "" {
+			return nil, invalid(field+".content_type", "required")
+		}
+		content := ins.Content
+		switch {
+		case ins.Content != "" && ins.ContentBase64 != "":
+			return nil, invalid(field, "content and content_base64 can't both be set")
+		case ins.ContentBase64 != "":
+			raw, err := base64.StdEncoding.DecodeString(ins.ContentBase64)
+			if err != nil {
+				return nil, invalid(field+".content_base64", "%s", err)
+			}
+			content = string(raw)
+		case ins.Content == "":
+			return nil, invalid(field+".content", "required")
+		}
+		data[i] = taproot.NewInscriptionData(content, ins.ContentType)
+	}
+	ins, err := s.Builder.Inscribe(ctx, from, to, data, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := psbtResponse(ins.Commit, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	var reveal bytes.Buffer
+	if err := ins.Reveal.Serialize(&reveal); err != nil {
+		return nil, err
+	}
+	res := InscriptionResponse{PsbtResponse: *commit, RevealTx: hex.EncodeToString(reveal.Bytes()), RevealTxId: ins.Reveal.TxHash().String()}
+	for i := range data {
+		res.InscriptionIds = append(res.InscriptionIds, fmt.Sprintf("%si%d", res.RevealTxId, i))
+	}
+	return &res, nil
+}
+
+type SubmitRequest struct {
+	Psbt     string `json:"psbt"`                // Base64, signed
+	RevealTx string `json:"reveal_tx,omitempty"` // Hex, sent after the psbt tx which it spends
+}
+
+type SubmitResponse struct {
+	TxIds []string `json:"tx_ids"`
+}
+
+// Finalize & broadcast a signed psbt, then the reveal spending it if any
+func (s *Server) Submit(ctx context.Context, req SubmitRequest) (*SubmitResponse, error) {
+	if req.Psbt == "" {
+		return nil, invalid("psbt", "required")
+	}
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(req.Psbt), true)
+	if err != nil {
+		return nil, invalid("psbt", "%s", err)
+	}
+	var then []*wire.MsgTx
+	if req.RevealTx != "" {
+		raw, err := hex.DecodeString(req.RevealTx)
+		if err != nil {
+			return nil, invalid("reveal_tx", "%s", err)
+		}
+		reveal := wire.NewMsgTx(wire.TxVersion)
+		if err := reveal.Deserialize(bytes.NewReader(raw)); err != nil {
+			return nil, invalid("reveal_tx", "%s", err)
+		}
+		commitHash := packet.UnsignedTx.TxHash()
+		for _, in := range reveal.TxIn {
+			if in.PreviousOutPoint.Hash != commitHash {
+				return nil, invalid("reveal_tx", "doesn't spend the psbt tx %s", commitHash)
+			}
+		}
+		then = append(then, reveal)
+	}
+	txIds, err := s.Builder.Submit(packet, then...)
+	if err != nil {
+		return nil, err
+	}
+	return &SubmitResponse{TxIds: txIds}, nil
+}
+
+type Brc20DepositRequest struct {
+	InscriptionId    string `json:"inscription_id"`
+	TxId             string `json:"tx_id"`
+	Ticker           string `json:"ticker"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Amount           string `json:"amount"` // Decimal, e.g. 1.5
+	MinConfirmations int64  `json:"min_confirmations,omitempty"`
+	MinBlockHeight   int64  `json:"min_block_height,omitempty"`
+}
+
+type Brc20DepositResponse struct {
+	InscriptionId string             `json:"inscription_id"`
+	TxId          string             `json:"tx_id"`
+	Ticker        string             `json:"ticker"`
+	Amount        common.TokenAmount `json:"amount"` // Base units, 18 decimals
+	From          string             `json:"from"`
+	To            string             `json:"to"`
+	BlockHeight   int64              `json:"block_height,omitempty"`
+}
+
+// BRC-20 deposit verified against the brc20 sources
+func (s *Server) VerifyBrc20Deposit(ctx context.Context, req Brc20DepositRequest) (*Brc20DepositResponse, error) {
+	if _, err := inscriptions.GenesisOutPoint(req.InscriptionId); err != nil {
+		return nil, invalid("inscription_id", "%s", err)
+	}
+	if err := txId("tx_id", req.TxId); err != nil {
+		return nil, err
+	}
+	ticker := strings.ToLower(req.Ticker)
+	if err := brc20.ValidateTicker(ticker); err != nil {
+		return nil, invalid("ticker", "%s", err)
+	}
+	from, err := s.address("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.address("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := common.ParseTokenAmount(req.Amount, brc20.MaxDecimals)
+	if err != nil || amount.IsZero() {
+		return nil, invalid("amount", "%q should be a positive decimal with up to %d decimals", req.Amount, brc20.MaxDecimals)
+	}
+	if req.MinConfirmations < 0 {
+		return nil, invalid("min_confirmations", "can't be negative")
+	}
+	transfer, err := s.Brc20Deposits.Verify(ctx, brc20.VerifyBrc20DepositData{
+		InscriptionId:    req.InscriptionId,
+		TxId:             req.TxId,
+		Tick:             ticker,
+		FromWalletAddr:   from.EncodeAddress(),
+		ToWalletAddr:     to.EncodeAddress(),
+		Amount:           amount,
+		MinConfirmations: req.MinConfirmations,
+		MinBlockHeight:   req.MinBlockHeight,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Brc20DepositResponse{
+		InscriptionId: transfer.InscriptionId,
+		TxId:          transfer.TxId,
+		Ticker:        transfer.Tick,
+		Amount:        transfer.Amount,
+		From:          transfer.SourceWallet,
+		To:            transfer.SpentWallet,
+		BlockHeight:   transfer.BlockHeight,
+	}, nil
+}
+
+type RunesDepositRequest struct {
+	TxId         string `json:"tx_id"`
+	RuneId       string `json:"rune_id"`
+	From         string `json:"from,omitempty"` // Any sender is accepted when empty
+	To           string `json:"to"`
+	Amount       string `json:"amount"`                 // Decimal with up to divisibility decimals
+	Divisibility uint8  `json:"divisibility,omitempty"` // Of the rune, amount is in base units by default
+}
+
+// Runes deposit verified from the tx and the runes its inputs held
+func (s *Server) VerifyRunesDeposit(ctx context.Context, req RunesDepositRequest) (*runes.RunesDepositReceipt, error) {
+	if err := txId("tx_id", req.TxId); err != nil {
+		return nil, err
+	}
+	rune, err := runeId("rune_id", req.RuneId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.address("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := common.ParseTokenAmount(req.Amount, req.Divisibility)
+	if err != nil || amount.IsZero() {
+		return nil, invalid("amount", "%q should be a positive decimal with up to %d decimals", req.Amount, req.Divisibility)
+	}
+	deposit := runes.RunesDeposit{TxId: req.TxId, RuneId: rune.String(), ToAddr: to.EncodeAddress(), Amount: amount}
+	if req.From != "" {
+		from, err := s.address("from", req.From)
+		if err != nil {
+			return nil, err
+		}
+		deposit.FromAddr = from.EncodeAddress()
+	}
+	receipt, err := s.RunesDeposits.Verify(ctx, deposit)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
@@ -0,0 +1,246 @@
+package api
+
+// HTTP/JSON api of the service, for the services which used to shell out to the cli. Every endpoint
+// but /healthz and /openapi.yaml needs one of the api keys, errors are returned as
+// {"error": {"code", "message", "field"}}
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/txbuilder"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed openapi.yaml
+var OpenApiSpec []byte
+
+var ErrNoApiKeys = errors.New("no api key configured, see api.api_keys")
+
+type Brc20DepositVerifier interface {
+	Verify(ctx context.Context, data brc20.VerifyBrc20DepositData) (*client.Brc20Transfer, error)
+}
+
+type RunesDepositVerifier interface {
+	Verify(ctx context.Context, deposit runes.RunesDeposit) (*runes.RunesDepositReceipt, error)
+}
+
+var (
+	_ Brc20DepositVerifier = brc20.Brc20Verifier{}
+	_ RunesDepositVerifier = runes.RunesDepositVerifier{}
+)
+
+type Server struct {
+	Builder       *txbuilder.Builder // Its utxo & runes sources serve the balances too
+	Fees          client.FeeEstimator
+	Brc20         client.Brc20BalanceSource
+	Brc20Deposits Brc20DepositVerifier
+	RunesDeposits RunesDepositVerifier
+	Params        *chaincfg.Params
+	ApiKeys       []string
+	Timeout       time.Duration
+	MaxBodyBytes  int64
+	FeePriority   client.FeePriority // Of requests without a fee rate, or with "auto"
+	routes        []route
+}
+
+// Server of the api section, with the same sources & verifiers as the cli
+func New(c config.Config) (*Server, error) {
+	if len(c.ApiConfig.ApiKeys) == 0 {
+		return nil, ErrNoApiKeys
+	}
+	builder, err := txbuilder.New(c)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := client.NewFeeEstimator(c)
+	if err != nil {
+		return nil, err
+	}
+	priority, err := client.ParseFeePriority(c.FeeConfig.GetDefaultPriority())
+	if err != nil {
+		return nil, err
+	}
+	balances, err := brc20.NewBrc20Consensus(c)
+	if err != nil {
+		return nil, err
+	}
+	brc20Deposits, err := brc20.NewBrc20Verifier(c)
+	if err != nil {
+		return nil, err
+	}
+	runesDeposits, err := runes.NewRunesDepositVerifier(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		Builder:       builder,
+		Fees:          fees,
+		Brc20:         balances,
+		Brc20Deposits: brc20Deposits,
+		RunesDeposits: runesDeposits,
+		Params:        c.BtcConfig.GetChainConfigParams(),
+		ApiKeys:       c.ApiConfig.ApiKeys,
+		Timeout:       c.ApiConfig.GetTimeout(),
+		MaxBodyBytes:  c.ApiConfig.GetMaxBodyBytes(),
+		FeePriority:   priority,
+	}, nil
+}
+
+// Serve on the address until the context is done, letting the requests in flight finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), s.Timeout)
+		defer cancel()
+		done <- srv.Shutdown(shutdown)
+	}()
+	log.Info().Str("addr", addr).Msg("api listening")
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
+
+type handler func(w http.ResponseWriter, r *http.Request, params map[string]string) error
+
+type route struct {
+	method  string
+	pattern []string // Path segments, {name} ones are parameters
+	public  bool     // Served without an api key
+	handle  handler
+}
+
+func (s *Server) Handler() http.Handler {
+	s.routes = []route{
+		{http.MethodGet, split("/healthz"), true, s.health},
+		{http.MethodGet, split("/openapi.yaml"), true, s.openApi},
+		{http.MethodGet, split("/v1/fees"), false, s.fees},
+		{http.MethodGet, split("/v1/addresses/{address}/utxos"), false, s.utxos},
+		{http.MethodGet, split("/v1/addresses/{address}/brc20/{ticker}"), false, s.brc20Balance},
+		{http.MethodGet, split("/v1/addresses/{address}/runes"), false, s.runesBalances},
+		{http.MethodPost, split("/v1/psbt/btc"), false, jsonOp(s.BuildBtcSend)},
+		{http.MethodPost, split("/v1/psbt/brc20"), false, jsonOp(s.BuildBrc20Transfer)},
+		{http.MethodPost, split("/v1/psbt/runes"), false, jsonOp(s.BuildRunesTransfer)},
+		{http.MethodPost, split("/v1/psbt/inscription"), false, jsonOp(s.BuildInscription)},
+		{http.MethodPost, split("/v1/psbt/submit"), false, jsonOp(s.Submit)},
+		{http.MethodPost, split("/v1/deposits/brc20/verify"), false, jsonOp(s.VerifyBrc20Deposit)},
+		{http.MethodPost, split("/v1/deposits/runes/verify"), false, jsonOp(s.VerifyRunesDeposit)},
+	}
+	return http.HandlerFunc(s.serve)
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// Route parameters of the path, nil when it doesn't match
+func (r route) match(segments []string) map[string]string {
+	if len(segments) != len(r.pattern) {
+		return nil
+	}
+	params := make(map[string]string)
+	for i, p := range r.pattern {
+		if strings.HasPrefix(p, "{") {
+			if segments[i] == "" {
+				return nil
+			}
+			params[strings.Trim(p, "{}")] = segments[i]
+		} else if p != segments[i] {
+			return nil
+		}
+	}
+	return params
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		log.Info().Str("method", r.Method).Str("path", r.URL.Path).Int("status", rec.status).Dur("took", time.Since(start)).Msg("api request")
+	}()
+
+	segments := split(r.URL.Path)
+	allowed := make([]string, 0, 1)
+	for _, route := range s.routes {
+		params := route.match(segments)
+		if params == nil {
+			continue
+		}
+		if route.method != r.Method {
+			allowed = append(allowed, route.method)
+			continue
+		}
+		if !route.public && !s.authorized(r) {
+			writeError(rec, r, &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "missing or invalid api key"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.Timeout)
+		defer cancel()
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(rec, r.Body, s.MaxBodyBytes)
+		}
+		if err := route.handle(rec, r.WithContext(ctx), params); err != nil {
+			writeError(rec, r, err)
+		}
+		return
+	}
+	if len(allowed) > 0 {
+		rec.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(rec, r, &Error{Status: http.StatusMethodNotAllowed, Code: CodeMethodNotAllowed, Message: r.Method + " isn't allowed on " + r.URL.Path})
+		return
+	}
+	writeError(rec, r, &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "no endpoint at " + r.URL.Path})
+}
+
+// Api key of the X-API-Key header or of a bearer token
+func (s *Server) authorized(r *http.Request) bool {
+	key := r.Header.Get("X-API-Key")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); key == "" && ok {
+		key = strings.TrimSpace(bearer)
+	}
+	return s.ValidKey(key)
+}
+
+// Whether the key is one of the api keys
+func (s *Server) ValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	valid := false
+	for _, k := range s.ApiKeys {
+		// Every key is compared, so that the timing doesn't tell which one matched
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/internal/testutil"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/txbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "0123456789abcdef0123"
+
+type fixture struct {
+	server *Server
+	url    string
+	from   string
+	to     string
+}
+
+func newFixture(t *testing.T) *fixture {
+	// One output of 20000 sats to the sender
+	w := testutil.NewWallet(t)
+	w.Chain.SendErr = &btcjson.RPCError{Code: btcjson.ErrRPCVerifyRejected, Message: "bad-txns"}
+	w.Fund(t, 20000)
+
+	held := testutil.Runes{
+		{Outpoint: chainhash.Hash{2}.String() + ":0", Runes: []client.RuneBalance{{RuneId: "840000:3", RuneName: "DOG•GO•TO•THE•MOON", Amount: common.NewTokenAmountFromInt64(300, 0)}}},
+		{Outpoint: chainhash.Hash{3}.String() + ":1", Runes: []client.RuneBalance{{RuneId: "840000:3", Amount: common.NewTokenAmountFromInt64(200, 0)}, {RuneId: "1:0", Amount: common.NewTokenAmountFromInt64(7, 0)}}},
+	}
+	cfg := config.Config{BtcConfig: config.BtcConfig{ChainConfig: string(config.NetworkRegtest)}}
+	s := &Server{
+		Builder:       &txbuilder.Builder{Chain: w.Chain, Utxos: w.Utxos, Runes: held, Params: &chaincfg.RegressionNetParams, Config: cfg},
+		Fees:          client.StaticFeeEstimator(3),
+		Brc20:         testutil.Brc20{"ordi": {OverallBalance: "1500000000000000000", AvailableBalance: "500000000000000000", BlockHeight: 100}},
+		Brc20Deposits: testutil.Brc20Deposits{},
+		RunesDeposits: testutil.RunesDeposits{Err: fmt.Errorf("%w: 0 of 1", runes.ErrNotEnoughConfirmations)},
+		Params:        &chaincfg.RegressionNetParams,
+		ApiKeys:       []string{"another key of the api", testKey},
+		Timeout:       5 * time.Second,
+		MaxBodyBytes:  4096,
+		FeePriority:   client.FeePriorityHalfHour,
+	}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return &fixture{server: s, url: srv.URL, from: w.From.EncodeAddress(), to: w.To.EncodeAddress()}
+}
+
+// Send a request with the api key, decoding the json answer into res
+func (f *fixture) do(t *testing.T, method, path string, body any, res any) int {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, f.url+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", testKey)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	if res != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+	}
+	return resp.StatusCode
+}
+
+type errorBody struct {
+	Error Error `json:"error"`
+}
+
+func TestAuth(t *testing.T) {
+	f := newFixture(t)
+
+	resp, err := http.Get(f.url + "/v1/fees")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+
+	req, err := http.NewRequest(http.MethodGet, f.url+"/v1/fees", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testKey)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req.Header.Set("Authorization", "Bearer "+testKey+"x")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Every route is documented, the spec itself is public
+	resp, err = http.Get(f.url + "/openapi.yaml")
+	require.NoError(t, err)
+	spec, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	for _, r := range f.server.routes {
+		path := "/" + strings.Join(r.pattern, "/")
+		require.Contains(t, string(spec), "\n  "+path+":\n", "%s isn't documented", path)
+	}
+}
+
+func TestServer(t *testing.T) {
+	f := newFixture(t)
+
+	var e errorBody
+	require.Equal(t, http.StatusNotFound, f.do(t, http.MethodGet, "/v1/nothing", nil, &e))
+	require.Equal(t, CodeNotFound, e.Error.Code)
+	require.Equal(t, http.StatusMethodNotAllowed, f.do(t, http.MethodGet, "/v1/psbt/btc", nil, &e))
+	require.Equal(t, CodeMethodNotAllowed, e.Error.Code)
+
+	var fees FeesResponse
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodGet, "/v1/fees", nil, &fees))
+	require.Equal(t, FeesResponse{Fastest: 3, HalfHour: 3, Hour: 3, Economy: 3, Default: "halfhour"}, fees)
+
+	var utxos []Utxo
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodGet, "/v1/addresses/"+f.from+"/utxos", nil, &utxos))
+	require.Len(t, utxos, 1)
+	require.Equal(t, uint64(20000), utxos[0].Value)
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodGet, "/v1/addresses/bc1qnotanaddress/utxos", nil, &e))
+	require.Equal(t, "address", e.Error.Field)
+
+	var balance map[string]any
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodGet, "/v1/addresses/"+f.from+"/brc20/ORDI", nil, &balance))
+	require.Equal(t, "ordi", balance["ticker"])
+	require.Equal(t, "500000000000000000", balance["available_balance"])
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodGet, "/v1/addresses/"+f.from+"/brc20/or", nil, &e))
+	require.Equal(t, "ticker", e.Error.Field)
+	require.Equal(t, http.StatusNotFound, f.do(t, http.MethodGet, "/v1/addresses/"+f.from+"/brc20/sats", nil, &e))
+
+	var runeBalances []map[string]any
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodGet, "/v1/addresses/"+f.from+"/runes", nil, &runeBalances))
+	require.Equal(t, []map[string]any{
+		{"rune_id": "1:0", "amount": "7"},
+		{"rune_id": "840000:3", "rune_name": "DOG•GO•TO•THE•MOON", "amount": "500"},
+	}, runeBalances)
+}
+
+func TestBuildAndSubmit(t *testing.T) {
+	f := newFixture(t)
+	var e errorBody
+
+	var unsigned PsbtResponse
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodPost, "/v1/psbt/btc", map[string]any{"from": f.from, "to": f.to, "amount": 5000, "fee_rate": 2}, &unsigned))
+	require.Equal(t, uint64(2), unsigned.FeeRate)
+	require.Equal(t, 2*unsigned.VSize, unsigned.Fee)
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(unsigned.Psbt), true)
+	require.NoError(t, err)
+	require.Equal(t, int64(5000), packet.UnsignedTx.TxOut[0].Value)
+
+	// The default priority is estimated
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodPost, "/v1/psbt/btc", map[string]any{"from": f.from, "to": f.to, "amount": 5000}, &unsigned))
+	require.Equal(t, uint64(3), unsigned.FeeRate)
+
+	for _, c := range []struct {
+		body  any
+		code  string
+		field string
+	}{
+		{map[string]any{"from": f.from, "to": "nope", "amount": 5000}, CodeInvalidRequest, "to"},
+		{map[string]any{"from": f.from, "to": f.to, "amount": "5000"}, CodeInvalidRequest, "amount"},
+		{map[string]any{"from": f.from, "to": f.to, "amount": 5000, "fee_rate": "soon"}, CodeInvalidRequest, "fee_rate"},
+		{map[string]any{"from": f.from, "to": f.to, "amount": 5000, "memo": "hi"}, CodeInvalidRequest, ""},
+		{map[string]any{"from": f.from, "to": f.to, "amount": 100}, CodeInvalidRequest, ""},
+		{map[string]any{"from": f.from, "to": f.to, "amount": 50000}, CodeInsufficientFunds, ""},
+		{map[string]any{"from": f.from, "to": f.to, "amount": 5000, "padding": strings.Repeat("x", 5000)}, CodeRequestTooLarge, ""},
+	} {
+		e = errorBody{}
+		require.NotEqual(t, http.StatusOK, f.do(t, http.MethodPost, "/v1/psbt/btc", c.body, &e), "%v", c.body)
+		require.Equal(t, c.code, e.Error.Code, "%v", c.body)
+		require.Equal(t, c.field, e.Error.Field, "%v", c.body)
+	}
+
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/psbt/runes", map[string]any{"from": f.from, "to": f.to, "rune_id": "840000", "amount": "1"}, &e))
+	require.Equal(t, "rune_id", e.Error.Field)
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/psbt/brc20", map[string]any{"from": f.from, "to": f.to, "inscription_id": "abc"}, &e))
+	require.Equal(t, "inscription_id", e.Error.Field)
+
+	var inscription InscriptionResponse
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodPost, "/v1/psbt/inscription", map[string]any{
+		"from": f.from, "to": f.to, "fee_rate": "fast",
+		"inscriptions": []map[string]string{{"content_type": "text/plain;charset=utf-8", "content": "hello"}, {"content_type": "image/png", "content_base64": "iVBORw0KGgo="}},
+	}, &inscription))
+	require.Equal(t, []string{inscription.RevealTxId + "i0", inscription.RevealTxId + "i1"}, inscription.InscriptionIds)
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/psbt/inscription", map[string]any{
+		"from": f.from, "to": f.to, "inscriptions": []map[string]string{{"content": "hello"}},
+	}, &e))
+	require.Equal(t, "inscriptions[0].content_type", e.Error.Field)
+
+	// Unsigned PSBTs can't be finalized, the node rejects the signed ones of this test
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/psbt/submit", map[string]any{"psbt": inscription.Psbt, "reveal_tx": inscription.RevealTx}, &e))
+	require.Equal(t, CodeNotFinalized, e.Error.Code)
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/psbt/submit", map[string]any{"psbt": unsigned.Psbt, "reveal_tx": inscription.RevealTx}, &e))
+	require.Equal(t, "reveal_tx", e.Error.Field)
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/psbt/submit", map[string]any{"psbt": "cHNidP8"}, &e))
+	require.Equal(t, "psbt", e.Error.Field)
+}
+
+func TestVerifyDeposits(t *testing.T) {
+	f := newFixture(t)
+	var e errorBody
+	txId := chainhash.Hash{9}.String()
+	deposit := map[string]any{"inscription_id": txId + "i0", "tx_id": txId, "ticker": "ordi", "from": f.from, "to": f.to, "amount": "1.5"}
+
+	var verified Brc20DepositResponse
+	require.Equal(t, http.StatusOK, f.do(t, http.MethodPost, "/v1/deposits/brc20/verify", deposit, &verified))
+	require.Equal(t, "1500000000000000000", verified.Amount.Int().String())
+	require.Equal(t, f.to, verified.To)
+
+	f.server.Brc20Deposits = testutil.Brc20Deposits{Err: fmt.Errorf("%w: amount mismatch", brc20.ErrInvalidBrc20Transfer)}
+	require.Equal(t, http.StatusUnprocessableEntity, f.do(t, http.MethodPost, "/v1/deposits/brc20/verify", deposit, &e))
+	require.Equal(t, CodeDepositMismatch, e.Error.Code)
+	f.server.Brc20Deposits = testutil.Brc20Deposits{Err: client.ErrIndexerBehind}
+	require.Equal(t, http.StatusServiceUnavailable, f.do(t, http.MethodPost, "/v1/deposits/brc20/verify", deposit, &e))
+	require.Equal(t, CodeIndexerBehind, e.Error.Code)
+	f.server.Brc20Deposits = testutil.Brc20Deposits{Err: fmt.Errorf("disk on fire")}
+	require.Equal(t, http.StatusInternalServerError, f.do(t, http.MethodPost, "/v1/deposits/brc20/verify", deposit, &e))
+	require.Equal(t, CodeInternal, e.Error.Code)
+	require.NotContains(t, e.Error.Message, "fire")
+
+	deposit["amount"] = "1.5.0"
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/deposits/brc20/verify", deposit, &e))
+	require.Equal(t, "amount", e.Error.Field)
+
+	runesDeposit := map[string]any{"tx_id": txId, "rune_id": "840000:3", "to": f.to, "amount": "100"}
+	require.Equal(t, http.StatusConflict, f.do(t, http.MethodPost, "/v1/deposits/runes/verify", runesDeposit, &e))
+	require.Equal(t, CodeNotConfirmed, e.Error.Code)
+	runesDeposit["amount"] = "1.5"
+	require.Equal(t, http.StatusBadRequest, f.do(t, http.MethodPost, "/v1/deposits/runes/verify", runesDeposit, &e))
+	require.Equal(t, "amount", e.Error.Field)
+}
@@ -0,0 +1,94 @@
+package brc20
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+)
+
+// Indexers keep every amount as an integer with 18 decimals
+const MaxDecimals = 18
+
+var (
+	ErrInvalidAmount   = common.ErrInvalidTokenAmount
+	ErrTooManyDecimals = common.ErrTooManyDecimals
+	ErrAmountOverflow  = errors.New("amount exceeds the max brc20 value")
+	ErrInvalidDecimals = errors.New("decimals should be between 0 and 18")
+	ErrInvalidTicker   = errors.New("ticker should be 4 or 5 bytes")
+	ErrSelfMintTicker  = errors.New("5 byte tickers have to be deployed with self_mint")
+	ErrInvalidLimit    = errors.New("limit should be positive and not more than max")
+	ErrAboveMintLimit  = errors.New("amount exceeds the limit per mint of the ticker")
+)
+
+// (2^64 - 1) * 10^18, the largest value the indexers accept
+var MaxAmount = common.NewTokenAmount(new(big.Int).Mul(new(big.Int).SetUint64(^uint64(0)), new(big.Int).Exp(big.NewInt(10), big.NewInt(MaxDecimals), nil)), MaxDecimals)
+
+// BRC-20 amount, always expressed with 18 decimals
+type Amount = common.TokenAmount
+
+// Parse a decimal string the way the indexers do. Only digits and at most one dot are allowed,
+// the dot can't be the first or last character and the fraction can't be longer than decimals
+func ParseAmount(s string, decimals uint8) (Amount, error) {
+	if decimals > MaxDecimals {
+		return Amount{}, ErrInvalidDecimals
+	}
+	amt, err := common.ParseTokenAmount(s, decimals)
+	if err != nil {
+		return Amount{}, err
+	}
+	amt, _ = amt.Rescale(MaxDecimals)
+	if amt.Cmp(MaxAmount) > 0 {
+		return Amount{}, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	}
+	return amt, nil
+}
+
+// Amount from the 18 decimal integer returned by the indexers
+func NewAmountFromInt(val *big.Int) Amount {
+	return common.NewTokenAmount(val, MaxDecimals)
+}
+
+// Tickers are 4 or 5 bytes long, the length being checked on the utf-8 bytes
+func ValidateTicker(ticker string) error {
+	if l := len([]byte(ticker)); l != 4 && l != 5 {
+		return fmt.Errorf("%w: %q is %d bytes", ErrInvalidTicker, ticker, l)
+	}
+	return nil
+}
+
+// Amounts of mint & transfer inscriptions have to be positive and within the max value
+func validateOpAmount(ticker string, amt Amount) error {
+	if err := ValidateTicker(ticker); err != nil {
+		return err
+	}
+	if amt.Sign() <= 0 {
+		return fmt.Errorf("%w: amount should be positive", ErrInvalidAmount)
+	}
+	if amt.Cmp(MaxAmount) > 0 {
+		return ErrAmountOverflow
+	}
+	return nil
+}
+
+// Amounts have to fit the decimals of the deploy, mints being within its limit. The indexers ignore
+// the operations which don't, so these are refused before anything is inscribed
+func validateDeployedAmount(ctx context.Context, source client.Brc20TickerSource, op, ticker string, amt Amount) error {
+	if err := validateOpAmount(ticker, amt); err != nil {
+		return err
+	}
+	info, err := source.GetBrc20TickerInfo(ctx, ticker)
+	if err != nil {
+		return fmt.Errorf("error looking up the deploy of %s: %w", ticker, err)
+	}
+	if info.Decimals >= 0 && info.Decimals <= MaxDecimals && !amt.FitsDecimals(uint8(info.Decimals)) {
+		return fmt.Errorf("%w: %s has %d decimals, got %s", ErrTooManyDecimals, ticker, info.Decimals, amt)
+	}
+	if op == "mint" && info.LimitPerMint.Sign() > 0 && amt.Cmp(info.LimitPerMint) > 0 {
+		return fmt.Errorf("%w: %s mints up to %s, got %s", ErrAboveMintLimit, ticker, info.LimitPerMint, amt)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package brc20
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ordinox/btc-service/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	valid := map[string]string{
+		"1":                    "1000000000000000000",
+		"0.5":                  "500000000000000000",
+		"10.00001":             "10000010000000000000",
+		"007":                  "7000000000000000000",
+		"0.000000000000000001": "1",
+	}
+	for in, raw := range valid {
+		amt, err := ParseAmount(in, MaxDecimals)
+		require.NoError(t, err, in)
+		require.Equal(t, raw, amt.Int().String(), in)
+	}
+
+	invalid := []string{"", ".5", "5.", "1.2.3", "-1", "1e5", " 1", "0x10"}
+	for _, in := range invalid {
+		_, err := ParseAmount(in, MaxDecimals)
+		require.ErrorIs(t, err, ErrInvalidAmount, in)
+	}
+
+	_, err := ParseAmount("1.123", 2)
+	require.ErrorIs(t, err, ErrTooManyDecimals)
+
+	_, err = ParseAmount("18446744073709551616", MaxDecimals)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestAmountString(t *testing.T) {
+	for _, in := range []string{"1", "0.5", "10.00001", "0.000000000000000001", "18446744073709551615"} {
+		amt, err := ParseAmount(in, MaxDecimals)
+		require.NoError(t, err)
+		require.Equal(t, in, amt.String())
+	}
+}
+
+func TestDeployValidation(t *testing.T) {
+	max, _ := ParseAmount("21000000", MaxDecimals)
+	lim, _ := ParseAmount("1000", MaxDecimals)
+	dec := uint8(2)
+
+	d, err := newDeploy("ordi", max, DeployOptions{Limit: &lim, Decimals: &dec})
+	require.NoError(t, err)
+	bz, _ := json.Marshal(d)
+	require.JSONEq(t, `{"p":"brc-20","op":"deploy","tick":"ordi","max":"21000000","lim":"1000","dec":"2"}`, string(bz))
+
+	_, err = newDeploy("ord", max, DeployOptions{})
+	require.ErrorIs(t, err, ErrInvalidTicker)
+
+	_, err = newDeploy("ordix", max, DeployOptions{})
+	require.ErrorIs(t, err, ErrSelfMintTicker)
+
+	_, err = newDeploy("ordix", max, DeployOptions{SelfMint: true})
+	require.NoError(t, err)
+
+	tooPrecise, _ := ParseAmount("0.001", MaxDecimals)
+	_, err = newDeploy("ordi", max, DeployOptions{Limit: &tooPrecise, Decimals: &dec})
+	require.ErrorIs(t, err, ErrTooManyDecimals)
+
+	_, err = newDeploy("ordi", lim, DeployOptions{Limit: &max})
+	require.ErrorIs(t, err, ErrInvalidLimit)
+}
+
+type fakeTickers map[string]*client.Brc20TickerInfo
+
+func (f fakeTickers) GetBrc20TickerInfo(ctx context.Context, ticker string) (*client.Brc20TickerInfo, error) {
+	info, ok := f[ticker]
+	if !ok {
+		return nil, client.ErrNotFound
+	}
+	return info, nil
+}
+
+// Mints & transfers the indexers would ignore are refused
+func TestDeployedAmountValidation(t *testing.T) {
+	ctx := context.Background()
+	amount := func(s string) Amount {
+		amt, err := ParseAmount(s, MaxDecimals)
+		require.NoError(t, err)
+		return amt
+	}
+	tickers := fakeTickers{"ordi": {Tick: "ordi", Decimals: 2, LimitPerMint: amount("1000"), MaxSupply: amount("21000000")}}
+
+	require.NoError(t, validateDeployedAmount(ctx, tickers, "mint", "ordi", amount("1000")))
+	require.NoError(t, validateDeployedAmount(ctx, tickers, "transfer", "ordi", amount("5000.25")))
+	require.ErrorIs(t, validateDeployedAmount(ctx, tickers, "mint", "ordi", amount("1000.01")), ErrAboveMintLimit)
+	require.ErrorIs(t, validateDeployedAmount(ctx, tickers, "mint", "ordi", amount("0.001")), ErrTooManyDecimals)
+	require.ErrorIs(t, validateDeployedAmount(ctx, tickers, "transfer", "ordi", amount("1.125")), ErrTooManyDecimals)
+	require.ErrorIs(t, validateDeployedAmount(ctx, tickers, "transfer", "sats", amount("1")), client.ErrNotFound)
+	require.ErrorIs(t, validateDeployedAmount(ctx, tickers, "transfer", "ordi", Amount{}), ErrInvalidAmount)
+}
@@ -0,0 +1,271 @@
+package brc20
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/config"
+)
+
+var (
+	ErrIndexersDisagree = errors.New("brc20 indexers disagree")
+	ErrNoQuorum         = errors.New("not enough brc20 indexers agree")
+)
+
+type ConsensusStatus string
+
+const (
+	ConsensusAgreement    ConsensusStatus = "agreement"    // Every source answered the same
+	ConsensusPartial      ConsensusStatus = "partial"      // The sources which answered agree, others failed
+	ConsensusDisagreement ConsensusStatus = "disagreement" // Sources answered differently
+)
+
+// Indexer taking part in the consensus
+type NamedSource struct {
+	Name   string
+	Source client.Brc20EventSource
+}
+
+// Answer of a single source
+type Vote[T any] struct {
+	Source    string
+	Value     T
+	Height    int64 // Last block processed by the source, 0 when it doesn't report it
+	Err       error
+	Abstained bool // The source can't answer the lookup, e.g. OPI without an inscription id
+	key       string
+}
+
+// Answers of all the sources, compared once normalized
+type Consensus[T any] struct {
+	Status   ConsensusStatus
+	Value    T        // Answer of the largest group of agreeing sources
+	Height   int64    // Lowest height reported by that group, so confirmations are never over counted
+	Agreeing []string // Sources of that group
+	Votes    []Vote[T]
+}
+
+// Fail unless at least quorum sources agree and none disagrees
+func (c Consensus[T]) Check(quorum int) error {
+	if c.Status == ConsensusDisagreement {
+		return fmt.Errorf("%w: %s", ErrIndexersDisagree, c.Details())
+	}
+	if len(c.Agreeing) < quorum {
+		return fmt.Errorf("%w: %d of %d required, %s", ErrNoQuorum, len(c.Agreeing), quorum, c.Details())
+	}
+	return nil
+}
+
+// Answer of every source, on a single line
+func (c Consensus[T]) Details() string {
+	details := make([]string, len(c.Votes))
+	for i, v := range c.Votes {
+		switch {
+		case v.Abstained:
+			details[i] = v.Source + " abstained"
+		case v.Err != nil:
+			details[i] = fmt.Sprintf("%s failed (%s)", v.Source, v.Err)
+		default:
+			details[i] = fmt.Sprintf("%s at %d: %s", v.Source, v.Height, v.key)
+		}
+	}
+	return strings.Join(details, "; ")
+}
+
+// Group the answers by key
+func tally[T any](votes []Vote[T], key func(T) string) Consensus[T] {
+	groups := make(map[string][]int)
+	var order []string
+	failed := false
+	for i := range votes {
+		if votes[i].Abstained {
+			continue
+		}
+		if votes[i].Err != nil {
+			failed = true
+			continue
+		}
+		votes[i].key = key(votes[i].Value)
+		if _, ok := groups[votes[i].key]; !ok {
+			order = append(order, votes[i].key)
+		}
+		groups[votes[i].key] = append(groups[votes[i].key], i)
+	}
+
+	res := Consensus[T]{Status: ConsensusAgreement, Votes: votes}
+	if len(groups) > 1 {
+		res.Status = ConsensusDisagreement
+	} else if failed || len(groups) == 0 {
+		res.Status = ConsensusPartial
+	}
+	if len(order) == 0 {
+		return res
+	}
+	best := order[0]
+	for _, k := range order[1:] {
+		if len(groups[k]) > len(groups[best]) {
+			best = k
+		}
+	}
+	res.Value = votes[groups[best][0]].Value
+	for _, i := range groups[best] {
+		res.Agreeing = append(res.Agreeing, votes[i].Source)
+		if res.Height == 0 || (votes[i].Height > 0 && votes[i].Height < res.Height) {
+			res.Height = votes[i].Height
+		}
+	}
+	return res
+}
+
+// Query every source at once
+func poll[T any](sources []NamedSource, query func(source client.Brc20EventSource) Vote[T]) []Vote[T] {
+	votes := make([]Vote[T], len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source NamedSource) {
+			defer wg.Done()
+			votes[i] = query(source.Source)
+			votes[i].Source = source.Name
+		}(i, source)
+	}
+	wg.Wait()
+	return votes
+}
+
+// Brc20EventSource answering with the transfers a quorum of indexers agree on.
+// Any disagreement fails the lookup, however many sources agree
+type Brc20Consensus struct {
+	Sources []NamedSource
+	Quorum  int // Sources which have to answer & agree, defaults to all of them
+}
+
+var (
+	_ client.Brc20EventSource   = Brc20Consensus{}
+	_ client.Brc20BalanceSource = Brc20Consensus{}
+)
+
+// Consensus of the indexers listed in brc20.sources. Local indexers can be added to the sources afterwards
+func NewBrc20Consensus(config config.Config) (Brc20Consensus, error) {
+	names := config.GetBrc20Sources()
+	sources := make([]NamedSource, len(names))
+	for i, name := range names {
+		source, err := newNamedBrc20Source(name, config)
+		if err != nil {
+			return Brc20Consensus{}, err
+		}
+		sources[i] = NamedSource{Name: name, Source: source}
+	}
+	return Brc20Consensus{Sources: sources, Quorum: config.GetBrc20Quorum()}, nil
+}
+
+func (c Brc20Consensus) quorum() int {
+	if c.Quorum <= 0 {
+		return len(c.Sources)
+	}
+	return c.Quorum
+}
+
+// Transfers of every source. Sources which can't look up by the given id abstain
+func (c Brc20Consensus) CheckTransfers(ctx context.Context, txId, inscriptionId string) Consensus[[]client.Brc20Transfer] {
+	votes := poll(c.Sources, func(source client.Brc20EventSource) Vote[[]client.Brc20Transfer] {
+		res, err := source.GetBrc20Transfers(ctx, txId, inscriptionId)
+		if errors.Is(err, client.ErrTxIdRequired) || errors.Is(err, client.ErrInscriptionIdRequired) {
+			return Vote[[]client.Brc20Transfer]{Abstained: true}
+		}
+		if err != nil {
+			return Vote[[]client.Brc20Transfer]{Err: err}
+		}
+		sort.Slice(res.Transfers, func(a, b int) bool {
+			return transferKey(res.Transfers[a]) < transferKey(res.Transfers[b])
+		})
+		return Vote[[]client.Brc20Transfer]{Value: res.Transfers, Height: res.BlockHeight}
+	})
+	res := tally(votes, func(transfers []client.Brc20Transfer) string {
+		keys := make([]string, len(transfers))
+		for i, t := range transfers {
+			keys[i] = transferKey(t)
+		}
+		return "[" + strings.Join(keys, ", ") + "]"
+	})
+	res.Value = mergeTransferHeights(res)
+	return res
+}
+
+// Transfers agreed on, each at the highest block a source reports for it
+func mergeTransferHeights(res Consensus[[]client.Brc20Transfer]) []client.Brc20Transfer {
+	merged := make([]client.Brc20Transfer, len(res.Value))
+	copy(merged, res.Value)
+	for _, v := range res.Votes {
+		if v.Err != nil || v.Abstained || !slices.Contains(res.Agreeing, v.Source) {
+			continue
+		}
+		for i, t := range v.Value {
+			merged[i].BlockHeight = max(merged[i].BlockHeight, t.BlockHeight)
+		}
+	}
+	return merged
+}
+
+// Fields every indexer reports, normalized. Amounts compare by value, block heights & pkScripts are left out
+func transferKey(t client.Brc20Transfer) string {
+	return fmt.Sprintf("%s %s %s %s %s->%s", t.InscriptionId, t.TxId, strings.ToLower(t.Tick), t.Amount,
+		normalizeWallet(t.SourceWallet), normalizeWallet(t.SpentWallet))
+}
+
+// Bech32 addresses are case insensitive
+func normalizeWallet(wallet string) string {
+	if sameWallet(wallet, strings.ToLower(wallet)) {
+		return strings.ToLower(wallet)
+	}
+	return wallet
+}
+
+func (c Brc20Consensus) GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*client.Brc20Transfers, error) {
+	res := c.CheckTransfers(ctx, txId, inscriptionId)
+	if err := res.Check(c.quorum()); err != nil {
+		return nil, err
+	}
+	return &client.Brc20Transfers{Transfers: res.Value, BlockHeight: res.Height}, nil
+}
+
+func (c Brc20Consensus) GetBrc20Balance(ctx context.Context, address, ticker string) (*client.Brc20Balance, error) {
+	res := c.CheckBalance(ctx, address, ticker)
+	if err := res.Check(c.quorum()); err != nil {
+		return nil, err
+	}
+	return &res.Value, nil
+}
+
+// Balance of every source which serves balances, the others abstain.
+// Sources at different heights can legitimately disagree, the heights are part of the votes
+func (c Brc20Consensus) CheckBalance(ctx context.Context, address, ticker string) Consensus[client.Brc20Balance] {
+	votes := poll(c.Sources, func(source client.Brc20EventSource) Vote[client.Brc20Balance] {
+		balances, ok := source.(client.Brc20BalanceSource)
+		if !ok {
+			return Vote[client.Brc20Balance]{Abstained: true}
+		}
+		res, err := balances.GetBrc20Balance(ctx, address, ticker)
+		if err != nil {
+			return Vote[client.Brc20Balance]{Err: err}
+		}
+		return Vote[client.Brc20Balance]{Value: *res, Height: int64(res.BlockHeight)}
+	})
+	return tally(votes, func(b client.Brc20Balance) string {
+		return normalizeAmount(b.OverallBalance) + "/" + normalizeAmount(b.AvailableBalance)
+	})
+}
+
+func normalizeAmount(amount string) string {
+	if value, ok := new(big.Int).SetString(amount, 10); ok {
+		return value.String()
+	}
+	return amount
+}
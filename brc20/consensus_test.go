@@ -0,0 +1,95 @@
+package brc20
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ordinox/btc-service/client"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSource struct{ err error }
+
+func (s failingSource) GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*client.Brc20Transfers, error) {
+	return nil, s.err
+}
+
+type balanceSource struct {
+	staticSource
+	balance client.Brc20Balance
+}
+
+func (s balanceSource) GetBrc20Balance(ctx context.Context, address, ticker string) (*client.Brc20Balance, error) {
+	return &s.balance, nil
+}
+
+func TestBrc20Consensus(t *testing.T) {
+	ctx := context.Background()
+	amount, _ := new(big.Int).SetString("5000000000000000000", 10)
+	transfer := client.Brc20Transfer{
+		InscriptionId: "inscription",
+		TxId:          "tx",
+		Tick:          "ordi",
+		Amount:        NewAmountFromInt(amount),
+		SourceWallet:  "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080",
+		SpentWallet:   "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+	}
+	// Same transfer as reported by another indexer, which also knows its block
+	other := transfer
+	other.Tick, other.SourceWallet, other.BlockHeight = "ORDI", "BCRT1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KYGT080", 90
+	opi := staticSource{Transfers: []client.Brc20Transfer{transfer}, BlockHeight: 102}
+	bis := staticSource{Transfers: []client.Brc20Transfer{other}, BlockHeight: 100}
+
+	consensus := Brc20Consensus{Sources: []NamedSource{{"opi", opi}, {"bis", bis}}}
+	res := consensus.CheckTransfers(ctx, "tx", "inscription")
+	require.Equal(t, ConsensusAgreement, res.Status)
+	require.Equal(t, []string{"opi", "bis"}, res.Agreeing)
+	require.Equal(t, int64(100), res.Height)
+	require.Equal(t, int64(90), res.Value[0].BlockHeight)
+	transfers, err := consensus.GetBrc20Transfers(ctx, "tx", "inscription")
+	require.NoError(t, err)
+	require.Len(t, transfers.Transfers, 1)
+
+	// A failing source only counts against the quorum
+	down := failingSource{errors.New("503 Service Unavailable")}
+	consensus = Brc20Consensus{Sources: []NamedSource{{"opi", opi}, {"bis", down}}, Quorum: 1}
+	res = consensus.CheckTransfers(ctx, "tx", "inscription")
+	require.Equal(t, ConsensusPartial, res.Status)
+	require.NoError(t, res.Check(1))
+	require.ErrorIs(t, res.Check(2), ErrNoQuorum)
+	require.Contains(t, res.Details(), "bis failed (503 Service Unavailable)")
+
+	// So does a source which can't look the transfer up
+	consensus.Sources[1].Source = failingSource{client.ErrTxIdRequired}
+	res = consensus.CheckTransfers(ctx, "", "inscription")
+	require.True(t, res.Votes[1].Abstained)
+	require.ErrorIs(t, res.Check(2), ErrNoQuorum)
+
+	// Any disagreement fails, whatever the quorum
+	wrongAmount := transfer
+	wrongAmount.Amount = NewAmountFromInt(big.NewInt(5))
+	consensus = Brc20Consensus{Sources: []NamedSource{
+		{"opi", opi},
+		{"bis", bis},
+		{"local", staticSource{Transfers: []client.Brc20Transfer{wrongAmount}}},
+	}, Quorum: 2}
+	res = consensus.CheckTransfers(ctx, "tx", "inscription")
+	require.Equal(t, ConsensusDisagreement, res.Status)
+	require.Equal(t, []string{"opi", "bis"}, res.Agreeing)
+	_, err = consensus.GetBrc20Transfers(ctx, "tx", "inscription")
+	require.ErrorIs(t, err, ErrIndexersDisagree)
+
+	// Sources without balances abstain, amounts are compared by value
+	consensus = Brc20Consensus{Sources: []NamedSource{
+		{"opi", balanceSource{balance: client.Brc20Balance{OverallBalance: "10", AvailableBalance: "5", BlockHeight: 840000}}},
+		{"bis", bis},
+		{"local", balanceSource{balance: client.Brc20Balance{OverallBalance: "010", AvailableBalance: "5", BlockHeight: 839999}}},
+	}}
+	balance := consensus.CheckBalance(ctx, "bc1q", "ordi")
+	require.Equal(t, ConsensusAgreement, balance.Status)
+	require.Equal(t, []string{"opi", "local"}, balance.Agreeing)
+	require.Equal(t, int64(839999), balance.Height)
+	require.NoError(t, balance.Check(2))
+}
@@ -1,34 +1,88 @@
 package brc20
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/inscriptions"
-	"github.com/ordinox/btc-service/taproot"
 )
 
 type deploy struct {
-	P    string `json:"p"`
-	Op   string `json:"op"`
-	Tick string `json:"tick"`
-	Max  string `json:"max"`
-	Lim  string `json:"lim"`
+	P        string `json:"p"`
+	Op       string `json:"op"`
+	Tick     string `json:"tick"`
+	Max      string `json:"max"`
+	Lim      string `json:"lim"`
+	Dec      string `json:"dec,omitempty"`
+	SelfMint string `json:"self_mint,omitempty"`
 }
 
-func InscribeDeploy(ticker string, cap uint, privateKey *btcec.PrivateKey, receiver btcutil.Address, feeRate uint64, config config.Config) (*inscriptions.SingleInscriptionResult, error) {
-	deploy := deploy{
+type DeployOptions struct {
+	Limit    *Amount // Max amount per mint, defaults to max
+	Decimals *uint8  // Defaults to 18
+	SelfMint bool    // Only the deployer can mint, required for 5 byte tickers
+}
+
+// Build the deploy op, rejecting anything the indexers would ignore
+func newDeploy(ticker string, max Amount, opts DeployOptions) (*deploy, error) {
+	if err := ValidateTicker(ticker); err != nil {
+		return nil, err
+	}
+	if len([]byte(ticker)) == 5 && !opts.SelfMint {
+		return nil, ErrSelfMintTicker
+	}
+
+	decimals := uint8(MaxDecimals)
+	if opts.Decimals != nil {
+		decimals = *opts.Decimals
+	}
+	if decimals > MaxDecimals {
+		return nil, ErrInvalidDecimals
+	}
+
+	if max.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: max should be positive", ErrInvalidAmount)
+	}
+	if max.Cmp(MaxAmount) > 0 {
+		return nil, ErrAmountOverflow
+	}
+	if !max.FitsDecimals(decimals) {
+		return nil, fmt.Errorf("%w: max %s with %d decimals", ErrTooManyDecimals, max, decimals)
+	}
+
+	limit := max
+	if opts.Limit != nil {
+		limit = *opts.Limit
+	}
+	if limit.Sign() <= 0 || limit.Cmp(max) > 0 {
+		return nil, ErrInvalidLimit
+	}
+	if !limit.FitsDecimals(decimals) {
+		return nil, fmt.Errorf("%w: lim %s with %d decimals", ErrTooManyDecimals, limit, decimals)
+	}
+
+	d := &deploy{
 		P:    "brc-20",
 		Op:   "deploy",
 		Tick: ticker,
-		Max:  fmt.Sprintf("%d", cap),
-		Lim:  fmt.Sprintf("%d", cap),
+		Max:  max.String(),
+		Lim:  limit.String(),
+	}
+	if opts.Decimals != nil {
+		d.Dec = fmt.Sprintf("%d", decimals)
+	}
+	if opts.SelfMint {
+		d.SelfMint = "true"
 	}
+	return d, nil
+}
 
-	bz, _ := json.Marshal(deploy)
-	inscriptionData := taproot.NewInscriptionData(string(bz), taproot.ContentTypeText)
-	return inscriptions.InscribeNative(receiver, privateKey, inscriptionData, feeRate, config)
+func InscribeDeploy(ticker string, max Amount, opts DeployOptions, privateKey *btcec.PrivateKey, receiver btcutil.Address, feeRate uint64, config config.Config) (*inscriptions.InscriptionResult, error) {
+	deploy, err := newDeploy(ticker, max, opts)
+	if err != nil {
+		return nil, err
+	}
+	return inscribe(deploy, receiver, privateKey, feeRate, config)
 }
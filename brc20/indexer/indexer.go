@@ -0,0 +1,553 @@
+package indexer
+
+// BRC-20 indexer on top of the local inscription tracker.
+// Follows OPI's rules for deploy, mint & transfer, keeping the overall and available balance of every pkscript.
+// Reorgs are not handled, the indexer has to be rebuilt if the chain it followed is replaced
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/inscriptions/tracker"
+)
+
+const (
+	EventDeployInscribe   = "deploy-inscribe"
+	EventMintInscribe     = "mint-inscribe"
+	EventTransferInscribe = "transfer-inscribe"
+	EventTransferTransfer = "transfer-transfer"
+)
+
+var ErrReconcileMismatch = errors.New("local brc20 state differs from the remote indexer")
+
+var _ client.Brc20Backend = &Indexer{}
+var _ client.Brc20EventSource = &Indexer{}
+var _ client.Brc20TransferableSource = &Indexer{}
+var _ client.Brc20TickerSource = &Indexer{}
+
+type ticker struct {
+	tick          string
+	decimals      uint8
+	max           *big.Int
+	limit         *big.Int
+	minted        *big.Int
+	selfMint      bool
+	inscriptionId string
+}
+
+type balance struct {
+	overall   *big.Int
+	available *big.Int
+}
+
+// Transfer inscription which hasn't moved yet
+type transferable struct {
+	tick     string
+	amount   *big.Int
+	pkScript []byte
+	height   int64
+	number   int64
+}
+
+type Indexer struct {
+	tracker *tracker.Tracker
+	params  *chaincfg.Params
+
+	tickers       map[string]*ticker
+	balances      map[string]map[string]*balance  // pkscript hex -> tick -> balance
+	transferables map[string]*transferable        // inscription id -> transfer
+	events        map[string][]client.Brc20Event  // inscription id -> events
+	transfers     map[string]client.Brc20Transfer // inscription id -> transfer-transfer
+	txTransfers   map[string][]string             // tx id -> inscription ids transferred
+}
+
+// Create an indexer which starts indexing at startHeight.
+// On regtest startHeight should be 0, anywhere else the state before startHeight is unknown
+func New(fetcher tracker.PrevOutFetcher, startHeight int64, params *chaincfg.Params) *Indexer {
+	return &Indexer{
+		tracker:       tracker.New(fetcher, startHeight),
+		params:        params,
+		tickers:       make(map[string]*ticker),
+		balances:      make(map[string]map[string]*balance),
+		transferables: make(map[string]*transferable),
+		events:        make(map[string][]client.Brc20Event),
+		transfers:     make(map[string]client.Brc20Transfer),
+		txTransfers:   make(map[string][]string),
+	}
+}
+
+// Height of the last indexed block
+func (i *Indexer) Height() int64 {
+	return i.tracker.Height()
+}
+
+// Index the brc20 operations of a block. The tracker only emits the events once the whole block is
+// resolved, so a block failing to index leaves the balances as they were and can be indexed again
+func (i *Indexer) IndexBlock(height int64, block *wire.MsgBlock) error {
+	events, err := i.tracker.IndexBlock(height, block)
+	if err != nil {
+		return err
+	}
+	for _, evt := range events {
+		switch evt.Kind {
+		case tracker.EventInscribed:
+			if !evt.SpentAsFee {
+				i.onInscribed(evt.Inscription)
+			}
+		case tracker.EventTransferred:
+			i.onTransferred(evt)
+		}
+	}
+	return nil
+}
+
+// Index blocks up to the tip of the node
+func (i *Indexer) Sync(rpc *client.BtcRpcClient) error {
+	tip, err := rpc.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	for height := i.Height() + 1; height <= tip; height++ {
+		hash, err := rpc.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		block, err := rpc.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		if err := i.IndexBlock(height, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get all the brc20 events of an inscription, in the order they happened
+func (i *Indexer) GetEventsByInscriptionId(ctx context.Context, inscriptionId string) ([]client.Brc20Event, error) {
+	events := i.events[inscriptionId]
+	res := make([]client.Brc20Event, len(events))
+	copy(res, events)
+	return res, nil
+}
+
+func (i *Indexer) GetBrc20Balance(ctx context.Context, address, tick string) (*client.Brc20Balance, error) {
+	addr, err := btcutil.DecodeAddress(address, i.params)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	res := &client.Brc20Balance{
+		OverallBalance:   "0",
+		AvailableBalance: "0",
+		BlockHeight:      int(i.Height()),
+	}
+	if b, ok := i.balances[hex.EncodeToString(pkScript)][strings.ToLower(tick)]; ok {
+		res.OverallBalance = b.overall.String()
+		res.AvailableBalance = b.available.String()
+	}
+	return res, nil
+}
+
+// Get the transfers of a tx or of an inscription, either of them can be empty
+func (i *Indexer) GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*client.Brc20Transfers, error) {
+	ids := []string{inscriptionId}
+	if txId != "" {
+		ids = i.txTransfers[txId]
+	}
+	res := &client.Brc20Transfers{Transfers: make([]client.Brc20Transfer, 0), BlockHeight: i.Height()}
+	for _, id := range ids {
+		transfer, ok := i.transfers[id]
+		if !ok || (inscriptionId != "" && id != inscriptionId) {
+			continue
+		}
+		res.Transfers = append(res.Transfers, transfer)
+	}
+	return res, nil
+}
+
+// Get the unspent transfer inscriptions of an address, oldest first
+func (i *Indexer) GetBrc20Transferables(ctx context.Context, address, tick string) ([]client.Brc20Transferable, error) {
+	addr, err := btcutil.DecodeAddress(address, i.params)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0)
+	for id, t := range i.transferables {
+		if t.tick == strings.ToLower(tick) && bytes.Equal(t.pkScript, pkScript) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(a, b int) bool { return i.transferables[ids[a]].number < i.transferables[ids[b]].number })
+
+	res := make([]client.Brc20Transferable, len(ids))
+	for idx, id := range ids {
+		t := i.transferables[id]
+		res[idx] = client.Brc20Transferable{
+			Tick:          t.tick,
+			InscriptionId: id,
+			Amount:        brc20.NewAmountFromInt(t.amount),
+			GenesisHeight: t.height,
+		}
+	}
+	return res, nil
+}
+
+// Get the deploy parameters & supply of a ticker
+func (i *Indexer) GetBrc20TickerInfo(ctx context.Context, tick string) (*client.Brc20TickerInfo, error) {
+	t, ok := i.tickers[strings.ToLower(tick)]
+	if !ok {
+		return nil, fmt.Errorf("%w: ticker %s", client.ErrNotFound, tick)
+	}
+	max := brc20.NewAmountFromInt(t.max)
+	remaining, err := max.Sub(brc20.NewAmountFromInt(t.minted))
+	if err != nil {
+		return nil, err
+	}
+	return &client.Brc20TickerInfo{
+		Tick:                t.tick,
+		OriginalTick:        t.tick,
+		MaxSupply:           max,
+		RemainingSupply:     remaining,
+		LimitPerMint:        brc20.NewAmountFromInt(t.limit),
+		Decimals:            int(t.decimals),
+		IsSelfMint:          t.selfMint,
+		DeployInscriptionId: t.inscriptionId,
+	}, nil
+}
+
+// Compare the balance of an address with the remote indexer
+func (i *Indexer) ReconcileBalance(ctx context.Context, remote client.Brc20Backend, address, tick string) error {
+	local, err := i.GetBrc20Balance(ctx, address, tick)
+	if err != nil {
+		return err
+	}
+	other, err := remote.GetBrc20Balance(ctx, address, tick)
+	if err != nil {
+		return err
+	}
+	if !equalAmounts(local.OverallBalance, other.OverallBalance) || !equalAmounts(local.AvailableBalance, other.AvailableBalance) {
+		return fmt.Errorf("%w: balance of %s for %s is %s/%s at height %d, remote has %s/%s at height %d", ErrReconcileMismatch,
+			tick, address, local.OverallBalance, local.AvailableBalance, local.BlockHeight,
+			other.OverallBalance, other.AvailableBalance, other.BlockHeight)
+	}
+	return nil
+}
+
+// Compare the events of an inscription with the remote indexer
+func (i *Indexer) ReconcileEvents(ctx context.Context, remote client.Brc20Backend, inscriptionId string) error {
+	local, _ := i.GetEventsByInscriptionId(ctx, inscriptionId)
+	other, err := remote.GetEventsByInscriptionId(ctx, inscriptionId)
+	if err != nil {
+		return err
+	}
+	if len(local) != len(other) {
+		return fmt.Errorf("%w: %s has %d events, remote has %d", ErrReconcileMismatch, inscriptionId, len(local), len(other))
+	}
+	for idx := range local {
+		l, o := local[idx], other[idx]
+		if l.EventType != o.EventType || !strings.EqualFold(l.Tick, o.Tick) || !equalAmounts(l.Amount, o.Amount) ||
+			l.SourcePkScript != o.SourcePkScript || l.SpentPkScript != o.SpentPkScript || l.MintedPkScript != o.MintedPkScript {
+			return fmt.Errorf("%w: event %d of %s is %+v, remote has %+v", ErrReconcileMismatch, idx, inscriptionId, l, o)
+		}
+	}
+	return nil
+}
+
+func equalAmounts(a, b string) bool {
+	x, ok1 := new(big.Int).SetString(a, 10)
+	y, ok2 := new(big.Int).SetString(b, 10)
+	if !ok1 || !ok2 {
+		return a == b
+	}
+	return x.Cmp(y) == 0
+}
+
+func (i *Indexer) onInscribed(inscription tracker.Inscription) {
+	op, ok := parseOperation(inscription)
+	if !ok {
+		return
+	}
+	switch op["op"] {
+	case "deploy":
+		i.deploy(inscription, op)
+	case "mint":
+		i.mint(inscription, op)
+	case "transfer":
+		i.inscribeTransfer(inscription, op)
+	}
+}
+
+func (i *Indexer) deploy(inscription tracker.Inscription, op map[string]string) {
+	tick := op["tick"]
+	selfMint := op["self_mint"] == "true"
+	switch len([]byte(tick)) {
+	case 4:
+		selfMint = false
+	case 5:
+		if !selfMint {
+			return
+		}
+	default:
+		return
+	}
+	key := strings.ToLower(tick)
+	if _, ok := i.tickers[key]; ok {
+		return
+	}
+
+	decimals := uint8(brc20.MaxDecimals)
+	if dec, ok := op["dec"]; ok {
+		if len(dec) == 0 || len(dec) > 2 || strings.Trim(dec, "0123456789") != "" {
+			return
+		}
+		d, err := strconv.Atoi(dec)
+		if err != nil || d > brc20.MaxDecimals {
+			return
+		}
+		decimals = uint8(d)
+	}
+
+	maxStr, ok := op["max"]
+	if !ok {
+		return
+	}
+	max, err := brc20.ParseAmount(maxStr, decimals)
+	if err != nil {
+		return
+	}
+	if max.Sign() == 0 {
+		// Self mint tokens can be deployed with an unlimited supply
+		if !selfMint {
+			return
+		}
+		max = brc20.MaxAmount
+	}
+	limit := max
+	if limStr, ok := op["lim"]; ok {
+		limit, err = brc20.ParseAmount(limStr, decimals)
+		if err != nil {
+			return
+		}
+		if limit.Sign() == 0 {
+			if !selfMint {
+				return
+			}
+			limit = brc20.MaxAmount
+		}
+	}
+
+	i.tickers[key] = &ticker{
+		tick:          key,
+		decimals:      decimals,
+		max:           max.Int(),
+		limit:         limit.Int(),
+		minted:        new(big.Int),
+		selfMint:      selfMint,
+		inscriptionId: inscription.Id,
+	}
+	i.addEvent(inscription.Id, client.Brc20Event{
+		Tick:           key,
+		Amount:         max.Int().String(),
+		EventType:      EventDeployInscribe,
+		SourceWallet:   i.wallet(inscription.PkScript),
+		SourcePkScript: hex.EncodeToString(inscription.PkScript),
+	})
+}
+
+func (i *Indexer) mint(inscription tracker.Inscription, op map[string]string) {
+	t, amount, ok := i.opAmount(op)
+	if !ok {
+		return
+	}
+	if amount.Cmp(t.limit) > 0 {
+		return
+	}
+	if t.selfMint && inscription.Parent != t.inscriptionId {
+		return
+	}
+	remaining := new(big.Int).Sub(t.max, t.minted)
+	if remaining.Sign() <= 0 {
+		return
+	}
+	if remaining.Cmp(amount) < 0 {
+		amount = remaining
+	}
+	t.minted.Add(t.minted, amount)
+	b := i.balance(inscription.PkScript, t.tick)
+	b.overall.Add(b.overall, amount)
+	b.available.Add(b.available, amount)
+
+	i.addEvent(inscription.Id, client.Brc20Event{
+		Tick:           t.tick,
+		Amount:         amount.String(),
+		EventType:      EventMintInscribe,
+		MintedWallet:   i.wallet(inscription.PkScript),
+		MintedPkScript: hex.EncodeToString(inscription.PkScript),
+	})
+}
+
+func (i *Indexer) inscribeTransfer(inscription tracker.Inscription, op map[string]string) {
+	t, amount, ok := i.opAmount(op)
+	if !ok {
+		return
+	}
+	b := i.balance(inscription.PkScript, t.tick)
+	if b.available.Cmp(amount) < 0 {
+		return
+	}
+	b.available.Sub(b.available, amount)
+	i.transferables[inscription.Id] = &transferable{
+		tick:     t.tick,
+		amount:   amount,
+		pkScript: inscription.PkScript,
+		height:   inscription.GenesisHeight,
+		number:   inscription.Number,
+	}
+
+	i.addEvent(inscription.Id, client.Brc20Event{
+		Tick:           t.tick,
+		Amount:         amount.String(),
+		EventType:      EventTransferInscribe,
+		SourceWallet:   i.wallet(inscription.PkScript),
+		SourcePkScript: hex.EncodeToString(inscription.PkScript),
+	})
+}
+
+// Only the first move of a transfer inscription counts, inscriptions spent as fee go back to the sender
+func (i *Indexer) onTransferred(evt tracker.Event) {
+	tr, ok := i.transferables[evt.Inscription.Id]
+	if !ok {
+		return
+	}
+	delete(i.transferables, evt.Inscription.Id)
+
+	receiver := evt.To
+	if evt.SpentAsFee {
+		receiver = tr.pkScript
+	}
+	sender := i.balance(tr.pkScript, tr.tick)
+	sender.overall.Sub(sender.overall, tr.amount)
+	b := i.balance(receiver, tr.tick)
+	b.overall.Add(b.overall, tr.amount)
+	b.available.Add(b.available, tr.amount)
+
+	transfer := client.Brc20Transfer{
+		InscriptionId:  evt.Inscription.Id,
+		TxId:           evt.TxId,
+		Tick:           tr.tick,
+		Amount:         brc20.NewAmountFromInt(tr.amount),
+		SourceWallet:   i.wallet(tr.pkScript),
+		SourcePkScript: hex.EncodeToString(tr.pkScript),
+		SpentWallet:    i.wallet(receiver),
+		SpentPkScript:  hex.EncodeToString(receiver),
+		BlockHeight:    evt.Height,
+	}
+	i.transfers[transfer.InscriptionId] = transfer
+	i.txTransfers[transfer.TxId] = append(i.txTransfers[transfer.TxId], transfer.InscriptionId)
+
+	i.addEvent(evt.Inscription.Id, client.Brc20Event{
+		Tick:           tr.tick,
+		Amount:         tr.amount.String(),
+		EventType:      EventTransferTransfer,
+		UsingTxID:      evt.TxId,
+		SourceWallet:   transfer.SourceWallet,
+		SourcePkScript: transfer.SourcePkScript,
+		SpentWallet:    transfer.SpentWallet,
+		SpentPkScript:  transfer.SpentPkScript,
+	})
+}
+
+// Ticker and amount of a mint or transfer, the amount being parsed with the ticker's decimals
+func (i *Indexer) opAmount(op map[string]string) (*ticker, *big.Int, bool) {
+	t, ok := i.tickers[strings.ToLower(op["tick"])]
+	if !ok {
+		return nil, nil, false
+	}
+	amtStr, ok := op["amt"]
+	if !ok {
+		return nil, nil, false
+	}
+	amt, err := brc20.ParseAmount(amtStr, t.decimals)
+	if err != nil || amt.Sign() == 0 {
+		return nil, nil, false
+	}
+	return t, amt.Int(), true
+}
+
+func (i *Indexer) balance(pkScript []byte, tick string) *balance {
+	key := hex.EncodeToString(pkScript)
+	if _, ok := i.balances[key]; !ok {
+		i.balances[key] = make(map[string]*balance)
+	}
+	b, ok := i.balances[key][tick]
+	if !ok {
+		b = &balance{overall: new(big.Int), available: new(big.Int)}
+		i.balances[key][tick] = b
+	}
+	return b
+}
+
+func (i *Indexer) addEvent(inscriptionId string, evt client.Brc20Event) {
+	i.events[inscriptionId] = append(i.events[inscriptionId], evt)
+}
+
+// Address of a pkscript, empty for non standard scripts
+func (i *Indexer) wallet(pkScript []byte) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, i.params)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return addrs[0].EncodeAddress()
+}
+
+var knownFields = map[string]bool{"p": true, "op": true, "tick": true, "max": true, "lim": true, "dec": true, "amt": true, "self_mint": true}
+
+// Decode a brc20 operation. Like OPI, only text & json inscriptions are considered and every field has to be a string
+func parseOperation(inscription tracker.Inscription) (map[string]string, bool) {
+	if !strings.HasPrefix(inscription.ContentType, "text/plain") && !strings.HasPrefix(inscription.ContentType, "application/json") {
+		return nil, false
+	}
+	raw := make(map[string]any)
+	if err := json.Unmarshal(inscription.Body, &raw); err != nil {
+		return nil, false
+	}
+	op := make(map[string]string, len(raw))
+	for k, v := range raw {
+		val, ok := v.(string)
+		if !ok {
+			if knownFields[k] {
+				return nil, false
+			}
+			continue
+		}
+		op[k] = val
+	}
+	if op["p"] != "brc-20" {
+		return nil, false
+	}
+	if _, ok := op["tick"]; !ok {
+		return nil, false
+	}
+	return op, true
+}
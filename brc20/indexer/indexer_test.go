@@ -0,0 +1,173 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/inscriptions/tracker"
+	"github.com/stretchr/testify/require"
+)
+
+type mapFetcher map[wire.OutPoint]*wire.TxOut
+
+func (m mapFetcher) FetchPrevOut(outpoint wire.OutPoint) (*wire.TxOut, error) {
+	out, ok := m[outpoint]
+	if !ok {
+		return nil, tracker.ErrPrevOutNotFound
+	}
+	return out, nil
+}
+
+type wallet struct {
+	address  string
+	pkScript []byte
+}
+
+func newWallet(t *testing.T) wallet {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	addr, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(key.PubKey()), &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	return wallet{address: addr.EncodeAddress(), pkScript: pkScript}
+}
+
+type chain struct {
+	t       *testing.T
+	fetcher mapFetcher
+	indexer *Indexer
+	height  int64
+	funding int
+}
+
+func newChain(t *testing.T) *chain {
+	fetcher := mapFetcher{}
+	return &chain{t: t, fetcher: fetcher, indexer: New(fetcher, 0, &chaincfg.RegressionNetParams)}
+}
+
+// Reveal tx sending the inscription to the wallet
+func (c *chain) inscribe(to wallet, body string) *wire.MsgTx {
+	c.funding++
+	funding := wire.OutPoint{Hash: chainhash.DoubleHashH([]byte(fmt.Sprint(c.funding)))}
+	c.fetcher[funding] = wire.NewTxOut(10_000, []byte{txscript.OP_TRUE})
+
+	script, err := txscript.NewScriptBuilder().
+		AddData(make([]byte, 32)).
+		AddOp(txscript.OP_CHECKSIG).
+		AddOp(txscript.OP_FALSE).
+		AddOp(txscript.OP_IF).
+		AddData([]byte("ord")).
+		AddData([]byte{1}).
+		AddData([]byte("text/plain;charset=utf-8")).
+		AddOp(txscript.OP_0).
+		AddData([]byte(body)).
+		AddOp(txscript.OP_ENDIF).
+		Script()
+	require.NoError(c.t, err)
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: funding, Witness: wire.TxWitness{make([]byte, 64), script, make([]byte, 33)}})
+	tx.AddTxOut(wire.NewTxOut(546, to.pkScript))
+	return tx
+}
+
+func (c *chain) send(from *wire.MsgTx, to wallet) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Hash: from.TxHash()}})
+	tx.AddTxOut(wire.NewTxOut(546, to.pkScript))
+	return tx
+}
+
+func (c *chain) mine(txs ...*wire.MsgTx) {
+	require.NoError(c.t, c.indexer.IndexBlock(c.height, &wire.MsgBlock{Transactions: txs}))
+	c.height++
+	for _, tx := range txs {
+		for i, out := range tx.TxOut {
+			c.fetcher[wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)}] = out
+		}
+	}
+}
+
+func (c *chain) requireBalance(w wallet, overall, available string) {
+	balance, err := c.indexer.GetBrc20Balance(context.Background(), w.address, "ORDI")
+	require.NoError(c.t, err)
+	require.Equal(c.t, overall, balance.OverallBalance)
+	require.Equal(c.t, available, balance.AvailableBalance)
+}
+
+func TestIndexer(t *testing.T) {
+	alice, bob := newWallet(t), newWallet(t)
+	c := newChain(t)
+
+	deploy := c.inscribe(alice, `{"p":"brc-20","op":"deploy","tick":"ordi","max":"150","lim":"100","dec":"2"}`)
+	duplicate := c.inscribe(bob, `{"p":"brc-20","op":"deploy","tick":"ORDI","max":"1"}`)
+	c.mine(deploy, duplicate)
+
+	mint := c.inscribe(alice, `{"p":"brc-20","op":"mint","tick":"ordi","amt":"100"}`)
+	overLimit := c.inscribe(bob, `{"p":"brc-20","op":"mint","tick":"ordi","amt":"101"}`)
+	tooPrecise := c.inscribe(bob, `{"p":"brc-20","op":"mint","tick":"ordi","amt":"1.001"}`)
+	numeric := c.inscribe(bob, `{"p":"brc-20","op":"mint","tick":"ordi","amt":1}`)
+	// Only 50 are left to mint
+	capped := c.inscribe(bob, `{"p":"brc-20","op":"mint","tick":"ordi","amt":"80"}`)
+	c.mine(mint, overLimit, tooPrecise, numeric, capped)
+	c.requireBalance(alice, "100000000000000000000", "100000000000000000000")
+	c.requireBalance(bob, "50000000000000000000", "50000000000000000000")
+	info, err := c.indexer.GetBrc20TickerInfo(context.Background(), "ORDI")
+	require.NoError(t, err)
+	require.Equal(t, 2, info.Decimals)
+	require.Equal(t, "100", info.LimitPerMint.String())
+	require.True(t, info.RemainingSupply.IsZero())
+	require.Equal(t, deploy.TxHash().String()+"i0", info.DeployInscriptionId)
+	_, err = c.indexer.GetBrc20TickerInfo(context.Background(), "sats")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	transfer := c.inscribe(alice, `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"40.5"}`)
+	overspend := c.inscribe(alice, `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"60"}`)
+	c.mine(transfer, overspend)
+	c.requireBalance(alice, "100000000000000000000", "59500000000000000000")
+
+	moved := c.send(transfer, bob)
+	c.mine(moved)
+	c.requireBalance(alice, "59500000000000000000", "59500000000000000000")
+	c.requireBalance(bob, "90500000000000000000", "90500000000000000000")
+
+	// Moving it again doesn't transfer anything
+	c.mine(c.send(moved, alice))
+	c.requireBalance(bob, "90500000000000000000", "90500000000000000000")
+
+	for _, tx := range []*wire.MsgTx{duplicate, overLimit, tooPrecise, numeric, overspend} {
+		events, err := c.indexer.GetEventsByInscriptionId(context.Background(), tx.TxHash().String()+"i0")
+		require.NoError(t, err)
+		require.Empty(t, events)
+	}
+
+	events, err := c.indexer.GetEventsByInscriptionId(context.Background(), transfer.TxHash().String()+"i0")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, EventTransferInscribe, events[0].EventType)
+	require.Equal(t, EventTransferTransfer, events[1].EventType)
+	require.Equal(t, "40500000000000000000", events[1].Amount)
+	require.Equal(t, alice.address, events[1].SourceWallet)
+	require.Equal(t, bob.address, events[1].SpentWallet)
+	require.Equal(t, moved.TxHash().String(), events[1].UsingTxID)
+
+	transfers, err := c.indexer.GetBrc20Transfers(context.Background(), moved.TxHash().String(), "")
+	require.NoError(t, err)
+	require.Len(t, transfers.Transfers, 1)
+	require.Equal(t, int64(3), transfers.Transfers[0].BlockHeight)
+	require.Equal(t, int64(4), transfers.BlockHeight)
+
+	// The indexer can be reconciled against itself
+	require.NoError(t, c.indexer.ReconcileBalance(context.Background(), c.indexer, alice.address, "ordi"))
+	require.NoError(t, c.indexer.ReconcileEvents(context.Background(), c.indexer, transfer.TxHash().String()+"i0"))
+}
@@ -0,0 +1,29 @@
+package brc20
+
+import (
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/inscriptions"
+	"github.com/ordinox/btc-service/taproot"
+)
+
+// Inscribe the brc20 operation into the destination using the inscriber backend set in the config
+func inscribe(op any, destination btcutil.Address, privateKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*inscriptions.InscriptionResult, error) {
+	bz, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+	inscriber, err := inscriptions.NewInscriber(config)
+	if err != nil {
+		return nil, err
+	}
+	return inscriber.Inscribe(inscriptions.InscribeRequest{
+		Inscriptions: []taproot.InscriptionData{taproot.NewInscriptionData(string(bz), taproot.ContentTypeText)},
+		Destination:  destination,
+		PrivateKey:   privateKey,
+		FeeRate:      feeRate,
+	})
+}
@@ -1,14 +1,13 @@
 package brc20
 
 import (
-	"encoding/json"
-	"math/big"
+	"context"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/inscriptions"
-	"github.com/ordinox/btc-service/taproot"
 )
 
 type mint struct {
@@ -18,15 +17,21 @@ type mint struct {
 	Amt  string `json:"amt"`
 }
 
-func InscribeMint(ticker string, amt *big.Float, destination btcutil.Address, privateKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*inscriptions.SingleInscriptionResult, error) {
+// Inscribe a mint into the "destination" address. The amount is checked against the deploy of the ticker on OPI
+func InscribeMint(ticker string, amt Amount, destination btcutil.Address, privateKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*inscriptions.InscriptionResult, error) {
+	opi, err := client.NewOpiClient(config.OpiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDeployedAmount(context.Background(), opi, "mint", ticker, amt); err != nil {
+		return nil, err
+	}
 	mint := mint{
 		P:    "brc-20",
 		Op:   "mint",
 		Tick: ticker,
-		Amt:  amt.Text('f', 4),
+		Amt:  amt.String(),
 	}
 
-	bz, _ := json.Marshal(mint)
-	inscription := taproot.NewInscriptionData(string(bz), taproot.ContentTypeText)
-	return inscriptions.InscribeNative(destination, privateKey, inscription, feeRate, config)
+	return inscribe(mint, destination, privateKey, feeRate, config)
 }
@@ -0,0 +1,252 @@
+package brc20
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/inscriptions"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Transferables considered when planning, keeps the search and the transfer tx small
+	maxPlanCandidates = 20
+
+	// UTXOs below this value might be carrying inscriptions and are never used for fees
+	minFeeUtxoValue = 6500
+
+	dustLimit = 546
+)
+
+var (
+	ErrInscriptionUtxoNotFound = errors.New("inscription utxo not found or already spent")
+	ErrNoFeeUtxo               = errors.New("no utxo available to pay the transfer fee")
+)
+
+// Which transfer inscriptions to send for an amount
+type TransferPlan struct {
+	Reuse     []client.Brc20Transferable // Existing transfer inscriptions sent as they are
+	Remainder Amount                     // Has to be inscribed, zero when the existing inscriptions add up to the amount
+}
+
+// Pick the combination of transferables with the largest total not exceeding amt, preferring fewer inscriptions.
+// Only the remainder has to be inscribed
+func PlanTransfer(ticker string, amt Amount, transferables []client.Brc20Transferable) TransferPlan {
+	candidates := make([]client.Brc20Transferable, 0, len(transferables))
+	for _, t := range transferables {
+		if strings.EqualFold(t.Tick, ticker) && t.Amount.Sign() > 0 && t.Amount.Cmp(amt) <= 0 {
+			candidates = append(candidates, t)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Amount.Cmp(candidates[j].Amount) > 0 })
+	if len(candidates) > maxPlanCandidates {
+		candidates = candidates[:maxPlanCandidates]
+	}
+
+	// suffix[i] is the total of candidates[i:], used to prune branches which can't beat the best sum
+	zero := NewAmountFromInt(big.NewInt(0))
+	suffix := make([]Amount, len(candidates)+1)
+	suffix[len(candidates)] = zero
+	for i := len(candidates) - 1; i >= 0; i-- {
+		suffix[i] = suffix[i+1].Add(candidates[i].Amount)
+	}
+
+	var (
+		best     []int
+		bestSum  = zero
+		chosen   = make([]int, 0, len(candidates))
+		search   func(i int, sum Amount) bool
+		isBetter = func(sum Amount, count int) bool {
+			cmp := sum.Cmp(bestSum)
+			return cmp > 0 || (cmp == 0 && best != nil && count < len(best))
+		}
+	)
+	search = func(i int, sum Amount) bool {
+		if isBetter(sum, len(chosen)) {
+			best = append([]int{}, chosen...)
+			bestSum = sum
+		}
+		if sum.Cmp(amt) == 0 {
+			return true
+		}
+		if i == len(candidates) || sum.Add(suffix[i]).Cmp(bestSum) < 0 {
+			return false
+		}
+		if next := sum.Add(candidates[i].Amount); next.Cmp(amt) <= 0 {
+			chosen = append(chosen, i)
+			found := search(i+1, next)
+			chosen = chosen[:len(chosen)-1]
+			if found {
+				return true
+			}
+		}
+		return search(i+1, sum)
+	}
+	search(0, zero)
+
+	plan := TransferPlan{Reuse: make([]client.Brc20Transferable, len(best))}
+	for i, idx := range best {
+		plan.Reuse[i] = candidates[idx]
+	}
+	plan.Remainder, _ = amt.Sub(bestSum)
+	return plan
+}
+
+// Send several inscriptions in one tx, each one to its own output with its postage
+// Note: "from" address has to be a P2PKH address holding the inscriptions and the fee utxo
+func TransferInscriptions(from, to btcutil.Address, inscriptionIds []string, privKey *btcec.PrivateKey, feeRate uint64, config config.Config) (string, error) {
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return "", err
+	}
+	inscriptionUtxos := make([]common.Utxo, len(inscriptionIds))
+	for i, id := range inscriptionIds {
+		utxo, err := inscriptionUtxo(rpc, id)
+		if err != nil {
+			return "", err
+		}
+		inscriptionUtxos[i] = utxo
+	}
+	return TransferInscriptionUtxos(from, to, inscriptionUtxos, privKey, feeRate, config)
+}
+
+// Same as TransferInscriptions with the inscription utxos already known, e.g. the reveal outputs of an inscription result.
+// They can be unconfirmed, as long as the transfer stays within the mempool ancestor limit
+func TransferInscriptionUtxos(from, to btcutil.Address, inscriptionUtxos []common.Utxo, privKey *btcec.PrivateKey, feeRate uint64, config config.Config) (string, error) {
+	from, pkData, err := common.VerifyPrivateKey(privKey, from, config.BtcConfig.GetChainConfigParams())
+	if err != nil {
+		return "", fmt.Errorf("error verifying privatekey: %s", err.Error())
+	}
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return "", err
+	}
+
+	exclude := make(map[string]bool)
+	for _, utxo := range inscriptionUtxos {
+		exclude[fmt.Sprintf("%s:%d", utxo.GetTxID(), utxo.GetVout())] = true
+	}
+	feeUtxo, err := selectFeeUtxo(rpc, from, exclude, config)
+	if err != nil {
+		return "", err
+	}
+	if _, err := common.SpendDepth(append([]common.Utxo{feeUtxo}, inscriptionUtxos...)...); err != nil {
+		return "", err
+	}
+
+	tx, err := BuildMultiTransferTx(feeUtxo, inscriptionUtxos, from, to)
+	if err != nil {
+		return "", err
+	}
+	gas, err := tx.EstimateGas(feeRate)
+	if err != nil {
+		return "", err
+	}
+	if feeUtxo.GetValueInSats() < gas+dustLimit {
+		return "", fmt.Errorf("%w: fee utxo has %d sats, %d needed", ErrNoFeeUtxo, feeUtxo.GetValueInSats(), gas+dustLimit)
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(feeUtxo.GetValueInSats()-gas), tx.SenderPkScript))
+
+	for i := range tx.TxIn {
+		if err := tx.SignP2PKH(privKey, pkData, i); err != nil {
+			return "", err
+		}
+	}
+	h, err := rpc.SendRawTransaction(tx.MsgTx, true)
+	if err != nil {
+		log.Err(err).Msg("error broadcasting txn")
+		return "", err
+	}
+	log.Info().Msgf("hash: %s fee: %d inscriptions: %d", h.String(), gas, len(inscriptionUtxos))
+	return h.String(), nil
+}
+
+// First utxo of the address big enough to be a fee utxo, along with its unconfirmed depth
+func selectFeeUtxo(rpc *client.BtcRpcClient, from btcutil.Address, exclude map[string]bool, config config.Config) (common.Utxo, error) {
+	utxos, err := common.GetUtxos(from.EncodeAddress(), config.BtcConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, utxo := range utxos.Result {
+		if exclude[fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Vout)] || utxo.Value <= minFeeUtxoValue {
+			continue
+		}
+		depth, err := rpc.MempoolDepth(utxo.TxHash)
+		if err != nil {
+			return nil, err
+		}
+		return common.TxOutUtxo{TxHash: utxo.TxHash, Vout: utxo.Vout, Value: utxo.Value, Depth: depth}, nil
+	}
+	return nil, ErrNoFeeUtxo
+}
+
+// Genesis output of an inscription, failing if it was spent (in the mempool as well)
+func inscriptionUtxo(rpc *client.BtcRpcClient, inscriptionId string) (common.TxOutUtxo, error) {
+	outpoint, err := inscriptions.GenesisOutPoint(inscriptionId)
+	if err != nil {
+		return common.TxOutUtxo{}, err
+	}
+	out, err := rpc.GetTxOut(&outpoint.Hash, outpoint.Index, true)
+	if err != nil {
+		return common.TxOutUtxo{}, err
+	}
+	if out == nil {
+		return common.TxOutUtxo{}, fmt.Errorf("%w: %s", ErrInscriptionUtxoNotFound, inscriptionId)
+	}
+	value, err := btcutil.NewAmount(out.Value)
+	if err != nil {
+		return common.TxOutUtxo{}, err
+	}
+	pkScript, err := hex.DecodeString(out.ScriptPubKey.Hex)
+	if err != nil {
+		return common.TxOutUtxo{}, err
+	}
+	utxo := common.TxOutUtxo{TxHash: outpoint.Hash.String(), Vout: outpoint.Index, Value: uint64(value), PkScript: pkScript}
+	if out.Confirmations == 0 {
+		if utxo.Depth, err = rpc.MempoolDepth(utxo.TxHash); err != nil {
+			return common.TxOutUtxo{}, err
+		}
+	}
+	return utxo, nil
+}
+
+// Build an unsigned tx sending every inscription utxo to the destination, the fee utxo being the last input.
+// Each inscription sits on the first sat of its input, so output i carries inscription i
+func BuildMultiTransferTx(feeUtxo common.Utxo, inscriptionUtxos []common.Utxo, senderAddr, destinationAddr btcutil.Address) (*common.WrappedTx, error) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	destinationAddrScript, err := txscript.PayToAddrScript(destinationAddr)
+	if err != nil {
+		return nil, err
+	}
+	senderAddrScript, err := txscript.PayToAddrScript(senderAddr)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]common.Utxo, 0, len(inscriptionUtxos)+1)
+	inputs = append(append(inputs, inscriptionUtxos...), feeUtxo)
+	for _, utxo := range inputs {
+		outpoint, err := wire.NewOutPointFromString(fmt.Sprintf("%s:%d", utxo.GetTxID(), utxo.GetVout()))
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxIn(common.NewRbfTxIn(outpoint, nil, nil))
+	}
+	for _, utxo := range inscriptionUtxos {
+		tx.AddTxOut(wire.NewTxOut(int64(utxo.GetValueInSats()), destinationAddrScript))
+	}
+	return &common.WrappedTx{
+		MsgTx:          tx,
+		SenderPkScript: senderAddrScript,
+	}, nil
+}
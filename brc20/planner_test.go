@@ -0,0 +1,50 @@
+package brc20
+
+import (
+	"testing"
+
+	"github.com/ordinox/btc-service/client"
+	"github.com/stretchr/testify/require"
+)
+
+func transferables(t *testing.T, tick string, amounts ...string) []client.Brc20Transferable {
+	res := make([]client.Brc20Transferable, len(amounts))
+	for i, amt := range amounts {
+		amount, err := ParseAmount(amt, MaxDecimals)
+		require.NoError(t, err)
+		res[i] = client.Brc20Transferable{Tick: tick, InscriptionId: tick + amt, Amount: amount}
+	}
+	return res
+}
+
+func requirePlan(t *testing.T, plan TransferPlan, remainder string, reused ...string) {
+	require.Equal(t, remainder, plan.Remainder.String())
+	ids := make([]string, len(plan.Reuse))
+	for i, transferable := range plan.Reuse {
+		ids[i] = transferable.InscriptionId
+	}
+	require.ElementsMatch(t, reused, ids)
+}
+
+func TestPlanTransfer(t *testing.T) {
+	amount := func(s string) Amount {
+		amt, err := ParseAmount(s, MaxDecimals)
+		require.NoError(t, err)
+		return amt
+	}
+
+	// Exact match through a combination
+	requirePlan(t, PlanTransfer("ordi", amount("10"), transferables(t, "ordi", "7", "4", "3", "2.5")), "0", "ordi7", "ordi3")
+
+	// A single inscription is preferred over several with the same total
+	requirePlan(t, PlanTransfer("ordi", amount("10"), transferables(t, "ordi", "5", "3", "2", "10")), "0", "ordi10")
+
+	// Only the remainder has to be inscribed
+	requirePlan(t, PlanTransfer("ordi", amount("10"), transferables(t, "ordi", "6", "3")), "1", "ordi6", "ordi3")
+	requirePlan(t, PlanTransfer("ordi", amount("1.5"), transferables(t, "ordi", "0.2", "0.7")), "0.6", "ordi0.2", "ordi0.7")
+
+	// Larger inscriptions and other ticks are never used
+	requirePlan(t, PlanTransfer("ORDI", amount("5"), transferables(t, "ordi", "6", "2")), "3", "ordi2")
+	requirePlan(t, PlanTransfer("ordi", amount("5"), transferables(t, "sats", "5")), "5")
+	requirePlan(t, PlanTransfer("ordi", amount("5"), nil), "5")
+}
@@ -5,9 +5,8 @@ package brc20
 // 2. build txn for signing
 // 3. with a transaction id, check if a brc20 token came into the address being monitored
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"math/big"
 	"strings"
 	"time"
 
@@ -20,7 +19,6 @@ import (
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/inscriptions"
-	"github.com/ordinox/btc-service/taproot"
 	"github.com/rs/zerolog/log"
 )
 
@@ -31,90 +29,73 @@ type transfer struct {
 	Amt  string `json:"amt"`
 }
 
-// Inscribe a "transfer inscription" into the "destination" address
-func InscribeTransfer(ticker string, amt *big.Float, destination btcutil.Address, privateKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*inscriptions.SingleInscriptionResult, error) {
+// Inscribe a "transfer inscription" into the "destination" address. The amount is checked against the
+// deploy of the ticker on OPI
+func InscribeTransfer(ticker string, amt Amount, destination btcutil.Address, privateKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*inscriptions.InscriptionResult, error) {
+	opi, err := client.NewOpiClient(config.OpiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDeployedAmount(context.Background(), opi, "transfer", ticker, amt); err != nil {
+		return nil, err
+	}
+	return inscribeTransfer(ticker, amt, destination, privateKey, feeRate, config)
+}
+
+func inscribeTransfer(ticker string, amt Amount, destination btcutil.Address, privateKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*inscriptions.InscriptionResult, error) {
 	transfer := transfer{
 		P:    "brc-20",
 		Op:   "transfer",
 		Tick: ticker,
-		Amt:  amt.Text('f', 4),
+		Amt:  amt.String(),
 	}
-	bz, _ := json.Marshal(transfer)
-	inscription := taproot.NewInscriptionData(string(bz), taproot.ContentTypeText)
-	return inscriptions.InscribeNative(destination, privateKey, inscription, feeRate, config)
+	return inscribe(transfer, destination, privateKey, feeRate, config)
 }
 
-func getUtxos(client *client.BtcRpcClient, from btcutil.Address, inscriptionTxId string, config config.Config) (iUtxo, fUtxo common.Utxo, err error) {
-	var utxos []common.Utxo
-	mUtxos, err := common.GetUtxos(from.EncodeAddress(), config.BtcConfig)
-	if err != nil {
-		return nil, nil, err
-	}
-	utxos = mUtxos.Result.ToUtxo()
-	for i := range utxos {
-		utxo := utxos[i]
-		if fUtxo != nil && iUtxo != nil {
-			break
-		}
-		if inscriptionTxId == utxo.GetTxID() {
-			iUtxo = utxo
-		} else if utxo.GetValueInSats() > 6500 {
-			// TODO: Check if this can be potential inscription
-			fUtxo = utxo
-		}
-	}
-	return
-}
+// How long a transfer waits for the inscription utxos to show up
+const transferWaitTimeout = 2 * time.Minute
 
 // Given an inscription ID, transfer a that inscription to a new address
+// A reveal tx id can be given as well, its first inscription is transferred
 // Note: From Address has to be a P2PKH address
-func TransferInscription(from, to btcutil.Address, inscriptionId string, privKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*string, error) {
-	fmt.Printf("--transferring brc20 from=%s to=%s", from.String(), to.String())
-	client := client.NewBitcoinClient(config)
-
-	inscriptionTxId := strings.TrimRight(inscriptionId, "i0")
-	var inscriptionUtxo common.Utxo
-	var feeUtxo common.Utxo
-
-	count := 0
-	// 120 second backoff till utxo is found in the mempool
-	for {
-		fmt.Println("Finding UTXOs - Attempt ", count+1)
-		if count == 120 {
-			fmt.Printf("-- Err InscriptionUtxoFound? %t  FeeUtxoFound? %t \n", inscriptionUtxo != nil, feeUtxo != nil)
-			return nil, fmt.Errorf("couldn't finalise inscription/fee UTXO within the backoff time (120s)")
-		}
-		var err error
-		inscriptionUtxo, feeUtxo, err = getUtxos(client, from, inscriptionTxId, config)
-		if err != nil {
-			fmt.Printf("-- err getting utxos InscriptionUtxoFound? %t FeeUtxoFound? %t \n", inscriptionUtxo != nil, feeUtxo != nil)
-			return nil, err
-		}
-		if inscriptionUtxo != nil && feeUtxo != nil {
-			fmt.Printf("utxos found")
-			break
-		}
-		count = count + 1
-		time.Sleep(1 * time.Second)
+func TransferInscription(ctx context.Context, from, to btcutil.Address, inscriptionId string, privKey *btcec.PrivateKey, feeRate uint64, config config.Config) (*string, error) {
+	log.Debug().Str("from", from.String()).Str("to", to.String()).Str("inscription_id", inscriptionId).Msg("transferring brc20")
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
 	}
 
-	if feeUtxo == nil {
-		return nil, fmt.Errorf("no fee utxo found")
+	if !strings.Contains(inscriptionId, "i") {
+		inscriptionId += "i0"
 	}
-
-	hash, err := Transfer(feeUtxo, inscriptionUtxo, from, to, privKey, privKey.PubKey(), feeRate, config)
+	outpoint, err := inscriptions.GenesisOutPoint(inscriptionId)
 	if err != nil {
 		return nil, err
 	}
-	// fmt.Println(hash.String())
 
+	ctx, cancel := context.WithTimeout(ctx, transferWaitTimeout)
+	defer cancel()
+	waiter := client.NewWaiter(rpc)
+	if _, err := waiter.WaitForSpendable(ctx, *outpoint); err != nil {
+		return nil, fmt.Errorf("inscription utxo %s not found: %w", outpoint, err)
+	}
+
+	// Taken from the node, the utxo api can lag behind it
+	utxo, err := inscriptionUtxo(rpc, inscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := TransferInscriptionUtxos(from, to, []common.Utxo{utxo}, privKey, feeRate, config)
+	if err != nil {
+		return nil, err
+	}
 	return &hash, nil
 }
 
 // Transfer the given UTXOs from the "from" address to the "to" address
 // Note: "from" address has to be a P2PKH address
 func Transfer(cUtxo, iUtxo common.Utxo, senderAddr, destAddr btcutil.Address, senderPk *btcec.PrivateKey, senderPubKey *btcec.PublicKey, feeRate uint64, config config.Config) (string, error) {
-	fmt.Println("Transfer Called ")
+	log.Debug().Str("from", senderAddr.String()).Str("to", destAddr.String()).Msg("transfer called")
 	senderAddr, senderPkData, err := common.VerifyPrivateKey(senderPk, senderAddr, config.BtcConfig.GetChainConfigParams())
 	if err != nil {
 		return "", fmt.Errorf("error verifying privatekey: %s", err.Error())
@@ -165,8 +146,11 @@ func Transfer(cUtxo, iUtxo common.Utxo, senderAddr, destAddr btcutil.Address, se
 	tx.TxIn[0].SignatureScript = sigScript0
 	tx.TxIn[1].SignatureScript = sigScript1
 
-	client := client.NewBitcoinClient(config)
-	h, err := client.SendRawTransaction(tx.MsgTx, true)
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return "", err
+	}
+	h, err := rpc.SendRawTransaction(tx.MsgTx, true)
 	if err != nil {
 		log.Err(err).Msg("error broadcasting txn")
 		return "", err
@@ -176,23 +160,108 @@ func Transfer(cUtxo, iUtxo common.Utxo, senderAddr, destAddr btcutil.Address, se
 	return h.String(), nil
 }
 
-// Full suite of Inscribing a BRC20 transfer into the "from" address
-// Transferring the Transfer Inscription from the "from" address to the "to" address
+type SendBrc20Result struct {
+	InscriptionIds []string                        // Every transfer inscription sent
+	Inscribed      *inscriptions.InscriptionResult // Inscription of the remainder, nil when existing ones covered the amount
+	TxHash         string
+}
+
+// Send a BRC20 amount from the "from" address to the "to" address, reusing the transfer inscriptions "from" already has.
+// Only the remainder is inscribed, then all the inscriptions are sent in a single tx
 // Note: inscriberPrivateKey's P2TR address needs to have UTXOs for inscribing a transfer inscription
 // And, "from" address should be P2PKH address
-func SendBrc20(ticker string, from, to btcutil.Address, amt *big.Float, feeRate uint64, inscriberPrivateKey, senderPrivateKey *btcec.PrivateKey, config config.Config) (inscriptionId, hash string, err error) {
-	res, err := InscribeTransfer(ticker, amt, from, inscriberPrivateKey, feeRate, config)
+func SendBrc20(ctx context.Context, ticker string, from, to btcutil.Address, amt Amount, feeRate uint64, inscriberPrivateKey, senderPrivateKey *btcec.PrivateKey, config config.Config) (*SendBrc20Result, error) {
+	opi, err := client.NewOpiClient(config.OpiConfig)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	hashPtr, err := TransferInscription(from, to, res.RevealTx, senderPrivateKey, feeRate, config)
+	return SendBrc20WithSource(ctx, opi, ticker, from, to, amt, feeRate, inscriberPrivateKey, senderPrivateKey, config)
+}
+
+// Indexer a send is planned with, OPI or the local indexer
+type Brc20SendSource interface {
+	client.Brc20TransferableSource
+	client.Brc20TickerSource
+}
+
+func SendBrc20WithSource(ctx context.Context, source Brc20SendSource, ticker string, from, to btcutil.Address, amt Amount, feeRate uint64, inscriberPrivateKey, senderPrivateKey *btcec.PrivateKey, config config.Config) (*SendBrc20Result, error) {
+	if err := validateDeployedAmount(ctx, source, "transfer", ticker, amt); err != nil {
+		return nil, err
+	}
+	transferables, err := source.GetBrc20Transferables(ctx, from.EncodeAddress(), ticker)
 	if err != nil {
-		return inscriptionId, "", err
+		return nil, err
+	}
+	// The indexer doesn't know about transfers sitting in the mempool
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
+	unspent := make([]client.Brc20Transferable, 0, len(transferables))
+	utxos := make(map[string]common.Utxo)
+	for _, t := range transferables {
+		if utxo, err := inscriptionUtxo(rpc, t.InscriptionId); err == nil {
+			unspent = append(unspent, t)
+			utxos[t.InscriptionId] = utxo
+		}
+	}
+
+	plan := PlanTransfer(ticker, amt, unspent)
+	res := &SendBrc20Result{InscriptionIds: make([]string, 0, len(plan.Reuse)+1)}
+	inscriptionUtxos := make([]common.Utxo, 0, len(plan.Reuse)+1)
+	for _, t := range plan.Reuse {
+		res.InscriptionIds = append(res.InscriptionIds, t.InscriptionId)
+		inscriptionUtxos = append(inscriptionUtxos, utxos[t.InscriptionId])
+	}
+	log.Info().Msgf("reusing %d transfer inscriptions, inscribing %s %s", len(plan.Reuse), plan.Remainder.String(), ticker)
+
+	if plan.Remainder.Sign() > 0 {
+		res.Inscribed, err = inscribeTransfer(ticker, plan.Remainder, from, inscriberPrivateKey, feeRate, config)
+		if err != nil {
+			return nil, err
+		}
+		res.InscriptionIds = append(res.InscriptionIds, res.Inscribed.InscriptionIds...)
+		reveals, err := revealUtxos(ctx, rpc, res.Inscribed)
+		if err != nil {
+			return res, err
+		}
+		inscriptionUtxos = append(inscriptionUtxos, reveals...)
+	}
+
+	res.TxHash, err = TransferInscriptionUtxos(from, to, inscriptionUtxos, senderPrivateKey, feeRate, config)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Outputs holding the inscriptions of a result. They're returned by the inscribers,
+// otherwise the node is polled until the reveal outputs are spendable
+func revealUtxos(ctx context.Context, rpc *client.BtcRpcClient, res *inscriptions.InscriptionResult) ([]common.Utxo, error) {
+	utxos := make([]common.Utxo, len(res.InscriptionIds))
+	if len(res.Reveals) == len(res.InscriptionIds) {
+		for i, reveal := range res.Reveals {
+			utxos[i] = reveal
+		}
+		return utxos, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transferWaitTimeout)
+	defer cancel()
+	waiter := client.NewWaiter(rpc)
+	for i, id := range res.InscriptionIds {
+		outpoint, err := inscriptions.GenesisOutPoint(id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := waiter.WaitForSpendable(ctx, *outpoint); err != nil {
+			return nil, fmt.Errorf("inscription utxo %s not found: %w", outpoint, err)
+		}
+		if utxos[i], err = inscriptionUtxo(rpc, id); err != nil {
+			return nil, err
+		}
 	}
-	inscriptionId = res.RevealTx
-	hash = *hashPtr
-	err = nil
-	return
+	return utxos, nil
 }
 
 // 89e68ee66bbed960bd2ac69159bce2d188c8a1e19c6196de7ce3e7dfe91ecb9e
@@ -212,9 +281,9 @@ func BuildTransferTx(cardinalUtxo, inscriptionUtxo common.Utxo, senderAddr, dest
 	utxo0, err := wire.NewOutPointFromString(fmt.Sprintf("%s:%d", inscriptionUtxo.GetTxID(), inscriptionUtxo.GetVout()))
 	utxo1, err := wire.NewOutPointFromString(fmt.Sprintf("%s:%d", cardinalUtxo.GetTxID(), cardinalUtxo.GetVout()))
 
-	txin0 := wire.NewTxIn(utxo0, nil, [][]byte{})
+	txin0 := common.NewRbfTxIn(utxo0, nil, [][]byte{})
 	tx.AddTxIn(txin0)
-	txin1 := wire.NewTxIn(utxo1, nil, [][]byte{})
+	txin1 := common.NewRbfTxIn(utxo1, nil, [][]byte{})
 	tx.AddTxIn(txin1)
 	txout := wire.NewTxOut(546, destinationAddrScript)
 	tx.AddTxOut(txout)
@@ -1,99 +1,198 @@
 package brc20
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/btcsuite/btcd/btcutil/bech32"
 	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 )
 
 var (
-	ErrNoEventsFound        = errors.New("no events found")
-	ErrInvalidBrc20Transfer = errors.New("error verifying brc20 transfer")
+	ErrNoEventsFound           = errors.New("no events found")
+	ErrInvalidBrc20Transfer    = errors.New("error verifying brc20 transfer")
+	ErrNotEnoughConfirmations  = errors.New("brc20 transfer doesn't have enough confirmations")
+	ErrTransferBelowMinHeight  = errors.New("brc20 transfer was mined below the min block height")
+	ErrUnknownTransferHeight   = errors.New("unable to find the block of the brc20 transfer")
+	ErrTransferAheadOfIndexer  = errors.New("brc20 transfer is in a block the indexer hasn't processed")
+	ErrMissingTransferHeights  = errors.New("source doesn't report block heights and no chain lookup is set")
+	ErrUnverifiableBrc20Source = errors.New("no brc20 source configured")
 )
 
-// Return the sender and reciever of a BRC20 transfer
-func VerifyBrc20Deposit(config config.Config, inscriptionId string) (string, string, error) {
-	// Regtest verification
-	client := client.NewOpiClient(config.OpiConfig)
-	events, err := client.GetEventsByInscriptionId(inscriptionId)
+// Resolves the block height of a tx, 0 if unconfirmed
+type TxHeightLookup interface {
+	GetTxBlockHeight(txId string) (int64, error)
+}
+
+var _ TxHeightLookup = &client.BtcRpcClient{}
+
+// Verifies BRC20 transfers against any indexer. Every check is applied the same way on all networks
+type Brc20Verifier struct {
+	Source client.Brc20EventSource
+	Chain  TxHeightLookup // Used when the source doesn't report the block of a transfer
+}
+
+// The configured brc20 sources, by default BIS on mainnet and OPI everywhere else, with bitcoin core to find
+// the block of a transfer. With several sources, a quorum of them has to agree on the transfer
+func NewBrc20Verifier(config config.Config) (Brc20Verifier, error) {
+	rpc, err := client.NewBitcoinClient(config)
 	if err != nil {
-		return "", "", err
+		return Brc20Verifier{}, err
+	}
+	source, err := newBrc20EventSource(config)
+	if err != nil {
+		return Brc20Verifier{}, err
+	}
+	return Brc20Verifier{Source: source, Chain: rpc}, nil
+}
+
+// The configured source, or the consensus of the configured sources when there are several
+func newBrc20EventSource(config config.Config) (client.Brc20EventSource, error) {
+	consensus, err := NewBrc20Consensus(config)
+	if err != nil {
+		return nil, err
 	}
+	if len(consensus.Sources) == 1 {
+		return consensus.Sources[0].Source, nil
+	}
+	return consensus, nil
+}
 
-	for _, evt := range events {
-		if evt.EventType == "transfer-transfer" {
-			return evt.SourceWallet, evt.SpentWallet, nil
+func newNamedBrc20Source(name string, config config.Config) (client.Brc20EventSource, error) {
+	switch name {
+	case "opi":
+		return client.NewOpiClient(config.OpiConfig)
+	case "bis":
+		bis, err := client.NewBISClient(config.BISConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnverifiableBrc20Source, err)
 		}
+		return bis, nil
 	}
-	return "", "", errors.New("unable to fetch transfer-inscription transfer")
+	return nil, fmt.Errorf("%w: unknown source %s", ErrUnverifiableBrc20Source, name)
 }
 
 type VerifyBrc20DepositData struct {
 	InscriptionId, TxId, Tick    string
 	FromWalletAddr, ToWalletAddr string
-	Amount                       float64
-	Decimals                     int // Decimals of the amount we are checking
+	Amount                       common.TokenAmount // Compared by value, the decimals don't have to be 18
+	MinConfirmations             int64              // Defaults to 1
+	MinBlockHeight               int64              // Transfers mined below this height are rejected
 }
 
-func VerifyBrc20TransferV2(config config.Config, data VerifyBrc20DepositData) error {
-	if config.BtcConfig.ChainConfig == "mainnet" {
+// Return the sender and reciever of a BRC20 transfer
+func VerifyBrc20Deposit(ctx context.Context, config config.Config, inscriptionId string) (string, string, error) {
+	source, err := newBrc20EventSource(config)
+	if err != nil {
+		return "", "", err
+	}
+	res, err := source.GetBrc20Transfers(ctx, "", inscriptionId)
+	if err != nil {
+		return "", "", err
+	}
+	if len(res.Transfers) == 0 {
+		return "", "", errors.New("unable to fetch transfer-inscription transfer")
+	}
+	return res.Transfers[0].SourceWallet, res.Transfers[0].SpentWallet, nil
+}
 
-		// Use BIS
-		client := client.NewBISClient(config.BISConfig)
-		events, err := client.GetEventsByTransactionId(data.TxId)
-		if err != nil {
-			return err
+func VerifyBrc20TransferV2(ctx context.Context, config config.Config, data VerifyBrc20DepositData) error {
+	verifier, err := NewBrc20Verifier(config)
+	if err != nil {
+		return err
+	}
+	_, err = verifier.Verify(ctx, data)
+	return err
+}
+
+// Find the transfer matching the deposit and check that it is deep enough in the chain
+func (v Brc20Verifier) Verify(ctx context.Context, data VerifyBrc20DepositData) (*client.Brc20Transfer, error) {
+	if v.Source == nil {
+		return nil, ErrUnverifiableBrc20Source
+	}
+	res, err := v.Source.GetBrc20Transfers(ctx, data.TxId, data.InscriptionId)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Transfers) == 0 {
+		return nil, fmt.Errorf("no events yet %w", ErrNoEventsFound)
+	}
+
+	var transfer *client.Brc20Transfer
+	mismatch := ""
+	for _, t := range res.Transfers {
+		if reason := matchTransfer(t, data); reason != "" {
+			mismatch = reason
+			continue
 		}
-		if len(events.Data) == 0 {
-			return fmt.Errorf("no events yet %w", ErrNoEventsFound)
+		transfer = &t
+		break
+	}
+	if transfer == nil {
+		return nil, fmt.Errorf("invalid transfer, %s: %w", mismatch, ErrInvalidBrc20Transfer)
+	}
+
+	height := transfer.BlockHeight
+	if height == 0 {
+		if v.Chain == nil {
+			return nil, ErrMissingTransferHeights
 		}
-		for _, evt := range events.Data {
-			if evt.EventType != "transfer-transfer" {
-				continue
-			}
-			if !strings.EqualFold(evt.Event.SourceWallet, data.FromWalletAddr) {
-				continue
-			}
-			if !strings.EqualFold(evt.Event.SpentWallet, data.ToWalletAddr) {
-				continue
-			}
-			if !strings.EqualFold(evt.Event.Tick, data.Tick) {
-				continue
-			}
-			amtParsed, err := common.ParseStringFloat64(evt.Event.Amount, data.Decimals)
-			if err != nil {
-				return err
-			}
-			if amtParsed != data.Amount {
-				continue
-			}
-			// If control reaches here, then an event passed the check
-			return nil
+		if height, err = v.Chain.GetTxBlockHeight(transfer.TxId); err != nil {
+			return nil, err
 		}
-
-		return fmt.Errorf("invalid transfer %w", ErrInvalidBrc20Transfer)
-	} else {
-		client := client.NewOpiClient(config.OpiConfig)
-		events, err := client.GetEventsByInscriptionId(data.InscriptionId)
-		if err != nil {
-			return err
+		if height == 0 {
+			return nil, fmt.Errorf("%w: %s is unconfirmed", ErrUnknownTransferHeight, transfer.TxId)
 		}
+		transfer.BlockHeight = height
+	}
+	if height > res.BlockHeight {
+		return nil, fmt.Errorf("%w: transfer at %d, indexer at %d", ErrTransferAheadOfIndexer, height, res.BlockHeight)
+	}
+	if height < data.MinBlockHeight {
+		return nil, fmt.Errorf("%w: transfer at %d, min %d", ErrTransferBelowMinHeight, height, data.MinBlockHeight)
+	}
+	minConfirmations := data.MinConfirmations
+	if minConfirmations < 1 {
+		minConfirmations = 1
+	}
+	// Confirmations as seen by the indexer, a lagging indexer can only under count
+	if confirmations := res.BlockHeight - height + 1; confirmations < minConfirmations {
+		return nil, fmt.Errorf("%w: %d of %d", ErrNotEnoughConfirmations, confirmations, minConfirmations)
+	}
+	return transfer, nil
+}
 
-		for _, evt := range events {
-			if evt.EventType == "transfer-transfer" {
-				if evt.SourceWallet != data.FromWalletAddr {
-					continue
-				}
-				if evt.SpentWallet != data.ToWalletAddr {
-					continue
-				}
-				return nil
-			}
-		}
+// Reason why the transfer doesn't match the deposit, empty if it does
+func matchTransfer(transfer client.Brc20Transfer, data VerifyBrc20DepositData) string {
+	switch {
+	case data.TxId != "" && transfer.TxId != data.TxId:
+		return fmt.Sprintf("tx %s instead of %s", transfer.TxId, data.TxId)
+	case data.InscriptionId != "" && transfer.InscriptionId != data.InscriptionId:
+		return fmt.Sprintf("inscription %s instead of %s", transfer.InscriptionId, data.InscriptionId)
+	case !strings.EqualFold(transfer.Tick, data.Tick):
+		return fmt.Sprintf("tick %s instead of %s", transfer.Tick, data.Tick)
+	case transfer.Amount.Cmp(data.Amount) != 0:
+		return fmt.Sprintf("amount %s instead of %s", transfer.Amount, data.Amount)
+	case !sameWallet(transfer.SourceWallet, data.FromWalletAddr):
+		return fmt.Sprintf("sender %s instead of %s", transfer.SourceWallet, data.FromWalletAddr)
+	case !sameWallet(transfer.SpentWallet, data.ToWalletAddr):
+		return fmt.Sprintf("receiver %s instead of %s", transfer.SpentWallet, data.ToWalletAddr)
+	}
+	return ""
+}
+
+// Bech32 addresses are case insensitive, base58 ones aren't
+func sameWallet(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if !strings.EqualFold(a, b) {
+		return false
 	}
-	return ErrInvalidBrc20Transfer
+	_, _, err := bech32.DecodeNoLimit(a)
+	return err == nil
 }
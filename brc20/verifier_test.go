@@ -1,8 +1,11 @@
 package brc20
 
 import (
+	"context"
+	"math/big"
 	"testing"
 
+	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/config"
 	"github.com/stretchr/testify/require"
 )
@@ -11,13 +14,82 @@ func TestBis(t *testing.T) {
 	config.Init()
 	config := config.GetDefaultConfig()
 	config.BtcConfig.ChainConfig = "mainnet"
-	err := VerifyBrc20TransferV2(config, VerifyBrc20DepositData{
+	amt, err := ParseAmount("1", MaxDecimals)
+	require.NoError(t, err)
+	err = VerifyBrc20TransferV2(context.Background(), config, VerifyBrc20DepositData{
 		FromWalletAddr: "1JCX1jsCuiPsPj9nJWrZYCdYnauF99Z1mU",
 		ToWalletAddr:   "bc1pwfk592dwzz4t9wwp7yxs74tctctl9v2aez07j5lkhzx938hvur6sakvwvf",
 		TxId:           "af6a79c5c9d124bdeca189dd8375c6e17f2f24258a485e5515faec0ba9070180",
-		Amount:         1,
-		Decimals:       18,
+		Amount:         amt,
 		Tick:           "bzrk",
 	})
 	require.NoError(t, err)
 }
+
+type staticSource client.Brc20Transfers
+
+func (s staticSource) GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*client.Brc20Transfers, error) {
+	res := client.Brc20Transfers(s)
+	return &res, nil
+}
+
+type staticChain int64
+
+func (c staticChain) GetTxBlockHeight(txId string) (int64, error) {
+	return int64(c), nil
+}
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+	amount, _ := new(big.Int).SetString("100000000000000000001", 10)
+	transfer := client.Brc20Transfer{
+		InscriptionId: "inscription",
+		TxId:          "tx",
+		Tick:          "ORDI",
+		Amount:        NewAmountFromInt(amount),
+		SourceWallet:  "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080",
+		SpentWallet:   "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+	}
+	deposit := VerifyBrc20DepositData{
+		TxId:             "tx",
+		Tick:             "ordi",
+		FromWalletAddr:   "BCRT1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KYGT080",
+		ToWalletAddr:     "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		MinConfirmations: 3,
+	}
+	deposit.Amount, _ = ParseAmount("100.000000000000000001", MaxDecimals)
+	source := staticSource{Transfers: []client.Brc20Transfer{transfer}, BlockHeight: 102}
+
+	// Height comes from the chain when the source doesn't report it
+	_, err := Brc20Verifier{Source: source}.Verify(ctx, deposit)
+	require.ErrorIs(t, err, ErrMissingTransferHeights)
+	res, err := Brc20Verifier{Source: source, Chain: staticChain(100)}.Verify(ctx, deposit)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), res.BlockHeight)
+
+	_, err = Brc20Verifier{Source: source, Chain: staticChain(101)}.Verify(ctx, deposit)
+	require.ErrorIs(t, err, ErrNotEnoughConfirmations)
+	_, err = Brc20Verifier{Source: source, Chain: staticChain(0)}.Verify(ctx, deposit)
+	require.ErrorIs(t, err, ErrUnknownTransferHeight)
+
+	withHeight := transfer
+	withHeight.BlockHeight = 90
+	_, err = Brc20Verifier{Source: staticSource{Transfers: []client.Brc20Transfer{withHeight}, BlockHeight: 102}}.Verify(ctx, deposit)
+	require.NoError(t, err)
+	minHeight := deposit
+	minHeight.MinBlockHeight = 95
+	_, err = Brc20Verifier{Source: staticSource{Transfers: []client.Brc20Transfer{withHeight}, BlockHeight: 102}}.Verify(ctx, minHeight)
+	require.ErrorIs(t, err, ErrTransferBelowMinHeight)
+
+	// Amounts are compared exactly
+	rounded := deposit
+	rounded.Amount, _ = ParseAmount("100", MaxDecimals)
+	_, err = Brc20Verifier{Source: source, Chain: staticChain(100)}.Verify(ctx, rounded)
+	require.ErrorIs(t, err, ErrInvalidBrc20Transfer)
+
+	// Base58 addresses are case sensitive
+	wrongCase := deposit
+	wrongCase.ToWalletAddr = "MIPCBBFG9GMICH81KJ8TQQDGOZUB1ZJRFN"
+	_, err = Brc20Verifier{Source: source, Chain: staticChain(100)}.Verify(ctx, wrongCase)
+	require.ErrorIs(t, err, ErrInvalidBrc20Transfer)
+}
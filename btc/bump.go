@@ -0,0 +1,317 @@
+package btc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+)
+
+const (
+	// Bitcoin core's default incremental relay fee (sats/vB), a replacement pays it on top of the fees it evicts
+	incrementalRelayFeeRate = 1
+
+	dustLimit = 546
+
+	// Signatures can change size when re-signing, so funding is retried a few times
+	maxFundAttempts = 4
+)
+
+var (
+	ErrNoChangeOutput  = errors.New("tx has no change output to take the higher fee from")
+	ErrFeeRateTooLow   = errors.New("fee rate has to be higher than the current one")
+	ErrChangeTooLow    = errors.New("change can't cover the higher fee")
+	ErrAmbiguousChange = errors.New("tx pays back to the key more than once, the change can't be told apart")
+	ErrHasDescendants  = errors.New("tx is spent by unconfirmed txs which a replacement would evict")
+)
+
+// Node queries needed for bumping, satisfied by BtcRpcClient
+type BumpChain interface {
+	GetRawTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error)
+	MempoolEntry(txId string) (*client.MempoolEntry, error)
+	SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error)
+}
+
+var _ BumpChain = &client.BtcRpcClient{}
+
+type BumpResult struct {
+	Replaced []string // Original txs, parents first
+	TxIds    []string // Replacements, in the same order
+	Fees     []int64  // Fees of the replacements
+}
+
+// Replace an unconfirmed tx with the same inputs and a higher fee, taken from its change output.
+// Every other output is kept as it is, so inscriptions & runes end up where they would have.
+// Commit/reveal pairs are rebuilt together, whichever of the two is given. Txs spent by anything else,
+// like a chained transfer or a CPFP child, are refused since the replacement would evict them
+func BumpFee(txId string, feeRate uint64, privKey *btcec.PrivateKey, config config.Config) (*BumpResult, error) {
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return Bump(rpc, txId, feeRate, privKey)
+}
+
+func Bump(chain BumpChain, txId string, feeRate uint64, privKey *btcec.PrivateKey) (*BumpResult, error) {
+	b := bumper{chain: chain, feeRate: int64(feeRate), key: privKey, scripts: common.NewKeyScripts(privKey)}
+	tx, err := b.load(txId)
+	if err != nil {
+		return nil, err
+	}
+	if b.isReveal(tx) {
+		commit, err := b.load(tx.msg.TxIn[0].PreviousOutPoint.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+		return b.bumpPair(commit, tx)
+	}
+	for _, child := range tx.entry.SpentBy {
+		childTx, err := b.load(child)
+		if err != nil {
+			return nil, err
+		}
+		if b.isReveal(childTx) && childTx.msg.TxIn[0].PreviousOutPoint.Hash == tx.msg.TxHash() {
+			return b.bumpPair(tx, childTx)
+		}
+	}
+	return b.bumpSingle(tx)
+}
+
+// Unconfirmed tx along with the outputs it spends
+type pendingTx struct {
+	msg      *wire.MsgTx
+	prevOuts []*wire.TxOut
+	entry    *client.MempoolEntry
+}
+
+func (p pendingTx) fee() int64 {
+	return txFee(p.msg, p.prevOuts)
+}
+
+// Fees of the tx and everything spending it, all of which get evicted by a replacement
+func (p pendingTx) evictedFees() (int64, error) {
+	fees, err := btcutil.NewAmount(p.entry.Fees.Descendant)
+	return int64(fees), err
+}
+
+type bumper struct {
+	chain   BumpChain
+	feeRate int64
+	key     *btcec.PrivateKey
+	scripts common.KeyScripts
+}
+
+func (b bumper) load(txId string) (pendingTx, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return pendingTx{}, err
+	}
+	entry, err := b.chain.MempoolEntry(txId)
+	if err != nil {
+		return pendingTx{}, err
+	}
+	tx, err := b.chain.GetRawTransaction(hash)
+	if err != nil {
+		return pendingTx{}, err
+	}
+	msg := tx.MsgTx()
+	prevOuts := make([]*wire.TxOut, len(msg.TxIn))
+	for i, in := range msg.TxIn {
+		parent, err := b.chain.GetRawTransaction(&in.PreviousOutPoint.Hash)
+		if err != nil {
+			return pendingTx{}, err
+		}
+		if int(in.PreviousOutPoint.Index) >= len(parent.MsgTx().TxOut) {
+			return pendingTx{}, fmt.Errorf("input %d spends a missing output %s", i, in.PreviousOutPoint)
+		}
+		prevOuts[i] = parent.MsgTx().TxOut[in.PreviousOutPoint.Index]
+	}
+	return pendingTx{msg: msg, prevOuts: prevOuts, entry: entry}, nil
+}
+
+// A reveal only spends inscription script paths of a single commit
+func (b bumper) isReveal(p pendingTx) bool {
+	for i, in := range p.msg.TxIn {
+		if !txscript.IsPayToTaproot(p.prevOuts[i].PkScript) || b.scripts.Owns(p.prevOuts[i].PkScript) || len(in.Witness) != 3 {
+			return false
+		}
+		if in.PreviousOutPoint.Hash != p.msg.TxIn[0].PreviousOutPoint.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// Only output paying back to the key. Only the value of the change changes, so when it is the last output,
+// like in every tx this service builds except rune transfers, no sat moves to a different output.
+// A tx paying the key twice, e.g. sending to its own address, is refused rather than guessing
+func (b bumper) changeOutput(msg *wire.MsgTx) (int, error) {
+	change := -1
+	for i, out := range msg.TxOut {
+		if out.Value == 0 || !b.scripts.Owns(out.PkScript) {
+			continue
+		}
+		if change >= 0 {
+			return 0, fmt.Errorf("%w: outputs %d and %d", ErrAmbiguousChange, change, i)
+		}
+		change = i
+	}
+	if change < 0 {
+		return 0, ErrNoChangeOutput
+	}
+	return change, nil
+}
+
+// Refuse replacing a tx spent by anything but the txs rebuilt along with it
+func (b bumper) checkDescendants(p pendingTx, rebuilt ...string) error {
+	for _, child := range p.entry.SpentBy {
+		if !slices.Contains(rebuilt, child) {
+			return fmt.Errorf("%w: %s spends %s", ErrHasDescendants, child, p.msg.TxHash())
+		}
+	}
+	return nil
+}
+
+// Fee of a replacement, at least the fee rate and enough to cover the evicted fees plus its own relay
+func (b bumper) replacementFee(vsize, evicted int64) int64 {
+	return max(vsize*b.feeRate, evicted+vsize*incrementalRelayFeeRate)
+}
+
+func (b bumper) bumpSingle(p pendingTx) (*BumpResult, error) {
+	if err := b.checkDescendants(p); err != nil {
+		return nil, err
+	}
+	if b.feeRate*vsize(p.msg) <= p.fee() {
+		return nil, fmt.Errorf("%w: pays %d sats for %d vB", ErrFeeRateTooLow, p.fee(), vsize(p.msg))
+	}
+	evicted, err := p.evictedFees()
+	if err != nil {
+		return nil, err
+	}
+	msg := replaceable(p.msg)
+	changeIdx, err := b.changeOutput(msg)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := b.fund(msg, p.prevOuts, changeIdx, func(vsize int64) int64 { return b.replacementFee(vsize, evicted) })
+	if err != nil {
+		return nil, err
+	}
+	hash, err := b.chain.SendRawTransaction(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	return &BumpResult{Replaced: []string{p.msg.TxHash().String()}, TxIds: []string{hash.String()}, Fees: []int64{fee}}, nil
+}
+
+// The commit carries the reveal fee in its inscription outputs, so both are rebuilt. The new commit pays
+// for the evicted reveal, since the node looks at it on its own
+func (b bumper) bumpPair(commit, reveal pendingTx) (*BumpResult, error) {
+	if err := b.checkDescendants(commit, reveal.msg.TxHash().String()); err != nil {
+		return nil, err
+	}
+	if err := b.checkDescendants(reveal); err != nil {
+		return nil, err
+	}
+	if b.feeRate*(vsize(commit.msg)+vsize(reveal.msg)) <= commit.fee()+reveal.fee() {
+		return nil, fmt.Errorf("%w: pays %d sats for %d vB", ErrFeeRateTooLow, commit.fee()+reveal.fee(), vsize(commit.msg)+vsize(reveal.msg))
+	}
+	evicted, err := commit.evictedFees()
+	if err != nil {
+		return nil, err
+	}
+
+	newCommit := replaceable(commit.msg)
+	// Like when inscribing, the last inscription output carries the reveal fee
+	if delta := b.feeRate*vsize(reveal.msg) - reveal.fee(); delta > 0 {
+		carrier := reveal.msg.TxIn[len(reveal.msg.TxIn)-1].PreviousOutPoint.Index
+		newCommit.TxOut[carrier].Value += delta
+	}
+	changeIdx, err := b.changeOutput(newCommit)
+	if err != nil {
+		return nil, err
+	}
+	commitFee, err := b.fund(newCommit, commit.prevOuts, changeIdx, func(vsize int64) int64 { return b.replacementFee(vsize, evicted) })
+	if err != nil {
+		return nil, err
+	}
+
+	newReveal := replaceable(reveal.msg)
+	commitHash := newCommit.TxHash()
+	revealPrevOuts := make([]*wire.TxOut, len(newReveal.TxIn))
+	for i, in := range newReveal.TxIn {
+		in.PreviousOutPoint.Hash = commitHash
+		revealPrevOuts[i] = newCommit.TxOut[in.PreviousOutPoint.Index]
+	}
+	if err := common.SignInputs(newReveal, revealPrevOuts, b.key); err != nil {
+		return nil, err
+	}
+
+	res := &BumpResult{Replaced: []string{commit.msg.TxHash().String(), reveal.msg.TxHash().String()}}
+	for _, tx := range []struct {
+		msg *wire.MsgTx
+		fee int64
+	}{{newCommit, commitFee}, {newReveal, txFee(newReveal, revealPrevOuts)}} {
+		hash, err := b.chain.SendRawTransaction(tx.msg, true)
+		if err != nil {
+			return res, err
+		}
+		res.TxIds = append(res.TxIds, hash.String())
+		res.Fees = append(res.Fees, tx.fee)
+	}
+	return res, nil
+}
+
+// Sign the tx, lowering the change until the fee covers minFee at the signed size
+func (b bumper) fund(msg *wire.MsgTx, prevOuts []*wire.TxOut, changeIdx int, minFee func(vsize int64) int64) (int64, error) {
+	for attempt := 0; ; attempt++ {
+		if err := common.SignInputs(msg, prevOuts, b.key); err != nil {
+			return 0, err
+		}
+		fee, needed := txFee(msg, prevOuts), minFee(vsize(msg))
+		if fee >= needed {
+			return fee, nil
+		}
+		if attempt == maxFundAttempts {
+			return 0, fmt.Errorf("%w: couldn't reach %d sats of fee", ErrChangeTooLow, needed)
+		}
+		change := msg.TxOut[changeIdx]
+		if change.Value-(needed-fee) < dustLimit {
+			return 0, fmt.Errorf("%w: %d sats of change, %d more sats of fee needed", ErrChangeTooLow, change.Value, needed-fee)
+		}
+		change.Value -= needed - fee
+	}
+}
+
+// Copy of the tx with every input signalling replaceability
+func replaceable(msg *wire.MsgTx) *wire.MsgTx {
+	tx := msg.Copy()
+	for _, in := range tx.TxIn {
+		in.Sequence = common.RbfSequenceNum
+	}
+	return tx
+}
+
+func txFee(msg *wire.MsgTx, prevOuts []*wire.TxOut) int64 {
+	var fee int64
+	for _, out := range prevOuts {
+		fee += out.Value
+	}
+	for _, out := range msg.TxOut {
+		fee -= out.Value
+	}
+	return fee
+}
+
+func vsize(msg *wire.MsgTx) int64 {
+	return mempool.GetTxVirtualSize(btcutil.NewTx(msg))
+}
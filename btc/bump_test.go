@@ -0,0 +1,256 @@
+package btc
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMempool struct {
+	txs     map[chainhash.Hash]*wire.MsgTx
+	entries map[string]*client.MempoolEntry
+	sent    []*wire.MsgTx
+}
+
+func newFakeMempool() *fakeMempool {
+	return &fakeMempool{txs: map[chainhash.Hash]*wire.MsgTx{}, entries: map[string]*client.MempoolEntry{}}
+}
+
+func (m *fakeMempool) GetRawTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error) {
+	tx, ok := m.txs[*txHash]
+	if !ok {
+		return nil, client.ErrNotInMempool
+	}
+	return btcutil.NewTx(tx), nil
+}
+
+func (m *fakeMempool) MempoolEntry(txId string) (*client.MempoolEntry, error) {
+	entry, ok := m.entries[txId]
+	if !ok {
+		return nil, client.ErrNotInMempool
+	}
+	return entry, nil
+}
+
+func (m *fakeMempool) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
+	m.sent = append(m.sent, tx)
+	hash := tx.TxHash()
+	return &hash, nil
+}
+
+// Confirmed tx paying to the scripts
+func (m *fakeMempool) fund(values []int64, pkScripts ...[]byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.DoubleHashH([]byte{byte(len(m.txs))})}, nil, nil))
+	for i, pkScript := range pkScripts {
+		tx.AddTxOut(wire.NewTxOut(values[i], pkScript))
+	}
+	m.txs[tx.TxHash()] = tx
+	return tx
+}
+
+// Add an unconfirmed tx, fees being the fees of the tx and its descendants
+func (m *fakeMempool) add(tx *wire.MsgTx, fees int64, spentBy ...string) {
+	m.txs[tx.TxHash()] = tx
+	entry := &client.MempoolEntry{SpentBy: spentBy}
+	entry.Fees.Descendant = btcutil.Amount(fees).ToBTC()
+	m.entries[tx.TxHash().String()] = entry
+}
+
+func (m *fakeMempool) prevOuts(tx *wire.MsgTx) *txscript.MultiPrevOutFetcher {
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for _, in := range tx.TxIn {
+		parent := m.txs[in.PreviousOutPoint.Hash]
+		for _, sent := range m.sent {
+			if sent.TxHash() == in.PreviousOutPoint.Hash {
+				parent = sent
+			}
+		}
+		fetcher.AddPrevOut(in.PreviousOutPoint, parent.TxOut[in.PreviousOutPoint.Index])
+	}
+	return fetcher
+}
+
+func (m *fakeMempool) requireValid(t *testing.T, tx *wire.MsgTx) {
+	fetcher := m.prevOuts(tx)
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	for i, in := range tx.TxIn {
+		prevOut := fetcher.FetchPrevOutput(in.PreviousOutPoint)
+		engine, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, fetcher)
+		require.NoError(t, err)
+		require.NoError(t, engine.Execute(), "input %d", i)
+		require.Equal(t, common.RbfSequenceNum, in.Sequence)
+	}
+}
+
+func (m *fakeMempool) requireFeeRate(t *testing.T, tx *wire.MsgTx, feeRate int64) int64 {
+	fetcher := m.prevOuts(tx)
+	prevOuts := make([]*wire.TxOut, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		prevOuts[i] = fetcher.FetchPrevOutput(in.PreviousOutPoint)
+	}
+	fee := txFee(tx, prevOuts)
+	require.GreaterOrEqual(t, fee, feeRate*vsize(tx))
+	return fee
+}
+
+func TestBumpTransfer(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	scripts := common.NewKeyScripts(key)
+	receiver := []byte{txscript.OP_TRUE}
+
+	m := newFakeMempool()
+	funding := m.fund([]int64{546, 10_000}, scripts.P2PKHCompressed, scripts.P2PKHCompressed)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash(), Index: 0}, nil, nil))
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash(), Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(546, receiver))
+	tx.AddTxOut(wire.NewTxOut(9_600, scripts.P2PKHCompressed))
+	require.NoError(t, common.SignInputs(tx, funding.TxOut, key))
+	m.add(tx, 400)
+
+	_, err = Bump(m, tx.TxHash().String(), 1, key)
+	require.ErrorIs(t, err, ErrFeeRateTooLow)
+
+	res, err := Bump(m, tx.TxHash().String(), 20, key)
+	require.NoError(t, err)
+	require.Equal(t, []string{tx.TxHash().String()}, res.Replaced)
+	require.Len(t, m.sent, 1)
+
+	bumped := m.sent[0]
+	require.Equal(t, bumped.TxHash().String(), res.TxIds[0])
+	m.requireValid(t, bumped)
+	require.Equal(t, res.Fees[0], m.requireFeeRate(t, bumped, 20))
+	require.Equal(t, tx.TxOut[0], bumped.TxOut[0])
+	require.Equal(t, 9_600-(res.Fees[0]-400), bumped.TxOut[1].Value)
+
+	// Not enough change for the fee
+	_, err = Bump(m, tx.TxHash().String(), 1000, key)
+	require.ErrorIs(t, err, ErrChangeTooLow)
+
+	// A tx spending the change would be evicted
+	child := wire.NewMsgTx(2)
+	child.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: tx.TxHash(), Index: 1}, nil, nil))
+	child.AddTxOut(wire.NewTxOut(9_000, receiver))
+	require.NoError(t, common.SignInputs(child, tx.TxOut[1:], key))
+	m.add(child, 600)
+	m.add(tx, 1000, child.TxHash().String())
+	m.sent = nil
+	_, err = Bump(m, tx.TxHash().String(), 20, key)
+	require.ErrorIs(t, err, ErrHasDescendants)
+	require.Empty(t, m.sent)
+}
+
+// The change is the only output paying back to the key, wherever it is
+func TestBumpChangeOutput(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	scripts := common.NewKeyScripts(key)
+	receiver := []byte{txscript.OP_TRUE}
+	runestone := []byte{txscript.OP_RETURN, txscript.OP_13}
+
+	m := newFakeMempool()
+	funding := m.fund([]int64{10_000}, scripts.P2TR)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash()}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(9_000, scripts.P2TR))
+	tx.AddTxOut(wire.NewTxOut(546, receiver))
+	tx.AddTxOut(wire.NewTxOut(0, runestone))
+	require.NoError(t, common.SignInputs(tx, funding.TxOut, key))
+	m.add(tx, 454)
+
+	res, err := Bump(m, tx.TxHash().String(), 20, key)
+	require.NoError(t, err)
+	bumped := m.sent[0]
+	m.requireValid(t, bumped)
+	require.Equal(t, 9_000-(res.Fees[0]-454), bumped.TxOut[0].Value)
+	require.Equal(t, tx.TxOut[1:], bumped.TxOut[1:])
+
+	// Sending to the key's own address leaves two candidates
+	self := wire.NewMsgTx(2)
+	self.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash()}, nil, nil))
+	self.AddTxOut(wire.NewTxOut(546, scripts.P2TR))
+	self.AddTxOut(wire.NewTxOut(9_000, scripts.P2TR))
+	require.NoError(t, common.SignInputs(self, funding.TxOut, key))
+	m.add(self, 454)
+	_, err = Bump(m, self.TxHash().String(), 20, key)
+	require.ErrorIs(t, err, ErrAmbiguousChange)
+}
+
+func TestBumpCommitReveal(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	scripts := common.NewKeyScripts(key)
+
+	// Inscription script path, the way the inscribers build it
+	lockScript, err := txscript.NewScriptBuilder().
+		AddData(schnorr.SerializePubKey(key.PubKey())).
+		AddOp(txscript.OP_CHECKSIG).
+		AddOp(txscript.OP_FALSE).
+		AddOp(txscript.OP_IF).
+		AddData([]byte("ord")).
+		AddOp(txscript.OP_ENDIF).
+		Script()
+	require.NoError(t, err)
+	leaf := txscript.NewBaseTapLeaf(lockScript)
+	tree := txscript.AssembleTaprootScriptTree(leaf)
+	proof := tree.LeafMerkleProofs[0].ToControlBlock(key.PubKey())
+	controlBlock, err := proof.ToBytes()
+	require.NoError(t, err)
+	rootHash := tree.RootNode.TapHash()
+	outputKey := txscript.ComputeTaprootOutputKey(key.PubKey(), rootHash[:])
+	inscriptionScript, err := txscript.PayToTaprootScript(outputKey)
+	require.NoError(t, err)
+
+	m := newFakeMempool()
+	funding := m.fund([]int64{50_000}, scripts.P2TR)
+	commit := wire.NewMsgTx(2)
+	commit.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash()}, nil, nil))
+	commit.AddTxOut(wire.NewTxOut(546+200, inscriptionScript))
+	commit.AddTxOut(wire.NewTxOut(50_000-746-150, scripts.P2TR))
+	require.NoError(t, common.SignInputs(commit, funding.TxOut, key))
+
+	reveal := wire.NewMsgTx(2)
+	reveal.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: commit.TxHash()}, nil, wire.TxWitness{make([]byte, 64), lockScript, controlBlock}))
+	reveal.AddTxOut(wire.NewTxOut(546, scripts.P2PKHCompressed))
+	require.NoError(t, common.SignInputs(reveal, commit.TxOut[:1], key))
+
+	m.add(commit, 350+200, reveal.TxHash().String())
+	m.add(reveal, 200)
+
+	// Either tx of the pair can be given
+	for _, txId := range []string{reveal.TxHash().String(), commit.TxHash().String()} {
+		m.sent = nil
+		res, err := Bump(m, txId, 10, key)
+		require.NoError(t, err)
+		require.Equal(t, []string{commit.TxHash().String(), reveal.TxHash().String()}, res.Replaced)
+		require.Len(t, m.sent, 2)
+
+		newCommit, newReveal := m.sent[0], m.sent[1]
+		m.requireValid(t, newCommit)
+		m.requireValid(t, newReveal)
+		require.Equal(t, newCommit.TxHash(), newReveal.TxIn[0].PreviousOutPoint.Hash)
+		require.Equal(t, reveal.TxOut, newReveal.TxOut)
+		require.GreaterOrEqual(t, res.Fees[0], int64(350+200+vsize(newCommit)))
+		m.requireFeeRate(t, newCommit, 10)
+		m.requireFeeRate(t, newReveal, 10)
+	}
+
+	// Moving the inscription before the reveal confirms keeps the pair from being bumped
+	transfer := wire.NewMsgTx(2)
+	transfer.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: reveal.TxHash()}, nil, nil))
+	transfer.AddTxOut(wire.NewTxOut(546, []byte{txscript.OP_TRUE}))
+	m.add(transfer, 100)
+	m.add(reveal, 300, transfer.TxHash().String())
+	_, err = Bump(m, commit.TxHash().String(), 10, key)
+	require.ErrorIs(t, err, ErrHasDescendants)
+}
@@ -0,0 +1,217 @@
+package btc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+)
+
+var (
+	ErrTxConfirmed    = errors.New("tx is already confirmed")
+	ErrNoOwnedOutput  = errors.New("tx has no output the key can spend")
+	ErrInvalidFunding = errors.New("invalid funding utxo")
+)
+
+// Node queries needed for CPFP, satisfied by BtcRpcClient
+type CpfpChain interface {
+	GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error)
+	SubmitPackage(txs ...*wire.MsgTx) error
+	SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error)
+}
+
+var _ CpfpChain = &client.BtcRpcClient{}
+
+type CpfpResult struct {
+	ParentTxId     string
+	ChildTxId      string
+	ChildFee       int64
+	PackageFeeRate float64 // sats/vB of parent + child
+	PackageRelay   bool    // Broadcast with submitpackage
+}
+
+// Accelerate an unconfirmed tx which can't be replaced, e.g. an incoming deposit or a reveal whose commit confirmed,
+// by spending one of its outputs back to the key with a fee bringing parent + child up to the fee rate.
+// The fee comes from funding, a cardinal utxo of the key, so the output spent keeps its value and whatever
+// inscription or runes it holds
+func Accelerate(txId string, packageFeeRate uint64, privKey *btcec.PrivateKey, funding wire.OutPoint, config config.Config) (*CpfpResult, error) {
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return Cpfp(rpc, txId, packageFeeRate, privKey, funding)
+}
+
+func Cpfp(chain CpfpChain, txId string, packageFeeRate uint64, privKey *btcec.PrivateKey, funding wire.OutPoint) (*CpfpResult, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := chain.GetRawTransactionVerbose(hash)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Confirmations > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTxConfirmed, txId)
+	}
+	parentFee, err := verboseTxFee(chain, parent)
+	if err != nil {
+		return nil, err
+	}
+	feeRate := int64(packageFeeRate)
+	if parentFee >= feeRate*int64(parent.Vsize) {
+		return nil, fmt.Errorf("%w: pays %d sats for %d vB", ErrFeeRateTooLow, parentFee, parent.Vsize)
+	}
+
+	b := bumper{feeRate: feeRate, key: privKey, scripts: common.NewKeyScripts(privKey)}
+	prevOut, vout, err := b.ownedOutput(parent)
+	if err != nil {
+		return nil, err
+	}
+	fundingOut, err := b.fundingOutput(chain, funding)
+	if err != nil {
+		return nil, err
+	}
+	if funding.Hash == *hash {
+		return nil, fmt.Errorf("%w: %s is an output of the tx accelerated", ErrInvalidFunding, funding)
+	}
+
+	// The output goes back to the same script with the same value, so an inscription or runes on it stay there.
+	// The fee is taken from the change of the funding utxo
+	child := wire.NewMsgTx(wire.TxVersion)
+	child.AddTxIn(common.NewRbfTxIn(wire.NewOutPoint(hash, vout), nil, nil))
+	child.AddTxIn(common.NewRbfTxIn(&funding, nil, nil))
+	child.AddTxOut(wire.NewTxOut(prevOut.Value, prevOut.PkScript))
+	child.AddTxOut(wire.NewTxOut(fundingOut.Value, fundingOut.PkScript))
+	childFee, err := b.fund(child, []*wire.TxOut{prevOut, fundingOut}, 1, func(vsize int64) int64 {
+		return max(feeRate*(int64(parent.Vsize)+vsize)-parentFee, vsize*incrementalRelayFeeRate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CpfpResult{
+		ParentTxId:     txId,
+		ChildTxId:      child.TxHash().String(),
+		ChildFee:       childFee,
+		PackageFeeRate: float64(parentFee+childFee) / float64(int64(parent.Vsize)+vsize(child)),
+		PackageRelay:   true,
+	}
+	parentMsg, err := decodeTx(parent.Hex)
+	if err != nil {
+		return nil, err
+	}
+	err = chain.SubmitPackage(parentMsg, child)
+	if errors.Is(err, client.ErrPackageRelayUnsupported) {
+		// The parent has to be in the mempool already
+		res.PackageRelay = false
+		_, err = chain.SendRawTransaction(child, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Largest output paying to the key
+func (b bumper) ownedOutput(tx *btcjson.TxRawResult) (*wire.TxOut, uint32, error) {
+	var (
+		best *wire.TxOut
+		vout uint32
+	)
+	for _, out := range tx.Vout {
+		txOut, err := verboseTxOut(out)
+		if err != nil {
+			return nil, 0, err
+		}
+		if b.scripts.Owns(txOut.PkScript) && (best == nil || txOut.Value > best.Value) {
+			best, vout = txOut, out.N
+		}
+	}
+	if best == nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrNoOwnedOutput, tx.Txid)
+	}
+	return best, vout, nil
+}
+
+// Output of the key paying for the child
+func (b bumper) fundingOutput(chain CpfpChain, funding wire.OutPoint) (*wire.TxOut, error) {
+	tx, err := chain.GetRawTransactionVerbose(&funding.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if int(funding.Index) >= len(tx.Vout) {
+		return nil, fmt.Errorf("%w: %s doesn't exist", ErrInvalidFunding, funding)
+	}
+	out, err := verboseTxOut(tx.Vout[funding.Index])
+	if err != nil {
+		return nil, err
+	}
+	if !b.scripts.Owns(out.PkScript) {
+		return nil, fmt.Errorf("%w: %s isn't spendable by the key", ErrInvalidFunding, funding)
+	}
+	return out, nil
+}
+
+// Fee of a verbose tx, from the outputs it spends
+func verboseTxFee(chain CpfpChain, tx *btcjson.TxRawResult) (int64, error) {
+	var fee int64
+	for _, in := range tx.Vin {
+		hash, err := chainhash.NewHashFromStr(in.Txid)
+		if err != nil {
+			return 0, err
+		}
+		parent, err := chain.GetRawTransactionVerbose(hash)
+		if err != nil {
+			return 0, err
+		}
+		if int(in.Vout) >= len(parent.Vout) {
+			return 0, fmt.Errorf("input spends a missing output %s:%d", in.Txid, in.Vout)
+		}
+		value, err := btcutil.NewAmount(parent.Vout[in.Vout].Value)
+		if err != nil {
+			return 0, err
+		}
+		fee += int64(value)
+	}
+	for _, out := range tx.Vout {
+		value, err := btcutil.NewAmount(out.Value)
+		if err != nil {
+			return 0, err
+		}
+		fee -= int64(value)
+	}
+	return fee, nil
+}
+
+func verboseTxOut(out btcjson.Vout) (*wire.TxOut, error) {
+	value, err := btcutil.NewAmount(out.Value)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := hex.DecodeString(out.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewTxOut(int64(value), pkScript), nil
+}
+
+func decodeTx(rawHex string) (*wire.MsgTx, error) {
+	raw, err := hex.DecodeString(rawHex)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := btcutil.NewTxFromBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	return tx.MsgTx(), nil
+}
@@ -0,0 +1,151 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCpfpChain struct {
+	*fakeMempool
+	confirmed    map[chainhash.Hash]bool
+	packageRelay bool
+	packages     [][]*wire.MsgTx
+}
+
+func (c *fakeCpfpChain) GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	tx, ok := c.txs[*txHash]
+	if !ok {
+		return nil, client.ErrNotInMempool
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	res := &btcjson.TxRawResult{Hex: hex.EncodeToString(buf.Bytes()), Txid: txHash.String(), Vsize: int32(vsize(tx))}
+	if c.confirmed[*txHash] {
+		res.Confirmations = 1
+	}
+	for _, in := range tx.TxIn {
+		res.Vin = append(res.Vin, btcjson.Vin{Txid: in.PreviousOutPoint.Hash.String(), Vout: in.PreviousOutPoint.Index})
+	}
+	for i, out := range tx.TxOut {
+		res.Vout = append(res.Vout, btcjson.Vout{
+			Value:        btcutil.Amount(out.Value).ToBTC(),
+			N:            uint32(i),
+			ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: hex.EncodeToString(out.PkScript)},
+		})
+	}
+	return res, nil
+}
+
+func (c *fakeCpfpChain) SubmitPackage(txs ...*wire.MsgTx) error {
+	if !c.packageRelay {
+		return client.ErrPackageRelayUnsupported
+	}
+	c.packages = append(c.packages, txs)
+	return nil
+}
+
+func TestCpfp(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	scripts := common.NewKeyScripts(key)
+
+	c := &fakeCpfpChain{fakeMempool: newFakeMempool(), confirmed: map[chainhash.Hash]bool{}}
+	funding := c.fund([]int64{30_000, 20_000}, []byte{txscript.OP_TRUE}, scripts.P2PKHCompressed)
+	c.confirmed[funding.TxHash()] = true
+	cardinal := wire.OutPoint{Hash: funding.TxHash(), Index: 1}
+
+	// Incoming deposit paying ~2 sats/vB, the largest output we own is used
+	deposit := wire.NewMsgTx(2)
+	deposit.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: funding.TxHash()}, nil, nil))
+	deposit.AddTxOut(wire.NewTxOut(546, scripts.P2TR))
+	deposit.AddTxOut(wire.NewTxOut(10_000, scripts.P2PKHCompressed))
+	deposit.AddTxOut(wire.NewTxOut(30_000-10_546-300, []byte{txscript.OP_TRUE}))
+	c.add(deposit, 300)
+
+	_, err = Cpfp(c, deposit.TxHash().String(), 2, key, cardinal)
+	require.ErrorIs(t, err, ErrFeeRateTooLow)
+
+	res, err := Cpfp(c, deposit.TxHash().String(), 15, key, cardinal)
+	require.NoError(t, err)
+	require.False(t, res.PackageRelay)
+	require.Len(t, c.sent, 1)
+	child := c.sent[0]
+	require.Equal(t, res.ChildTxId, child.TxHash().String())
+	require.Equal(t, wire.OutPoint{Hash: deposit.TxHash(), Index: 1}, child.TxIn[0].PreviousOutPoint)
+	require.Equal(t, cardinal, child.TxIn[1].PreviousOutPoint)
+	require.Equal(t, deposit.TxOut[1], child.TxOut[0])
+	require.Equal(t, scripts.P2PKHCompressed, child.TxOut[1].PkScript)
+	c.requireValid(t, child)
+	require.Equal(t, res.ChildFee, 20_000-child.TxOut[1].Value)
+	require.GreaterOrEqual(t, 300+res.ChildFee, 15*(vsize(deposit)+vsize(child)))
+	require.GreaterOrEqual(t, res.PackageFeeRate, 15.0)
+
+	// Parent and child go together when the node has package relay
+	c.packageRelay, c.sent = true, nil
+	res, err = Cpfp(c, deposit.TxHash().String(), 15, key, cardinal)
+	require.NoError(t, err)
+	require.True(t, res.PackageRelay)
+	require.Empty(t, c.sent)
+	require.Len(t, c.packages, 1)
+	require.Equal(t, deposit.TxHash(), c.packages[0][0].TxHash())
+	require.Equal(t, res.ChildTxId, c.packages[0][1].TxHash().String())
+
+	// The funding has to be another utxo of the key
+	_, err = Cpfp(c, deposit.TxHash().String(), 15, key, wire.OutPoint{Hash: funding.TxHash()})
+	require.ErrorIs(t, err, ErrInvalidFunding)
+	_, err = Cpfp(c, deposit.TxHash().String(), 15, key, wire.OutPoint{Hash: deposit.TxHash()})
+	require.ErrorIs(t, err, ErrInvalidFunding)
+
+	c.confirmed[deposit.TxHash()] = true
+	_, err = Cpfp(c, deposit.TxHash().String(), 15, key, cardinal)
+	require.ErrorIs(t, err, ErrTxConfirmed)
+
+	_, err = Cpfp(c, funding.TxHash().String(), 15, key, cardinal)
+	require.ErrorIs(t, err, ErrTxConfirmed)
+
+	other, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delete(c.confirmed, deposit.TxHash())
+	_, err = Cpfp(c, deposit.TxHash().String(), 15, other, cardinal)
+	require.ErrorIs(t, err, ErrNoOwnedOutput)
+}
+
+// A reveal only has the postage of the inscription, which is kept as it is
+func TestCpfpPostage(t *testing.T) {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	scripts := common.NewKeyScripts(key)
+
+	c := &fakeCpfpChain{fakeMempool: newFakeMempool(), confirmed: map[chainhash.Hash]bool{}}
+	commit := c.fund([]int64{546 + 150, 5_000}, []byte{txscript.OP_TRUE}, scripts.P2TR)
+	c.confirmed[commit.TxHash()] = true
+	reveal := wire.NewMsgTx(2)
+	reveal.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: commit.TxHash()}, nil, nil))
+	reveal.AddTxOut(wire.NewTxOut(546, scripts.P2TR))
+	c.add(reveal, 150)
+
+	res, err := Cpfp(c, reveal.TxHash().String(), 10, key, wire.OutPoint{Hash: commit.TxHash(), Index: 1})
+	require.NoError(t, err)
+	child := c.sent[0]
+	c.requireValid(t, child)
+	require.Equal(t, reveal.TxOut[0], child.TxOut[0])
+	require.Equal(t, 5_000-res.ChildFee, child.TxOut[1].Value)
+	require.GreaterOrEqual(t, res.PackageFeeRate, 10.0)
+
+	// Not enough left for the change
+	_, err = Cpfp(c, reveal.TxHash().String(), 100, key, wire.OutPoint{Hash: commit.TxHash(), Index: 1})
+	require.ErrorIs(t, err, ErrChangeTooLow)
+}
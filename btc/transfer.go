@@ -41,7 +41,7 @@ func TransferBtc(
 	fmt.Println("selected utxo: ", utxo.GetTxID())
 
 	dummySigScript := bytes.Repeat([]byte{0x00}, 105)
-	txin0 := wire.NewTxIn(utxo0, dummySigScript, [][]byte{})
+	txin0 := common.NewRbfTxIn(utxo0, dummySigScript, [][]byte{})
 
 	tx.AddTxIn(txin0)
 	txout0 := wire.NewTxOut(int64(amtInSats), destinationAddrScript)
@@ -76,9 +76,11 @@ func TransferBtc(
 
 	tx.TxIn[0].SignatureScript = sigScript0
 
-	// TODO: Send tx
-	client := client.NewBitcoinClient(config)
-	h, err := client.SendRawTransaction(tx.MsgTx, true)
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return err
+	}
+	h, err := rpc.SendRawTransaction(tx.MsgTx, true)
 
 	if err != nil {
 		fmt.Println("Error broadcasting tx")
@@ -1,22 +1,40 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/rs/zerolog/log"
 )
 
 type BISClient struct {
-	baseUrl string
-	apiKey  string
+	baseUrl     string
+	apiKey      string
+	http        *http.Client
+	maxAttempts int
+	backoff     time.Duration // Before the first retry, doubled for every other one unless the api asks for a wait
+	pageSize    int
+	minHeight   int64 // Fail with ErrIndexerBehind while BIS is below it, see WithMinHeight
 }
 
 var _ RunesUnspentOutput = BISRunesUnspentOutput{}
+var _ Brc20EventSource = BISClient{}
+var _ Brc20BalanceSource = BISClient{}
+var _ Brc20TransferableSource = BISClient{}
+var _ RunesUtxoSource = BISClient{}
+var _ RunesInputSource = BISClient{}
+var _ InscriptionUtxoSource = BISClient{}
 
 func (u BISRunesUnspentOutput) GetPkScript() string {
 	return u.Pkscript
@@ -38,101 +56,428 @@ func (u BISRunesUnspentOutput) GetRuneNames() []string {
 	return u.RuneNames
 }
 
+// Balances with the divisibility of each rune
+func (u BISRunesUnspentOutput) GetBalances() []common.TokenAmount {
+	balances := make([]common.TokenAmount, len(u.Balances))
+	for i, b := range u.Balances {
+		balances[i] = b
+		if i < len(u.Decimals) {
+			balances[i] = b.WithDecimals(uint8(u.Decimals[i]))
+		}
+	}
+	return balances
+}
+
 var (
-	ErrApiBadGateway = errors.New("error bad gateway")
+	ErrApiBadGateway   = errors.New("error bad gateway")
+	ErrBisConfig       = errors.New("invalid bis config")
+	ErrBisUnauthorized = errors.New("bis api key rejected")
+	ErrRateLimited     = errors.New("rate limited by the indexer")
+	ErrBisPagesMoved   = errors.New("bis moved to another block while listing")
 )
 
+const (
+	bisPageSize   = 2000        // Largest count BIS accepts for wallet lists
+	maxRetryAfter = time.Minute // A longer Retry-After fails the request instead of waiting
+)
+
+// Error returned by the BIS api
+type BisApiError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // Wait asked for with a Retry-After header, 0 when not given
+}
+
+func (e *BisApiError) Error() string {
+	return fmt.Sprintf("bis %s: %d %s", e.Endpoint, e.StatusCode, e.Message)
+}
+
+// A 404 is ErrNotFound, 401 & 403 are ErrBisUnauthorized, 429 is ErrRateLimited and a 5xx ErrApiBadGateway
+func (e *BisApiError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrBisUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrApiBadGateway:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// The api is overloaded or restarting, unlike a rejected request
+func (e *BisApiError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
 type BISRunesUnspentOutputList = []BISRunesUnspentOutput
 
-func NewBISClient(c config.BISConfig) *BISClient {
-	if len(c.APIKey) == 0 {
-		panic("BIS API_KEY is not defined")
+// Create a BIS client, nothing is requested until it's used
+func NewBISClient(c config.BISConfig) (*BISClient, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("%w: bis_config.api_key not set", ErrBisConfig)
 	}
-	return &BISClient{"https://api.bestinslot.xyz", c.APIKey}
+	baseUrl := c.GetBaseUrl()
+	u, err := url.Parse(baseUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: bis_config.base_url %q should be an http(s) url", ErrBisConfig, baseUrl)
+	}
+	return &BISClient{
+		baseUrl:     strings.TrimSuffix(baseUrl, "/"),
+		apiKey:      c.APIKey,
+		http:        &http.Client{Timeout: c.GetTimeout()},
+		maxAttempts: c.GetMaxAttempts(),
+		backoff:     defaultMinBackoff,
+		pageSize:    bisPageSize,
+	}, nil
 }
 
-func authenticatedBisGetRequest(endpoint, headerKey, headerValue string) ([]byte, error) {
-	client := &http.Client{}
+// Copy of the client failing with ErrIndexerBehind while BIS answers from below the height
+func (b BISClient) WithMinHeight(height int64) *BISClient {
+	b.minHeight = height
+	return &b
+}
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+// GET a response, returning the block height BIS answered at. A null data is ErrNotFound
+func (b BISClient) get(ctx context.Context, path string, query url.Values, result any) (int, error) {
+	endpoint := b.baseUrl + path + "?" + query.Encode()
+	body, err := b.fetch(ctx, endpoint)
 	if err != nil {
-		log.Err(err).Msgf("error creating request [url = %s]", endpoint)
-		return nil, err
+		return 0, err
+	}
+	res := BISResponseWrapper[json.RawMessage]{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, fmt.Errorf("invalid response from %s: %w", endpoint, err)
+	}
+	if int64(res.BlockHeight) < b.minHeight {
+		return res.BlockHeight, fmt.Errorf("%w: bis at %d, %d required", ErrIndexerBehind, res.BlockHeight, b.minHeight)
+	}
+	if len(res.Data) == 0 || string(res.Data) == "null" {
+		return res.BlockHeight, fmt.Errorf("%w: %s", ErrNotFound, endpoint)
+	}
+	return res.BlockHeight, json.Unmarshal(res.Data, result)
+}
+
+// BIS answers empty lists with null data, unlike a 404 which is a wrong endpoint or an unknown id
+func noData(err error) bool {
+	var apiErr *BisApiError
+	return errors.Is(err, ErrNotFound) && !errors.As(err, &apiErr)
+}
+
+// GET every page of a list. The list is fetched again from the start when BIS moves to another block
+// in between, it could otherwise miss or repeat items
+func bisGetAll[T any](ctx context.Context, b BISClient, path string, query url.Values) ([]T, int, error) {
+	items, height := make([]T, 0), -1
+	for restarts := 0; ; {
+		query.Set("offset", strconv.Itoa(len(items)))
+		query.Set("count", strconv.Itoa(b.pageSize))
+		page := make([]T, 0)
+		pageHeight, err := b.get(ctx, path, query, &page)
+		if err != nil && !noData(err) {
+			return nil, 0, err
+		}
+		if height >= 0 && pageHeight != height {
+			if restarts++; restarts >= b.maxAttempts {
+				return nil, 0, fmt.Errorf("%w: %s", ErrBisPagesMoved, path)
+			}
+			items, height = make([]T, 0), -1
+			continue
+		}
+		items, height = append(items, page...), pageHeight
+		if len(page) < b.pageSize {
+			return items, height, nil
+		}
 	}
+}
 
-	req.Header.Set(headerKey, headerValue)
+// GET with retries while the api is unreachable, overloaded or rate limiting, the body of a 200 is returned
+func (b BISClient) fetch(ctx context.Context, endpoint string) ([]byte, error) {
+	backoff := b.backoff
+	for attempt := 1; ; attempt++ {
+		body, err := b.fetchOnce(ctx, endpoint)
+		var apiErr *BisApiError
+		if err == nil || (errors.As(err, &apiErr) && !apiErr.Temporary()) || ctx.Err() != nil || attempt >= b.maxAttempts {
+			return body, err
+		}
+		wait := jitter(backoff)
+		if apiErr != nil && apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > maxRetryAfter {
+				return nil, err
+			}
+			wait = apiErr.RetryAfter
+		}
+		log.Warn().Err(err).Msgf("bis request failed, attempt %d/%d, retrying in %s", attempt, b.maxAttempts, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = min(2*backoff, defaultMaxBackoff)
+	}
+}
 
-	resp, err := client.Do(req)
+func (b BISClient) fetchOnce(ctx context.Context, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		log.Err(err).Msgf("error fetching events by inscription id [url = %s]", endpoint)
 		return nil, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
+	req.Header.Set("x-api-key", b.apiKey)
+	res, err := b.http.Do(req)
 	if err != nil {
-		log.Err(err).Msg("error reading msg body")
 		return nil, err
 	}
-	if resp.StatusCode >= 500 {
-		log.Err(err).Msg("error bestinslot api bad gateway")
-		return nil, fmt.Errorf("BIS bad gateway: %w", ErrApiBadGateway)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
 	}
+	if res.StatusCode != http.StatusOK {
+		return nil, &BisApiError{
+			Endpoint:   endpoint,
+			StatusCode: res.StatusCode,
+			Message:    bisErrorMessage(res.StatusCode, body),
+			RetryAfter: retryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+	return body, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Info().Msgf("GET %s", string(endpoint))
-		log.Err(err).Msgf("http status not ok: [resp = %s]", string(bodyBytes))
-		return nil, err
+// Retry-After in seconds or as an http date
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// The message field of a json body, see opiErrorMessage otherwise
+func bisErrorMessage(status int, body []byte) string {
+	var res struct {
+		Message string `json:"message"`
 	}
-	return bodyBytes, nil
+	if err := json.Unmarshal(body, &res); err == nil && res.Message != "" {
+		return res.Message
+	}
+	return opiErrorMessage(status, body)
 }
 
 // Get all brc20 events with the given transaction ID
-func (b BISClient) GetEventsByTransactionId(txId string) (*BISResponseWrapper[[]BISBrc20Event], error) {
-	endpoint := "https://api.bestinslot.xyz/v3/brc20/event_from_txid?txid=" + txId
-	res, err := authenticatedBisGetRequest(endpoint, "x-api-key", b.apiKey)
+func (b BISClient) GetEventsByTransactionId(ctx context.Context, txId string) (*BISResponseWrapper[[]BISBrc20Event], error) {
+	res := &BISResponseWrapper[[]BISBrc20Event]{Data: make([]BISBrc20Event, 0)}
+	height, err := b.get(ctx, "/v3/brc20/event_from_txid", url.Values{"txid": {txId}}, &res.Data)
+	if err != nil && !noData(err) {
+		return nil, err
+	}
+	res.BlockHeight = height
+	return res, nil
+}
+
+// Get the transfer-transfer events of a tx. BIS doesn't report the block of the event
+func (b BISClient) GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*Brc20Transfers, error) {
+	if txId == "" {
+		return nil, ErrTxIdRequired
+	}
+	events, err := b.GetEventsByTransactionId(ctx, txId)
 	if err != nil {
 		return nil, err
 	}
-	bisResponse := BISResponseWrapper[[]BISBrc20Event]{}
-	if err := json.Unmarshal(res, &bisResponse); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(res))
+	res := &Brc20Transfers{Transfers: make([]Brc20Transfer, 0), BlockHeight: int64(events.BlockHeight)}
+	for _, evt := range events.Data {
+		if evt.EventType != "transfer-transfer" || (inscriptionId != "" && evt.InscriptionID != inscriptionId) {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(evt.Event.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount in bis event: %s", evt.Event.Amount)
+		}
+		res.Transfers = append(res.Transfers, Brc20Transfer{
+			InscriptionId:  evt.InscriptionID,
+			TxId:           txId,
+			Tick:           evt.Event.Tick,
+			Amount:         common.NewTokenAmount(amount, 18),
+			SourceWallet:   evt.Event.SourceWallet,
+			SourcePkScript: evt.Event.SourcePkScript,
+			SpentWallet:    evt.Event.SpentWallet,
+			SpentPkScript:  evt.Event.SpentPkScript,
+		})
+	}
+	return res, nil
+}
+
+// Get the BRC20 balances of a wallet, for every ticker it holds
+func (b BISClient) GetBrc20WalletBalances(ctx context.Context, address string) (*BISResponseWrapper[[]BISBrc20Balance], error) {
+	res := &BISResponseWrapper[[]BISBrc20Balance]{Data: make([]BISBrc20Balance, 0)}
+	height, err := b.get(ctx, "/v3/brc20/wallet_balances", url.Values{"address": {address}}, &res.Data)
+	if err != nil && !noData(err) {
 		return nil, err
 	}
-	return &bisResponse, nil
+	res.BlockHeight = height
+	return res, nil
 }
 
-// Fetch runes UTXOs from BIS API
-func (b BISClient) FetchRunesUtxos(address string) ([]RunesUnspentOutput, error) {
-	endpoint := fmt.Sprintf("%s/v3/runes/wallet_valid_outputs?address=%s&order=asc&offset=0&count=2000&sort_by=output", b.baseUrl, address)
-	res, err := authenticatedBisGetRequest(endpoint, "x-api-key", b.apiKey)
+// Get the BRC20 balance of a wallet for the ticker, zero when it holds none
+func (b BISClient) GetBrc20Balance(ctx context.Context, address, ticker string) (*Brc20Balance, error) {
+	balances, err := b.GetBrc20WalletBalances(ctx, address)
 	if err != nil {
 		return nil, err
 	}
+	res := &Brc20Balance{OverallBalance: "0", AvailableBalance: "0", BlockHeight: balances.BlockHeight}
+	for _, balance := range balances.Data {
+		if strings.EqualFold(balance.Ticker, ticker) {
+			res.OverallBalance, res.AvailableBalance = balance.OverallBalance, balance.AvailableBalance
+		}
+	}
+	return res, nil
+}
 
-	bisResponse := BISResponseWrapper[[]BISRunesUnspentOutput]{}
-	if err := json.Unmarshal(res, &bisResponse); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(res))
+// Get every unspent transfer inscription of a wallet for the ticker
+func (b BISClient) GetBrc20WalletTransferables(ctx context.Context, address, ticker string) ([]BISBrc20Transferable, error) {
+	query := url.Values{"address": {address}, "ticker": {ticker}, "sort_by": {"ts"}, "order": {"asc"}}
+	transferables, _, err := bisGetAll[BISBrc20Transferable](ctx, b, "/v3/brc20/wallet_transferable", query)
+	return transferables, err
+}
+
+// Get the unspent transfer inscriptions of a wallet for the ticker
+func (b BISClient) GetBrc20Transferables(ctx context.Context, address, ticker string) ([]Brc20Transferable, error) {
+	transferables, err := b.GetBrc20WalletTransferables(ctx, address, ticker)
+	if err != nil {
 		return nil, err
 	}
+	res := make([]Brc20Transferable, len(transferables))
+	for i, t := range transferables {
+		amount, ok := new(big.Int).SetString(t.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount in bis transferable: %s", t.Amount)
+		}
+		res[i] = Brc20Transferable{
+			Tick:          t.Ticker,
+			InscriptionId: t.InscriptionId,
+			Amount:        common.NewTokenAmount(amount, 18),
+			GenesisHeight: t.BlockHeight,
+		}
+	}
+	return res, nil
+}
 
-	data := make([]RunesUnspentOutput, 0)
-	for _, i := range bisResponse.Data {
-		data = append(data, i)
+// Get an inscription by id
+func (b BISClient) GetInscription(ctx context.Context, inscriptionId string) (*BISInscription, error) {
+	inscription := &BISInscription{}
+	if _, err := b.get(ctx, "/v3/inscription/single_info_id", url.Values{"inscription_id": {inscriptionId}}, inscription); err != nil {
+		return nil, err
+	}
+	return inscription, nil
+}
+
+// Get every inscription held by a wallet, oldest first
+func (b BISClient) GetWalletInscriptions(ctx context.Context, address string) ([]BISInscription, error) {
+	query := url.Values{"address": {address}, "sort_by": {"inscr_num"}, "order": {"asc"}}
+	inscriptions, _, err := bisGetAll[BISInscription](ctx, b, "/v3/wallet/inscriptions", query)
+	return inscriptions, err
+}
+
+// Get every outpoint (txid:vout) holding an inscription of the wallet
+func (b BISClient) GetInscriptionOutpoints(ctx context.Context, address string) ([]string, error) {
+	inscriptions, err := b.GetWalletInscriptions(ctx, address)
+	if err != nil {
+		return nil, err
 	}
+	res := make([]string, 0, len(inscriptions))
+	for _, i := range inscriptions {
+		// Satpoints are txid:vout:offset
+		if idx := strings.LastIndex(i.Satpoint, ":"); idx > 0 {
+			res = append(res, i.Satpoint[:idx])
+		}
+	}
+	return res, nil
+}
 
+// Get the runes balances of a wallet, with the divisibility of each rune
+func (b BISClient) GetRunesWalletBalances(ctx context.Context, address string) ([]BISRunesBalance, error) {
+	balances := make([]BISRunesBalance, 0)
+	if _, err := b.get(ctx, "/v3/runes/wallet_balances", url.Values{"address": {address}}, &balances); err != nil && !noData(err) {
+		return nil, err
+	}
+	for i := range balances {
+		balances[i].TotalBalance = balances[i].TotalBalance.WithDecimals(uint8(balances[i].Decimals))
+	}
+	return balances, nil
+}
+
+func (b BISClient) walletValidOutputs(ctx context.Context, address string) ([]BISRunesUnspentOutput, error) {
+	query := url.Values{"address": {address}, "sort_by": {"output"}, "order": {"asc"}}
+	outputs, _, err := bisGetAll[BISRunesUnspentOutput](ctx, b, "/v3/runes/wallet_valid_outputs", query)
+	return outputs, err
+}
+
+// Fetch every runes UTXO of a wallet
+func (b BISClient) FetchRunesUtxos(ctx context.Context, address string) ([]RunesUnspentOutput, error) {
+	outputs, err := b.walletValidOutputs(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]RunesUnspentOutput, len(outputs))
+	for i, o := range outputs {
+		data[i] = o
+	}
 	return data, nil
 }
 
-func (b BISClient) GetRunesEventsByTxID(txId string) (*BISResponseWrapper[[]BISRuneEvent], error) {
-	endpoint := "https://api.bestinslot.xyz/v3/runes/events_on_tx?txid=" + txId
-	res, err := authenticatedBisGetRequest(endpoint, "x-api-key", b.apiKey)
+// Get every outpoint holding runes
+func (b BISClient) GetRunesUtxos(ctx context.Context, address string) ([]RunesUtxo, error) {
+	outputs, err := b.walletValidOutputs(ctx, address)
 	if err != nil {
 		return nil, err
 	}
-	bisResponse := BISResponseWrapper[[]BISRuneEvent]{}
-	if err := json.Unmarshal(res, &bisResponse); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(res))
+	res := make([]RunesUtxo, len(outputs))
+	for i, o := range outputs {
+		if len(o.Balances) != len(o.RuneIds) || len(o.Decimals) != len(o.RuneIds) {
+			return nil, fmt.Errorf("mismatched rune balances on %s from bis", o.Output)
+		}
+		res[i] = RunesUtxo{Outpoint: o.Output, PkScript: o.Pkscript, WalletAddr: o.WalletAddr, Runes: make([]RuneBalance, len(o.RuneIds))}
+		balances := o.GetBalances()
+		for j, runeId := range o.RuneIds {
+			res[i].Runes[j] = RuneBalance{RuneId: runeId, Amount: balances[j]}
+			if j < len(o.SpacedRuneNames) {
+				res[i].Runes[j].RuneName = o.SpacedRuneNames[j]
+			}
+		}
+	}
+	return res, nil
+}
+
+func (b BISClient) GetRunesEventsByTxID(ctx context.Context, txId string) (*BISResponseWrapper[[]BISRuneEvent], error) {
+	res := &BISResponseWrapper[[]BISRuneEvent]{Data: make([]BISRuneEvent, 0)}
+	height, err := b.get(ctx, "/v3/runes/events_on_tx", url.Values{"txid": {txId}}, &res.Data)
+	if err != nil && !noData(err) {
+		return nil, err
+	}
+	res.BlockHeight = height
+	return res, nil
+}
+
+// Get the outpoints spent by a transaction with the runes they held, from its input events
+func (b BISClient) GetRunesInputs(ctx context.Context, txId string) ([]RunesUtxo, error) {
+	events, err := b.GetRunesEventsByTxID(ctx, txId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return nil, err
 	}
-	return &bisResponse, nil
+	inputs := runesInputs{}
+	if events == nil {
+		return inputs, nil
+	}
+	for _, e := range events.Data {
+		if e.EventType != "input" {
+			continue
+		}
+		if err := inputs.add(e.Outpoint, e.Pkscript, e.WalletAddr, e.RuneID, e.SpacedRuneName, e.Amount, uint8(e.Decimals)); err != nil {
+			return nil, fmt.Errorf("%w from bis", err)
+		}
+	}
+	return inputs, nil
 }
@@ -1,8 +1,16 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
@@ -12,17 +20,218 @@ import (
 func TestBisClient(t *testing.T) {
 	config.Init()
 	config := config.GetDefaultConfig()
-	client := NewBISClient(config.BISConfig)
-	events, err := client.GetEventsByTransactionId("e20ac63402f36e9eba2e2a27e3699e65ca2998e319e2d4de69f235efd032ff0a")
+	client, err := NewBISClient(config.BISConfig)
+	require.NoError(t, err)
+	events, err := client.GetEventsByTransactionId(context.Background(), "e20ac63402f36e9eba2e2a27e3699e65ca2998e319e2d4de69f235efd032ff0a")
 	require.NoError(t, err)
 	fmt.Println(events.BlockHeight)
 
 	for _, e := range events.Data {
 		fmt.Println(e.EventType)
 		fmt.Println(e.Event.Amount)
-		fmt.Println(common.ParseStringFloat64(e.Event.Amount, 18))
+		amt, _ := new(big.Int).SetString(e.Event.Amount, 10)
+		fmt.Println(common.NewTokenAmount(amt, 18))
 		fmt.Println(e.Event.Tick)
 		fmt.Println(e.Event.SourceWallet)
 		fmt.Println(e.Event.SpentWallet)
 	}
 }
+
+// BIS api answering the routes to requests with the api key, failing the first requests to every path
+// with the status
+type fakeBis struct {
+	sync.Mutex
+	failures   int
+	status     int
+	retryAfter string
+	calls      map[string]int
+	routes     map[string]func(w http.ResponseWriter, r *http.Request)
+}
+
+func (b *fakeBis) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-api-key") != "key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message":"invalid api key"}`))
+		return
+	}
+	b.Lock()
+	b.calls[r.URL.Path]++
+	call, failures, status, retryAfter := b.calls[r.URL.Path], b.failures, b.status, b.retryAfter
+	b.Unlock()
+	if call <= failures {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		return
+	}
+	route, ok := b.routes[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	route(w, r)
+}
+
+// List of n outputs served in pages, at the height returned for each request
+func pagedOutputs(n int, height func(offset int) int) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		page := make([]BISRunesUnspentOutput, 0)
+		for i := offset; i < min(offset+count, n); i++ {
+			page = append(page, BISRunesUnspentOutput{
+				Output:          fmt.Sprintf("tx:%d", i),
+				RuneIds:         []string{"1:0"},
+				Balances:        []common.TokenAmount{common.NewTokenAmountFromInt64(150, 0)},
+				SpacedRuneNames: []string{"UNCOMMON•GOODS"},
+				Decimals:        []int{2},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(BISResponseWrapper[[]BISRunesUnspentOutput]{Data: page, BlockHeight: height(offset)})
+	}
+}
+
+func newTestBisClient(t *testing.T, bis *fakeBis, key string) *BISClient {
+	server := httptest.NewServer(bis)
+	t.Cleanup(server.Close)
+	c, err := NewBISClient(config.BISConfig{APIKey: key, BaseUrl: server.URL + "/", Timeout: 100 * time.Millisecond, MaxAttempts: 3})
+	require.NoError(t, err)
+	c.backoff = time.Millisecond
+	return c
+}
+
+func TestBisClientRequests(t *testing.T) {
+	ctx := context.Background()
+	bis := &fakeBis{failures: 2, status: http.StatusServiceUnavailable, calls: map[string]int{}, routes: map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v3/brc20/event_from_txid": expect("txid=abc", reply(`{"data":[{"inscription_id":"defi0","event_type":"transfer-transfer",
+			"event":{"tick":"ordi","amount":"5000000000000000000","source_wallet":"bc1q","spent_wallet":"bc1p"}}],"block_height":840000}`)),
+		"/v3/brc20/wallet_balances":      reply(`{"data":[{"ticker":"ORDI","overall_balance":"10","available_balance":"4"}],"block_height":840001}`),
+		"/v3/brc20/wallet_transferable":  expect("ticker=ordi", reply(`{"data":[{"inscription_id":"defi0","ticker":"ordi","amount":"6","block_height":839000}],"block_height":840001}`)),
+		"/v3/runes/wallet_balances":      reply(`{"data":[{"rune_id":"1:0","spaced_rune_name":"UNCOMMON•GOODS","total_balance":"250","decimals":2}],"block_height":840001}`),
+		"/v3/inscription/single_info_id": reply(`{"data":null,"block_height":840001}`),
+	}}
+	c := newTestBisClient(t, bis, "key")
+
+	// Retried until the api answers
+	transfers, err := c.GetBrc20Transfers(ctx, "abc", "")
+	require.NoError(t, err)
+	require.Equal(t, int64(840000), transfers.BlockHeight)
+	require.Equal(t, "5", transfers.Transfers[0].Amount.String())
+	require.Equal(t, 3, bis.calls["/v3/brc20/event_from_txid"])
+	_, err = c.GetBrc20Transfers(ctx, "", "defi0")
+	require.ErrorIs(t, err, ErrTxIdRequired)
+
+	bis.failures = 0
+	balance, err := c.GetBrc20Balance(ctx, "bc1q", "ordi")
+	require.NoError(t, err)
+	require.Equal(t, Brc20Balance{OverallBalance: "10", AvailableBalance: "4", BlockHeight: 840001}, *balance)
+	balance, err = c.GetBrc20Balance(ctx, "bc1q", "sats")
+	require.NoError(t, err)
+	require.Equal(t, "0", balance.OverallBalance)
+
+	transferables, err := c.GetBrc20Transferables(ctx, "bc1q", "ordi")
+	require.NoError(t, err)
+	require.Equal(t, "0.000000000000000006", transferables[0].Amount.String())
+	require.Equal(t, int64(839000), transferables[0].GenesisHeight)
+
+	runes, err := c.GetRunesWalletBalances(ctx, "bc1q")
+	require.NoError(t, err)
+	require.Equal(t, "2.5", runes[0].TotalBalance.String())
+	_, err = c.WithMinHeight(840002).GetRunesWalletBalances(ctx, "bc1q")
+	require.ErrorIs(t, err, ErrIndexerBehind)
+	_, err = c.WithMinHeight(840001).GetRunesWalletBalances(ctx, "bc1q")
+	require.NoError(t, err)
+
+	// Missing, either with a 404 or without data
+	_, err = c.GetInscription(ctx, "abci0")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = c.GetWalletInscriptions(ctx, "bc1q")
+	var apiErr *BisApiError
+	require.ErrorAs(t, err, &apiErr)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, 1, bis.calls["/v3/wallet/inscriptions"])
+
+	bis.routes["/v3/wallet/inscriptions"] = reply(`{"data":[{"inscription_id":"abci0","satpoint":"abc:1:0","output_value":10000}],"block_height":840001}`)
+	outpoints, err := c.GetInscriptionOutpoints(ctx, "bc1q")
+	require.NoError(t, err)
+	require.Equal(t, []string{"abc:1"}, outpoints)
+
+	// Rejected keys aren't retried
+	_, err = newTestBisClient(t, bis, "other").GetBrc20Balance(ctx, "bc1q", "ordi")
+	require.ErrorIs(t, err, ErrBisUnauthorized)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "invalid api key", apiErr.Message)
+}
+
+func TestBisClientRateLimit(t *testing.T) {
+	ctx := context.Background()
+	bis := &fakeBis{failures: 1, status: http.StatusTooManyRequests, calls: map[string]int{}, routes: map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v3/runes/events_on_tx": reply(`{"data":[{"event_type":"output","rune_id":"1:0","amount":"5"}],"block_height":840000}`),
+	}}
+	c := newTestBisClient(t, bis, "key")
+
+	events, err := c.GetRunesEventsByTxID(ctx, "abc")
+	require.NoError(t, err)
+	require.Len(t, events.Data, 1)
+	require.Equal(t, 2, bis.calls["/v3/runes/events_on_tx"])
+
+	// Waits longer than maxRetryAfter fail right away
+	bis.retryAfter, bis.calls = "120", map[string]int{}
+	_, err = c.GetRunesEventsByTxID(ctx, "abc")
+	require.ErrorIs(t, err, ErrRateLimited)
+	var apiErr *BisApiError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 2*time.Minute, apiErr.RetryAfter)
+	require.Equal(t, 1, bis.calls["/v3/runes/events_on_tx"])
+
+	require.Equal(t, 3*time.Second, retryAfter("3"))
+	require.InDelta(t, 30*time.Second, retryAfter(time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat)), float64(time.Second))
+	require.Zero(t, retryAfter("soon"))
+}
+
+func TestBisClientPagination(t *testing.T) {
+	ctx := context.Background()
+	bis := &fakeBis{calls: map[string]int{}, routes: map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v3/runes/wallet_valid_outputs": pagedOutputs(5, func(int) int { return 840000 }),
+	}}
+	c := newTestBisClient(t, bis, "key")
+	c.pageSize = 2
+
+	outputs, err := c.FetchRunesUtxos(ctx, "bc1q")
+	require.NoError(t, err)
+	require.Len(t, outputs, 5)
+	require.Equal(t, "tx:4", outputs[4].GetOutpoint())
+	require.Equal(t, 3, bis.calls["/v3/runes/wallet_valid_outputs"])
+	utxos, err := c.GetRunesUtxos(ctx, "bc1q")
+	require.NoError(t, err)
+	require.Len(t, utxos, 5)
+	require.Equal(t, "1.5", utxos[4].Balance("1:0").String())
+	require.Equal(t, "UNCOMMON•GOODS", utxos[4].Runes[0].RuneName)
+
+	// The list is fetched again when a block comes in between pages
+	height := 840000
+	bis.routes["/v3/runes/wallet_valid_outputs"] = pagedOutputs(4, func(offset int) int {
+		if offset == 2 && height == 840000 {
+			height++
+		}
+		return height
+	})
+	outputs, err = c.FetchRunesUtxos(ctx, "bc1q")
+	require.NoError(t, err)
+	require.Len(t, outputs, 4)
+
+	bis.routes["/v3/runes/wallet_valid_outputs"] = pagedOutputs(4, func(offset int) int { return 840000 + offset })
+	_, err = c.FetchRunesUtxos(ctx, "bc1q")
+	require.ErrorIs(t, err, ErrBisPagesMoved)
+}
+
+func TestBisClientConfig(t *testing.T) {
+	_, err := NewBISClient(config.BISConfig{})
+	require.ErrorIs(t, err, ErrBisConfig)
+	_, err = NewBISClient(config.BISConfig{APIKey: "key", BaseUrl: "api.bestinslot.xyz"})
+	require.ErrorIs(t, err, ErrBisConfig)
+	c, err := NewBISClient(config.BISConfig{APIKey: "key"})
+	require.NoError(t, err)
+	require.Equal(t, config.DefaultBisUrl, c.baseUrl)
+}
@@ -1,52 +1,369 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
-	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/ordinox/btc-service/config"
 	"github.com/rs/zerolog/log"
 )
 
+var (
+	ErrRpcConfig  = errors.New("invalid rpc config")
+	ErrRpcTimeout = errors.New("rpc call timed out")
+)
+
+// Bitcoin core rpc client. Every call goes through the rpc transport, with the per-call timeout, the
+// retries and the cookie read again when the node rotates it
 type BtcRpcClient struct {
-	*rpcclient.Client
 	TrackedAddreses map[string]bool
+
+	rpc         *rpcTransport
+	ctx         context.Context
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// Connect to bitcoin core with cookie or user/pass auth, over TLS when enabled.
+// The wallet is loaded when one is set, read & broadcast calls work without one
+func NewBitcoinClient(config config.Config) (*BtcRpcClient, error) {
+	btcConfig := config.BtcConfig
+	if err := btcConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRpcConfig, err)
+	}
+	var certs []byte
+	if btcConfig.RpcCert != "" {
+		var err error
+		if certs, err = os.ReadFile(btcConfig.RpcCert); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrRpcConfig, err)
+		}
+	}
+	host := btcConfig.GetRpcHostWithWallet()
+	transport, err := newRpcTransport(host, btcConfig.RpcTLS, certs, btcConfig.RpcUser, btcConfig.RpcPass, btcConfig.CookiePath)
+	if err != nil {
+		return nil, err
+	}
+	c := &BtcRpcClient{
+		TrackedAddreses: make(map[string]bool),
+		rpc:             transport,
+		ctx:             context.Background(),
+		timeout:         btcConfig.GetRpcTimeout(),
+		maxAttempts:     btcConfig.GetRpcMaxAttempts(),
+		backoff:         defaultMinBackoff,
+	}
+	if btcConfig.WalletName != "" {
+		err := c.do("loadwallet", []any{btcConfig.WalletName}, nil)
+		var rpcErr *btcjson.RPCError
+		if err != nil && !(errors.As(err, &rpcErr) && strings.Contains(rpcErr.Message, "already loaded")) {
+			return nil, fmt.Errorf("error loading wallet %s: %w", btcConfig.WalletName, err)
+		}
+	}
+	return c, nil
+}
+
+// Copy of the client whose calls are bound to the context
+func (c *BtcRpcClient) WithContext(ctx context.Context) *BtcRpcClient {
+	bound := *c
+	bound.ctx = ctx
+	return &bound
+}
+
+// Close the idle connections to the node
+func (c *BtcRpcClient) Shutdown() {
+	c.rpc.http.CloseIdleConnections()
+}
+
+// Call a method with the per-call timeout
+func (c *BtcRpcClient) do(method string, params []any, result any) error {
+	return c.doWithTimeout(c.timeout, method, params, result)
+}
+
+func (c *BtcRpcClient) doWithTimeout(timeout time.Duration, method string, params []any, result any) error {
+	return c.retry(timeout, method, func(ctx context.Context) error {
+		return c.rpc.call(ctx, method, params, result)
+	})
+}
+
+// Retry with backoff while the node can't be reached, errors returned by the node aren't retried
+func (c *BtcRpcClient) retry(timeout time.Duration, method string, call func(ctx context.Context) error) error {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(c.ctx, timeout)
+		err := call(ctx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && c.ctx.Err() == nil {
+			err = fmt.Errorf("%w: %s after %s", ErrRpcTimeout, method, timeout)
+		}
+		if err == nil || !retryable(err) || c.ctx.Err() != nil || attempt >= c.maxAttempts {
+			return err
+		}
+		log.Warn().Err(err).Msgf("rpc call %s failed, attempt %d/%d", method, attempt, c.maxAttempts)
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+		backoff = min(2*backoff, defaultMaxBackoff)
+	}
+}
+
+// Failures to reach the node, unlike errors returned by it
+func retryable(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return !errors.As(err, &rpcErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrRpcUnauthorized) && !errors.Is(err, ErrRpcConfig)
+}
+
+func (c *BtcRpcClient) RawRequest(method string, params []json.RawMessage) (json.RawMessage, error) {
+	args := make([]any, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+	var res json.RawMessage
+	err := c.do(method, args, &res)
+	return res, err
+}
+
+func (c *BtcRpcClient) GetBlockChainInfo() (*btcjson.GetBlockChainInfoResult, error) {
+	res := &btcjson.GetBlockChainInfoResult{}
+	return res, c.do("getblockchaininfo", nil, res)
+}
+
+func (c *BtcRpcClient) GetBlockCount() (int64, error) {
+	var height int64
+	err := c.do("getblockcount", nil, &height)
+	return height, err
 }
 
-func NewBitcoinClient(config config.Config) *BtcRpcClient {
-	host := config.BtcConfig.GetRpcHostWithWallet()
-	cookiePath := config.BtcConfig.CookiePath
-	user := ""
-	pass := ""
-	if config.BtcConfig.SandshrewApiKey != "" {
-		host = fmt.Sprintf("%s/%s", "mainnet.sandshrew.io/v1", config.BtcConfig.SandshrewApiKey)
-		cookiePath = ""
-		user = "user"
-		pass = "pass"
+func (c *BtcRpcClient) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	var hash string
+	if err := c.do("getblockhash", []any{height}, &hash); err != nil {
+		return nil, err
 	}
+	return chainhash.NewHashFromStr(hash)
+}
 
-	connConfig := &rpcclient.ConnConfig{
-		Host:         host,
-		HTTPPostMode: true, // Bitcoin Core
-		DisableTLS:   true,
-		CookiePath:   cookiePath,
-		User:         user,
-		Pass:         pass,
+func (c *BtcRpcClient) GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error) {
+	var raw string
+	if err := c.do("getblock", []any{blockHash.String(), 0}, &raw); err != nil {
+		return nil, err
 	}
-	client, err := rpcclient.New(connConfig, nil)
+	data, err := hex.DecodeString(raw)
 	if err != nil {
-		log.Fatal().Err(err).Msg("unable to create btc rpc client")
+		return nil, err
+	}
+	block := &wire.MsgBlock{}
+	return block, block.Deserialize(bytes.NewReader(data))
+}
+
+func (c *BtcRpcClient) GetBlockHeaderVerbose(blockHash *chainhash.Hash) (*btcjson.GetBlockHeaderVerboseResult, error) {
+	res := &btcjson.GetBlockHeaderVerboseResult{}
+	return res, c.do("getblockheader", []any{blockHash.String(), true}, res)
+}
+
+func (c *BtcRpcClient) GetRawTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error) {
+	var raw string
+	if err := c.do("getrawtransaction", []any{txHash.String(), false}, &raw); err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewTxFromBytes(data)
+}
+
+func (c *BtcRpcClient) GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	res := &btcjson.TxRawResult{}
+	return res, c.do("getrawtransaction", []any{txHash.String(), true}, res)
+}
+
+// Unspent output, nil when it is spent or doesn't exist
+func (c *BtcRpcClient) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error) {
+	var res *btcjson.GetTxOutResult
+	return res, c.do("gettxout", []any{txHash.String(), index, mempool}, &res)
+}
+
+func (c *BtcRpcClient) GetMempoolEntry(txHash string) (*btcjson.GetMempoolEntryResult, error) {
+	res := &btcjson.GetMempoolEntryResult{}
+	return res, c.do("getmempoolentry", []any{txHash}, res)
+}
+
+func (c *BtcRpcClient) EstimateSmartFee(confTarget int64, mode *btcjson.EstimateSmartFeeMode) (*btcjson.EstimateSmartFeeResult, error) {
+	params := []any{confTarget}
+	if mode != nil {
+		params = append(params, *mode)
 	}
-	if config.BtcConfig.SandshrewApiKey == "" {
-		if _, err := client.LoadWallet(config.BtcConfig.WalletName); err != nil {
-			if !strings.Contains(err.Error(), "already loaded") {
-				log.Fatal().Err(err).Msgf("error loading wallet: %s", config.BtcConfig.WalletName)
-			}
+	res := &btcjson.EstimateSmartFeeResult{}
+	return res, c.do("estimatesmartfee", params, res)
+}
+
+// Broadcast the tx. Broadcasting is idempotent, so when a retry finds the tx already known
+// an earlier attempt went through without its response making it back
+func (c *BtcRpcClient) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	params := []any{hex.EncodeToString(buf.Bytes())}
+	if allowHighFees {
+		params = append(params, 0) // maxfeerate, 0 lifts the limit
+	}
+	var (
+		hash     string
+		attempts int
+		txHash   = tx.TxHash()
+	)
+	err := c.retry(c.timeout, "sendrawtransaction", func(ctx context.Context) error {
+		attempts++
+		err := c.rpc.call(ctx, "sendrawtransaction", params, &hash)
+		if attempts > 1 && alreadyKnown(err) {
+			hash = txHash.String()
+			return nil
 		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return &BtcRpcClient{
-		Client:          client,
-		TrackedAddreses: make(map[string]bool),
+	return chainhash.NewHashFromStr(hash)
+}
+
+func alreadyKnown(err error) bool {
+	var rpcErr *btcjson.RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == btcjson.ErrRPCVerifyAlreadyInChain || strings.Contains(rpcErr.Message, "txn-already-known") || strings.Contains(rpcErr.Message, "txn-already-in-mempool")
+}
+
+// Height of the block including the tx, 0 when it is still in the mempool.
+// Needs txindex on the node for txs not related to the wallet
+func (c *BtcRpcClient) GetTxBlockHeight(txId string) (int64, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := c.GetRawTransactionVerbose(hash)
+	if err != nil {
+		return 0, err
+	}
+	if tx.BlockHash == "" {
+		return 0, nil
+	}
+	blockHash, err := chainhash.NewHashFromStr(tx.BlockHash)
+	if err != nil {
+		return 0, err
+	}
+	header, err := c.GetBlockHeaderVerbose(blockHash)
+	if err != nil {
+		return 0, err
+	}
+	return int64(header.Height), nil
+}
+
+// Long polls bitcoin core for the next block
+func (c *BtcRpcClient) WaitForNewBlock(timeout time.Duration) error {
+	return c.doWithTimeout(timeout+c.timeout, "waitfornewblock", []any{timeout.Milliseconds()}, nil)
+}
+
+// Unconfirmed depth of a tx, its mempool ancestor count including itself. 0 once it is confirmed
+func (c *BtcRpcClient) MempoolDepth(txId string) (int, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return 0, err
+	}
+	entry, err := c.GetMempoolEntry(hash.String())
+	if isNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(entry.AncestorCount), nil
+}
+
+var ErrNotInMempool = errors.New("tx is not in the mempool")
+
+// Subset of getmempoolentry, btcjson's result lacks spentby. Fees are in BTC
+type MempoolEntry struct {
+	VSize   int64    `json:"vsize"`
+	SpentBy []string `json:"spentby"`
+	Depends []string `json:"depends"`
+	Fees    struct {
+		Base       float64 `json:"base"`
+		Descendant float64 `json:"descendant"`
+	} `json:"fees"`
+}
+
+// Mempool entry of a tx, ErrNotInMempool once it is confirmed or if it was never broadcast
+func (c *BtcRpcClient) MempoolEntry(txId string) (*MempoolEntry, error) {
+	param, err := json.Marshal(txId)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.RawRequest("getmempoolentry", []json.RawMessage{param})
+	if isNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotInMempool, txId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := &MempoolEntry{}
+	if err := json.Unmarshal(res, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+var (
+	ErrPackageRelayUnsupported = errors.New("node doesn't support submitpackage")
+	ErrPackageRejected         = errors.New("package rejected")
+)
+
+// Submit txs as a package (child last), so that a child can pay for a parent below the mempool min fee.
+// Needs bitcoin core 26 or later, ErrPackageRelayUnsupported otherwise
+func (c *BtcRpcClient) SubmitPackage(txs ...*wire.MsgTx) error {
+	rawTxs := make([]string, len(txs))
+	for i, tx := range txs {
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			return err
+		}
+		rawTxs[i] = hex.EncodeToString(buf.Bytes())
+	}
+	param, err := json.Marshal(rawTxs)
+	if err != nil {
+		return err
+	}
+	res, err := c.RawRequest("submitpackage", []json.RawMessage{param})
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code {
+		return ErrPackageRelayUnsupported
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPackageRejected, err.Error())
+	}
+	// package_msg only exists from bitcoin core 28
+	var result struct {
+		PackageMsg string `json:"package_msg"`
+	}
+	if err := json.Unmarshal(res, &result); err != nil {
+		return err
+	}
+	if result.PackageMsg != "" && result.PackageMsg != "success" {
+		return fmt.Errorf("%w: %s", ErrPackageRejected, result.PackageMsg)
 	}
+	return nil
 }
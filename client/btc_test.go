@@ -10,7 +10,10 @@ import (
 func TestBtcClient(t *testing.T) {
 	config.Init()
 	fmt.Println("???", config.GetDefaultConfig().BtcConfig.SandshrewApiKey)
-	client := NewBitcoinClient(config.GetDefaultConfig())
+	client, err := NewBitcoinClient(config.GetDefaultConfig())
+	if err != nil {
+		panic(err)
+	}
 	info, err := client.GetBlockChainInfo()
 	if err != nil {
 		panic(err)
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/ordinox/btc-service/config"
+	"github.com/rs/zerolog/log"
+)
+
+type FeePriority string
+
+const (
+	FeePriorityFastest  FeePriority = "fastest"  // Next block
+	FeePriorityHalfHour FeePriority = "halfhour" // ~3 blocks
+	FeePriorityHour     FeePriority = "hour"     // ~6 blocks
+	FeePriorityEconomy  FeePriority = "economy"  // Within a day
+)
+
+var (
+	ErrUnknownFeePriority     = errors.New("unknown fee priority")
+	ErrUnknownFeeSource       = errors.New("unknown fee source")
+	ErrFeeEstimateUnavailable = errors.New("no fee estimate available")
+)
+
+// Parse a priority name, fast & slow being aliases of fastest & economy
+func ParseFeePriority(name string) (FeePriority, error) {
+	switch p := FeePriority(strings.ToLower(name)); p {
+	case "fast":
+		return FeePriorityFastest, nil
+	case "slow":
+		return FeePriorityEconomy, nil
+	case FeePriorityFastest, FeePriorityHalfHour, FeePriorityHour, FeePriorityEconomy:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFeePriority, name)
+}
+
+// Fee rate in sats/vB for a priority
+type FeeEstimator interface {
+	EstimateFeeRate(priority FeePriority) (uint64, error)
+}
+
+var (
+	_ FeeEstimator = CoreFeeEstimator{}
+	_ FeeEstimator = MempoolFeeEstimator{}
+	_ FeeEstimator = StaticFeeEstimator(0)
+	_ FeeEstimator = FallbackFeeEstimator{}
+	_ FeeEstimator = ClampedFeeEstimator{}
+)
+
+// Node queries needed for core estimates, satisfied by BtcRpcClient
+type SmartFeeEstimator interface {
+	EstimateSmartFee(confTarget int64, mode *btcjson.EstimateSmartFeeMode) (*btcjson.EstimateSmartFeeResult, error)
+}
+
+var _ SmartFeeEstimator = &BtcRpcClient{}
+
+// Estimates from bitcoin core's estimatesmartfee
+type CoreFeeEstimator struct {
+	Chain SmartFeeEstimator
+}
+
+func (e CoreFeeEstimator) EstimateFeeRate(priority FeePriority) (uint64, error) {
+	target, mode := int64(0), &btcjson.EstimateModeConservative
+	switch priority {
+	case FeePriorityFastest:
+		target = 1
+	case FeePriorityHalfHour:
+		target = 3
+	case FeePriorityHour:
+		target = 6
+	case FeePriorityEconomy:
+		target, mode = 144, &btcjson.EstimateModeEconomical
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFeePriority, priority)
+	}
+	res, err := e.Chain.EstimateSmartFee(target, mode)
+	if err != nil {
+		return 0, err
+	}
+	if res.FeeRate == nil || *res.FeeRate <= 0 {
+		// Happens on fresh nodes & regtest, where there isn't enough data yet
+		return 0, fmt.Errorf("%w: estimatesmartfee %d: %v", ErrFeeEstimateUnavailable, target, res.Errors)
+	}
+	// BTC/kvB to sats/vB, rounded up so the target is still met
+	return uint64(math.Ceil(*res.FeeRate * 1e8 / 1000)), nil
+}
+
+// Estimates from a mempool.space compatible api
+type MempoolFeeEstimator struct {
+	Url        string // e.g. https://mempool.space/api
+	HttpClient *http.Client
+}
+
+func NewMempoolFeeEstimator(url string) MempoolFeeEstimator {
+	url, _ = strings.CutSuffix(url, "/")
+	return MempoolFeeEstimator{Url: url, HttpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (e MempoolFeeEstimator) EstimateFeeRate(priority FeePriority) (uint64, error) {
+	res, err := e.HttpClient.Get(e.Url + "/v1/fees/recommended")
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%w: %s returned %s", ErrFeeEstimateUnavailable, e.Url, res.Status)
+	}
+	fees := struct {
+		FastestFee  uint64 `json:"fastestFee"`
+		HalfHourFee uint64 `json:"halfHourFee"`
+		HourFee     uint64 `json:"hourFee"`
+		EconomyFee  uint64 `json:"economyFee"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&fees); err != nil {
+		return 0, err
+	}
+	var rate uint64
+	switch priority {
+	case FeePriorityFastest:
+		rate = fees.FastestFee
+	case FeePriorityHalfHour:
+		rate = fees.HalfHourFee
+	case FeePriorityHour:
+		rate = fees.HourFee
+	case FeePriorityEconomy:
+		rate = fees.EconomyFee
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFeePriority, priority)
+	}
+	if rate == 0 {
+		return 0, fmt.Errorf("%w: %s has no %s fee", ErrFeeEstimateUnavailable, e.Url, priority)
+	}
+	return rate, nil
+}
+
+// Same fee rate for every priority
+type StaticFeeEstimator uint64
+
+func (e StaticFeeEstimator) EstimateFeeRate(priority FeePriority) (uint64, error) {
+	if e == 0 {
+		return 0, fmt.Errorf("%w: static fee rate not set", ErrFeeEstimateUnavailable)
+	}
+	return uint64(e), nil
+}
+
+// Try each estimator in order, returning the first estimate
+type FallbackFeeEstimator []FeeEstimator
+
+func (e FallbackFeeEstimator) EstimateFeeRate(priority FeePriority) (uint64, error) {
+	errs := []error{ErrFeeEstimateUnavailable}
+	for _, estimator := range e {
+		rate, err := estimator.EstimateFeeRate(priority)
+		if err == nil {
+			return rate, nil
+		}
+		if errors.Is(err, ErrUnknownFeePriority) {
+			return 0, err
+		}
+		log.Warn().Err(err).Msgf("fee estimate failed, trying the next source")
+		errs = append(errs, err)
+	}
+	return 0, errors.Join(errs...)
+}
+
+// Keep estimates within [Min, Max], Max being ignored when 0
+type ClampedFeeEstimator struct {
+	FeeEstimator
+	Min uint64
+	Max uint64
+}
+
+func (e ClampedFeeEstimator) EstimateFeeRate(priority FeePriority) (uint64, error) {
+	rate, err := e.FeeEstimator.EstimateFeeRate(priority)
+	if err != nil {
+		return 0, err
+	}
+	if e.Max > 0 && rate > e.Max {
+		log.Warn().Msgf("fee estimate of %d sats/vB capped to %d", rate, e.Max)
+		rate = e.Max
+	}
+	return max(rate, e.Min), nil
+}
+
+// Estimator over the configured sources, within the configured limits
+func NewFeeEstimator(config config.Config) (FeeEstimator, error) {
+	sources := FallbackFeeEstimator{}
+	for _, source := range config.FeeConfig.GetSources() {
+		switch source {
+		case "core":
+			rpc, err := NewBitcoinClient(config)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, CoreFeeEstimator{Chain: rpc})
+		case "mempool":
+			if config.FeeConfig.MempoolApiUrl == "" {
+				return nil, fmt.Errorf("%w: mempool needs fee.mempool_api_url", ErrUnknownFeeSource)
+			}
+			sources = append(sources, NewMempoolFeeEstimator(config.FeeConfig.MempoolApiUrl))
+		case "static":
+			sources = append(sources, StaticFeeEstimator(config.FeeConfig.StaticFeeRate))
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFeeSource, source)
+		}
+	}
+	return ClampedFeeEstimator{
+		FeeEstimator: sources,
+		Min:          config.FeeConfig.GetMinFeeRate(),
+		Max:          config.FeeConfig.MaxFeeRate,
+	}, nil
+}
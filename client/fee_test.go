@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSmartFee map[int64]float64
+
+func (f fakeSmartFee) EstimateSmartFee(confTarget int64, mode *btcjson.EstimateSmartFeeMode) (*btcjson.EstimateSmartFeeResult, error) {
+	rate, ok := f[confTarget]
+	if !ok {
+		return &btcjson.EstimateSmartFeeResult{Errors: []string{"Insufficient data or no feerate found"}}, nil
+	}
+	return &btcjson.EstimateSmartFeeResult{FeeRate: &rate, Blocks: confTarget}, nil
+}
+
+func TestCoreFeeEstimator(t *testing.T) {
+	e := CoreFeeEstimator{Chain: fakeSmartFee{1: 0.00021, 3: 0.0001, 6: 0.000052}}
+	for priority, expected := range map[FeePriority]uint64{FeePriorityFastest: 21, FeePriorityHalfHour: 10, FeePriorityHour: 6} {
+		rate, err := e.EstimateFeeRate(priority)
+		require.NoError(t, err)
+		require.Equal(t, expected, rate, priority)
+	}
+	_, err := e.EstimateFeeRate(FeePriorityEconomy)
+	require.ErrorIs(t, err, ErrFeeEstimateUnavailable)
+	_, err = e.EstimateFeeRate("soon")
+	require.ErrorIs(t, err, ErrUnknownFeePriority)
+}
+
+func TestMempoolFeeEstimator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/fees/recommended", r.URL.Path)
+		_, _ = w.Write([]byte(`{"fastestFee":30,"halfHourFee":20,"hourFee":12,"economyFee":4,"minimumFee":2}`))
+	}))
+	defer server.Close()
+
+	e := NewMempoolFeeEstimator(server.URL + "/api/")
+	for priority, expected := range map[FeePriority]uint64{FeePriorityFastest: 30, FeePriorityHalfHour: 20, FeePriorityHour: 12, FeePriorityEconomy: 4} {
+		rate, err := e.EstimateFeeRate(priority)
+		require.NoError(t, err)
+		require.Equal(t, expected, rate, priority)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	_, err := NewMempoolFeeEstimator(down.URL).EstimateFeeRate(FeePriorityFastest)
+	require.ErrorIs(t, err, ErrFeeEstimateUnavailable)
+}
+
+type failingEstimator struct{}
+
+func (failingEstimator) EstimateFeeRate(priority FeePriority) (uint64, error) {
+	return 0, errors.New("node unreachable")
+}
+
+func TestFeePolicy(t *testing.T) {
+	e := ClampedFeeEstimator{FeeEstimator: FallbackFeeEstimator{failingEstimator{}, StaticFeeEstimator(0), StaticFeeEstimator(40)}, Min: 2, Max: 25}
+	rate, err := e.EstimateFeeRate(FeePriorityHour)
+	require.NoError(t, err)
+	require.Equal(t, uint64(25), rate)
+
+	e.FeeEstimator = StaticFeeEstimator(1)
+	rate, err = e.EstimateFeeRate(FeePriorityHour)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), rate)
+
+	e.FeeEstimator = FallbackFeeEstimator{failingEstimator{}}
+	_, err = e.EstimateFeeRate(FeePriorityHour)
+	require.ErrorIs(t, err, ErrFeeEstimateUnavailable)
+
+	for name, expected := range map[string]FeePriority{"fast": FeePriorityFastest, "slow": FeePriorityEconomy, "HalfHour": FeePriorityHalfHour} {
+		priority, err := ParseFeePriority(name)
+		require.NoError(t, err)
+		require.Equal(t, expected, priority)
+	}
+	_, err = ParseFeePriority("auto")
+	require.ErrorIs(t, err, ErrUnknownFeePriority)
+}
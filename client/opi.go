@@ -1,12 +1,22 @@
 package client
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/rs/zerolog/log"
 )
@@ -22,6 +32,68 @@ func (r RunesBalance) Copy() *RunesBalance {
 }
 
 var _ RunesUnspentOutput = OPIRunesUnspentOutput{}
+var _ Brc20Backend = OpiClient{}
+var _ Brc20EventSource = OpiClient{}
+var _ Brc20TransferableSource = OpiClient{}
+var _ Brc20TickerSource = OpiClient{}
+var _ RunesUtxoSource = OpiClient{}
+var _ RunesInputSource = OpiClient{}
+
+var (
+	ErrInscriptionIdRequired = errors.New("the indexer can only look up transfers by inscription id")
+	ErrTxIdRequired          = errors.New("the indexer can only look up transfers by tx id")
+	ErrOpiConfig             = errors.New("invalid opi config")
+	ErrNotFound              = errors.New("not found on the indexer")
+	ErrIndexerBehind         = errors.New("indexer hasn't processed the block yet")
+	ErrEventHashMismatch     = errors.New("brc20 event hash mismatch")
+)
+
+// Error returned by the OPI api, either as an http status or in the error field of a response
+type OpiApiError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+}
+
+func (e *OpiApiError) Error() string {
+	return fmt.Sprintf("opi %s: %d %s", e.Endpoint, e.StatusCode, e.Message)
+}
+
+// A 404 is ErrNotFound
+func (e *OpiApiError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// The api is overloaded or restarting, unlike a rejected request
+func (e *OpiApiError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Window of a list, cut locally since OPI returns whole lists
+type ListOptions struct {
+	Offset int
+	Limit  int // Everything from the offset when 0
+}
+
+type Page[T any] struct {
+	Items  []T
+	Offset int
+	Total  int
+}
+
+// Whether there are items past this page
+func (p Page[T]) More() bool {
+	return p.Offset+len(p.Items) < p.Total
+}
+
+func Paginate[T any](items []T, opts ListOptions) Page[T] {
+	start := min(max(opts.Offset, 0), len(items))
+	end := len(items)
+	if opts.Limit > 0 {
+		end = min(start+opts.Limit, end)
+	}
+	return Page[T]{Items: items[start:end], Offset: start, Total: len(items)}
+}
 
 func (u OPIRunesUnspentOutput) GetPkScript() string {
 	return u.Pkscript
@@ -43,148 +115,490 @@ func (u OPIRunesUnspentOutput) GetRuneIds() []string {
 	return u.RuneIds
 }
 
-// Create a new OPI client and check if the API is live
-func NewOpiClient(c config.OpiConfig) *OpiClient {
-	if len(c.Brc20Url) == 0 {
-		panic("OPI_CONFIG_ERROR: BRC20 URL UNDEFINED")
+// Base units, OPI doesn't return the divisibility of the runes
+func (u OPIRunesUnspentOutput) GetBalances() []common.TokenAmount {
+	return u.Balances
+}
+
+// Create an OPI client, nothing is requested until it's used. See Ping to check that the api is up
+func NewOpiClient(c config.OpiConfig) (*OpiClient, error) {
+	brc20Host, err := opiHost("opi.brc20_url", c.Brc20Url)
+	if err != nil {
+		return nil, err
+	}
+	runesHost, err := opiHost("opi.runes_url", c.RunesUrl)
+	if err != nil {
+		return nil, err
 	}
-	if len(c.RunesUrl) == 0 {
-		panic("OPI_CONFIG_ERROR: RUNES URL UNDEFINED")
+	return &OpiClient{
+		brc20Host:   brc20Host,
+		runesHost:   runesHost,
+		config:      c,
+		http:        &http.Client{Timeout: c.GetTimeout()},
+		maxAttempts: c.GetMaxAttempts(),
+		backoff:     defaultMinBackoff,
+	}, nil
+}
 
+// Url without its trailing slash, a missing url only fails the requests to that indexer
+func opiHost(key, value string) (string, error) {
+	if value == "" {
+		return "", nil
 	}
-	if !strings.HasPrefix(c.Brc20Url, "http") {
-		panic("OPI_CONFIG_ERROR: BRC20 URL should have http(s) protocol defined")
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%w: %s %q should be an http(s) url", ErrOpiConfig, key, value)
 	}
-	if !strings.HasPrefix(c.RunesUrl, "http") {
-		panic("OPI_CONFIG_ERROR: RUNES URL should have http(s) protocol defined")
+	return strings.TrimSuffix(value, "/"), nil
+}
+
+// Copy of the client failing with ErrIndexerBehind while the indexer is below the height
+func (c OpiClient) WithMinHeight(height int64) *OpiClient {
+	c.minHeight = height
+	return &c
+}
+
+// The brc20 & runes apis of OPI, served separately
+type opiApi struct {
+	name       string
+	host       string
+	heightPath string
+}
+
+func (c OpiClient) brc20Api() opiApi {
+	return opiApi{"brc20", c.brc20Host, c.config.Endpoints.FetchBrc20BlockHeight}
+}
+
+func (c OpiClient) runesApi() opiApi {
+	return opiApi{"runes", c.runesHost, c.config.Endpoints.FetchRunesBlockHeight}
+}
+
+// Check that the configured indexers are up
+func (c OpiClient) Ping(ctx context.Context) error {
+	if c.brc20Host == "" && c.runesHost == "" {
+		return fmt.Errorf("%w: neither opi.brc20_url nor opi.runes_url is set", ErrOpiConfig)
+	}
+	for _, api := range []opiApi{c.brc20Api(), c.runesApi()} {
+		if api.host == "" {
+			continue
+		}
+		if _, err := c.fetch(ctx, api.host+"/v1/"+api.name+"/ip"); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	brc20Host, _ := strings.CutSuffix(c.Brc20Url, "/")
-	runesHost, _ := strings.CutSuffix(c.RunesUrl, "/")
+// Fail with ErrIndexerBehind unless the configured indexers have processed the block
+func (c OpiClient) RequireHeight(ctx context.Context, height int64) error {
+	for _, api := range []opiApi{c.brc20Api(), c.runesApi()} {
+		if api.host == "" {
+			continue
+		}
+		if err := c.requireHeight(ctx, api, height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Make sure that these host are running
-	resp1, err := http.Get(brc20Host + "/v1/brc20/ip")
+func (c OpiClient) requireHeight(ctx context.Context, api opiApi, height int64) error {
+	current, err := c.blockHeight(ctx, api)
 	if err != nil {
-		log.Fatal().Err(err).Msgf("error connecting brc20 to opi endpoint [%s]", brc20Host)
+		return err
 	}
-	defer resp1.Body.Close()
+	return checkHeight(api, current, height)
+}
 
-	if resp1.StatusCode != http.StatusOK {
-		log.Fatal().Msgf("status error connecting to brc20 opi endpoint [%s]. status not 200, but [%d]", brc20Host, resp1.StatusCode)
+func checkHeight(api opiApi, current, height int64) error {
+	if current < height {
+		return fmt.Errorf("%w: %s indexer at %d, %d required", ErrIndexerBehind, api.name, current, height)
 	}
+	return nil
+}
 
-	// resp2, err := http.Get(runesHost + "/v1/runes/ip")
-	// if err != nil {
-	// 	log.Fatal().Err(err).Msgf("error connecting runes to opi endpoint [%s]", brc20Host)
-	// }
-	// defer resp2.Body.Close()
-	//
-	// if resp2.StatusCode != http.StatusOK {
-	// 	log.Fatal().Msgf("status error connecting to runes opi endpoint [%s]. status not 200", brc20Host)
-	// }
+// Get the last block processed by the BRC20 indexer
+func (c OpiClient) GetBrc20BlockHeight(ctx context.Context) (int64, error) {
+	return c.blockHeight(ctx, c.brc20Api())
+}
 
-	return &OpiClient{
-		brc20Host: brc20Host,
-		runesHost: runesHost,
-		config:    c,
+// Get the last block processed by the runes indexer
+func (c OpiClient) GetRunesBlockHeight(ctx context.Context) (int64, error) {
+	return c.blockHeight(ctx, c.runesApi())
+}
+
+// The block height endpoints answer in plain text
+func (c OpiClient) blockHeight(ctx context.Context, api opiApi) (int64, error) {
+	if api.host == "" {
+		return 0, fmt.Errorf("%w: opi.%s_url not set", ErrOpiConfig, api.name)
+	}
+	body, err := c.fetch(ctx, api.host+api.heightPath)
+	if err != nil {
+		return 0, err
 	}
+	height, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block height from %s: %w", api.host+api.heightPath, err)
+	}
+	return height, nil
+}
+
+// GET a json response, checking its error field and, with a min height, the height of the indexer
+func (c OpiClient) get(ctx context.Context, api opiApi, path string, query url.Values, result any) error {
+	if api.host == "" {
+		return fmt.Errorf("%w: opi.%s_url not set", ErrOpiConfig, api.name)
+	}
+	if c.minHeight > 0 {
+		if err := c.requireHeight(ctx, api, c.minHeight); err != nil {
+			return err
+		}
+	}
+	endpoint := api.host + path + "?" + query.Encode()
+	body, err := c.fetch(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	res := Response[json.RawMessage]{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", endpoint, err)
+	}
+	if res.Error != nil {
+		return &OpiApiError{Endpoint: endpoint, StatusCode: http.StatusOK, Message: fmt.Sprint(res.Error)}
+	}
+	// The runes api reports the height of the data it answered with, which is behind after a reorg
+	if c.minHeight > 0 && res.DbBlockHeight > 0 {
+		if err := checkHeight(api, res.DbBlockHeight, c.minHeight); err != nil {
+			return err
+		}
+	}
+	if len(res.Result) == 0 || string(res.Result) == "null" {
+		return fmt.Errorf("%w: %s", ErrNotFound, endpoint)
+	}
+	return json.Unmarshal(res.Result, result)
+}
+
+// GET with retries while the api is unreachable or overloaded, the body of a 200 is returned
+func (c OpiClient) fetch(ctx context.Context, endpoint string) ([]byte, error) {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		body, err := c.fetchOnce(ctx, endpoint)
+		var apiErr *OpiApiError
+		if err == nil || (errors.As(err, &apiErr) && !apiErr.Temporary()) || ctx.Err() != nil || attempt >= c.maxAttempts {
+			return body, err
+		}
+		log.Warn().Err(err).Msgf("opi request failed, attempt %d/%d", attempt, c.maxAttempts)
+		select {
+		case <-time.After(jitter(backoff)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = min(2*backoff, defaultMaxBackoff)
+	}
+}
+
+// Between half and all of the backoff, so clients failing together don't retry together
+func jitter(backoff time.Duration) time.Duration {
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
 }
 
-// HTTP GET request wrapper
-func getRequest(endpoint string) ([]byte, error) {
-	resp, err := http.Get(endpoint)
+func (c OpiClient) fetchOnce(ctx context.Context, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		log.Err(err).Msgf("error fetching events by inscription id [url = %s]", endpoint)
 		return nil, err
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
+	res, err := c.http.Do(req)
 	if err != nil {
-		log.Err(err).Msg("error reading msg body")
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		log.Info().Msgf("GET %s", string(endpoint))
-		log.Err(err).Msgf("http status not ok: [resp = %s]", string(bodyBytes))
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
 		return nil, err
 	}
-	return bodyBytes, nil
+	if res.StatusCode != http.StatusOK {
+		return nil, &OpiApiError{Endpoint: endpoint, StatusCode: res.StatusCode, Message: opiErrorMessage(res.StatusCode, body)}
+	}
+	return body, nil
+}
+
+// The error field of a json body, the body itself otherwise
+func opiErrorMessage(status int, body []byte) string {
+	res := Response[json.RawMessage]{}
+	if err := json.Unmarshal(body, &res); err == nil && res.Error != nil {
+		return fmt.Sprint(res.Error)
+	}
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		return http.StatusText(status)
+	}
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return msg
 }
 
 // Get all events by Inscription ID
 // Events prove if a BRC20 was transferred or not
-func (c OpiClient) GetEventsByInscriptionId(inscriptionId string) ([]Brc20Event, error) {
-	endpoint := fmt.Sprintf("%s%s?inscription_id=%s", c.brc20Host, c.config.Endpoints.FetchEventsByInscriptionId, inscriptionId)
-	data := Response[[]Brc20Event]{}
-	bodyBytes, err := getRequest(endpoint)
+func (c OpiClient) GetEventsByInscriptionId(ctx context.Context, inscriptionId string) ([]Brc20Event, error) {
+	events := make([]Brc20Event, 0)
+	err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchEventsByInscriptionId, url.Values{"inscription_id": {inscriptionId}}, &events)
+	return events, err
+}
+
+// Get BRC20 balance
+func (c OpiClient) GetBrc20Balance(ctx context.Context, address, ticker string) (*Brc20Balance, error) {
+	balance := &Brc20Balance{}
+	err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20Balance, url.Values{"address": {address}, "ticker": {ticker}}, balance)
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(bodyBytes))
-		return nil, err
-	}
-
-	return data.Result, nil
+	return balance, nil
 }
 
-// Get BRC20 balance
-func (c OpiClient) GetBrc20Balance(address, ticker string) (*Brc20Balance, error) {
-	endpoint := fmt.Sprintf("%s%s?address=%s&ticker=%s", c.brc20Host, c.config.Endpoints.FetchBrc20Balance, address, ticker)
-	bodyBytes, err := getRequest(endpoint)
-	data := Response[Brc20Balance]{}
+// Get the transfer-transfer event of an inscription. OPI doesn't report the block of the event
+func (c OpiClient) GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*Brc20Transfers, error) {
+	if inscriptionId == "" {
+		return nil, ErrInscriptionIdRequired
+	}
+	height, err := c.GetBrc20BlockHeight(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(bodyBytes))
+	events, err := c.GetEventsByInscriptionId(ctx, inscriptionId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	res := &Brc20Transfers{Transfers: make([]Brc20Transfer, 0), BlockHeight: height}
+	for _, evt := range events {
+		if evt.EventType != "transfer-transfer" || (txId != "" && evt.UsingTxID != txId) {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(evt.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount in opi event: %s", evt.Amount)
+		}
+		res.Transfers = append(res.Transfers, Brc20Transfer{
+			InscriptionId:  inscriptionId,
+			TxId:           evt.UsingTxID,
+			Tick:           evt.Tick,
+			Amount:         common.NewTokenAmount(amount, 18),
+			SourceWallet:   evt.SourceWallet,
+			SourcePkScript: evt.SourcePkScript,
+			SpentWallet:    evt.SpentWallet,
+			SpentPkScript:  evt.SpentPkScript,
+		})
+	}
+	return res, nil
+}
+
+// Get the unspent transfer inscriptions of a wallet for the ticker
+func (c OpiClient) GetBrc20Transferables(ctx context.Context, address, ticker string) ([]Brc20Transferable, error) {
+	notes := Brc20TransferableNotes{}
+	err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20TransferableNotes, url.Values{"address": {address}}, &notes)
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return nil, err
 	}
-	return &data.Result, nil
+	res := make([]Brc20Transferable, 0)
+	for _, t := range notes.UnusedTxes {
+		if !strings.EqualFold(t.Tick, ticker) {
+			continue
+		}
+		t.Amount = t.Amount.WithDecimals(18)
+		res = append(res, t)
+	}
+	return res, nil
 }
 
 // Get Runes Balance
-func (c OpiClient) GetRunesBalance(address string) ([]RunesBalance, error) {
-	endpoint := fmt.Sprintf("%s%s?address=%s", c.runesHost, c.config.Endpoints.FetchRunesBalance, address)
-	bodyBytes, err := getRequest(endpoint)
-	data := Response[[]RunesBalance]{}
-	if err != nil {
+func (c OpiClient) GetRunesBalance(ctx context.Context, address string) ([]RunesBalance, error) {
+	balances := make([]RunesBalance, 0)
+	err := c.get(ctx, c.runesApi(), c.config.Endpoints.FetchRunesBalance, url.Values{"address": {address}}, &balances)
+	return balances, err
+}
+
+// Get Runes Events by TxID
+func (c OpiClient) GetRunesEventsByTxID(ctx context.Context, txId string) ([]RunesEvent, error) {
+	events := make([]RunesEvent, 0)
+	err := c.get(ctx, c.runesApi(), c.config.Endpoints.FetchRunesEventsByTransactionId, url.Values{"transaction_id": {txId}}, &events)
+	return events, err
+}
+
+// Get all Rune UTXOs
+func (c OpiClient) GetRunesUnspentOutpoints(ctx context.Context, address string) ([]OPIRunesUnspentOutput, error) {
+	outputs := make([]OPIRunesUnspentOutput, 0)
+	err := c.get(ctx, c.runesApi(), c.config.Endpoints.FetchRunesUnspentOutpoint, url.Values{"address": {address}}, &outputs)
+	return outputs, err
+}
+
+// Get the outpoints holding runes. OPI doesn't return the divisibility, it's looked up once for every rune
+func (c OpiClient) GetRunesUtxos(ctx context.Context, address string) ([]RunesUtxo, error) {
+	outputs, err := c.GetRunesUnspentOutpoints(ctx, address)
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return nil, err
 	}
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(bodyBytes))
+	infos := make(map[string]*RunesTickerInfo)
+	res := make([]RunesUtxo, len(outputs))
+	for i, o := range outputs {
+		res[i] = RunesUtxo{Outpoint: o.Outpoint, PkScript: o.Pkscript, WalletAddr: o.WalletAddr, Runes: make([]RuneBalance, len(o.RuneIds))}
+		for j, runeId := range o.RuneIds {
+			info, ok := infos[runeId]
+			if !ok {
+				if info, err = c.GetRunesTickerInfo(ctx, runeId); err != nil {
+					return nil, err
+				}
+				infos[runeId] = info
+			}
+			if j >= len(o.Balances) {
+				return nil, fmt.Errorf("no balance for %s on %s from opi", runeId, o.Outpoint)
+			}
+			res[i].Runes[j] = RuneBalance{RuneId: runeId, RuneName: info.SpacedRuneName, Amount: o.Balances[j].WithDecimals(info.Divisibility)}
+		}
+	}
+	return res, nil
+}
+
+// Get the outpoints spent by a transaction with the runes they held, from its input events
+func (c OpiClient) GetRunesInputs(ctx context.Context, txId string) ([]RunesUtxo, error) {
+	events, err := c.GetRunesEventsByTxID(ctx, txId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return nil, err
 	}
-	return data.Result, nil
+	infos := make(map[string]*RunesTickerInfo)
+	inputs := runesInputs{}
+	for _, e := range events {
+		if e.EventType != "input" {
+			continue
+		}
+		outpoint, _ := e.Outpoint.(string)
+		pkScript, _ := e.Pkscript.(string)
+		wallet, _ := e.WalletAddr.(string)
+		info, ok := infos[e.RuneID]
+		if !ok {
+			if info, err = c.GetRunesTickerInfo(ctx, e.RuneID); err != nil {
+				return nil, err
+			}
+			infos[e.RuneID] = info
+		}
+		if err := inputs.add(outpoint, pkScript, wallet, e.RuneID, info.SpacedRuneName, e.Amount, info.Divisibility); err != nil {
+			return nil, fmt.Errorf("%w from opi", err)
+		}
+	}
+	return inputs, nil
 }
 
-// Get Runes Events by TxID
-func (c OpiClient) GetRunesEventsByTxID(txId string) ([]RunesEvent, error) {
-	endpoint := fmt.Sprintf("%s%s?transaction_id=%s", c.runesHost, c.config.Endpoints.FetchRunesEventsByTransactionId, txId)
-	data := Response[[]RunesEvent]{}
-	bodyBytes, err := getRequest(endpoint)
-	if err != nil {
+// Get the BRC20 events of a block, in the order they were indexed
+func (c OpiClient) GetBrc20ActivityOnBlock(ctx context.Context, height int64) ([]Brc20BlockEvent, error) {
+	events := make([]Brc20BlockEvent, 0)
+	err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20ActivityOnBlock, url.Values{"block_height": {strconv.FormatInt(height, 10)}}, &events)
+	return events, err
+}
+
+// Get the BRC20 balance of a pkScript as it was at the end of a block
+func (c OpiClient) GetBrc20BalanceAtHeight(ctx context.Context, pkScript, ticker string, height int64) (*Brc20Balance, error) {
+	balance := &Brc20Balance{}
+	query := url.Values{"pkscript": {pkScript}, "ticker": {ticker}, "block_height": {strconv.FormatInt(height, 10)}}
+	if err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20BalanceOnBlock, query, balance); err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(bodyBytes))
+	balance.BlockHeight = int(height)
+	return balance, nil
+}
+
+// Get the wallets holding a ticker, see Paginate for listing them in pages
+func (c OpiClient) GetBrc20Holders(ctx context.Context, ticker string) ([]Brc20Holder, error) {
+	holders := make([]Brc20Holder, 0)
+	err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20Holders, url.Values{"ticker": {ticker}}, &holders)
+	return holders, err
+}
+
+// Get the deploy parameters & supply of a ticker
+func (c OpiClient) GetBrc20TickerInfo(ctx context.Context, ticker string) (*Brc20TickerInfo, error) {
+	info := &Brc20TickerInfo{}
+	if err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20TickerInfo, url.Values{"ticker": {ticker}}, info); err != nil {
 		return nil, err
 	}
+	info.MaxSupply = info.MaxSupply.WithDecimals(18)
+	info.RemainingSupply = info.RemainingSupply.WithDecimals(18)
+	info.LimitPerMint = info.LimitPerMint.WithDecimals(18)
+	return info, nil
+}
 
-	return data.Result, nil
+// Amount minted so far
+func (t Brc20TickerInfo) MintedSupply() (common.TokenAmount, error) {
+	return t.MaxSupply.Sub(t.RemainingSupply)
 }
 
-// Get all Rune UTXOs
-func (c OpiClient) GetRunesUnspentOutpoints(address string) ([]OPIRunesUnspentOutput, error) {
-	endpoint := fmt.Sprintf("%s%s?address=%s", c.runesHost, c.config.Endpoints.FetchRunesUnspentOutpoint, address)
-	data := RunesResponseData[[]OPIRunesUnspentOutput]{}
-	bodyBytes, err := getRequest(endpoint)
+// Get the event hashes of a block
+func (c OpiClient) GetBrc20EventHash(ctx context.Context, height int64) (*Brc20EventHash, error) {
+	hash := &Brc20EventHash{}
+	if err := c.get(ctx, c.brc20Api(), c.config.Endpoints.FetchBrc20EventHash, url.Values{"block_height": {strconv.FormatInt(height, 10)}}, hash); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// Check that the cumulative hash chains from the one of the previous block, OPI hashes the previous
+// cumulative hash followed by the hash of the block's events
+func (h Brc20EventHash) Follows(prev Brc20EventHash) error {
+	if h.BlockHeight != prev.BlockHeight+1 {
+		return fmt.Errorf("%w: block %d doesn't follow %d", ErrEventHashMismatch, h.BlockHeight, prev.BlockHeight)
+	}
+	sum := sha256.Sum256([]byte(prev.CumulativeEventHash + h.BlockEventHash))
+	if expected := hex.EncodeToString(sum[:]); !strings.EqualFold(expected, h.CumulativeEventHash) {
+		return fmt.Errorf("%w: cumulative hash of %d is %s, %s chains from %d", ErrEventHashMismatch, h.BlockHeight, h.CumulativeEventHash, expected, prev.BlockHeight)
+	}
+	return nil
+}
+
+// Check the events indexed up to a block: the cumulative hash has to chain from the previous block's
+// and, when given, match the one of a trusted indexer
+func (c OpiClient) VerifyBrc20EventHash(ctx context.Context, height int64, trusted string) (*Brc20EventHash, error) {
+	hash, err := c.GetBrc20EventHash(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := c.GetBrc20EventHash(ctx, height-1)
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		log.Err(err).Msgf("error unmarshalling response: [resp = %s]", string(bodyBytes))
+	if err := hash.Follows(*prev); err != nil {
+		return nil, err
+	}
+	if trusted != "" && !strings.EqualFold(trusted, hash.CumulativeEventHash) {
+		return nil, fmt.Errorf("%w: cumulative hash of %d is %s, trusted one is %s", ErrEventHashMismatch, height, hash.CumulativeEventHash, trusted)
+	}
+	return hash, nil
+}
+
+// Get the etching terms & supply of a rune, by id (block:tx) or by name
+func (c OpiClient) GetRunesTickerInfo(ctx context.Context, rune string) (*RunesTickerInfo, error) {
+	info := &RunesTickerInfo{}
+	if err := c.get(ctx, c.runesApi(), c.config.Endpoints.FetchRunesTickerInfo, runeQuery(rune), info); err != nil {
 		return nil, err
 	}
-	return data.Result, nil
+	info.Premine = info.Premine.WithDecimals(info.Divisibility)
+	info.TermsAmount = info.TermsAmount.WithDecimals(info.Divisibility)
+	info.Burned = info.Burned.WithDecimals(info.Divisibility)
+	return info, nil
+}
+
+// Premine and every mint so far, burned runes included
+func (r RunesTickerInfo) Supply() common.TokenAmount {
+	minted := new(big.Int).Mul(r.TermsAmount.Int(), r.MintCount.Int())
+	return common.NewTokenAmount(minted.Add(minted, r.Premine.Int()), r.Divisibility)
+}
+
+// Get the wallets holding a rune, by id (block:tx) or by name. See Paginate for listing them in pages
+func (c OpiClient) GetRunesHolders(ctx context.Context, rune string) ([]RunesBalance, error) {
+	holders := make([]RunesBalance, 0)
+	err := c.get(ctx, c.runesApi(), c.config.Endpoints.FetchRunesHolders, runeQuery(rune), &holders)
+	return holders, err
+}
+
+// Runes are looked up by id or by name, the name without its spacers
+func runeQuery(rune string) url.Values {
+	if strings.Contains(rune, ":") {
+		return url.Values{"rune_id": {rune}}
+	}
+	return url.Values{"rune_name": {strings.NewReplacer("•", "", ".", "").Replace(strings.ToUpper(rune))}}
 }
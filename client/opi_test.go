@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/config"
+	"github.com/stretchr/testify/require"
+)
+
+// OPI brc20 & runes apis on a single server, failing the first requests to every path with a 503
+type fakeOpi struct {
+	sync.Mutex
+	failures int
+	calls    map[string]int
+	routes   map[string]func(w http.ResponseWriter, r *http.Request)
+}
+
+func (o *fakeOpi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.Lock()
+	o.calls[r.URL.Path]++
+	call, failures := o.calls[r.URL.Path], o.failures
+	o.Unlock()
+	if call <= failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	route, ok := o.routes[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Cannot GET " + r.URL.Path))
+		return
+	}
+	route(w, r)
+}
+
+func reply(body string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// Answer with a 400 unless the request has the query params
+func expect(query string, handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	expected, _ := url.ParseQuery(query)
+	return func(w http.ResponseWriter, r *http.Request) {
+		for key := range expected {
+			if r.URL.Query().Get(key) != expected.Get(key) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(key + " is " + r.URL.Query().Get(key)))
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+func newTestOpiClient(t *testing.T, opi *fakeOpi) *OpiClient {
+	server := httptest.NewServer(opi)
+	t.Cleanup(server.Close)
+	c, err := NewOpiClient(config.OpiConfig{
+		Brc20Url:    server.URL + "/",
+		RunesUrl:    server.URL,
+		Endpoints:   config.Defaults().OpiConfig.Endpoints,
+		Timeout:     100 * time.Millisecond,
+		MaxAttempts: 3,
+	})
+	require.NoError(t, err)
+	c.backoff = time.Millisecond
+	return c
+}
+
+func TestOpiClient(t *testing.T) {
+	ctx := context.Background()
+	opi := &fakeOpi{failures: 2, calls: map[string]int{}, routes: map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v1/brc20/ip":           reply("127.0.0.1"),
+		"/v1/runes/ip":           reply("127.0.0.1"),
+		"/v1/brc20/block_height": reply("840000\n"),
+		"/v1/runes/block_height": reply("840002"),
+		"/v1/brc20/get_current_balance_of_wallet": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("ticker") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"ticker is required","result":null}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"error":null,"result":{"overall_balance":"10","available_balance":"5","block_height":840000}}`))
+		},
+		"/v1/brc20/event":                         reply(`{"error":"no events found","result":null}`),
+		"/v1/runes/get_current_balance_of_wallet": reply(`{"error":null,"result":null,"db_block_height":840001}`),
+	}}
+	c := newTestOpiClient(t, opi)
+
+	// Retried until the api answers
+	require.NoError(t, c.Ping(ctx))
+	height, err := c.GetBrc20BlockHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(840000), height)
+	require.Equal(t, 3, opi.calls["/v1/brc20/ip"])
+
+	opi.failures = 0
+	balance, err := c.GetBrc20Balance(ctx, "bc1q", "ordi")
+	require.NoError(t, err)
+	require.Equal(t, "5", balance.AvailableBalance)
+
+	// Rejected requests aren't retried
+	_, err = c.GetBrc20Balance(ctx, "bc1q", "")
+	var apiErr *OpiApiError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, "ticker is required", apiErr.Message)
+	require.Equal(t, 2, opi.calls["/v1/brc20/get_current_balance_of_wallet"])
+
+	_, err = c.GetEventsByInscriptionId(ctx, "abci0")
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, "no events found", apiErr.Message)
+	_, err = c.GetRunesBalance(ctx, "bc1q")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = c.GetRunesEventsByTxID(ctx, "abc")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, 1, opi.calls["/v1/runes/event"])
+
+	// Unreachable for longer than the attempts
+	opi.failures, opi.calls = 5, map[string]int{}
+	_, err = c.GetBrc20BlockHeight(ctx)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	require.Equal(t, 3, opi.calls["/v1/brc20/block_height"])
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.GetBrc20BlockHeight(canceled)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Checked before the request, and against the height the runes api reports in the response
+	opi.failures = 0
+	require.NoError(t, c.RequireHeight(ctx, 840000))
+	require.ErrorIs(t, c.RequireHeight(ctx, 840001), ErrIndexerBehind)
+	_, err = c.WithMinHeight(840001).GetBrc20Balance(ctx, "bc1q", "ordi")
+	require.ErrorIs(t, err, ErrIndexerBehind)
+	// The data came from a lower block than the indexer reports
+	_, err = c.WithMinHeight(840002).GetRunesBalance(ctx, "bc1q")
+	require.ErrorIs(t, err, ErrIndexerBehind)
+	_, err = c.WithMinHeight(840001).GetRunesBalance(ctx, "bc1q")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestOpiEndpoints(t *testing.T) {
+	ctx := context.Background()
+	hashes := map[string]Brc20EventHash{"839999": {BlockHeight: 839999, BlockEventHash: "aa", CumulativeEventHash: "bb"}}
+	sum := sha256.Sum256([]byte("bb" + "cc"))
+	hashes["840000"] = Brc20EventHash{BlockHeight: 840000, BlockEventHash: "cc", CumulativeEventHash: hex.EncodeToString(sum[:])}
+	hashes["840001"] = Brc20EventHash{BlockHeight: 840001, BlockEventHash: "dd", CumulativeEventHash: "ee"}
+	opi := &fakeOpi{calls: map[string]int{}, routes: map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v1/brc20/ticker_info": reply(`{"error":null,"result":{"tick":"ordi","original_tick":"ORDI","max_supply":"21000000000000000000000000",
+			"remaining_supply":"1000000000000000000000000","limit_per_mint":"1000000000000000000000","decimals":18,"block_height":779832}}`),
+		"/v1/brc20/balance_on_block": expect("pkscript=0014abcd&block_height=800000",
+			reply(`{"error":null,"result":{"overall_balance":"7","available_balance":"7"}}`)),
+		"/v1/brc20/activity_on_block": reply(`{"error":null,"result":[{"event_type":"deploy-inscribe","inscription_id":"abci0","tick":"ordi",
+			"max_supply":"21","deployer_wallet":"bc1q"},{"event_type":"transfer-transfer","inscription_id":"defi0","tick":"ordi","amount":"5","spent_wallet":"bc1p"}]}`),
+		"/v1/brc20/get_hash_of_all_activity": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(Response[Brc20EventHash]{Result: hashes[r.URL.Query().Get("block_height")]})
+		},
+		"/v1/runes/ticker_info": expect("rune_name=UNCOMMONGOODS",
+			reply(`{"error":null,"result":{"rune_id":"1:0","divisibility":2,"premine":"100","terms_amount":"150","mint_count":"3","terms_cap":"10"},"db_block_height":840000}`)),
+		"/v1/runes/holders": expect("rune_id=1:0",
+			reply(`{"error":null,"result":[{"wallet_addr":"bc1q","total_balance":"250"}],"db_block_height":840000}`)),
+	}}
+	c := newTestOpiClient(t, opi)
+
+	info, err := c.GetBrc20TickerInfo(ctx, "ordi")
+	require.NoError(t, err)
+	require.Equal(t, "21000000", info.MaxSupply.String())
+	minted, err := info.MintedSupply()
+	require.NoError(t, err)
+	require.Equal(t, "20000000", minted.String())
+
+	balance, err := c.GetBrc20BalanceAtHeight(ctx, "0014abcd", "ordi", 800000)
+	require.NoError(t, err)
+	require.Equal(t, 800000, balance.BlockHeight)
+
+	events, err := c.GetBrc20ActivityOnBlock(ctx, 840000)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "bc1q", events[0].DeployerWallet)
+	require.Equal(t, "defi0", events[1].InscriptionId)
+	require.Equal(t, "5", events[1].Amount)
+
+	// The cumulative hash has to chain from the previous block and match the trusted one
+	hash, err := c.VerifyBrc20EventHash(ctx, 840000, "")
+	require.NoError(t, err)
+	require.Equal(t, "cc", hash.BlockEventHash)
+	_, err = c.VerifyBrc20EventHash(ctx, 840000, hashes["840000"].CumulativeEventHash)
+	require.NoError(t, err)
+	_, err = c.VerifyBrc20EventHash(ctx, 840000, "ff")
+	require.ErrorIs(t, err, ErrEventHashMismatch)
+	_, err = c.VerifyBrc20EventHash(ctx, 840001, "")
+	require.ErrorIs(t, err, ErrEventHashMismatch)
+
+	rune, err := c.GetRunesTickerInfo(ctx, "uncommon•goods")
+	require.NoError(t, err)
+	require.Equal(t, "5.5", rune.Supply().String())
+	require.Equal(t, "1", rune.Premine.String())
+
+	holders, err := c.GetRunesHolders(ctx, "1:0")
+	require.NoError(t, err)
+	require.Equal(t, "250", holders[0].TotalBalance.String())
+}
+
+func TestOpiRunesUtxos(t *testing.T) {
+	opi := &fakeOpi{calls: map[string]int{}, routes: map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v1/runes/get_unspent_rune_outpoints_of_wallet": expect("address=bc1q", reply(`{"error":null,"result":[
+			{"outpoint":"aa:0","wallet_addr":"bc1q","rune_ids":["1:0","2:0"],"balances":["250","3"]},
+			{"outpoint":"bb:1","wallet_addr":"bc1q","rune_ids":["1:0"],"balances":["5"]}]}`)),
+		"/v1/runes/event": expect("transaction_id=cc", reply(`{"error":null,"result":[
+			{"event_type":"input","outpoint":"`+outpoint(1, 0)+`","wallet_addr":"bc1q","rune_id":"1:0","amount":"250"},
+			{"event_type":"input","outpoint":"`+outpoint(1, 0)+`","wallet_addr":"bc1q","rune_id":"2:0","amount":"3"},
+			{"event_type":"input","outpoint":"`+outpoint(1, 1)+`","wallet_addr":"bc1p","rune_id":"1:0","amount":"5"},
+			{"event_type":"output","outpoint":"`+outpoint(2, 0)+`","wallet_addr":"bc1p","rune_id":"1:0","amount":"255"}]}`)),
+		"/v1/runes/ticker_info": func(w http.ResponseWriter, r *http.Request) {
+			divisibility := map[string]int{"1:0": 2, "2:0": 0}[r.URL.Query().Get("rune_id")]
+			_ = json.NewEncoder(w).Encode(Response[map[string]any]{Result: map[string]any{"spaced_rune_name": "R" + r.URL.Query().Get("rune_id"), "divisibility": divisibility}})
+		},
+	}}
+	c := newTestOpiClient(t, opi)
+
+	// The divisibility of every rune is looked up once
+	utxos, err := c.GetRunesUtxos(context.Background(), "bc1q")
+	require.NoError(t, err)
+	require.Len(t, utxos, 2)
+	require.Equal(t, "2.5", utxos[0].Balance("1:0").String())
+	require.Equal(t, "3", utxos[0].Balance("2:0").String())
+	require.Equal(t, "R1:0", utxos[0].Runes[0].RuneName)
+	require.Equal(t, "0.05", utxos[1].Balance("1:0").String())
+	require.Equal(t, 2, opi.calls["/v1/runes/ticker_info"])
+
+	// Runes spent by a transaction, grouped by outpoint
+	inputs, err := c.GetRunesInputs(context.Background(), "cc")
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+	require.Equal(t, outpoint(1, 0), inputs[0].Outpoint)
+	require.Equal(t, "2.5", inputs[0].Balance("1:0").String())
+	require.Equal(t, "3", inputs[0].Balance("2:0").String())
+	require.Equal(t, "bc1p", inputs[1].WalletAddr)
+	require.Equal(t, "0.05", inputs[1].Balance("1:0").String())
+}
+
+func outpoint(tx byte, index uint32) string {
+	return wire.NewOutPoint(&chainhash.Hash{tx}, index).String()
+}
+
+func TestOpiClientConfig(t *testing.T) {
+	_, err := NewOpiClient(config.OpiConfig{Brc20Url: "localhost:8000"})
+	require.ErrorIs(t, err, ErrOpiConfig)
+
+	// Nothing is requested until the client is used, a missing url only fails the requests to that indexer
+	c, err := NewOpiClient(config.OpiConfig{Brc20Url: "http://127.0.0.1:1", Endpoints: config.Defaults().OpiConfig.Endpoints, MaxAttempts: 1})
+	require.NoError(t, err)
+	_, err = c.GetRunesBalance(context.Background(), "bc1q")
+	require.ErrorIs(t, err, ErrOpiConfig)
+	require.Error(t, c.Ping(context.Background()))
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	page := Paginate(items, ListOptions{Offset: 1, Limit: 2})
+	require.Equal(t, []int{2, 3}, page.Items)
+	require.True(t, page.More())
+
+	page = Paginate(items, ListOptions{Offset: 3})
+	require.Equal(t, []int{4, 5}, page.Items)
+	require.False(t, page.More())
+
+	page = Paginate(items, ListOptions{Offset: 10, Limit: 2})
+	require.Empty(t, page.Items)
+	require.Equal(t, 5, page.Total)
+}
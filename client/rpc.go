@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcjson"
+)
+
+var ErrRpcUnauthorized = errors.New("rpc credentials rejected")
+
+// JSON-RPC over http with per-call contexts. rpcclient sends http requests one at a time without
+// any timeout, so a single stuck call would hold every other one
+type rpcTransport struct {
+	url        string
+	http       *http.Client
+	user, pass string
+	cookiePath string
+	id         atomic.Uint64
+}
+
+func newRpcTransport(host string, useTLS bool, certs []byte, user, pass, cookiePath string) (*rpcTransport, error) {
+	scheme, transport := "http", http.DefaultTransport.(*http.Transport).Clone()
+	if useTLS {
+		scheme = "https"
+		if len(certs) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(certs) {
+				return nil, fmt.Errorf("%w: no certificate found in rpc_cert", ErrRpcConfig)
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		}
+	}
+	return &rpcTransport{
+		url:        scheme + "://" + host,
+		http:       &http.Client{Transport: transport},
+		user:       user,
+		pass:       pass,
+		cookiePath: cookiePath,
+	}, nil
+}
+
+// Credentials, the cookie is read on every call since bitcoin core rewrites it when restarting
+func (t *rpcTransport) credentials() (string, string, error) {
+	if t.cookiePath == "" {
+		return t.user, t.pass, nil
+	}
+	cookie, err := os.ReadFile(t.cookiePath)
+	if err != nil {
+		return "", "", err
+	}
+	user, pass, ok := strings.Cut(strings.TrimSpace(string(cookie)), ":")
+	if !ok {
+		return "", "", fmt.Errorf("%w: malformed cookie %s", ErrRpcConfig, t.cookiePath)
+	}
+	return user, pass, nil
+}
+
+// Call a method, errors returned by the node are *btcjson.RPCError
+func (t *rpcTransport) call(ctx context.Context, method string, params []any, result any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "1.0", "id": t.id.Add(1), "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	user, pass, err := t.credentials()
+	if err != nil {
+		return err
+	}
+	if user != "" || pass != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	res, err := t.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %s", ErrRpcUnauthorized, res.Status)
+	}
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	// Bitcoin core answers rpc errors with a 404 or 500 and a JSON body, anything else didn't come from the rpc
+	var resp struct {
+		Result json.RawMessage   `json:"result"`
+		Error  *btcjson.RPCError `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("%s from %s: %s", res.Status, method, strings.TrimSpace(string(raw)))
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/config"
+	"github.com/stretchr/testify/require"
+)
+
+// Bitcoin core over http, failing the first calls to every method with a 503
+type fakeNode struct {
+	sync.Mutex
+	failures int
+	delay    time.Duration
+	calls    map[string]int
+	paths    []string
+	handle   func(method string, call int) (any, *btcjson.RPCError)
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, pass, _ := r.BasicAuth()
+	if user != "user" || pass != "pass" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Method string          `json:"method"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	n.Lock()
+	n.paths = append(n.paths, r.URL.Path)
+	n.calls[req.Method]++
+	call, failures, delay := n.calls[req.Method], n.failures, n.delay
+	n.Unlock()
+	if call <= failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("Work queue depth exceeded"))
+		return
+	}
+	time.Sleep(delay)
+	result, rpcErr := n.handle(req.Method, call)
+	_ = json.NewEncoder(w).Encode(map[string]any{"result": result, "error": rpcErr, "id": req.ID})
+}
+
+func newTestClient(t *testing.T, node *fakeNode, wallet string) *BtcRpcClient {
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+	c, err := NewBitcoinClient(config.Config{BtcConfig: config.BtcConfig{
+		ChainConfig:    "regtest",
+		RpcHost:        strings.TrimPrefix(server.URL, "http://"),
+		RpcUser:        "user",
+		RpcPass:        "pass",
+		WalletName:     wallet,
+		RpcTimeout:     100 * time.Millisecond,
+		RpcMaxAttempts: 3,
+	}})
+	require.NoError(t, err)
+	c.backoff = time.Millisecond
+	t.Cleanup(c.Shutdown)
+	return c
+}
+
+func TestBtcClientRetries(t *testing.T) {
+	node := &fakeNode{failures: 2, calls: map[string]int{}, handle: func(method string, call int) (any, *btcjson.RPCError) {
+		switch method {
+		case "getblockcount":
+			return 120, nil
+		case "getblockhash":
+			return strings.Repeat("00", 32), nil
+		case "loadwallet":
+			return nil, &btcjson.RPCError{Code: -35, Message: "Wallet \"w1\" is already loaded."}
+		case "getmempoolentry":
+			return nil, &btcjson.RPCError{Code: btcjson.ErrRPCNoTxInfo, Message: "Transaction not in mempool"}
+		}
+		return nil, &btcjson.RPCError{Code: btcjson.ErrRPCMethodNotFound.Code, Message: "Method not found"}
+	}}
+
+	// Wallet-less, calls go to the node itself
+	c := newTestClient(t, node, "")
+	height, err := c.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, int64(120), height)
+	require.Equal(t, 3, node.calls["getblockcount"])
+	require.Equal(t, "/", node.paths[0])
+
+	// Block & tx reads go through the same transport
+	node.failures = 2
+	_, err = c.GetBlockHash(120)
+	require.NoError(t, err)
+	require.Equal(t, 3, node.calls["getblockhash"])
+
+	// Errors returned by the node aren't retried
+	node.failures = 0
+	_, err = c.MempoolEntry(strings.Repeat("00", 32))
+	require.ErrorIs(t, err, ErrNotInMempool)
+	require.Equal(t, 1, node.calls["getmempoolentry"])
+
+	// Unreachable for longer than the attempts
+	node.failures, node.calls = 5, map[string]int{}
+	_, err = c.GetBlockCount()
+	require.Error(t, err)
+	require.Equal(t, 3, node.calls["getblockcount"])
+
+	node.failures, node.paths = 0, nil
+	c = newTestClient(t, node, "w1")
+	_, err = c.GetBlockCount()
+	require.NoError(t, err)
+	require.Equal(t, []string{"/wallet/w1", "/wallet/w1"}, node.paths)
+
+	// Configs built in code are checked too
+	_, err = NewBitcoinClient(config.Config{BtcConfig: config.BtcConfig{ChainConfig: "mainet"}})
+	require.ErrorIs(t, err, config.ErrUnknownNetwork)
+}
+
+func TestBtcClientTimeouts(t *testing.T) {
+	node := &fakeNode{calls: map[string]int{}, delay: 300 * time.Millisecond, handle: func(method string, call int) (any, *btcjson.RPCError) {
+		return 1, nil
+	}}
+	c := newTestClient(t, node, "")
+	c.maxAttempts = 1
+	_, err := c.GetBlockCount()
+	require.ErrorIs(t, err, ErrRpcTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.WithContext(ctx).GetBlockCount()
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBtcClientRebroadcast(t *testing.T) {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	// The first broadcast goes through but its response is lost
+	node := &fakeNode{calls: map[string]int{}, handle: func(method string, call int) (any, *btcjson.RPCError) {
+		if call == 1 {
+			time.Sleep(300 * time.Millisecond)
+			return tx.TxHash().String(), nil
+		}
+		return nil, &btcjson.RPCError{Code: btcjson.ErrRPCVerifyAlreadyInChain, Message: "txn-already-known"}
+	}}
+	c := newTestClient(t, node, "")
+	hash, err := c.SendRawTransaction(tx, true)
+	require.NoError(t, err)
+	require.Equal(t, tx.TxHash(), *hash)
+
+	// Without a retry it's a genuine rejection
+	_, err = c.SendRawTransaction(tx, true)
+	var rpcErr *btcjson.RPCError
+	require.True(t, errors.As(err, &rpcErr))
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/common"
+)
+
+// Balance of the rune on the outpoint, zero when it holds none
+func (u RunesUtxo) Balance(runeId string) common.TokenAmount {
+	for _, r := range u.Runes {
+		if r.RuneId == runeId {
+			return r.Amount
+		}
+	}
+	return common.NewTokenAmountFromInt64(0, 0)
+}
+
+var ErrInvalidRunesEvent = errors.New("invalid runes event")
+
+func (u RunesUtxo) OutPoint() (*wire.OutPoint, error) {
+	return wire.NewOutPointFromString(u.Outpoint)
+}
+
+// Spent outpoints, in the order the events list them
+type runesInputs []RunesUtxo
+
+// Add the balance of a rune held by an outpoint, amount being in base units
+func (in *runesInputs) add(outpoint, pkScript, wallet, runeId, runeName, amount string, decimals uint8) error {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("%w: amount %q of %s on %s", ErrInvalidRunesEvent, amount, runeId, outpoint)
+	}
+	if _, err := wire.NewOutPointFromString(outpoint); err != nil {
+		return fmt.Errorf("%w: outpoint %q", ErrInvalidRunesEvent, outpoint)
+	}
+	balance := RuneBalance{RuneId: runeId, RuneName: runeName, Amount: common.NewTokenAmount(value, decimals)}
+	for i := range *in {
+		if (*in)[i].Outpoint == outpoint {
+			(*in)[i].Runes = append((*in)[i].Runes, balance)
+			return nil
+		}
+	}
+	*in = append(*in, RunesUtxo{Outpoint: outpoint, PkScript: pkScript, WalletAddr: wallet, Runes: []RuneBalance{balance}})
+	return nil
+}
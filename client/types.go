@@ -1,23 +1,30 @@
 package client
 
 import (
-	"math/big"
+	"context"
+	"net/http"
 	"time"
 
+	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 )
 
 type (
 	OpiClient struct {
-		brc20Host string
-		runesHost string
-		config    config.OpiConfig
+		brc20Host   string
+		runesHost   string
+		config      config.OpiConfig
+		http        *http.Client
+		maxAttempts int
+		backoff     time.Duration // Before the first retry, doubled for every other one
+		minHeight   int64         // Block the indexers must have processed, see WithMinHeight
 	}
 
 	// HTTP API Responses
 	Response[T any] struct {
-		Error  any `json:"error"`
-		Result T   `json:"result"`
+		Error         any   `json:"error"`
+		Result        T     `json:"result"`
+		DbBlockHeight int64 `json:"db_block_height"` // Only reported by the runes api
 	}
 
 	// BRC20 Transfer Events
@@ -30,6 +37,41 @@ type (
 		UsingTxID      string `json:"using_tx_id,omitempty"`
 		SpentWallet    string `json:"spent_wallet,omitempty"`
 		SpentPkScript  string `json:"spent_pkScript,omitempty"`
+		MintedWallet   string `json:"minted_wallet,omitempty"`
+		MintedPkScript string `json:"minted_pkScript,omitempty"`
+	}
+
+	// BRC20 transfer-transfer event, normalized across indexers
+	Brc20Transfer struct {
+		InscriptionId  string
+		TxId           string
+		Tick           string
+		Amount         common.TokenAmount // Amount with 18 decimals, as stored by the indexers
+		SourceWallet   string
+		SourcePkScript string // Empty when the indexer doesn't report it
+		SpentWallet    string
+		SpentPkScript  string // Empty when the indexer doesn't report it
+		BlockHeight    int64  // 0 when the indexer doesn't report it
+	}
+
+	// Transfers found by an indexer along with the last block it has processed
+	Brc20Transfers struct {
+		Transfers   []Brc20Transfer
+		BlockHeight int64
+	}
+
+	// Unspent transfer inscription, which can be sent as is
+	Brc20Transferable struct {
+		Tick          string             `json:"tick"`
+		InscriptionId string             `json:"inscription_id"`
+		Amount        common.TokenAmount `json:"amount"` // Amount with 18 decimals
+		GenesisHeight int64              `json:"genesis_height"`
+	}
+
+	// OPI's valid transfer notes of a wallet
+	Brc20TransferableNotes struct {
+		UnusedTxes  []Brc20Transferable `json:"unused_txes"`
+		BlockHeight int                 `json:"block_height"`
 	}
 
 	// Runes Transfer Events
@@ -49,42 +91,108 @@ type (
 		BlockHeight      int    `json:"block_height"`
 	}
 
-	// Runes Balance Response Wrapper
-	// Keeping this because we may need to verify blockheight
-	RunesResponseData[T any] struct {
-		Error         interface{} `json:"error"`
-		Result        T           `json:"result"`
-		DbBlockHeight int         `json:"db_block_height"`
+	// BRC20 event of a block, the deploy fields are only set for deploy-inscribe events
+	Brc20BlockEvent struct {
+		InscriptionId string `json:"inscription_id"`
+		Brc20Event
+		OriginalTick     string `json:"original_tick,omitempty"`
+		MaxSupply        string `json:"max_supply,omitempty"`
+		LimitPerMint     string `json:"limit_per_mint,omitempty"`
+		Decimals         string `json:"decimals,omitempty"`
+		DeployerWallet   string `json:"deployer_wallet,omitempty"`
+		DeployerPkScript string `json:"deployer_pkScript,omitempty"`
+	}
+
+	// Balance of a wallet holding a BRC20 ticker
+	Brc20Holder struct {
+		PkScript         string `json:"pkscript"`
+		Wallet           string `json:"wallet"`
+		OverallBalance   string `json:"overall_balance"`
+		AvailableBalance string `json:"available_balance"`
+	}
+
+	// Deploy parameters & supply of a BRC20 ticker
+	Brc20TickerInfo struct {
+		Tick                string             `json:"tick"`
+		OriginalTick        string             `json:"original_tick"`
+		MaxSupply           common.TokenAmount `json:"max_supply"` // Amounts with 18 decimals
+		RemainingSupply     common.TokenAmount `json:"remaining_supply"`
+		LimitPerMint        common.TokenAmount `json:"limit_per_mint"`
+		Decimals            int                `json:"decimals"`
+		IsSelfMint          bool               `json:"is_self_mint"`
+		DeployInscriptionId string             `json:"deploy_inscription_id"`
+		BlockHeight         int64              `json:"block_height"` // Of the deploy
+	}
+
+	// Hashes of the BRC20 events of a block, the cumulative one covers every block up to it.
+	// OPI operators publish them so that indexers can be checked against each other
+	Brc20EventHash struct {
+		BlockHeight         int64  `json:"block_height"`
+		BlockEventHash      string `json:"block_event_hash"`
+		CumulativeEventHash string `json:"cumulative_event_hash"`
+		IndexerVersion      string `json:"indexer_version"`
+	}
+
+	// Etching terms & supply of a rune
+	RunesTickerInfo struct {
+		RuneID         string             `json:"rune_id"`
+		RuneNumber     string             `json:"rune_number"`
+		RuneName       string             `json:"rune_name"`
+		SpacedRuneName string             `json:"spaced_rune_name"`
+		Symbol         string             `json:"symbol"`
+		Divisibility   uint8              `json:"divisibility"`
+		Premine        common.TokenAmount `json:"premine"`      // Amounts with the divisibility of the rune
+		TermsAmount    common.TokenAmount `json:"terms_amount"` // Minted by every mint
+		Burned         common.TokenAmount `json:"burned"`
+		TermsCap       common.TokenAmount `json:"terms_cap"`  // Counts, without decimals
+		MintCount      common.TokenAmount `json:"mint_count"` //
+		GenesisHeight  int64              `json:"genesis_height"`
+		Turbo          bool               `json:"turbo"`
 	}
 
 	// Runes Balance Response Wrapper
 	RunesBalance struct {
-		Pkscript     string `json:"pkscript"`
-		WalletAddr   string `json:"wallet_addr"`
-		RuneID       string `json:"rune_id"`
-		RuneName     string `json:"rune_name"`
-		TotalBalance string `json:"total_balance"`
+		Pkscript     string             `json:"pkscript"`
+		WalletAddr   string             `json:"wallet_addr"`
+		RuneID       string             `json:"rune_id"`
+		RuneName     string             `json:"rune_name"`
+		TotalBalance common.TokenAmount `json:"total_balance"` // Base units, OPI doesn't return the divisibility
+	}
+
+	// Outpoint holding runes, normalized across indexers
+	RunesUtxo struct {
+		Outpoint   string // txid:vout
+		PkScript   string
+		WalletAddr string
+		Runes      []RuneBalance
+	}
+
+	// Balance of a rune held by an outpoint
+	RuneBalance struct {
+		RuneId   string
+		RuneName string             // Spaced name, empty when the source doesn't report it
+		Amount   common.TokenAmount // Base units, with the divisibility of the rune
 	}
 
 	// OPI Runes Unspent Output
 	OPIRunesUnspentOutput struct {
-		Pkscript   string     `json:"pkscript"`
-		WalletAddr string     `json:"wallet_addr"`
-		Outpoint   string     `json:"outpoint"`
-		RuneIds    []string   `json:"rune_ids"`
-		Balances   []*big.Int `json:"balances"`
+		Pkscript   string               `json:"pkscript"`
+		WalletAddr string               `json:"wallet_addr"`
+		Outpoint   string               `json:"outpoint"`
+		RuneIds    []string             `json:"rune_ids"`
+		Balances   []common.TokenAmount `json:"balances"`
 	}
 
 	// BestInSlot Runes Unspent Output
 	BISRunesUnspentOutput struct {
-		Pkscript        string   `json:"pkscript"`
-		WalletAddr      string   `json:"wallet_addr"`
-		Output          string   `json:"output"`
-		RuneIds         []string `json:"rune_ids"`
-		Balances        []int64  `json:"balances"`
-		RuneNames       []string `json:"rune_names"`
-		SpacedRuneNames []string `json:"spaced_rune_names"`
-		Decimals        []int    `json:"decimals"`
+		Pkscript        string               `json:"pkscript"`
+		WalletAddr      string               `json:"wallet_addr"`
+		Output          string               `json:"output"`
+		RuneIds         []string             `json:"rune_ids"`
+		Balances        []common.TokenAmount `json:"balances"`
+		RuneNames       []string             `json:"rune_names"`
+		SpacedRuneNames []string             `json:"spaced_rune_names"`
+		Decimals        []int                `json:"decimals"`
 	}
 
 	BISResponseWrapper[T any] struct {
@@ -92,6 +200,45 @@ type (
 		BlockHeight int `json:"block_height"`
 	}
 
+	// BestInSlot BRC20 balance of a wallet, amounts with 18 decimals
+	BISBrc20Balance struct {
+		Ticker               string `json:"ticker"`
+		OverallBalance       string `json:"overall_balance"`
+		AvailableBalance     string `json:"available_balance"`
+		TransferrableBalance string `json:"transferrable_balance"`
+	}
+
+	// BestInSlot unspent transfer inscription
+	BISBrc20Transferable struct {
+		InscriptionId     string `json:"inscription_id"`
+		InscriptionNumber int64  `json:"inscription_number"`
+		Ticker            string `json:"ticker"`
+		Amount            string `json:"amount"` // Amount with 18 decimals
+		BlockHeight       int64  `json:"block_height"`
+	}
+
+	BISInscription struct {
+		InscriptionId     string `json:"inscription_id"`
+		InscriptionNumber int64  `json:"inscription_number"`
+		Wallet            string `json:"wallet"`
+		MimeType          string `json:"mime_type"`
+		Satpoint          string `json:"satpoint"`
+		GenesisHeight     int64  `json:"genesis_height"`
+		GenesisFee        int64  `json:"genesis_fee"`
+		OutputValue       int64  `json:"output_value"`
+	}
+
+	// BestInSlot runes balance of a wallet, with the divisibility of the rune
+	BISRunesBalance struct {
+		Pkscript       string             `json:"pkscript"`
+		WalletAddr     string             `json:"wallet_addr"`
+		RuneID         string             `json:"rune_id"`
+		RuneName       string             `json:"rune_name"`
+		SpacedRuneName string             `json:"spaced_rune_name"`
+		TotalBalance   common.TokenAmount `json:"total_balance"`
+		Decimals       int                `json:"decimals"`
+	}
+
 	BISBrc20Event struct {
 		InscriptionID string `json:"inscription_id"`
 		EventType     string `json:"event_type"`
@@ -136,4 +283,48 @@ type RunesUnspentOutput interface {
 	GetOutpoint() string
 	GetRuneIds() []string
 	GetRuneNames() []string
+	GetBalances() []common.TokenAmount
+}
+
+// Source of the outpoints holding runes: OPI, BIS or the local index
+type RunesUtxoSource interface {
+	GetRunesUtxos(ctx context.Context, address string) ([]RunesUtxo, error)
+}
+
+// Source of the outpoints holding inscriptions: BIS
+type InscriptionUtxoSource interface {
+	GetInscriptionOutpoints(ctx context.Context, address string) ([]string, error)
+}
+
+// Source of the outpoints a transaction spent along with the runes they held: OPI or BIS.
+// Empty when the indexer hasn't processed the transaction
+type RunesInputSource interface {
+	GetRunesInputs(ctx context.Context, txId string) ([]RunesUtxo, error)
+}
+
+// BRC20 state served either by OPI or by the local indexer
+type Brc20Backend interface {
+	Brc20BalanceSource
+	GetEventsByInscriptionId(ctx context.Context, inscriptionId string) ([]Brc20Event, error)
+}
+
+// Source of the current BRC20 balances
+type Brc20BalanceSource interface {
+	GetBrc20Balance(ctx context.Context, address, ticker string) (*Brc20Balance, error)
+}
+
+// Source of BRC20 transfers used for deposit verification.
+// OPI can only look up by inscription id and BIS only by tx id, the local indexer supports both
+type Brc20EventSource interface {
+	GetBrc20Transfers(ctx context.Context, txId, inscriptionId string) (*Brc20Transfers, error)
+}
+
+// Source of the transfer inscriptions a wallet can send without inscribing
+type Brc20TransferableSource interface {
+	GetBrc20Transferables(ctx context.Context, address, ticker string) ([]Brc20Transferable, error)
+}
+
+// Source of the deploy parameters of a ticker, ErrNotFound when it wasn't deployed
+type Brc20TickerSource interface {
+	GetBrc20TickerInfo(ctx context.Context, ticker string) (*Brc20TickerInfo, error)
 }
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+)
+
+// Chain queries the Waiter needs, satisfied by BtcRpcClient
+type WaiterChain interface {
+	GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error)
+	GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error)
+}
+
+// Blocks until the node sees a new block or the timeout expires, whichever comes first
+type BlockNotifier interface {
+	WaitForNewBlock(timeout time.Duration) error
+}
+
+var (
+	_ WaiterChain   = &BtcRpcClient{}
+	_ BlockNotifier = &BtcRpcClient{}
+)
+
+// Waits for txs and outputs to show up on chain. Confirmation waits are woken up by the notifier on every block,
+// everything else polls the node with an exponential backoff
+type Waiter struct {
+	Chain      WaiterChain
+	Notifier   BlockNotifier // Optional, confirmation waits poll when it is nil
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Waiter using bitcoin core for both queries and block notifications (waitfornewblock)
+func NewWaiter(client *BtcRpcClient) *Waiter {
+	return &Waiter{
+		Chain:      client,
+		Notifier:   client,
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+	}
+}
+
+// Wait until the tx is in the mempool or already mined
+func (w *Waiter) WaitForMempool(ctx context.Context, txId string) error {
+	_, err := w.WaitForConfirmations(ctx, txId, 0)
+	return err
+}
+
+// Wait until the tx has at least n confirmations, returning the tx as seen by the node at that point
+func (w *Waiter) WaitForConfirmations(ctx context.Context, txId string, n uint64) (*btcjson.TxRawResult, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return nil, err
+	}
+	var tx *btcjson.TxRawResult
+	err = w.wait(ctx, n > 0, func() (bool, error) {
+		res, err := w.Chain.GetRawTransactionVerbose(hash)
+		if isNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		tx = res
+		return res.Confirmations >= n, nil
+	})
+	return tx, err
+}
+
+// Wait until the outpoint exists and is unspent, counting the mempool
+func (w *Waiter) WaitForSpendable(ctx context.Context, outpoint wire.OutPoint) (*btcjson.GetTxOutResult, error) {
+	var out *btcjson.GetTxOutResult
+	err := w.wait(ctx, false, func() (bool, error) {
+		res, err := w.Chain.GetTxOut(&outpoint.Hash, outpoint.Index, true)
+		if err != nil {
+			return false, err
+		}
+		out = res
+		return res != nil, nil
+	})
+	return out, err
+}
+
+// Poll check until it is done, it fails or ctx is done
+func (w *Waiter) Until(ctx context.Context, check func() (bool, error)) error {
+	return w.wait(ctx, false, check)
+}
+
+func (w *Waiter) wait(ctx context.Context, onBlock bool, check func() (bool, error)) error {
+	backoff := w.MinBackoff
+	if backoff <= 0 {
+		backoff = defaultMinBackoff
+	}
+	maxBackoff := w.MaxBackoff
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		// Only a new block can add confirmations. The timeout keeps ctx cancellation responsive
+		if onBlock && w.Notifier != nil {
+			if err := w.Notifier.WaitForNewBlock(maxBackoff); err == nil {
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// getrawtransaction fails with RPC_INVALID_ADDRESS_OR_KEY for txs the node doesn't know about (yet)
+func isNotFound(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCNoTxInfo
+}
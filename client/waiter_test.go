@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// Chain where the tx shows up in the mempool after a few queries and gains a confirmation on every block
+type fakeChain struct {
+	queries       int
+	seenAfter     int
+	confirmations uint64
+	blocks        int
+}
+
+func (c *fakeChain) GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	c.queries++
+	if c.queries <= c.seenAfter {
+		return nil, &btcjson.RPCError{Code: btcjson.ErrRPCNoTxInfo, Message: "No such mempool or blockchain transaction"}
+	}
+	return &btcjson.TxRawResult{Txid: txHash.String(), Confirmations: c.confirmations}, nil
+}
+
+func (c *fakeChain) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error) {
+	c.queries++
+	if c.queries <= c.seenAfter {
+		return nil, nil
+	}
+	return &btcjson.GetTxOutResult{Value: 0.00000546}, nil
+}
+
+func (c *fakeChain) WaitForNewBlock(timeout time.Duration) error {
+	c.blocks++
+	c.confirmations++
+	return nil
+}
+
+func newTestWaiter(chain *fakeChain) *Waiter {
+	return &Waiter{Chain: chain, Notifier: chain, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func TestWaiter(t *testing.T) {
+	ctx := context.Background()
+	txId := chainhash.DoubleHashH([]byte("tx")).String()
+
+	chain := &fakeChain{seenAfter: 3}
+	require.NoError(t, newTestWaiter(chain).WaitForMempool(ctx, txId))
+	require.Equal(t, 4, chain.queries)
+	require.Zero(t, chain.blocks)
+
+	// Confirmations are only checked again after a new block
+	chain = &fakeChain{}
+	tx, err := newTestWaiter(chain).WaitForConfirmations(ctx, txId, 3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), tx.Confirmations)
+	require.Equal(t, 3, chain.blocks)
+
+	chain = &fakeChain{seenAfter: 2}
+	out, err := newTestWaiter(chain).WaitForSpendable(ctx, wire.OutPoint{Index: 1})
+	require.NoError(t, err)
+	require.NotNil(t, out)
+	require.Equal(t, 3, chain.queries)
+
+	_, err = newTestWaiter(chain).WaitForConfirmations(ctx, "not a txid", 1)
+	require.Error(t, err)
+}
+
+func TestWaiterCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	chain := &fakeChain{seenAfter: 1 << 30}
+	err := newTestWaiter(chain).WaitForMempool(ctx, chainhash.DoubleHashH([]byte("tx")).String())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Errors other than an unknown tx end the wait
+	boom := &btcjson.RPCError{Code: btcjson.ErrRPCMisc, Message: "boom"}
+	err = newTestWaiter(chain).Until(context.Background(), func() (bool, error) { return false, boom })
+	require.ErrorIs(t, err, boom)
+}
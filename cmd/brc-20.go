@@ -3,9 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/brc20/indexer"
+	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/inscriptions/tracker"
 	"github.com/spf13/cobra"
 )
 
@@ -15,32 +20,42 @@ func e2eCmd(config config.Config) *cobra.Command {
 		Short:  "mint and transfer in one command [ONLY FOR REGTEST]",
 		PreRun: preRunForceArgs(6),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			genBlocks := func() {
-				// you don't need block generation as long as txns are in the mempool
-				fmt.Println("no blocks generated")
-				// if err := generateBlocks(); err != nil {
-				// 	fmt.Println("Error generating blocks")
-				// 	fmt.Println(err.Error())
-				// 	os.Exit(1)
-				// }
+			rpc, err := client.NewBitcoinClient(config)
+			if err != nil {
+				return err
+			}
+			waiter := client.NewWaiter(rpc.WithContext(cmd.Context()))
+			// Mine the tx on regtest, otherwise it only has to reach the mempool
+			waitForTx := func(txId string) {
+				err := waiter.WaitForMempool(cmd.Context(), txId)
+				if err == nil && config.BtcConfig.GetChainConfigParams().Name == chaincfg.RegressionNetParams.Name {
+					if err = GenerateBlocks(); err == nil {
+						_, err = waiter.WaitForConfirmations(cmd.Context(), txId, 1)
+					}
+				}
+				if err != nil {
+					fmt.Println("Error waiting for", txId)
+					fmt.Println(err.Error())
+					os.Exit(1)
+				}
 			}
 
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			ticker := parseTicker(args[0])
-			amt := parseBigFloat(args[1])
+			amt := parseBrc20Amount(args[1], brc20.MaxDecimals)
 			fromAddr := parseBtcAddress(args[2], config)
 			toAddr := parseBtcAddress(args[3], config)
 			inscriberPrivateKey := parsePrivateKey(args[4])
 			senderPrivateKey := parsePrivateKey(args[5])
 
-			_, err := brc20.InscribeMint(ticker, amt, fromAddr, inscriberPrivateKey, uint64(feeRate), config)
+			mint, err := brc20.InscribeMint(ticker, amt, fromAddr, inscriberPrivateKey, uint64(feeRate), config)
 			if err != nil {
 				fmt.Println("Error occured while minting")
 				fmt.Println(err.Error())
 				os.Exit(1)
 			}
 
-			genBlocks()
+			waitForTx(mint.RevealTx)
 			fmt.Println("inscribing transfer inscription...")
 
 			insc, err := brc20.InscribeTransfer(ticker, amt, fromAddr, inscriberPrivateKey, uint64(feeRate), config)
@@ -50,20 +65,20 @@ func e2eCmd(config config.Config) *cobra.Command {
 				os.Exit(1)
 			}
 
-			genBlocks()
+			waitForTx(insc.RevealTx)
 
-			transferInscription := insc.RevealTx
+			transferInscription := insc.InscriptionIds[0]
 
 			fmt.Println("transferring inscription...")
 
-			res, err := brc20.TransferInscription(fromAddr, toAddr, transferInscription, senderPrivateKey, uint64(feeRate), config)
+			res, err := brc20.TransferInscription(cmd.Context(), fromAddr, toAddr, transferInscription, senderPrivateKey, uint64(feeRate), config)
 			if err != nil {
 				fmt.Println("Error occured while transferring")
 				fmt.Println(err.Error())
 				os.Exit(1)
 			}
 
-			genBlocks()
+			waitForTx(*res)
 
 			fmt.Println("done")
 			fmt.Println("inscription ID transferred: ", transferInscription)
@@ -72,8 +87,7 @@ func e2eCmd(config config.Config) *cobra.Command {
 			return nil
 		},
 	}
-	_ = e2eCmd.MarkFlagRequired("fee-rate")
-	_ = e2eCmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = e2eCmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
 	return &e2eCmd
 }
 
@@ -83,12 +97,12 @@ func transferCmd(config config.Config) *cobra.Command {
 		Short: "transfer inscriptions",
 		Args:  cobra.ExactArgs(6),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			fromAddr := parseBtcAddress(args[0], config)
 			toAddr := parseBtcAddress(args[1], config)
 			transferInscription := parseString(args[2])
 			privateKey := parsePrivateKey(args[3])
-			hashPtr, err := brc20.TransferInscription(fromAddr, toAddr, transferInscription, privateKey, uint64(feeRate), config)
+			hashPtr, err := brc20.TransferInscription(cmd.Context(), fromAddr, toAddr, transferInscription, privateKey, uint64(feeRate), config)
 			if err != nil {
 				fmt.Println("Error occured while transferring")
 				fmt.Println(err.Error())
@@ -98,8 +112,7 @@ func transferCmd(config config.Config) *cobra.Command {
 			return nil
 		},
 	}
-	_ = transferCmd.MarkFlagRequired("fee-rate")
-	_ = transferCmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = transferCmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
 	return &transferCmd
 }
 
@@ -109,28 +122,134 @@ func sendBrc20Cmd(config config.Config) *cobra.Command {
 		Short: "inscribe transfer + transfer inscription",
 		Args:  cobra.ExactArgs(6),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			ticker := parseTicker(args[0])
-			amt := parseBigFloat(args[1])
+			amt := parseBrc20Amount(args[1], brc20.MaxDecimals)
 			fromAddr := parseBtcAddress(args[2], config)
 			toAddr := parseBtcAddress(args[3], config)
 			inscriberPrivateKey := parsePrivateKey(args[4])
 			senderPrivateKey := parsePrivateKey(args[5])
 
-			inscriptionId, hash, err := brc20.SendBrc20(ticker, fromAddr, toAddr, amt, uint64(feeRate), inscriberPrivateKey, senderPrivateKey, config)
+			res, err := brc20.SendBrc20(cmd.Context(), ticker, fromAddr, toAddr, amt, uint64(feeRate), inscriberPrivateKey, senderPrivateKey, config)
 			if err != nil {
 				fmt.Println("Error occured while transferring")
 				fmt.Println(err.Error())
 				os.Exit(1)
 			}
 			fmt.Println("----")
-			fmt.Println("Inscription ID", inscriptionId)
-			fmt.Println("Tx Hash", hash)
+			if res.Inscribed != nil {
+				fmt.Println("Commit Tx", res.Inscribed.CommitTx)
+				fmt.Println("Reveal Tx", res.Inscribed.RevealTx)
+			}
+			fmt.Println("Inscription IDs", strings.Join(res.InscriptionIds, ", "))
+			fmt.Println("Tx Hash", res.TxHash)
 			fmt.Println("----")
 			return nil
 		},
 	}
-	_ = transferCmd.MarkFlagRequired("fee-rate")
-	_ = transferCmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = transferCmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
 	return &transferCmd
 }
+
+func reconcileCmd(config config.Config) *cobra.Command {
+	reconcileCmd := cobra.Command{
+		Use:   "reconcile TICKER ADDRESS [INSCRIPTION_ID...]",
+		Short: "index brc20 locally and compare balances & events with OPI",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, _ := cmd.Flags().GetInt64("from")
+			ticker := parseTicker(args[0])
+			addr := parseBtcAddress(args[1], config)
+
+			rpc, err := client.NewBitcoinClient(config)
+			if err != nil {
+				return err
+			}
+			rpc = rpc.WithContext(cmd.Context())
+			local := indexer.New(tracker.RpcPrevOutFetcher{Client: rpc}, from, config.BtcConfig.GetChainConfigParams())
+			if err := local.Sync(rpc); err != nil {
+				fmt.Println("Error occured while indexing")
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			fmt.Println("Indexed up to block", local.Height())
+
+			opi, err := client.NewOpiClient(config.OpiConfig)
+			if err != nil {
+				return err
+			}
+			// Compared at the height indexed locally, a lagging OPI would only report bogus mismatches
+			opi = opi.WithMinHeight(local.Height())
+			failed := false
+			if err := local.ReconcileBalance(cmd.Context(), opi, addr.EncodeAddress(), ticker); err != nil {
+				fmt.Println(err.Error())
+				failed = true
+			}
+			for _, inscriptionId := range args[2:] {
+				if err := local.ReconcileEvents(cmd.Context(), opi, inscriptionId); err != nil {
+					fmt.Println(err.Error())
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+			fmt.Println("Local state matches OPI")
+			return nil
+		},
+	}
+	_ = reconcileCmd.Flags().Int64("from", 0, "Height to start indexing from, the state before it is assumed empty")
+	return &reconcileCmd
+}
+
+func checkTransferCmd(config config.Config) *cobra.Command {
+	checkTransferCmd := cobra.Command{
+		Use:   "check-transfer",
+		Short: "compare a brc20 transfer across the configured indexers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			txId, _ := cmd.Flags().GetString("tx-id")
+			inscriptionId, _ := cmd.Flags().GetString("inscription-id")
+			consensus, err := brc20.NewBrc20Consensus(config)
+			if err != nil {
+				return err
+			}
+			res := consensus.CheckTransfers(cmd.Context(), txId, inscriptionId)
+			fmt.Println("Status:", res.Status)
+			fmt.Println("Agreeing:", strings.Join(res.Agreeing, ", "))
+			fmt.Println(res.Details())
+			if err := res.Check(config.GetBrc20Quorum()); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	_ = checkTransferCmd.Flags().String("tx-id", "", "Transaction moving the transfer inscription")
+	_ = checkTransferCmd.Flags().String("inscription-id", "", "Transfer inscription")
+	return &checkTransferCmd
+}
+
+func checkBalanceCmd(config config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check-balance TICKER ADDRESS",
+		Short: "compare a brc20 balance across the configured indexers",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ticker := parseTicker(args[0])
+			addr := parseBtcAddress(args[1], config)
+			consensus, err := brc20.NewBrc20Consensus(config)
+			if err != nil {
+				return err
+			}
+			res := consensus.CheckBalance(cmd.Context(), addr.EncodeAddress(), ticker)
+			fmt.Println("Status:", res.Status)
+			fmt.Println("Agreeing:", strings.Join(res.Agreeing, ", "))
+			fmt.Println(res.Details())
+			if err := res.Check(config.GetBrc20Quorum()); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+}
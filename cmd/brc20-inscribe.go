@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"encoding/hex"
 	"fmt"
 	"os"
 
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/ordinox/btc-service/brc20"
 	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/config"
@@ -17,11 +19,18 @@ func brc20Cmd(config config.Config) *cobra.Command {
 		Short: "interact with brc20 tokens on bitcoin",
 	}
 	brc20Cmd.AddCommand(
-		getBalance(config.OpiConfig),
+		getBalance(config),
+		brc20ActivityCmd(config),
+		brc20HoldersCmd(config),
+		brc20TickerCmd(config),
+		brc20EventHashCmd(config),
 		brc20InscribeCmd(config),
 		transferCmd(config),
 		e2eCmd(config),
 		sendBrc20Cmd(config),
+		reconcileCmd(config),
+		checkTransferCmd(config),
+		checkBalanceCmd(config),
 	)
 	return &brc20Cmd
 }
@@ -31,9 +40,10 @@ func brc20InscribeCmd(config config.Config) *cobra.Command {
 		Use:   "inscribe",
 		Short: "inscribe brc20 inscriptions",
 	}
-	_ = brc20InscribeCmd.MarkFlagRequired("fee-rate")
-	_ = brc20InscribeCmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
-	_ = brc20InscribeCmd.PersistentFlags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = brc20InscribeCmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
+	_ = brc20InscribeCmd.PersistentFlags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
+	_ = brc20InscribeCmd.PersistentFlags().Int64("postage", 0, "Sats locked in the inscription output (default 546)")
+	_ = brc20InscribeCmd.PersistentFlags().Uint64("reveal-fee-rate", 0, "Fee rate for the reveal tx (defaults to --fee-rate)")
 	brc20InscribeCmd.AddCommand(
 		inscribeDeployCmd(config),
 		inscribeMintCmd(config),
@@ -42,7 +52,7 @@ func brc20InscribeCmd(config config.Config) *cobra.Command {
 	return &brc20InscribeCmd
 }
 
-func getBalance(config config.OpiConfig) *cobra.Command {
+func getBalance(config config.Config) *cobra.Command {
 	getBalanceCmd := cobra.Command{
 		Use:   "balance  [ticker] [address]",
 		Short: "get brc20 balance for a token",
@@ -54,16 +64,30 @@ func getBalance(config config.OpiConfig) *cobra.Command {
 			if args[1] == "" {
 				return fmt.Errorf("address cannot be empty")
 			}
-			balance, err := client.NewOpiClient(config).GetBrc20Balance(args[1], args[0])
+			opi, err := client.NewOpiClient(config.OpiConfig)
 			if err != nil {
 				return err
 			}
+			var balance *client.Brc20Balance
+			if height, _ := cmd.Flags().GetInt64("height"); height > 0 {
+				pkScript, err := txscript.PayToAddrScript(parseBtcAddress(args[1], config))
+				if err != nil {
+					return err
+				}
+				balance, err = opi.GetBrc20BalanceAtHeight(cmd.Context(), hex.EncodeToString(pkScript), args[0], height)
+				if err != nil {
+					return err
+				}
+			} else if balance, err = opi.GetBrc20Balance(cmd.Context(), args[1], args[0]); err != nil {
+				return err
+			}
 			fmt.Println("Overall Balance: ", balance.OverallBalance)
 			fmt.Println("Available Balance: ", balance.AvailableBalance)
 			fmt.Println("Block Height", balance.BlockHeight)
 			return nil
 		},
 	}
+	_ = getBalanceCmd.Flags().Int64("height", 0, "Balance at the end of this block instead of the current one")
 	return &getBalanceCmd
 }
 
@@ -73,24 +97,37 @@ func inscribeDeployCmd(config config.Config) *cobra.Command {
 		Short:  "deploy a brc20 token",
 		PreRun: preRunForceArgs(4),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			ticker := parseTicker(args[0])
-			supply := parseUint64(args[1])
+			opts := brc20.DeployOptions{}
+			decimals := uint8(brc20.MaxDecimals)
+			if cmd.Flags().Changed("dec") {
+				dec, _ := cmd.Flags().GetUint8("dec")
+				decimals = dec
+				opts.Decimals = &decimals
+			}
+			supply := parseBrc20Amount(args[1], decimals)
+			if lim, _ := cmd.Flags().GetString("lim"); lim != "" {
+				limit := parseBrc20Amount(lim, decimals)
+				opts.Limit = &limit
+			}
+			opts.SelfMint, _ = cmd.Flags().GetBool("self-mint")
 			addr := parseBtcAddress(args[2], config)
 			privateKey := parsePrivateKey(args[3])
 
-			insc, err := brc20.InscribeDeploy(ticker, uint(supply), privateKey, addr, uint64(feeRate), config)
+			insc, err := brc20.InscribeDeploy(ticker, supply, opts, privateKey, addr, uint64(feeRate), inscriptionFlags(cmd, config))
 			if err != nil {
 				fmt.Println("Error occured while deploying")
 				fmt.Println(err.Error())
 				os.Exit(1)
 			}
-			fmt.Println("CommitTx:", insc.CommitTx)
-			fmt.Println("RevelTx:", insc.RevealTx)
-			fmt.Println("Fee:", insc.TotalFeePaid)
+			printInscriptionRes(insc)
 			return nil
 		},
 	}
+	_ = deployCmd.Flags().String("lim", "", "Max amount per mint (defaults to the supply)")
+	_ = deployCmd.Flags().Uint8("dec", brc20.MaxDecimals, "Decimals of the token")
+	_ = deployCmd.Flags().Bool("self-mint", false, "Only the deployer can mint (required for 5 byte tickers)")
 	return &deployCmd
 }
 
@@ -100,20 +137,18 @@ func inscribeMintCmd(config config.Config) *cobra.Command {
 		Short:  "mint a brc20 token",
 		PreRun: preRunForceArgs(4),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			ticker := parseTicker(args[0])
-			amt := parseBigFloat(args[1])
+			amt := parseBrc20Amount(args[1], brc20.MaxDecimals)
 			addr := parseBtcAddress(args[2], config)
 			privateKey := parsePrivateKey(args[3])
-			insc, err := brc20.InscribeMint(ticker, amt, addr, privateKey, uint64(feeRate), config)
+			insc, err := brc20.InscribeMint(ticker, amt, addr, privateKey, uint64(feeRate), inscriptionFlags(cmd, config))
 			if err != nil {
 				fmt.Println("Error occured while minting")
 				fmt.Println(err.Error())
 				os.Exit(1)
 			}
-			fmt.Println("CommitTx:", insc.CommitTx)
-			fmt.Println("RevelTx:", insc.RevealTx)
-			fmt.Println("Fee:", insc.TotalFeePaid)
+			printInscriptionRes(insc)
 			return nil
 		},
 	}
@@ -127,28 +162,33 @@ func inscribeTransferCmd(config config.Config) *cobra.Command {
 		Short:  "transfer brc20 tokens from the given address to another",
 		PreRun: preRunForceArgs(4),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			ticker := parseTicker(args[0])
-			amt := parseBigFloat(args[1])
+			amt := parseBrc20Amount(args[1], brc20.MaxDecimals)
 			addr := parseBtcAddress(args[2], config)
 			privateKey := parsePrivateKey(args[3])
 
-			insc, err := brc20.InscribeTransfer(ticker, amt, addr, privateKey, uint64(feeRate), config)
+			insc, err := brc20.InscribeTransfer(ticker, amt, addr, privateKey, uint64(feeRate), inscriptionFlags(cmd, config))
 			if err != nil {
 				fmt.Println("Error occured while inscribing transfer")
 				fmt.Println(err.Error())
 				os.Exit(1)
 			}
-			fmt.Println("CommitTx:", insc.CommitTx)
-			fmt.Println("RevelTx:", insc.RevealTx)
-			fmt.Println("Fee:", insc.TotalFeePaid)
+			printInscriptionRes(insc)
 			return nil
 		},
 	}
 	return &transferCmd
 }
 
-func printInscriptionRes(res *inscriptions.InscriptionResultRaw) {
-	fmt.Println("commit: ", res.Commit)
-	fmt.Println("inscriptionId: ", res.Inscriptions[0].Id)
+func printInscriptionRes(res *inscriptions.InscriptionResult) {
+	fmt.Println("CommitTx:", res.CommitTx)
+	fmt.Println("RevelTx:", res.RevealTx)
+	for _, id := range res.InscriptionIds {
+		fmt.Println("InscriptionId:", id)
+	}
+	fmt.Println("CommitFee:", res.CommitFee)
+	fmt.Println("RevealFee:", res.RevealFee)
+	fmt.Println("Postage:", res.Postage)
+	fmt.Println("Fee:", res.TotalFeePaid)
 }
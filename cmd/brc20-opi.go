@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/markkurossi/tabulate"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/spf13/cobra"
+)
+
+func brc20ActivityCmd(config config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "activity HEIGHT",
+		Short: "list the brc20 events of a block",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height := int64(parseUint64(args[0]))
+			opi, err := client.NewOpiClient(config.OpiConfig)
+			if err != nil {
+				return err
+			}
+			events, err := opi.GetBrc20ActivityOnBlock(cmd.Context(), height)
+			if err != nil {
+				return err
+			}
+			tab := tabulate.New(tabulate.ASCII)
+			tab.Header("Event")
+			tab.Header("Inscription")
+			tab.Header("Tick")
+			tab.Header("Amount")
+			tab.Header("From")
+			tab.Header("To")
+			for _, evt := range events {
+				amount, from, to := evt.Amount, evt.SourceWallet, evt.SpentWallet
+				switch evt.EventType {
+				case "deploy-inscribe":
+					amount, from = evt.MaxSupply, evt.DeployerWallet
+				case "mint-inscribe":
+					to = evt.MintedWallet
+				}
+				row := tab.Row()
+				row.Column(evt.EventType)
+				row.Column(evt.InscriptionId)
+				row.Column(evt.Tick)
+				row.Column(formatBrc20Amount(amount))
+				row.Column(from)
+				row.Column(to)
+			}
+			tab.Print(os.Stdout)
+			return nil
+		},
+	}
+}
+
+// OPI amounts are integers with 18 decimals
+func formatBrc20Amount(amount string) string {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return amount
+	}
+	return common.NewTokenAmount(value, 18).String()
+}
+
+func brc20HoldersCmd(config config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "holders TICKER",
+		Short: "list the wallets holding a brc20 ticker",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opi, err := client.NewOpiClient(config.OpiConfig)
+			if err != nil {
+				return err
+			}
+			holders, err := opi.GetBrc20Holders(cmd.Context(), parseTicker(args[0]))
+			if err != nil {
+				return err
+			}
+			page := client.Paginate(holders, pageFlags(cmd))
+			tab := tabulate.New(tabulate.ASCII)
+			tab.Header("Wallet")
+			tab.Header("Overall")
+			tab.Header("Available")
+			for _, h := range page.Items {
+				row := tab.Row()
+				row.Column(h.Wallet)
+				row.Column(formatBrc20Amount(h.OverallBalance))
+				row.Column(formatBrc20Amount(h.AvailableBalance))
+			}
+			tab.Print(os.Stdout)
+			printPage(page)
+			return nil
+		},
+	}
+	addPageFlags(cmd)
+	return cmd
+}
+
+func brc20TickerCmd(config config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ticker TICKER",
+		Short: "show the deploy parameters & supply of a brc20 ticker",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opi, err := client.NewOpiClient(config.OpiConfig)
+			if err != nil {
+				return err
+			}
+			info, err := opi.GetBrc20TickerInfo(cmd.Context(), parseTicker(args[0]))
+			if err != nil {
+				return err
+			}
+			minted, err := info.MintedSupply()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Ticker:", info.OriginalTick)
+			fmt.Println("Max Supply:", info.MaxSupply)
+			fmt.Println("Minted:", minted)
+			fmt.Println("Limit Per Mint:", info.LimitPerMint)
+			fmt.Println("Decimals:", info.Decimals)
+			fmt.Println("Self Mint:", info.IsSelfMint)
+			fmt.Println("Deploy Inscription:", info.DeployInscriptionId)
+			fmt.Println("Deploy Height:", info.BlockHeight)
+			return nil
+		},
+	}
+}
+
+func brc20EventHashCmd(config config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "event-hash HEIGHT",
+		Short: "check the cumulative brc20 event hash of a block",
+		Long:  "Check that the cumulative event hash of the block chains from the previous block and, with --trusted, matches the hash published by another OPI operator",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height := int64(parseUint64(args[0]))
+			trusted, _ := cmd.Flags().GetString("trusted")
+			opi, err := client.NewOpiClient(config.OpiConfig)
+			if err != nil {
+				return err
+			}
+			hash, err := opi.VerifyBrc20EventHash(cmd.Context(), height, trusted)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Block Event Hash:", hash.BlockEventHash)
+			fmt.Println("Cumulative Event Hash:", hash.CumulativeEventHash)
+			fmt.Println("Indexer Version:", hash.IndexerVersion)
+			return nil
+		},
+	}
+	_ = cmd.Flags().String("trusted", "", "Cumulative event hash of the block from a trusted indexer")
+	return cmd
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"os"
 	"strconv"
 
 	"github.com/alexellis/go-execute/v2"
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/ordinox/btc-service/btc"
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/watch"
 	"github.com/spf13/cobra"
 )
 
@@ -41,12 +44,12 @@ func getKeyPair(config config.BtcConfig) (string, string) {
 	return addrStr, pkHex
 }
 
-func getKeyPairCmd() *cobra.Command {
+func getKeyPairCmd(config config.Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "keypair",
 		Short: "get a btc address & privatekey hex",
 		Run: func(cmd *cobra.Command, args []string) {
-			addr, privKey := getKeyPair(config.GetDefaultConfig().BtcConfig)
+			addr, privKey := getKeyPair(config.BtcConfig)
 			fmt.Println()
 			fmt.Println("Address: ", addr)
 			fmt.Println()
@@ -107,14 +110,14 @@ func GenerateBlocks() error {
 	return nil
 }
 
-func getUtxosCmd() *cobra.Command {
+func getUtxosCmd(config config.Config) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "utxos",
 		Short: "get utxos for a legacy",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get utxos using electrum
-			utxos, err := common.GetUtxos(args[0], config.GetDefaultConfig().BtcConfig)
+			utxos, err := common.GetUtxos(args[0], config.BtcConfig)
 			if err != nil {
 				return err
 			}
@@ -132,7 +135,7 @@ func getUtxosCmd() *cobra.Command {
 
 func transferBtcCmd(config config.Config) *cobra.Command {
 	transferCmd := cobra.Command{
-		Use:   "transfer [fromAddr] [toAddr] [feeRate] [amt] [privateKey]",
+		Use:   "transfer [fromAddr] [toAddr] [feeRate|auto|fast|slow] [amt] [privateKey]",
 		Short: "transfer inscriptions",
 		Args:  cobra.ExactArgs(5),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -146,7 +149,7 @@ func transferBtcCmd(config config.Config) *cobra.Command {
 				return err
 			}
 
-			feeRate, err := strconv.Atoi(args[2])
+			feeRate, err := resolveFeeRate(args[2], config)
 			if err != nil {
 				return err
 			}
@@ -193,3 +196,83 @@ func transferBtcCmd(config config.Config) *cobra.Command {
 	}
 	return &transferCmd
 }
+
+func txCmd(config config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tx",
+		Short: "manage broadcast transactions",
+	}
+	cmd.AddCommand(bumpTxCmd(config), cpfpTxCmd(config))
+	return cmd
+}
+
+func bumpTxCmd(config config.Config) *cobra.Command {
+	bumpCmd := cobra.Command{
+		Use:   "bump TXID PRIVATE_KEY",
+		Short: "replace an unconfirmed tx with a higher fee taken from its change (commit/reveal pairs are bumped together)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feeRate := forceFeeRateFlag(cmd, config)
+			privateKey := parsePrivateKey(args[1])
+			res, err := btc.BumpFee(args[0], uint64(feeRate), privateKey, config)
+			if err != nil {
+				fmt.Println("Error occured while bumping the fee")
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			fmt.Println("----")
+			for i, txId := range res.TxIds {
+				fmt.Printf("%s replaced by %s (fee %d)\n", res.Replaced[i], txId, res.Fees[i])
+			}
+			fmt.Println("----")
+			if track, _ := cmd.Flags().GetBool("track"); track {
+				for i, txId := range res.TxIds {
+					if err := watch.TrackTx(config.WatchConfig.GetTrackDir(), txId, res.Replaced[i]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+	_ = bumpCmd.Flags().StringP("fee-rate", "f", "auto", "New fee rate: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
+	_ = bumpCmd.Flags().Bool("track", false, "Have the watcher follow the replacements, see watch track")
+	return &bumpCmd
+}
+
+func cpfpTxCmd(config config.Config) *cobra.Command {
+	cpfpCmd := cobra.Command{
+		Use:   "cpfp TXID PRIVATE_KEY FUNDING_TXID:VOUT",
+		Short: "spend an output of an unconfirmed tx back to the key, so that both reach the package fee rate",
+		Long: `Spends an output of the tx paying to the key back to it, unchanged, along with a cardinal utxo of the
+key paying the fee. Inscriptions & runes on the output stay where they are.`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feeRate := forceFeeRateFlag(cmd, config)
+			privateKey := parsePrivateKey(args[1])
+			funding, err := wire.NewOutPointFromString(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid funding utxo %s: %w", args[2], err)
+			}
+			res, err := btc.Accelerate(args[0], uint64(feeRate), privateKey, *funding, config)
+			if err != nil {
+				fmt.Println("Error occured while accelerating")
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			fmt.Println("----")
+			fmt.Println("Child Tx", res.ChildTxId)
+			fmt.Println("Child Fee", res.ChildFee)
+			fmt.Printf("Package Fee Rate %.2f sats/vB\n", res.PackageFeeRate)
+			fmt.Println("Package Relay", res.PackageRelay)
+			fmt.Println("----")
+			if track, _ := cmd.Flags().GetBool("track"); track {
+				return watch.TrackTx(config.WatchConfig.GetTrackDir(), res.ChildTxId, "")
+			}
+			return nil
+		},
+	}
+	_ = cpfpCmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate of the parent + child package: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
+	_ = cpfpCmd.Flags().Bool("track", false, "Have the watcher follow the child tx, see watch track")
+	return &cpfpCmd
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ordinox/btc-service/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Flags overriding single settings, by config key
+var settingFlags = map[string]string{
+	"network":  "btc.chain_cfg",
+	"rpc-host": "btc.rpc_host",
+	"wallet":   "btc.wallet_name",
+}
+
+func addConfigFlags(flags *pflag.FlagSet) {
+	_ = flags.String("config", "", "Config file, YAML or TOML (default $BTC_SERVICE_CONFIG, ./btc-service.yaml or the user config dir)")
+	_ = flags.String("profile", "", "Profile of the config file to apply (default $BTC_SERVICE_PROFILE)")
+	_ = flags.String("network", "", "Network: mainnet, testnet3, testnet4, signet or regtest")
+	_ = flags.String("rpc-host", "", "Bitcoin core rpc host")
+	_ = flags.String("wallet", "", "Bitcoin core wallet")
+}
+
+// The commands are built with the config, so the config flags are parsed ahead of cobra
+func loadConfig(args []string) (config.LoadOptions, config.Config, error) {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	addConfigFlags(flags)
+	_ = flags.Parse(args)
+
+	opts := config.LoadOptions{Overrides: map[string]any{}}
+	opts.Path, _ = flags.GetString("config")
+	opts.Profile, _ = flags.GetString("profile")
+	for flag, key := range settingFlags {
+		if value, _ := flags.GetString(flag); value != "" {
+			opts.Overrides[key] = value
+		}
+	}
+	c, err := config.Load(opts)
+	return opts, c, err
+}
+
+// Validate the config before running any command but the config ones
+func validateConfig(c config.Config) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cmd.Parent() != nil && cmd.Parent().Name() == "config" {
+			return nil
+		}
+		err := c.Validate()
+		// The problems are listed one per line, the usage would only bury them
+		cmd.SilenceUsage = err != nil
+		return err
+	}
+}
+
+func configCmd(opts config.LoadOptions, c config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "inspect the runtime config",
+	}
+	cmd.AddCommand(configShowCmd(opts, c), configValidateCmd(c))
+	return cmd
+}
+
+func configShowCmd(opts config.LoadOptions, c config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "print the config in effect, secrets masked",
+		Run: func(cmd *cobra.Command, args []string) {
+			if path := opts.ConfigFile(); path != "" {
+				fmt.Println("# file:", path)
+			} else {
+				fmt.Println("# file: none, defaults & env vars only")
+			}
+			if profile := opts.ProfileName(); profile != "" {
+				fmt.Println("# profile:", profile)
+			}
+			out, err := yaml.Marshal(c.Display())
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			fmt.Print(string(out))
+		},
+	}
+}
+
+func configValidateCmd(c config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "check the config, listing every problem",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := c.Validate(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Printf("config is valid (%s)\n", c.BtcConfig.GetNetwork())
+		},
+	}
+}
@@ -1,31 +1,46 @@
 package cmd
 
 import (
-	"github.com/ordinox/btc-service/config"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
 	"github.com/spf13/cobra"
 )
 
 func Execute() {
-	root := cobra.Command{
-		Use:   "btc-service",
-		Short: "cli for interacting with tokens on bitcoin",
+	opts, config, err := loadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
 	}
 
-	config.Init()
-
-	config := config.GetDefaultConfig()
+	root := cobra.Command{
+		Use:               "btc-service",
+		Short:             "cli for interacting with tokens on bitcoin",
+		PersistentPreRunE: validateConfig(config),
+	}
+	addConfigFlags(root.PersistentFlags())
 
 	root.AddCommand(
+		configCmd(opts, config),
 		brc20Cmd(config),
-		getKeyPairCmd(),
+		getKeyPairCmd(config),
 		genBlocksCmd(config.BtcConfig),
-		getUtxosCmd(),
+		getUtxosCmd(config),
 		transferBtcCmd(config),
 		satsToBtcCmd(),
 		runesCmd(config),
+		txCmd(config),
+		watchCmd(config),
+		webhookCmd(config),
+		serveCmd(config),
 	)
-	err := root.Execute()
-	if err != nil {
-		panic(err)
+	// Interrupting cancels any wait in progress
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := root.ExecuteContext(ctx); err != nil {
+		os.Exit(1)
 	}
 }
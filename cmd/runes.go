@@ -3,10 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/markkurossi/tabulate"
 	"github.com/ordinox/btc-service/client"
-	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/runes"
 	"github.com/spf13/cobra"
@@ -21,6 +21,10 @@ func runesCmd(config config.Config) (cmd *cobra.Command) {
 		mintRunesCmd(config),
 		transferRuneCmd(config),
 		runesBalanceCmd(config),
+		runesTickerCmd(config),
+		runesHoldersCmd(config),
+		runesUtxosCmd(config),
+		verifyRunesDepositCmd(config),
 		splitUtxoCmd(config),
 	)
 	return
@@ -31,7 +35,7 @@ func mintRunesCmd(config config.Config) (cmd *cobra.Command) {
 		Use:    "mint RUNE_ID FROM_ADDR PRIV_KEY_HEX",
 		PreRun: preRunForceArgs(3),
 		Run: func(cmd *cobra.Command, args []string) {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			rune := parseRune(args[0])
 			addr := parseBtcAddress(args[1], config)
 			privKey := parsePrivateKey(args[2])
@@ -46,8 +50,7 @@ func mintRunesCmd(config config.Config) (cmd *cobra.Command) {
 		},
 	}
 
-	_ = cmd.MarkFlagRequired("fee-rate")
-	_ = cmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = cmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
 	return
 }
 
@@ -56,13 +59,14 @@ func transferRuneCmd(config config.Config) (cmd *cobra.Command) {
 		Use:    "transfer RUNE_ID AMT FROM_ADDR TO_ADDR PRIV_KEY_HEX",
 		PreRun: preRunForceArgs(5),
 		Run: func(cmd *cobra.Command, args []string) {
-			feeRate := forceFeeRateFlag(cmd)
+			feeRate := forceFeeRateFlag(cmd, config)
 			rune := parseRune(args[0])
-			amt := parseBigInt(args[1])
+			divisibility, _ := cmd.Flags().GetUint8("divisibility")
+			amt := parseTokenAmount(args[1], divisibility)
 			addr := parseBtcAddress(args[2], config)
 			toAddr := parseBtcAddress(args[3], config)
 			privKey := parsePrivateKey(args[4])
-			hash, err := runes.TransferRune(rune, amt, addr, toAddr, privKey, uint64(feeRate), config)
+			hash, err := runes.TransferRune(cmd.Context(), rune, amt.Int(), addr, toAddr, privKey, uint64(feeRate), config)
 			if err != nil {
 				fmt.Println("error executing mint")
 				fmt.Println(err.Error())
@@ -73,8 +77,8 @@ func transferRuneCmd(config config.Config) (cmd *cobra.Command) {
 		},
 	}
 
-	_ = cmd.MarkFlagRequired("fee-rate")
-	_ = cmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = cmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
+	_ = cmd.Flags().Uint8("divisibility", 0, "Divisibility of the rune, AMT is in base units by default")
 	return
 }
 
@@ -84,8 +88,12 @@ func runesBalanceCmd(c config.Config) (cmd *cobra.Command) {
 		PreRun: preRunForceArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			addr := parseBtcAddress(args[0], c)
-			opiClient := client.NewOpiClient(c.OpiConfig)
-			bal, err := opiClient.GetRunesBalance(addr.EncodeAddress())
+			opiClient, err := client.NewOpiClient(c.OpiConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			bal, err := opiClient.GetRunesBalance(cmd.Context(), addr.EncodeAddress())
 			if err != nil {
 				fmt.Println("error connecting to OPI Runes")
 				fmt.Println(err)
@@ -99,6 +107,155 @@ func runesBalanceCmd(c config.Config) (cmd *cobra.Command) {
 	return
 }
 
+func runesTickerCmd(c config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:    "ticker RUNE",
+		Short:  "show the etching terms & supply of a rune, by id or name",
+		PreRun: preRunForceArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opiClient, err := client.NewOpiClient(c.OpiConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			info, err := opiClient.GetRunesTickerInfo(cmd.Context(), args[0])
+			if err != nil {
+				fmt.Println("error connecting to OPI Runes")
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Println("Rune:", info.SpacedRuneName, info.Symbol)
+			fmt.Println("Id:", info.RuneID)
+			fmt.Println("Number:", info.RuneNumber)
+			fmt.Println("Divisibility:", info.Divisibility)
+			fmt.Println("Premine:", info.Premine)
+			fmt.Println("Amount Per Mint:", info.TermsAmount)
+			fmt.Println("Mints:", info.MintCount, "of", info.TermsCap)
+			fmt.Println("Supply:", info.Supply())
+			fmt.Println("Burned:", info.Burned)
+			fmt.Println("Etched At:", info.GenesisHeight)
+		},
+	}
+	return
+}
+
+func runesHoldersCmd(c config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:    "holders RUNE",
+		Short:  "list the wallets holding a rune, by id or name",
+		PreRun: preRunForceArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opiClient, err := client.NewOpiClient(c.OpiConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			holders, err := opiClient.GetRunesHolders(cmd.Context(), args[0])
+			if err != nil {
+				fmt.Println("error connecting to OPI Runes")
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			page := client.Paginate(holders, pageFlags(cmd))
+			tab := tabulate.New(tabulate.ASCII)
+			_ = tabulate.Reflect(tab, 0, nil, page.Items)
+			tab.Print(os.Stdout)
+			printPage(page)
+		},
+	}
+	addPageFlags(cmd)
+	return
+}
+
+func runesUtxosCmd(c config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:    "utxos ADDRESS",
+		Short:  "list the outpoints holding runes, from the configured runes utxo source",
+		PreRun: preRunForceArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			addr := parseBtcAddress(args[0], c)
+			source, err := runes.NewRunesUtxoSource(c)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			utxos, err := source.GetRunesUtxos(cmd.Context(), addr.EncodeAddress())
+			if err != nil {
+				fmt.Println("error listing the runes utxos")
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			tab := tabulate.New(tabulate.ASCII)
+			tab.Header("Outpoint")
+			tab.Header("Rune")
+			tab.Header("Name")
+			tab.Header("Amount")
+			for _, utxo := range utxos {
+				for _, r := range utxo.Runes {
+					row := tab.Row()
+					row.Column(utxo.Outpoint)
+					row.Column(r.RuneId)
+					row.Column(r.RuneName)
+					row.Column(r.Amount.String())
+				}
+			}
+			tab.Print(os.Stdout)
+		},
+	}
+	return
+}
+
+func verifyRunesDepositCmd(c config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:    "verify-deposit TX_ID RUNE_ID AMT TO_ADDR",
+		Short:  "check the runes an address received in a transaction",
+		PreRun: preRunForceArgs(4),
+		Run: func(cmd *cobra.Command, args []string) {
+			divisibility, _ := cmd.Flags().GetUint8("divisibility")
+			from, _ := cmd.Flags().GetString("from")
+			deposit := runes.RunesDeposit{
+				TxId:   args[0],
+				RuneId: parseRune(args[1]).String(),
+				Amount: parseTokenAmount(args[2], divisibility),
+				ToAddr: parseBtcAddress(args[3], c).EncodeAddress(),
+			}
+			if from != "" {
+				deposit.FromAddr = parseBtcAddress(from, c).EncodeAddress()
+			}
+			verifier, err := runes.NewRunesDepositVerifier(c)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if cmd.Flags().Changed("min-confirmations") {
+				verifier.MinConfirmations, _ = cmd.Flags().GetInt64("min-confirmations")
+			}
+			if cmd.Flags().Changed("allow-other-senders") {
+				verifier.AllowOtherSenders, _ = cmd.Flags().GetBool("allow-other-senders")
+			}
+			receipt, err := verifier.Verify(cmd.Context(), deposit)
+			if receipt != nil {
+				fmt.Println("Rune:", receipt.RuneId, receipt.RuneName)
+				fmt.Println("Amount:", receipt.Amount)
+				fmt.Println("Outputs:", receipt.Outputs)
+				fmt.Println("Senders:", strings.Join(receipt.Senders, ", "))
+				fmt.Println("Confirmations:", receipt.Confirmations)
+			}
+			if err != nil {
+				fmt.Println("deposit not verified")
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Println("deposit verified")
+		},
+	}
+	_ = cmd.Flags().Uint8("divisibility", 0, "Divisibility of the rune, AMT is in base units by default")
+	_ = cmd.Flags().String("from", "", "Sender of the runes, any sender is accepted by default")
+	_ = cmd.Flags().Int64("min-confirmations", 1, "Confirmations the deposit needs, runes.min_confirmations by default")
+	_ = cmd.Flags().Bool("allow-other-senders", false, "Accept inputs of other addresses spending the rune, runes.allow_other_senders by default")
+	return
+}
+
 func splitUtxoCmd(c config.Config) (cmd *cobra.Command) {
 	cmd = &cobra.Command{
 		Use:    "split ADDRESS PRIVATE_KEY OUT_COUNT OUT_VALUE",
@@ -108,16 +265,9 @@ func splitUtxoCmd(c config.Config) (cmd *cobra.Command) {
 			privateKey := parsePrivateKey(args[1])
 			outCount := parseUint64(args[2])
 			outValue := parseUint64(args[3])
-			feeRate := forceFeeRateFlag(cmd)
-
-			utxo, err := common.SelectOneUtxo(addr.EncodeAddress(), outCount*outValue, c.BtcConfig)
-			if err != nil {
-				fmt.Println("error getting the requied UTXO")
-				fmt.Println(err)
-				os.Exit(1)
-			}
+			feeRate := forceFeeRateFlag(cmd, c)
 
-			h, err := runes.Split(addr, privateKey, common.BtcUnspent{*utxo}, outCount, outValue, uint64(feeRate), c)
+			h, err := runes.Split(cmd.Context(), addr, privateKey, outCount, outValue, uint64(feeRate), c)
 			if err != nil {
 				fmt.Println("error submitting txn")
 				fmt.Println(err)
@@ -126,7 +276,6 @@ func splitUtxoCmd(c config.Config) (cmd *cobra.Command) {
 			fmt.Println((*h).String())
 		},
 	}
-	_ = cmd.MarkFlagRequired("fee-rate")
-	_ = cmd.Flags().StringP("fee-rate", "f", "", "Fee rate for submitting transactions")
+	_ = cmd.Flags().StringP("fee-rate", "f", "auto", "Fee rate for submitting transactions: auto|fast|slow|fastest|halfhour|hour|economy or sats/vB")
 	return
 }
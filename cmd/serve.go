@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ordinox/btc-service/api"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/grpcapi"
+	"github.com/ordinox/btc-service/watch"
+	"github.com/spf13/cobra"
+)
+
+func serveCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "serve",
+		Short: "serve the http api: balances, utxos, fees, unsigned txs as psbts and deposit verification",
+		Long: `Serves the api described by /openapi.yaml on api.listen. Requests need one of api.api_keys,
+in the X-API-Key header or as a bearer token. Transactions are built unsigned, for the wallet of the
+sender to sign, and broadcast once submitted back signed.
+
+The same operations are served over gRPC on api.grpc_listen when set, see
+grpcapi/btcservicepb/btcservice.proto. With --watch the deposit watcher runs in the server too, its
+events being streamed by WatchDeposits on top of the watch.sinks.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := api.New(config)
+			if err != nil {
+				return err
+			}
+			listen, _ := cmd.Flags().GetString("listen")
+			if listen == "" {
+				listen = config.ApiConfig.GetListen()
+			}
+			grpcListen, _ := cmd.Flags().GetString("grpc")
+			if grpcListen == "" {
+				grpcListen = config.ApiConfig.GrpcListen
+			}
+			runWatcher, _ := cmd.Flags().GetBool("watch")
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			var serving []func() error
+			serving = append(serving, func() error { return server.ListenAndServe(ctx, listen) })
+			var feed *watch.Feed
+			if runWatcher {
+				w, err := watch.New(config)
+				if err != nil {
+					return err
+				}
+				feed = watch.NewFeed()
+				w.Sinks = append(w.Sinks, feed)
+				serving = append(serving, func() error { return w.Run(ctx) })
+			}
+			if grpcListen != "" {
+				grpcServer := grpcapi.New(server, feed)
+				serving = append(serving, func() error { return grpcServer.ListenAndServe(ctx, grpcListen) })
+			}
+
+			// Everything stops with the first one to stop
+			done := make(chan error, len(serving))
+			for _, serve := range serving {
+				go func(serve func() error) {
+					err := serve()
+					cancel()
+					done <- err
+				}(serve)
+			}
+			var errs []error
+			for range serving {
+				if err := <-done; err != nil && !errors.Is(err, context.Canceled) {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+	_ = cmd.Flags().String("listen", "", "Address to listen on, api.listen by default")
+	_ = cmd.Flags().String("grpc", "", "Address of the gRPC api, api.grpc_listen by default")
+	_ = cmd.Flags().Bool("watch", false, "Run the deposit watcher too, streaming its events over gRPC")
+	return
+}
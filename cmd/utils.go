@@ -3,13 +3,15 @@ package cmd
 import (
 	"encoding/hex"
 	"fmt"
-	"math/big"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/runes"
 	"github.com/spf13/cobra"
@@ -17,7 +19,6 @@ import (
 
 func preRunForceArgs(argLength int) func(*cobra.Command, []string) {
 	return func(cmd *cobra.Command, args []string) {
-		_ = cmd.MarkFlagRequired("fee-rate")
 		if len(args) < argLength {
 			_ = cmd.Help()
 			os.Exit(1)
@@ -25,26 +26,60 @@ func preRunForceArgs(argLength int) func(*cobra.Command, []string) {
 	}
 }
 
-func forceFeeRateFlag(cmd *cobra.Command) int {
+// Resolve --fee-rate, exiting when it can't be
+func forceFeeRateFlag(cmd *cobra.Command, config config.Config) int {
 	val, err := cmd.Flags().GetString("fee-rate")
 	if err != nil {
 		fmt.Println("Error: Fee Rate not set. Use --fee-rate")
 		_ = cmd.Help()
 		os.Exit(1)
 	}
-	if val == "" {
-		fmt.Println("Error: Fee Rate not set. Use --fee-rate")
-		_ = cmd.Help()
-		os.Exit(1)
-	}
-	fee, err := strconv.Atoi(val)
+	fee, err := resolveFeeRate(val, config)
 	if err != nil {
-		fmt.Println("Error: Fee Rate should be an integer")
+		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
 	return fee
 }
 
+// Fee rate in sats/vB, given as is or as a priority estimated with the configured fee sources
+func resolveFeeRate(val string, config config.Config) (int, error) {
+	if fee, err := strconv.Atoi(val); err == nil {
+		if fee <= 0 {
+			return 0, fmt.Errorf("fee rate should be positive")
+		}
+		return fee, nil
+	}
+	if val == "" || val == "auto" {
+		val = config.FeeConfig.GetDefaultPriority()
+	}
+	priority, err := client.ParseFeePriority(val)
+	if err != nil {
+		return 0, fmt.Errorf("%w, the fee rate should be an integer or one of auto|fast|slow|fastest|halfhour|hour|economy", err)
+	}
+	estimator, err := client.NewFeeEstimator(config)
+	if err != nil {
+		return 0, err
+	}
+	fee, err := estimator.EstimateFeeRate(priority)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("Using a %s fee rate of %d sats/vB\n", priority, fee)
+	return int(fee), nil
+}
+
+// Override the inscription config with the --postage & --reveal-fee-rate flags, when set
+func inscriptionFlags(cmd *cobra.Command, config config.Config) config.Config {
+	if postage, err := cmd.Flags().GetInt64("postage"); err == nil && postage > 0 {
+		config.InscriptionConfig.Postage = postage
+	}
+	if revealFeeRate, err := cmd.Flags().GetUint64("reveal-fee-rate"); err == nil && revealFeeRate > 0 {
+		config.InscriptionConfig.RevealFeeRate = revealFeeRate
+	}
+	return config
+}
+
 func parseBtcAddress(addrStr string, config config.Config) btcutil.Address {
 	addr, err := btcutil.DecodeAddress(addrStr, config.BtcConfig.GetChainConfigParams())
 	if err != nil {
@@ -98,7 +133,7 @@ func parseUint64(str string) uint64 {
 }
 
 func parseTicker(str string) string {
-	if len(str) != 4 {
+	if err := brc20.ValidateTicker(str); err != nil {
 		fmt.Printf("Error: Invalid Ticker %s\n", str)
 		os.Exit(1)
 	}
@@ -113,20 +148,38 @@ func parseString(str string) string {
 	return str
 }
 
-func parseBigFloat(str string) *big.Float {
-	val, ok := big.NewFloat(0).SetString(str)
-	if !ok {
-		fmt.Printf("Error: Invalid bigFloat: %s\n", str)
+func parseBrc20Amount(str string, decimals uint8) brc20.Amount {
+	val, err := brc20.ParseAmount(str, decimals)
+	if err != nil {
+		fmt.Printf("Error: Invalid amount: %s\n", err.Error())
 		os.Exit(1)
 	}
 	return val
 }
 
-func parseBigInt(str string) *big.Int {
-	val, ok := big.NewInt(0).SetString(str, 10)
-	if !ok {
-		fmt.Printf("Error: Invalid bigInt: %s\n", str)
+// Parse a decimal amount into base units of a token with the given decimals
+func parseTokenAmount(str string, decimals uint8) common.TokenAmount {
+	val, err := common.ParseTokenAmount(str, decimals)
+	if err != nil {
+		fmt.Printf("Error: Invalid amount: %s\n", err.Error())
 		os.Exit(1)
 	}
 	return val
 }
+
+func addPageFlags(cmd *cobra.Command) {
+	_ = cmd.Flags().Int("offset", 0, "Skip this many entries")
+	_ = cmd.Flags().Int("limit", 50, "Show at most this many entries, 0 for all")
+}
+
+func pageFlags(cmd *cobra.Command) client.ListOptions {
+	offset, _ := cmd.Flags().GetInt("offset")
+	limit, _ := cmd.Flags().GetInt("limit")
+	return client.ListOptions{Offset: offset, Limit: limit}
+}
+
+func printPage[T any](page client.Page[T]) {
+	if page.More() {
+		fmt.Printf("%d-%d of %d, see --offset & --limit\n", page.Offset+1, page.Offset+len(page.Items), page.Total)
+	}
+}
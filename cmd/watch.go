@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/watch"
+	"github.com/spf13/cobra"
+)
+
+func watchCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "watch [ADDR...]",
+		Short: "watch deposit addresses and send an event for every btc, brc20 or runes deposit",
+		Long: `Scans the blocks for deposits to watch.addresses and the addresses given, sending an event to the
+watch.sinks when a deposit is detected, once it has watch.confirmations and when a reorg drops it.
+Txs added with "watch track" are followed until confirmed or replaced. Progress is saved in
+watch.state_file, events are delivered at least once.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, err := watch.New(config)
+			if err != nil {
+				return err
+			}
+			if err := w.Watch(args...); err != nil {
+				return err
+			}
+			if once, _ := cmd.Flags().GetBool("once"); once {
+				if err := w.Poll(cmd.Context()); err != nil || w.Webhooks == nil {
+					return err
+				}
+				_, err := w.Webhooks.Deliver(cmd.Context())
+				return err
+			}
+			if err := w.Run(cmd.Context()); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+	_ = cmd.Flags().Bool("once", false, "Scan up to the tip once and exit")
+	cmd.AddCommand(watchTrackCmd(config))
+	return
+}
+
+func watchTrackCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "track TX_ID",
+		Short: "follow a broadcast tx, sending tx.broadcast then tx.confirmed or tx.replaced events",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			replaces, _ := cmd.Flags().GetString("replaces")
+			return watch.TrackTx(config.WatchConfig.GetTrackDir(), args[0], replaces)
+		},
+	}
+	_ = cmd.Flags().String("replaces", "", "Tracked tx replaced by this one, e.g. by a fee bump")
+	return
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/markkurossi/tabulate"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/webhook"
+	"github.com/spf13/cobra"
+)
+
+func webhookCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "webhook",
+		Short: "deliver the events queued in the webhook outbox",
+	}
+	cmd.AddCommand(
+		webhookDeliverCmd(config),
+		webhookListCmd(config),
+		webhookReplayCmd(config),
+	)
+	return
+}
+
+func webhookDeliverCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "deliver",
+		Short: "post the queued events to webhook.urls, retrying the failed ones until webhook.max_attempts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := webhook.NewDispatcher(config.WebhookConfig)
+			if err != nil {
+				return err
+			}
+			if once, _ := cmd.Flags().GetBool("once"); once {
+				delivered, err := d.Deliver(cmd.Context())
+				fmt.Println("delivered", delivered)
+				return err
+			}
+			interval, _ := cmd.Flags().GetDuration("interval")
+			if err := d.Run(cmd.Context(), interval); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+	_ = cmd.Flags().Bool("once", false, "Attempt the due deliveries once and exit")
+	_ = cmd.Flags().Duration("interval", time.Second, "Delay between two checks of the outbox")
+	return
+}
+
+func webhookListCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "list",
+		Short: "list the deliveries of the outbox",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outbox, err := webhook.NewOutbox(config.WebhookConfig.GetOutboxDir())
+			if err != nil {
+				return err
+			}
+			status, _ := cmd.Flags().GetString("status")
+			deliveries, err := outbox.List(webhook.Status(status))
+			if err != nil {
+				return err
+			}
+			tab := tabulate.New(tabulate.ASCII)
+			tab.Header("Delivery")
+			tab.Header("Event")
+			tab.Header("Url")
+			tab.Header("Attempts")
+			tab.Header("Next Attempt")
+			tab.Header("Last Error")
+			for _, d := range deliveries {
+				row := tab.Row()
+				row.Column(d.Id)
+				row.Column(d.EventId)
+				row.Column(d.Url)
+				row.Column(fmt.Sprint(d.Attempts))
+				row.Column(d.NextAttempt.Format(time.RFC3339))
+				row.Column(d.LastError)
+			}
+			tab.Print(os.Stdout)
+			return nil
+		},
+	}
+	_ = cmd.Flags().String("status", string(webhook.StatusPending), "pending, delivered or failed")
+	return
+}
+
+func webhookReplayCmd(config config.Config) (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "replay [DELIVERY_OR_EVENT_ID...]",
+		Short: "queue delivered or failed events again, by delivery or event id",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := webhook.NewDispatcher(config.WebhookConfig)
+			if err != nil {
+				return err
+			}
+			var ids []string
+			if failed, _ := cmd.Flags().GetBool("failed"); failed {
+				deliveries, err := d.Outbox.List(webhook.StatusFailed)
+				if err != nil {
+					return err
+				}
+				for _, delivery := range deliveries {
+					ids = append(ids, delivery.Id)
+				}
+			}
+			for _, id := range args {
+				deliveries, err := d.Outbox.Lookup(id)
+				if err != nil {
+					return err
+				}
+				for _, delivery := range deliveries {
+					ids = append(ids, delivery.Id)
+				}
+			}
+			if len(ids) == 0 {
+				return fmt.Errorf("nothing to replay, give delivery or event ids or --failed")
+			}
+			replayed, err := d.Replay(ids...)
+			for _, delivery := range replayed {
+				fmt.Println("replaying", delivery.EventId, "to", delivery.Url)
+			}
+			return err
+		},
+	}
+	_ = cmd.Flags().Bool("failed", false, "Replay every delivery out of attempts")
+	return
+}
@@ -0,0 +1,175 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidTokenAmount = errors.New("invalid token amount")
+	ErrTooManyDecimals    = errors.New("amount has more decimals than allowed")
+	ErrNegativeAmount     = errors.New("amount can't be negative")
+)
+
+// Exact token amount, kept as an integer of base units along with the decimals of the token.
+// JSON carries the base units, the way the indexers return them, while String() gives the decimal form
+type TokenAmount struct {
+	value    *big.Int
+	decimals uint8
+}
+
+// Amount from base units, e.g. NewTokenAmount(150, 2) is 1.5
+func NewTokenAmount(value *big.Int, decimals uint8) TokenAmount {
+	if value == nil {
+		return TokenAmount{decimals: decimals}
+	}
+	return TokenAmount{new(big.Int).Set(value), decimals}
+}
+
+func NewTokenAmountFromInt64(value int64, decimals uint8) TokenAmount {
+	return TokenAmount{big.NewInt(value), decimals}
+}
+
+// Parse a non negative decimal string. Only digits and at most one dot are allowed,
+// the dot can't be the first or last character and the fraction can't be longer than decimals
+func ParseTokenAmount(s string, decimals uint8) (TokenAmount, error) {
+	if len(s) == 0 || s[0] == '.' || s[len(s)-1] == '.' {
+		return TokenAmount{}, fmt.Errorf("%w: %q", ErrInvalidTokenAmount, s)
+	}
+	whole, fraction, hasDot := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasDot && !isDigits(fraction)) {
+		return TokenAmount{}, fmt.Errorf("%w: %q", ErrInvalidTokenAmount, s)
+	}
+	if len(fraction) > int(decimals) {
+		return TokenAmount{}, fmt.Errorf("%w: %q has more than %d decimals", ErrTooManyDecimals, s, decimals)
+	}
+	val, _ := new(big.Int).SetString(whole+fraction+strings.Repeat("0", int(decimals)-len(fraction)), 10)
+	return TokenAmount{val, decimals}, nil
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// The amount in base units
+func (a TokenAmount) Int() *big.Int {
+	if a.value == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.value)
+}
+
+func (a TokenAmount) Decimals() uint8 {
+	return a.decimals
+}
+
+func (a TokenAmount) Sign() int {
+	return a.Int().Sign()
+}
+
+func (a TokenAmount) IsZero() bool {
+	return a.Sign() == 0
+}
+
+// Same base units with different decimals, used when the decimals come separately from the value
+func (a TokenAmount) WithDecimals(decimals uint8) TokenAmount {
+	return TokenAmount{a.Int(), decimals}
+}
+
+// Same value expressed with different decimals. Fails if it can't be done without losing precision
+func (a TokenAmount) Rescale(decimals uint8) (TokenAmount, error) {
+	if decimals >= a.decimals {
+		return TokenAmount{new(big.Int).Mul(a.Int(), pow10(decimals-a.decimals)), decimals}, nil
+	}
+	q, r := new(big.Int).QuoRem(a.Int(), pow10(a.decimals-decimals), new(big.Int))
+	if r.Sign() != 0 {
+		return TokenAmount{}, fmt.Errorf("%w: %s has more than %d decimals", ErrTooManyDecimals, a.String(), decimals)
+	}
+	return TokenAmount{q, decimals}, nil
+}
+
+// Check if the amount can be expressed with the given number of decimals
+func (a TokenAmount) FitsDecimals(decimals uint8) bool {
+	_, err := a.Rescale(decimals)
+	return err == nil
+}
+
+// Both amounts with the decimals of the more precise one
+func align(a, b TokenAmount) (*big.Int, *big.Int, uint8) {
+	decimals := a.decimals
+	if b.decimals > decimals {
+		decimals = b.decimals
+	}
+	x, _ := a.Rescale(decimals)
+	y, _ := b.Rescale(decimals)
+	return x.value, y.value, decimals
+}
+
+// Compare the values, regardless of the decimals they are expressed with
+func (a TokenAmount) Cmp(b TokenAmount) int {
+	x, y, _ := align(a, b)
+	return x.Cmp(y)
+}
+
+func (a TokenAmount) Add(b TokenAmount) TokenAmount {
+	x, y, decimals := align(a, b)
+	return TokenAmount{x.Add(x, y), decimals}
+}
+
+// Subtract b, failing if the result would be negative
+func (a TokenAmount) Sub(b TokenAmount) (TokenAmount, error) {
+	x, y, decimals := align(a, b)
+	if x.Cmp(y) < 0 {
+		return TokenAmount{}, fmt.Errorf("%w: %s - %s", ErrNegativeAmount, a.String(), b.String())
+	}
+	return TokenAmount{x.Sub(x, y), decimals}, nil
+}
+
+// Shortest decimal representation
+func (a TokenAmount) String() string {
+	if a.decimals == 0 {
+		return a.Int().String()
+	}
+	whole, fraction := new(big.Int).QuoRem(a.Int(), pow10(a.decimals), new(big.Int))
+	if fraction.Sign() == 0 {
+		return whole.String()
+	}
+	fractionStr := fmt.Sprintf("%0*s", int(a.decimals), fraction.String())
+	return whole.String() + "." + strings.TrimRight(fractionStr, "0")
+}
+
+// Decimal form, used when printing tables
+func (a TokenAmount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Base units as a json string, strings are used since the values don't fit in a float64
+func (a TokenAmount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.Int().String() + `"`), nil
+}
+
+// Accepts base units either as a json string or a number. The decimals are kept as they are
+func (a *TokenAmount) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	val, ok := new(big.Int).SetString(string(data), 10)
+	if !ok || val.Sign() < 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidTokenAmount, string(data))
+	}
+	a.value = val
+	return nil
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenAmount(t *testing.T) {
+	amt, err := ParseTokenAmount("0.00001", 8)
+	require.NoError(t, err)
+	require.Equal(t, "1000", amt.Int().String())
+	require.Equal(t, "0.00001", amt.String())
+
+	// Values beyond float64 precision stay exact
+	big1, err := ParseTokenAmount("100000000000000000000.000000000000000001", 18)
+	require.NoError(t, err)
+	require.Equal(t, "100000000000000000000.000000000000000001", big1.String())
+
+	_, err = ParseTokenAmount("1.001", 2)
+	require.ErrorIs(t, err, ErrTooManyDecimals)
+	for _, in := range []string{"", ".5", "5.", "-1", "1e20", "1,5"} {
+		_, err = ParseTokenAmount(in, 8)
+		require.ErrorIs(t, err, ErrInvalidTokenAmount, in)
+	}
+
+	// Comparison & arithmetic work across decimals
+	a := NewTokenAmountFromInt64(150, 2)
+	b, _ := ParseTokenAmount("1.5", 18)
+	require.Equal(t, 0, a.Cmp(b))
+	require.Equal(t, "3", a.Add(b).String())
+	require.Equal(t, uint8(18), a.Add(b).Decimals())
+	diff, err := a.Sub(NewTokenAmountFromInt64(1, 0))
+	require.NoError(t, err)
+	require.Equal(t, "0.5", diff.String())
+	_, err = NewTokenAmountFromInt64(1, 0).Sub(a)
+	require.ErrorIs(t, err, ErrNegativeAmount)
+
+	_, err = b.Rescale(0)
+	require.ErrorIs(t, err, ErrTooManyDecimals)
+	rescaled, err := b.Rescale(1)
+	require.NoError(t, err)
+	require.Equal(t, "15", rescaled.Int().String())
+}
+
+func TestTokenAmountJSON(t *testing.T) {
+	var res struct {
+		Amounts []TokenAmount `json:"amounts"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amounts":["340282366920938463463374607431768211455",21000000]}`), &res))
+	require.Len(t, res.Amounts, 2)
+	max, _ := new(big.Int).SetString("340282366920938463463374607431768211455", 10)
+	require.Equal(t, 0, res.Amounts[0].Int().Cmp(max))
+	require.Equal(t, "0.21", res.Amounts[1].WithDecimals(8).String())
+
+	bz, err := json.Marshal(res)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amounts":["340282366920938463463374607431768211455","21000000"]}`, string(bz))
+
+	require.Error(t, json.Unmarshal([]byte(`["-1"]`), &res.Amounts))
+	require.Error(t, json.Unmarshal([]byte(`[1.5]`), &res.Amounts))
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Bitcoin core's default -limitancestorcount, a tx and its unconfirmed ancestors can't go beyond it
+const MaxMempoolAncestors = 25
+
+var ErrTooManyAncestors = errors.New("tx would exceed the mempool ancestor limit")
+
+// Unconfirmed depth of a utxo, only known for TxOutUtxo. Anything else is treated as confirmed
+func UnconfirmedDepth(u Utxo) int {
+	if t, ok := u.(TxOutUtxo); ok {
+		return t.Depth
+	}
+	return 0
+}
+
+// Depth of a tx spending the given utxos, failing when relay policy would reject it.
+// Ancestors shared by several parents are counted for each of them, so this errs on the safe side
+func SpendDepth(parents ...Utxo) (int, error) {
+	depth := 1
+	for _, p := range parents {
+		depth += UnconfirmedDepth(p)
+	}
+	if depth > MaxMempoolAncestors {
+		return depth, fmt.Errorf("%w: %d of %d", ErrTooManyAncestors, depth, MaxMempoolAncestors)
+	}
+	return depth, nil
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpendDepth(t *testing.T) {
+	depth, err := SpendDepth()
+	require.NoError(t, err)
+	require.Equal(t, 1, depth)
+
+	// Utxos from a utxo api are treated as confirmed
+	depth, err = SpendDepth(WebUtxo{TxHash: "a"}, TxOutUtxo{TxHash: "b", Depth: 3}, TxOutUtxo{TxHash: "c", Depth: 2})
+	require.NoError(t, err)
+	require.Equal(t, 6, depth)
+
+	depth, err = SpendDepth(TxOutUtxo{Depth: MaxMempoolAncestors - 1})
+	require.NoError(t, err)
+	require.Equal(t, MaxMempoolAncestors, depth)
+
+	_, err = SpendDepth(TxOutUtxo{Depth: MaxMempoolAncestors})
+	require.ErrorIs(t, err, ErrTooManyAncestors)
+}
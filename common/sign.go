@@ -0,0 +1,121 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Sequence of every input built by this service, it signals replaceability (BIP125) so that fees can be bumped
+const RbfSequenceNum = wire.MaxTxInSequenceNum - 10
+
+var ErrUnsignableInput = errors.New("input can't be signed with the key")
+
+// Input signalling replaceability
+func NewRbfTxIn(prevOut *wire.OutPoint, signatureScript []byte, witness [][]byte) *wire.TxIn {
+	in := wire.NewTxIn(prevOut, signatureScript, witness)
+	in.Sequence = RbfSequenceNum
+	return in
+}
+
+// Output scripts a private key can spend on its own
+type KeyScripts struct {
+	P2PKHCompressed   []byte
+	P2PKHUncompressed []byte
+	P2TR              []byte // Key path, with the BIP86 tweak
+}
+
+func NewKeyScripts(privKey *btcec.PrivateKey) KeyScripts {
+	pubKey := privKey.PubKey()
+	p2pkh := func(pubKeyData []byte) []byte {
+		script, _ := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_DUP).
+			AddOp(txscript.OP_HASH160).
+			AddData(btcutil.Hash160(pubKeyData)).
+			AddOp(txscript.OP_EQUALVERIFY).
+			AddOp(txscript.OP_CHECKSIG).
+			Script()
+		return script
+	}
+	p2tr, _ := txscript.PayToTaprootScript(txscript.ComputeTaprootKeyNoScript(pubKey))
+	return KeyScripts{
+		P2PKHCompressed:   p2pkh(pubKey.SerializeCompressed()),
+		P2PKHUncompressed: p2pkh(pubKey.SerializeUncompressed()),
+		P2TR:              p2tr,
+	}
+}
+
+// Check if the key can spend the script on its own
+func (k KeyScripts) Owns(pkScript []byte) bool {
+	return bytes.Equal(pkScript, k.P2PKHCompressed) || bytes.Equal(pkScript, k.P2PKHUncompressed) || bytes.Equal(pkScript, k.P2TR)
+}
+
+// Sign every input of the tx with the key, prevOuts being the outputs spent by the inputs in order.
+// P2PKH inputs are signed with SigHashAll, P2TR key path ones with SigHashDefault.
+// Other P2TR inputs are taken as script path spends whose witness already holds the signature placeholder,
+// the script and the control block, like inscription reveals
+func SignInputs(tx *wire.MsgTx, prevOuts []*wire.TxOut, privKey *btcec.PrivateKey) error {
+	if len(prevOuts) != len(tx.TxIn) {
+		return fmt.Errorf("%w: %d prevouts for %d inputs", ErrUnsignableInput, len(prevOuts), len(tx.TxIn))
+	}
+	scripts := NewKeyScripts(privKey)
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range tx.TxIn {
+		fetcher.AddPrevOut(in.PreviousOutPoint, prevOuts[i])
+	}
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+
+	for i, prevOut := range prevOuts {
+		var err error
+		switch {
+		case bytes.Equal(prevOut.PkScript, scripts.P2PKHCompressed):
+			err = signP2PKH(tx, i, prevOut.PkScript, privKey, privKey.PubKey().SerializeCompressed())
+		case bytes.Equal(prevOut.PkScript, scripts.P2PKHUncompressed):
+			err = signP2PKH(tx, i, prevOut.PkScript, privKey, privKey.PubKey().SerializeUncompressed())
+		case bytes.Equal(prevOut.PkScript, scripts.P2TR):
+			var witness wire.TxWitness
+			witness, err = txscript.TaprootWitnessSignature(tx, sigHashes, i, prevOut.Value, prevOut.PkScript, txscript.SigHashDefault, privKey)
+			tx.TxIn[i].Witness = witness
+		case txscript.IsPayToTaproot(prevOut.PkScript) && len(tx.TxIn[i].Witness) == 3:
+			err = signTapscript(tx, i, sigHashes, fetcher, privKey)
+		default:
+			err = fmt.Errorf("%w: input %d spends %x", ErrUnsignableInput, i, prevOut.PkScript)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func signP2PKH(tx *wire.MsgTx, idx int, pkScript []byte, privKey *btcec.PrivateKey, pubKeyData []byte) error {
+	sigHash, err := txscript.CalcSignatureHash(pkScript, txscript.SigHashAll, tx, idx)
+	if err != nil {
+		return err
+	}
+	signature := append(ecdsa.Sign(privKey, sigHash).Serialize(), byte(txscript.SigHashAll))
+	tx.TxIn[idx].SignatureScript, err = txscript.NewScriptBuilder().AddData(signature).AddData(pubKeyData).Script()
+	return err
+}
+
+// Replace the signature of a [signature, script, control block] witness
+func signTapscript(tx *wire.MsgTx, idx int, sigHashes *txscript.TxSigHashes, fetcher txscript.PrevOutputFetcher, privKey *btcec.PrivateKey) error {
+	witness := tx.TxIn[idx].Witness
+	sigHash, err := txscript.CalcTapscriptSignaturehash(sigHashes, txscript.SigHashDefault, tx, idx, fetcher, txscript.NewBaseTapLeaf(witness[1]))
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.Sign(privKey, sigHash)
+	if err != nil {
+		return err
+	}
+	tx.TxIn[idx].Witness = wire.TxWitness{sig.Serialize(), witness[1], witness[2]}
+	return nil
+}
@@ -75,3 +75,24 @@ func (w BtcUnspents) ToUtxo() []Utxo {
 func NewBtcUnspent(b btcjson.ListUnspentResult) BtcUnspent {
 	return BtcUnspent{b}
 }
+
+// Output of a tx this service built, spendable right away without waiting for a utxo api to list it
+type TxOutUtxo struct {
+	TxHash   string
+	Vout     uint32
+	Value    uint64
+	PkScript []byte
+	Depth    int // Unconfirmed txs up to and including the one creating the output, 0 once confirmed
+}
+
+func (t TxOutUtxo) GetTxID() string {
+	return t.TxHash
+}
+
+func (t TxOutUtxo) GetVout() uint32 {
+	return t.Vout
+}
+
+func (t TxOutUtxo) GetValueInSats() uint64 {
+	return t.Value
+}
@@ -38,6 +38,11 @@ func GetUtxos(address string, config config.BtcConfig) (*WebUtxoResponse, error)
 
 // Given an address and a min value, find an eligible UTXO for the address
 func SelectOneUtxo(addr string, minValue uint64, config config.BtcConfig) (*btcjson.ListUnspentResult, error) {
+	return SelectOneUtxoExcept(addr, minValue, nil, config)
+}
+
+// Same as SelectOneUtxo, skipping the excluded txid:vout outpoints, e.g. the ones holding runes
+func SelectOneUtxoExcept(addr string, minValue uint64, excluded map[string]bool, config config.BtcConfig) (*btcjson.ListUnspentResult, error) {
 	utxos, err := GetUtxos(addr, config)
 	if err != nil {
 		return nil, err
@@ -48,7 +53,7 @@ func SelectOneUtxo(addr string, minValue uint64, config config.BtcConfig) (*btcj
 	}
 
 	for _, utxo := range utxos.Result {
-		if utxo.Value < minValue {
+		if utxo.Value < minValue || excluded[fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Vout)] {
 			continue
 		}
 		return &btcjson.ListUnspentResult{
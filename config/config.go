@@ -2,35 +2,276 @@ package config
 
 import (
 	"fmt"
-	"strings"
+	"time"
+)
+
+const (
+	DefaultPostage int64 = 546
+
+	DefaultRpcTimeout     = 30 * time.Second
+	DefaultRpcMaxAttempts = 3
+
+	DefaultOpiTimeout     = 30 * time.Second
+	DefaultOpiMaxAttempts = 3
+
+	DefaultBisUrl         = "https://api.bestinslot.xyz"
+	DefaultBisTimeout     = 30 * time.Second
+	DefaultBisMaxAttempts = 3
+
+	DefaultFeeSource   = "core"
+	DefaultFeePriority = "halfhour"
+
+	DefaultWatchStateFile    = "watch-state.json"
+	DefaultWatchPollInterval = 30 * time.Second
+	DefaultWatchTrackDir     = "watch-track"
 
-	"github.com/btcsuite/btcd/chaincfg"
+	DefaultWebhookOutboxDir   = "webhook-outbox"
+	DefaultWebhookTimeout     = 10 * time.Second
+	DefaultWebhookMaxAttempts = 10
+	DefaultWebhookMinBackoff  = 5 * time.Second
+	DefaultWebhookMaxBackoff  = time.Hour
+	DefaultWebhookRetention   = 7 * 24 * time.Hour
+
+	DefaultApiListen       = "127.0.0.1:8080"
+	DefaultApiTimeout      = 60 * time.Second
+	DefaultApiMaxBodyBytes = 1 << 20
 )
 
-func GetDefaultConfig() Config {
-	return config
+// Rpc host of the wallet, the node itself when no wallet is set
+func (c BtcConfig) GetRpcHostWithWallet() string {
+	if c.WalletName == "" {
+		return c.GetRpcHost()
+	}
+	return fmt.Sprintf("%s/wallet/%s", c.GetRpcHost(), c.WalletName)
 }
 
-func (c BtcConfig) GetRpcHostWithWallet() string {
-	return fmt.Sprintf("%s/wallet/%s", strings.TrimRight(c.RpcHost, "/"), c.WalletName)
+func (c BtcConfig) GetRpcTimeout() time.Duration {
+	if c.RpcTimeout <= 0 {
+		return DefaultRpcTimeout
+	}
+	return c.RpcTimeout
+}
+
+func (c BtcConfig) GetRpcMaxAttempts() int {
+	if c.RpcMaxAttempts <= 0 {
+		return DefaultRpcMaxAttempts
+	}
+	return c.RpcMaxAttempts
+}
+
+func (c OpiConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultOpiTimeout
+	}
+	return c.Timeout
+}
+
+func (c OpiConfig) GetMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultOpiMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+func (c BISConfig) GetBaseUrl() string {
+	if c.BaseUrl == "" {
+		return DefaultBisUrl
+	}
+	return c.BaseUrl
+}
+
+func (c BISConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultBisTimeout
+	}
+	return c.Timeout
+}
+
+func (c BISConfig) GetMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultBisMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// Indexers checked for deposits
+func (c Config) GetBrc20Sources() []string {
+	if len(c.Brc20Config.Sources) > 0 {
+		return c.Brc20Config.Sources
+	}
+	if c.BtcConfig.GetNetwork() == NetworkMainnet {
+		return []string{"bis"}
+	}
+	return []string{"opi"}
+}
+
+func (c Config) GetBrc20Quorum() int {
+	if c.Brc20Config.Quorum <= 0 {
+		return len(c.GetBrc20Sources())
+	}
+	return c.Brc20Config.Quorum
+}
+
+// Indexer listing the outpoints holding runes
+func (c Config) GetRunesUtxoSource() string {
+	if c.RunesConfig.UtxoSource != "" {
+		return c.RunesConfig.UtxoSource
+	}
+	if c.BtcConfig.GetNetwork() == NetworkMainnet {
+		return "bis"
+	}
+	return "opi"
+}
+
+// Confirmations a runes deposit needs
+func (c RunesConfig) GetMinConfirmations() int64 {
+	if c.MinConfirmations < 1 {
+		return 1
+	}
+	return c.MinConfirmations
+}
+
+// Assets the deposit watcher looks for
+func (c WatchConfig) GetAssets() []string {
+	if len(c.Assets) == 0 {
+		return []string{"btc", "brc20", "runes"}
+	}
+	return c.Assets
+}
+
+func (c WatchConfig) GetConfirmations() int64 {
+	if c.Confirmations < 1 {
+		return 1
+	}
+	return c.Confirmations
+}
+
+func (c WatchConfig) GetStateFile() string {
+	if c.StateFile == "" {
+		return DefaultWatchStateFile
+	}
+	return c.StateFile
+}
+
+func (c WatchConfig) GetPollInterval() time.Duration {
+	if c.PollInterval <= 0 {
+		return DefaultWatchPollInterval
+	}
+	return c.PollInterval
+}
+
+func (c WatchConfig) GetSinks() []string {
+	if len(c.Sinks) == 0 {
+		return []string{"stdout"}
+	}
+	return c.Sinks
+}
+
+func (c WatchConfig) GetTrackDir() string {
+	if c.TrackDir == "" {
+		return DefaultWatchTrackDir
+	}
+	return c.TrackDir
+}
+
+func (c WebhookConfig) GetOutboxDir() string {
+	if c.OutboxDir == "" {
+		return DefaultWebhookOutboxDir
+	}
+	return c.OutboxDir
+}
+
+func (c WebhookConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultWebhookTimeout
+	}
+	return c.Timeout
+}
+
+func (c WebhookConfig) GetMaxAttempts() int {
+	if c.MaxAttempts < 1 {
+		return DefaultWebhookMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+func (c WebhookConfig) GetMinBackoff() time.Duration {
+	if c.MinBackoff <= 0 {
+		return DefaultWebhookMinBackoff
+	}
+	return c.MinBackoff
+}
+
+func (c WebhookConfig) GetMaxBackoff() time.Duration {
+	if c.MaxBackoff <= 0 {
+		return DefaultWebhookMaxBackoff
+	}
+	return c.MaxBackoff
+}
+
+func (c WebhookConfig) GetRetention() time.Duration {
+	if c.Retention <= 0 {
+		return DefaultWebhookRetention
+	}
+	return c.Retention
+}
+
+func (c ApiConfig) GetListen() string {
+	if c.Listen == "" {
+		return DefaultApiListen
+	}
+	return c.Listen
+}
+
+func (c ApiConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultApiTimeout
+	}
+	return c.Timeout
+}
+
+func (c ApiConfig) GetMaxBodyBytes() int64 {
+	if c.MaxBodyBytes <= 0 {
+		return DefaultApiMaxBodyBytes
+	}
+	return c.MaxBodyBytes
+}
+
+// Postage to be used for the inscription output
+func (c InscriptionConfig) GetPostage() int64 {
+	if c.Postage <= 0 {
+		return DefaultPostage
+	}
+	return c.Postage
+}
+
+// Fee rate to be used for the reveal tx, falls back to the commit fee rate
+func (c InscriptionConfig) GetRevealFeeRate(commitFeeRate uint64) uint64 {
+	if c.RevealFeeRate == 0 {
+		return commitFeeRate
+	}
+	return c.RevealFeeRate
+}
+
+// Fee estimation sources, in the order they are tried
+func (c FeeConfig) GetSources() []string {
+	if len(c.Sources) == 0 {
+		return []string{DefaultFeeSource}
+	}
+	return c.Sources
 }
 
-func (c BtcConfig) GetChainConfigParams() *chaincfg.Params {
-	if c.ChainConfig == "mainnet" {
-		return &chaincfg.MainNetParams
-	} else if c.ChainConfig == "testnet" {
-		return &chaincfg.SigNetParams
-	} else {
-		return &chaincfg.RegressionNetParams
+// Priority used when the fee rate is "auto"
+func (c FeeConfig) GetDefaultPriority() string {
+	if c.DefaultPriority == "" {
+		return DefaultFeePriority
 	}
+	return c.DefaultPriority
 }
 
-func (c BtcConfig) GetOrdChainConfigFlag() string {
-	if c.GetChainConfigParams().Name == chaincfg.MainNetParams.Name {
-		return ""
-	} else if c.GetChainConfigParams().Name == chaincfg.SigNetParams.Name {
-		return "-t"
-	} else {
-		return "-r"
+func (c FeeConfig) GetMinFeeRate() uint64 {
+	if c.MinFeeRate == 0 {
+		return 1
 	}
+	return c.MinFeeRate
 }
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Prefix of the env vars overriding settings, e.g. BTC_SERVICE_BTC_RPC_HOST for btc.rpc_host
+	EnvPrefix = "BTC_SERVICE"
+
+	// Section of the config file holding the named profiles
+	profilesKey = "profiles"
+)
+
+var (
+	ErrConfigFile     = errors.New("unable to read the config file")
+	ErrUnknownProfile = errors.New("unknown profile")
+	ErrUnknownSetting = errors.New("unknown setting")
+)
+
+var config Config
+
+// Load the config from the environment alone, for tools & tests without flags
+func Init() {
+	c, err := Load(LoadOptions{})
+	if err != nil {
+		log.Err(err).Msg("unable to load config")
+	}
+	config = c
+}
+
+func GetDefaultConfig() Config {
+	return config
+}
+
+type LoadOptions struct {
+	Path      string         // YAML or TOML file, BTC_SERVICE_CONFIG or the default locations when empty
+	Profile   string         // Profile applied over the file, BTC_SERVICE_PROFILE when empty
+	Overrides map[string]any // Settings taking precedence over everything else, e.g. from flags
+}
+
+// Config file to load, the first existing default location when no path is given
+func (o LoadOptions) ConfigFile() string {
+	if o.Path != "" {
+		return o.Path
+	}
+	if path := os.Getenv(EnvPrefix + "_CONFIG"); path != "" {
+		return path
+	}
+	dirs := []string{"."}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "btc-service"))
+	}
+	for _, dir := range dirs {
+		for _, name := range []string{"btc-service.yaml", "btc-service.yml", "btc-service.toml", "config.yaml", "config.toml"} {
+			path := filepath.Join(dir, name)
+			// config.* is only looked up in the btc-service config dir
+			if dir == "." && strings.HasPrefix(name, "config.") {
+				continue
+			}
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func (o LoadOptions) ProfileName() string {
+	if o.Profile != "" {
+		return o.Profile
+	}
+	return os.Getenv(EnvPrefix + "_PROFILE")
+}
+
+// Load the config. Later sources take precedence: defaults, the config file, the profile, env vars & overrides.
+// The network settings are validated, an unknown network being an error. See Config.Validate for the rest
+func Load(opts LoadOptions) (Config, error) {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// Env vars are only looked up for known keys, so every key gets a default
+	for key, value := range settings(Defaults()) {
+		v.SetDefault(key, value)
+	}
+
+	if path := opts.ConfigFile(); path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return Config{}, fmt.Errorf("%w %s: %s", ErrConfigFile, path, err)
+		}
+		if err := checkKeys(v); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if profile := opts.ProfileName(); profile != "" {
+		sub := v.Sub(profilesKey + "." + profile)
+		if sub == nil {
+			return Config{}, fmt.Errorf("%w: %s, the config file has %v", ErrUnknownProfile, profile, profiles(v))
+		}
+		if err := v.MergeConfigMap(sub.AllSettings()); err != nil {
+			return Config{}, err
+		}
+	}
+	for key, value := range opts.Overrides {
+		v.Set(key, value)
+	}
+
+	var c Config
+	if err := v.Unmarshal(&c); err != nil {
+		return Config{}, err
+	}
+	if err := c.BtcConfig.Validate(); err != nil {
+		return Config{}, fmt.Errorf("btc: %w", err)
+	}
+	return c, nil
+}
+
+// Values used when a setting is set nowhere else, the getters cover the other zero values
+func Defaults() Config {
+	return Config{
+		OpiConfig: OpiConfig{
+			Version: "0.3.0",
+			Endpoints: OpiEndpoints{
+				FetchEventsByInscriptionId:  "/v1/brc20/event",
+				FetchBrc20Balance:           "/v1/brc20/get_current_balance_of_wallet",
+				FetchBrc20BlockHeight:       "/v1/brc20/block_height",
+				FetchBrc20TransferableNotes: "/v1/brc20/get_valid_tx_notes_of_wallet",
+				FetchBrc20ActivityOnBlock:   "/v1/brc20/activity_on_block",
+				FetchBrc20BalanceOnBlock:    "/v1/brc20/balance_on_block",
+				FetchBrc20Holders:           "/v1/brc20/holders",
+				FetchBrc20TickerInfo:        "/v1/brc20/ticker_info",
+				FetchBrc20EventHash:         "/v1/brc20/get_hash_of_all_activity",
+
+				FetchRunesEventsByTransactionId: "/v1/runes/event",
+				FetchRunesBalance:               "/v1/runes/get_current_balance_of_wallet",
+				FetchRunesBlockHeight:           "/v1/runes/block_height",
+				FetchRunesTickerInfo:            "/v1/runes/ticker_info",
+				FetchRunesHolders:               "/v1/runes/holders",
+				FetchRunesUnspentOutpoint:       "/v1/runes/get_unspent_rune_outpoints_of_wallet",
+			},
+		},
+	}
+}
+
+// Reject keys of the file & its profiles which don't match a setting, typos would be silently ignored otherwise
+func checkKeys(v *viper.Viper) error {
+	known := settings(Config{})
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		if rest, ok := strings.CutPrefix(key, profilesKey+"."); ok {
+			if _, key, ok = strings.Cut(rest, "."); !ok {
+				continue
+			}
+		}
+		if _, ok := known[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func profiles(v *viper.Viper) []string {
+	names := []string{}
+	for name := range v.GetStringMap(profilesKey) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Every setting of the config keyed like in the config file, e.g. btc.rpc_host
+func settings(c Config) map[string]any {
+	res := map[string]any{}
+	walkSettings(reflect.ValueOf(c), "", func(key string, value reflect.Value) {
+		res[key] = value.Interface()
+	})
+	return res
+}
+
+// Settings nested like in the config file for display, secrets masked
+func (c Config) Display() map[string]any {
+	res := map[string]any{}
+	walkSettings(reflect.ValueOf(c), "", func(key string, value reflect.Value) {
+		display := value.Interface()
+		if d, ok := display.(time.Duration); ok {
+			display = d.String()
+		}
+		if value.Kind() == reflect.String && value.String() != "" && isSecret(key) {
+			display = "********"
+		}
+		if value.Kind() == reflect.Slice && value.Len() > 0 && isSecret(key) {
+			masked := make([]string, value.Len())
+			for i := range masked {
+				masked[i] = "********"
+			}
+			display = masked
+		}
+		section := res
+		parts := strings.Split(key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			if _, ok := section[part]; !ok {
+				section[part] = map[string]any{}
+			}
+			section = section[part].(map[string]any)
+		}
+		section[parts[len(parts)-1]] = display
+	})
+	return res
+}
+
+func isSecret(key string) bool {
+	return strings.HasSuffix(key, "api_key") || strings.HasSuffix(key, "_pass") || strings.HasSuffix(key, "secret") || strings.HasSuffix(key, "api_keys")
+}
+
+func walkSettings(value reflect.Value, prefix string, fn func(key string, value reflect.Value)) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			walkSettings(value.Field(i), key+".", fn)
+			continue
+		}
+		fn(key, value.Field(i))
+	}
+}
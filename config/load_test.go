@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+btc:
+  chain_cfg: mainnet
+  rpc_host: node.local
+  wallet_name: hot
+  sandshrew_api_key: secret
+inscription:
+  ord_timeout: 30s
+fee:
+  sources: ["mempool", "core"]
+  mempool_api_url: https://mempool.space/api
+webhook:
+  urls: ["https://example.com/events"]
+  secret: whsec
+api:
+  api_keys: ["0123456789abcdef"]
+profiles:
+  regtest:
+    btc:
+      chain_cfg: regtest
+      wallet_name: w1
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, "btc-service.yaml", testConfig)
+
+	c, err := Load(LoadOptions{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, c.Validate())
+	require.Equal(t, NetworkMainnet, c.BtcConfig.GetNetwork())
+	require.Equal(t, "node.local:8332", c.BtcConfig.GetRpcHost())
+	require.Equal(t, []string{"mempool", "core"}, c.FeeConfig.Sources)
+	require.Equal(t, 30*time.Second, c.InscriptionConfig.OrdTimeout)
+	require.Equal(t, "/v1/brc20/event", c.OpiConfig.Endpoints.FetchEventsByInscriptionId)
+
+	// Profile over the file, env vars over the profile, overrides over everything
+	t.Setenv("BTC_SERVICE_BTC_WALLET_NAME", "cold")
+	t.Setenv("BTC_SERVICE_FEE_SOURCES", "static,core")
+	c, err = Load(LoadOptions{Path: path, Profile: "regtest", Overrides: map[string]any{"btc.rpc_host": "127.0.0.1:1"}})
+	require.NoError(t, err)
+	require.Equal(t, NetworkRegtest, c.BtcConfig.GetNetwork())
+	require.Equal(t, "cold", c.BtcConfig.WalletName)
+	require.Equal(t, "127.0.0.1:1", c.BtcConfig.GetRpcHost())
+	require.Equal(t, []string{"static", "core"}, c.FeeConfig.Sources)
+	require.Equal(t, "secret", c.BtcConfig.SandshrewApiKey)
+	require.Equal(t, "********", c.Display()["btc"].(map[string]any)["sandshrew_api_key"])
+	require.Equal(t, "********", c.Display()["webhook"].(map[string]any)["secret"])
+	require.Equal(t, []string{"********"}, c.Display()["api"].(map[string]any)["api_keys"])
+
+	_, err = Load(LoadOptions{Path: path, Profile: "signet"})
+	require.ErrorIs(t, err, ErrUnknownProfile)
+
+	toml := writeConfig(t, "btc-service.toml", "[btc]\nchain_cfg = \"signet\"\nwallet_name = \"w\"\n")
+	c, err = Load(LoadOptions{Path: toml})
+	require.NoError(t, err)
+	require.Equal(t, NetworkSignet, c.BtcConfig.GetNetwork())
+
+	_, err = Load(LoadOptions{Path: writeConfig(t, "typo.yaml", "btc:\n  rpc_hots: localhost\n")})
+	require.ErrorIs(t, err, ErrUnknownSetting)
+	_, err = Load(LoadOptions{Path: path, Overrides: map[string]any{"btc.chain_cfg": "mainet"}})
+	require.ErrorIs(t, err, ErrUnknownNetwork)
+	_, err = Load(LoadOptions{Path: filepath.Join(t.TempDir(), "missing.yaml")})
+	require.ErrorIs(t, err, ErrConfigFile)
+}
+
+func TestValidate(t *testing.T) {
+	c := Config{
+		BtcConfig:         BtcConfig{ChainConfig: "testnet"},
+		OpiConfig:         OpiConfig{Brc20Url: "localhost:8000"},
+		InscriptionConfig: InscriptionConfig{Backend: "ord"},
+		FeeConfig:         FeeConfig{Sources: []string{"mempool", "oracle"}, DefaultPriority: "now", MinFeeRate: 5, MaxFeeRate: 2},
+		Brc20Config:       Brc20Config{Sources: []string{"opi", "bis"}, Quorum: 3},
+		RunesConfig:       RunesConfig{UtxoSource: "ord", MinConfirmations: -1},
+		WatchConfig:       WatchConfig{Sinks: []string{"stdout", "webhook", "kafka"}},
+		WebhookConfig:     WebhookConfig{MinBackoff: time.Minute, MaxBackoff: time.Second},
+		ApiConfig:         ApiConfig{ApiKeys: []string{"short"}},
+	}
+	err := c.Validate()
+	var problems ValidationError
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 15)
+	require.Contains(t, err.Error(), "btc.chain_cfg: unknown network: testnet is ambiguous")
+	require.Contains(t, err.Error(), "opi.brc20_url: \"localhost:8000\" should be an http(s) url")
+	require.Contains(t, err.Error(), "bis_config.api_key: required by the bis brc20 source")
+	require.Contains(t, err.Error(), "runes.utxo_source: \"ord\" should be opi or bis")
+	require.Contains(t, err.Error(), "brc20.quorum: 3 is more than the 2 brc20 sources")
+	require.Contains(t, err.Error(), "runes.min_confirmations: can't be negative")
+	require.Contains(t, err.Error(), "webhook.urls: required by the webhook sink")
+	require.Contains(t, err.Error(), "webhook.max_backoff: 1s is below webhook.min_backoff")
+	require.Contains(t, err.Error(), "api.api_keys: keys should be at least 16 characters")
+	require.Contains(t, err.Error(), "watch.sinks: \"kafka\" should be stdout, webhook or queue")
+}
+
+func TestDefaultSources(t *testing.T) {
+	for _, chain := range []string{"mainnet", "Mainnet", " MAINNET "} {
+		c := Config{BtcConfig: BtcConfig{ChainConfig: chain}}
+		require.Equal(t, []string{"bis"}, c.GetBrc20Sources(), chain)
+		require.Equal(t, "bis", c.GetRunesUtxoSource(), chain)
+	}
+	for _, chain := range []string{"", "signet", "regtest", "unknown"} {
+		c := Config{BtcConfig: BtcConfig{ChainConfig: chain}}
+		require.Equal(t, []string{"opi"}, c.GetBrc20Sources(), chain)
+		require.Equal(t, "opi", c.GetRunesUtxoSource(), chain)
+	}
+}
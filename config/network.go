@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+type Network string
+
+const (
+	NetworkMainnet  Network = "mainnet"
+	NetworkTestnet3 Network = "testnet3"
+	NetworkTestnet4 Network = "testnet4"
+	NetworkSignet   Network = "signet" // Default signet, or a custom one with btc.signet_challenge
+	NetworkRegtest  Network = "regtest"
+)
+
+var (
+	ErrUnknownNetwork         = errors.New("unknown network")
+	ErrInvalidSignetChallenge = errors.New("invalid signet challenge")
+)
+
+// Networks in the order they're listed to users
+var Networks = []Network{NetworkMainnet, NetworkTestnet3, NetworkTestnet4, NetworkSignet, NetworkRegtest}
+
+// Testnet4 (BIP94), btcd doesn't ship its params yet. Addresses are encoded like on testnet3
+var TestNet4Params = func() chaincfg.Params {
+	params := chaincfg.TestNet3Params
+	params.Name = string(NetworkTestnet4)
+	params.Net = wire.BitcoinNet(0x283f161c)
+	params.DefaultPort = "48333"
+	params.DNSSeeds = []chaincfg.DNSSeed{
+		{Host: "seed.testnet4.bitcoin.sprovoost.nl", HasFiltering: true},
+		{Host: "seed.testnet4.wiz.biz", HasFiltering: true},
+	}
+	genesisHash, _ := chainhash.NewHashFromStr("00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043")
+	params.GenesisHash = genesisHash
+	params.GenesisBlock = nil
+	params.Checkpoints = nil
+	return params
+}()
+
+// Parse a network name. "testnet" is refused, it used to mean signet in this service
+func ParseNetwork(name string) (Network, error) {
+	switch n := Network(strings.ToLower(strings.TrimSpace(name))); n {
+	case NetworkMainnet, NetworkTestnet3, NetworkTestnet4, NetworkSignet, NetworkRegtest:
+		return n, nil
+	case "testnet":
+		return "", fmt.Errorf("%w: testnet is ambiguous, use testnet3, testnet4 or signet", ErrUnknownNetwork)
+	}
+	return "", fmt.Errorf("%w: %q, expected one of %v", ErrUnknownNetwork, name, Networks)
+}
+
+// Bitcoin core's default rpc port
+func (n Network) RpcPort() string {
+	switch n {
+	case NetworkMainnet:
+		return "8332"
+	case NetworkTestnet3:
+		return "18332"
+	case NetworkTestnet4:
+		return "48332"
+	case NetworkSignet:
+		return "38332"
+	default:
+		return "18443"
+	}
+}
+
+// Value of ord's --chain
+func (n Network) OrdChain() string {
+	if n == NetworkTestnet3 {
+		return "testnet"
+	}
+	return string(n)
+}
+
+// Validate the network settings, called when the config is loaded and by the rpc client
+func (c BtcConfig) Validate() error {
+	network, err := ParseNetwork(c.ChainConfig)
+	if err != nil {
+		return err
+	}
+	if c.SignetChallenge != "" {
+		if network != NetworkSignet {
+			return fmt.Errorf("%w: signet_challenge set on %s", ErrInvalidSignetChallenge, network)
+		}
+		if _, err := hex.DecodeString(c.SignetChallenge); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidSignetChallenge, err)
+		}
+	}
+	return nil
+}
+
+// Network of btc.chain_config. Unknown names are rejected by Load, and configs built in code by
+// NewBitcoinClient, so only a config which went through neither gets regtest
+func (c BtcConfig) GetNetwork() Network {
+	network, err := ParseNetwork(c.ChainConfig)
+	if err != nil {
+		return NetworkRegtest
+	}
+	return network
+}
+
+func (c BtcConfig) GetChainConfigParams() *chaincfg.Params {
+	switch c.GetNetwork() {
+	case NetworkMainnet:
+		return &chaincfg.MainNetParams
+	case NetworkTestnet3:
+		return &chaincfg.TestNet3Params
+	case NetworkTestnet4:
+		return &TestNet4Params
+	case NetworkSignet:
+		if c.SignetChallenge != "" {
+			challenge, _ := hex.DecodeString(c.SignetChallenge)
+			params := chaincfg.CustomSignetParams(challenge, nil)
+			return &params
+		}
+		return &chaincfg.SigNetParams
+	default:
+		return &chaincfg.RegressionNetParams
+	}
+}
+
+// Global ord flags selecting the chain
+func (c BtcConfig) GetOrdChainArgs() []string {
+	return []string{"--chain", c.GetNetwork().OrdChain()}
+}
+
+// Rpc host, with the default port of the network when none is given. Over TLS the https port is kept
+func (c BtcConfig) GetRpcHost() string {
+	host, path, _ := strings.Cut(strings.TrimRight(c.RpcHost, "/"), "/")
+	if host == "" {
+		host = "localhost"
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil && !c.RpcTLS {
+		host = net.JoinHostPort(host, c.GetNetwork().RpcPort())
+	}
+	if path != "" {
+		return host + "/" + path
+	}
+	return host
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworks(t *testing.T) {
+	for _, name := range []string{"", "testnet", "mainet", "signet2"} {
+		require.ErrorIs(t, BtcConfig{ChainConfig: name}.Validate(), ErrUnknownNetwork, name)
+	}
+
+	for network, expected := range map[Network]struct {
+		params  *chaincfg.Params
+		ord     string
+		rpcHost string
+	}{
+		NetworkMainnet:  {&chaincfg.MainNetParams, "mainnet", "localhost:8332"},
+		NetworkTestnet3: {&chaincfg.TestNet3Params, "testnet", "localhost:18332"},
+		NetworkTestnet4: {&TestNet4Params, "testnet4", "localhost:48332"},
+		NetworkSignet:   {&chaincfg.SigNetParams, "signet", "localhost:38332"},
+		NetworkRegtest:  {&chaincfg.RegressionNetParams, "regtest", "localhost:18443"},
+	} {
+		c := BtcConfig{ChainConfig: string(network), RpcHost: "localhost"}
+		require.NoError(t, c.Validate())
+		require.Equal(t, expected.params.Name, c.GetChainConfigParams().Name)
+		require.Equal(t, []string{"--chain", expected.ord}, c.GetOrdChainArgs())
+		require.Equal(t, expected.rpcHost, c.GetRpcHost())
+	}
+
+	c := BtcConfig{ChainConfig: "Regtest", RpcHost: "10.0.0.2:9000/", WalletName: "w1"}
+	require.Equal(t, "10.0.0.2:9000/wallet/w1", c.GetRpcHostWithWallet())
+	require.Equal(t, NetworkRegtest, c.GetNetwork())
+	c.WalletName = ""
+	require.Equal(t, "10.0.0.2:9000", c.GetRpcHostWithWallet())
+
+	c = BtcConfig{ChainConfig: "mainnet", RpcHost: "mainnet.sandshrew.io/v1/key"}
+	require.Equal(t, "mainnet.sandshrew.io:8332/v1/key", c.GetRpcHost())
+	c.RpcTLS = true
+	require.Equal(t, "mainnet.sandshrew.io/v1/key", c.GetRpcHost())
+
+	// Testnet4 addresses are encoded like testnet3 ones
+	addr, err := btcutil.DecodeAddress("tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", &TestNet4Params)
+	require.NoError(t, err)
+	require.True(t, addr.IsForNet(&TestNet4Params))
+}
+
+func TestCustomSignet(t *testing.T) {
+	c := BtcConfig{ChainConfig: "signet", SignetChallenge: "51"}
+	require.NoError(t, c.Validate())
+	params := c.GetChainConfigParams()
+	require.NotEqual(t, chaincfg.SigNetParams.Net, params.Net)
+	require.Equal(t, chaincfg.SigNetParams.Bech32HRPSegwit, params.Bech32HRPSegwit)
+
+	require.ErrorIs(t, BtcConfig{ChainConfig: "signet", SignetChallenge: "zz"}.Validate(), ErrInvalidSignetChallenge)
+	require.ErrorIs(t, BtcConfig{ChainConfig: "mainnet", SignetChallenge: "51"}.Validate(), ErrInvalidSignetChallenge)
+}
@@ -1,40 +1,131 @@
 package config
 
+import "time"
+
 type (
 	Config struct {
 		BtcConfig BtcConfig `mapstructure:"btc"`
 		OpiConfig OpiConfig `mapstructure:"opi"`
 		BISConfig BISConfig `mapstructure:"bis_config"`
+
+		InscriptionConfig InscriptionConfig `mapstructure:"inscription"`
+		FeeConfig         FeeConfig         `mapstructure:"fee"`
+		Brc20Config       Brc20Config       `mapstructure:"brc20"`
+		RunesConfig       RunesConfig       `mapstructure:"runes"`
+		WatchConfig       WatchConfig       `mapstructure:"watch"`
+		WebhookConfig     WebhookConfig     `mapstructure:"webhook"`
+		ApiConfig         ApiConfig         `mapstructure:"api"`
 	}
 
 	BtcConfig struct {
-		RpcHost         string `mapstructure:"rpc_host"`
-		CookiePath      string `mapstructure:"cookie_path"`
-		WalletName      string `mapstructure:"wallet_name"`
-		ChainConfig     string `mapstructure:"chain_cfg"`
-		OrdPath         string `mapstructure:"ord_path"`
-		BitcoinDataDir  string `mapstructure:"bitcoin_data_dir"`
-		OrdDataDir      string `mapstructure:"ord_data_dir"`
-		ElectrumProxy   string `mapstructure:"electrum_proxy"`
-		SandshrewApiKey string `mapstructure:"sandshrew_api_key"`
+		RpcHost         string        `mapstructure:"rpc_host"`         // host[:port][/path], e.g. mainnet.sandshrew.io/v1/KEY with rpc_tls
+		RpcUser         string        `mapstructure:"rpc_user"`         // User/pass auth, instead of cookie_path
+		RpcPass         string        `mapstructure:"rpc_pass"`         //
+		RpcTLS          bool          `mapstructure:"rpc_tls"`          // https, verified with rpc_cert or the system roots
+		RpcCert         string        `mapstructure:"rpc_cert"`         // PEM file of the certificate(s) to trust
+		RpcTimeout      time.Duration `mapstructure:"rpc_timeout"`      // Timeout of a single rpc call, defaults to 30s
+		RpcMaxAttempts  int           `mapstructure:"rpc_max_attempts"` // Attempts of an rpc call failing to reach the node, defaults to 3
+		CookiePath      string        `mapstructure:"cookie_path"`
+		WalletName      string        `mapstructure:"wallet_name"`      // Loaded on start, no wallet is used when empty
+		ChainConfig     string        `mapstructure:"chain_cfg"`        // mainnet, testnet3, testnet4, signet or regtest
+		SignetChallenge string        `mapstructure:"signet_challenge"` // Hex script of a custom signet, the default signet when empty
+		OrdPath         string        `mapstructure:"ord_path"`
+		BitcoinDataDir  string        `mapstructure:"bitcoin_data_dir"`
+		OrdDataDir      string        `mapstructure:"ord_data_dir"`
+		ElectrumProxy   string        `mapstructure:"electrum_proxy"`
+		SandshrewApiKey string        `mapstructure:"sandshrew_api_key"` // Sandshrew multicall api, the rpc goes through rpc_host
 	}
 
 	OpiConfig struct {
-		Version   string       `mapstructure:"version"`
-		Brc20Url  string       `mapstructure:"brc20_url"`
-		Endpoints OpiEndpoints `mapstructure:"endpoints"`
-		RunesUrl  string       `mapstructure:"runes_url"`
+		Version     string        `mapstructure:"version"`
+		Brc20Url    string        `mapstructure:"brc20_url"`
+		Endpoints   OpiEndpoints  `mapstructure:"endpoints"`
+		RunesUrl    string        `mapstructure:"runes_url"`
+		Timeout     time.Duration `mapstructure:"timeout"`      // Timeout of a single request, defaults to 30s
+		MaxAttempts int           `mapstructure:"max_attempts"` // Attempts of requests failing with a network error or a 5xx, defaults to 3
 	}
 
 	OpiEndpoints struct {
 		FetchEventsByInscriptionId      string `mapstructure:"fetch_brc20_evts_by_inscription_id"`
 		FetchRunesEventsByTransactionId string `mapstructure:"fetch_runes_evts_by_txid"`
 		FetchBrc20Balance               string `mapstructure:"fetch_brc20_balance"`
+		FetchBrc20BlockHeight           string `mapstructure:"fetch_brc20_block_height"`
+		FetchBrc20TransferableNotes     string `mapstructure:"fetch_brc20_transferable_notes"`
+		FetchBrc20ActivityOnBlock       string `mapstructure:"fetch_brc20_activity_on_block"`
+		FetchBrc20BalanceOnBlock        string `mapstructure:"fetch_brc20_balance_on_block"`
+		FetchBrc20Holders               string `mapstructure:"fetch_brc20_holders"`
+		FetchBrc20TickerInfo            string `mapstructure:"fetch_brc20_ticker_info"`
+		FetchBrc20EventHash             string `mapstructure:"fetch_brc20_event_hash"`
 		FetchRunesBalance               string `mapstructure:"fetch_runes_balance"`
+		FetchRunesBlockHeight           string `mapstructure:"fetch_runes_block_height"`
+		FetchRunesTickerInfo            string `mapstructure:"fetch_runes_ticker_info"`
+		FetchRunesHolders               string `mapstructure:"fetch_runes_holders"`
 		FetchRunesUnspentOutpoint       string `mapstructure:"fetch_runes_unspent_outpoints"` // These names have techincal meaning and are not to be changed
 	}
 
 	BISConfig struct {
-		APIKey string `mapstructure:"api_key"`
+		APIKey      string        `mapstructure:"api_key"`
+		BaseUrl     string        `mapstructure:"base_url"`     // Defaults to https://api.bestinslot.xyz
+		Timeout     time.Duration `mapstructure:"timeout"`      // Per request, defaults to 30s
+		MaxAttempts int           `mapstructure:"max_attempts"` // Requests failing with a network error, a 429 or a 5xx are retried, defaults to 3
+	}
+
+	Brc20Config struct {
+		Sources []string `mapstructure:"sources"` // Indexers checked for deposits, "opi" and/or "bis". Defaults to bis on mainnet, opi elsewhere
+		Quorum  int      `mapstructure:"quorum"`  // Indexers which have to answer & agree, defaults to all of them
+	}
+
+	RunesConfig struct {
+		UtxoSource        string `mapstructure:"utxo_source"`         // Indexer listing the outpoints holding runes and the ones a deposit spent, "opi" or "bis". Defaults to bis on mainnet, opi elsewhere
+		MinConfirmations  int64  `mapstructure:"min_confirmations"`   // Confirmations a deposit needs, defaults to 1
+		AllowOtherSenders bool   `mapstructure:"allow_other_senders"` // Accept deposits where inputs of other addresses also spend the rune
+	}
+
+	WatchConfig struct {
+		Addresses     []string      `mapstructure:"addresses"`     // Deposit addresses watched by `btc-service watch`
+		Assets        []string      `mapstructure:"assets"`        // "btc", "brc20" and/or "runes", defaults to all of them. BRC20 uses the first of brc20.sources, runes runes.utxo_source
+		Confirmations int64         `mapstructure:"confirmations"` // Depth at which a deposit is confirmed, defaults to 1
+		StateFile     string        `mapstructure:"state_file"`    // Scan progress & pending deposits, defaults to watch-state.json
+		StartHeight   int64         `mapstructure:"start_height"`  // First block scanned when there's no state file yet, defaults to the tip
+		PollInterval  time.Duration `mapstructure:"poll_interval"` // Defaults to 30s
+		MinBtcValue   int64         `mapstructure:"min_btc_value"` // BTC deposits below it are ignored, e.g. the postage of inscriptions & runes
+		Sinks         []string      `mapstructure:"sinks"`         // "stdout", "webhook" and/or "queue", defaults to stdout. The webhook sink needs webhook.urls
+		QueueFile     string        `mapstructure:"queue_file"`    // JSON lines appended for every event, required by the queue sink
+		TrackDir      string        `mapstructure:"track_dir"`     // Transactions to follow, added by `watch track`, defaults to watch-track
+	}
+
+	WebhookConfig struct {
+		Urls        []string      `mapstructure:"urls"`         // Every event is POSTed to each of them
+		Secret      string        `mapstructure:"secret"`       // HMAC-SHA256 key signing the events, required with urls
+		OutboxDir   string        `mapstructure:"outbox_dir"`   // Events waiting for delivery, delivered & failed, defaults to webhook-outbox
+		Timeout     time.Duration `mapstructure:"timeout"`      // Per delivery, defaults to 10s
+		MaxAttempts int           `mapstructure:"max_attempts"` // Before a delivery fails for good and waits for a replay, defaults to 10
+		MinBackoff  time.Duration `mapstructure:"min_backoff"`  // Before the first retry, doubled for every other one. Defaults to 5s
+		MaxBackoff  time.Duration `mapstructure:"max_backoff"`  // Defaults to 1h
+		Retention   time.Duration `mapstructure:"retention"`    // Delivered events are removed from the outbox once older, defaults to 168h
+	}
+
+	ApiConfig struct {
+		Listen       string        `mapstructure:"listen"`         // Address of `btc-service serve`, defaults to 127.0.0.1:8080
+		ApiKeys      []string      `mapstructure:"api_keys"`       // Accepted in the X-API-Key header or as a bearer token, required by serve
+		Timeout      time.Duration `mapstructure:"timeout"`        // Of a single request, defaults to 60s
+		MaxBodyBytes int64         `mapstructure:"max_body_bytes"` // Requests with a larger body are rejected, defaults to 1MiB
+		GrpcListen   string        `mapstructure:"grpc_listen"`    // Address of the gRPC api of serve, off when empty
+	}
+
+	InscriptionConfig struct {
+		Backend       string        `mapstructure:"backend"`         // "native" (default) or "ord"
+		Postage       int64         `mapstructure:"postage"`         // Sats locked in the inscription output, defaults to 546
+		RevealFeeRate uint64        `mapstructure:"reveal_fee_rate"` // Fee rate for the reveal tx, defaults to the commit fee rate
+		OrdTimeout    time.Duration `mapstructure:"ord_timeout"`     // Timeout of a single ord invocation, defaults to 2m
+	}
+
+	FeeConfig struct {
+		Sources         []string `mapstructure:"sources"`          // "core", "mempool" and/or "static", tried in order. Defaults to core
+		MempoolApiUrl   string   `mapstructure:"mempool_api_url"`  // mempool.space compatible api, e.g. https://mempool.space/api
+		StaticFeeRate   uint64   `mapstructure:"static_fee_rate"`  // sats/vB returned by the static source for every priority
+		DefaultPriority string   `mapstructure:"default_priority"` // Used by --fee-rate auto, defaults to halfhour
+		MinFeeRate      uint64   `mapstructure:"min_fee_rate"`     // Estimates below it are raised to it, defaults to 1
+		MaxFeeRate      uint64   `mapstructure:"max_fee_rate"`     // Estimates above it are capped, 0 for no cap
 	}
 )
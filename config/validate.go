@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Fee priority names, see client.ParseFeePriority
+var feePriorities = []string{"fastest", "halfhour", "hour", "economy", "fast", "slow"}
+
+// Problems found in a config, one per setting
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Check the settings against each other, returning a ValidationError listing every problem
+func (c Config) Validate() error {
+	var problems ValidationError
+	add := func(key, format string, args ...any) {
+		problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+	}
+	checkUrl := func(key, value string) {
+		if value == "" {
+			return
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(key, "%q should be an http(s) url", value)
+		}
+	}
+
+	if c.BtcConfig.ChainConfig == "" {
+		add("btc.chain_cfg", "not set, expected one of %v", Networks)
+	} else if err := c.BtcConfig.Validate(); err != nil {
+		add("btc.chain_cfg", "%s", err)
+	}
+	if (c.BtcConfig.RpcUser == "") != (c.BtcConfig.RpcPass == "") {
+		add("btc.rpc_user", "btc.rpc_user and btc.rpc_pass go together")
+	}
+	if c.BtcConfig.RpcUser != "" && c.BtcConfig.CookiePath != "" {
+		add("btc.cookie_path", "set along with btc.rpc_user, use one of them")
+	}
+	if c.BtcConfig.RpcCert != "" && !c.BtcConfig.RpcTLS {
+		add("btc.rpc_cert", "only used with btc.rpc_tls")
+	}
+	if strings.Contains(c.BtcConfig.RpcHost, "://") {
+		add("btc.rpc_host", "%q should have no scheme, use btc.rpc_tls for https", c.BtcConfig.RpcHost)
+	}
+	checkUrl("btc.electrum_proxy", c.BtcConfig.ElectrumProxy)
+	checkUrl("opi.brc20_url", c.OpiConfig.Brc20Url)
+	checkUrl("opi.runes_url", c.OpiConfig.RunesUrl)
+	checkUrl("bis_config.base_url", c.BISConfig.BaseUrl)
+
+	switch c.InscriptionConfig.Backend {
+	case "", "native":
+	case "ord":
+		if c.BtcConfig.OrdPath == "" {
+			add("btc.ord_path", "required by the ord inscription backend")
+		}
+	default:
+		add("inscription.backend", "%q should be native or ord", c.InscriptionConfig.Backend)
+	}
+	if c.InscriptionConfig.Postage < 0 {
+		add("inscription.postage", "can't be negative")
+	}
+
+	for _, source := range c.FeeConfig.GetSources() {
+		switch source {
+		case "core":
+		case "mempool":
+			if c.FeeConfig.MempoolApiUrl == "" {
+				add("fee.mempool_api_url", "required by the mempool fee source")
+			}
+		case "static":
+			if c.FeeConfig.StaticFeeRate == 0 {
+				add("fee.static_fee_rate", "required by the static fee source")
+			}
+		default:
+			add("fee.sources", "%q should be core, mempool or static", source)
+		}
+	}
+	checkUrl("fee.mempool_api_url", c.FeeConfig.MempoolApiUrl)
+	if !slices.Contains(feePriorities, strings.ToLower(c.FeeConfig.GetDefaultPriority())) {
+		add("fee.default_priority", "%q should be one of %v", c.FeeConfig.DefaultPriority, feePriorities)
+	}
+	// The default sources are only needed for deposits, they're checked when used
+	for _, source := range c.Brc20Config.Sources {
+		switch source {
+		case "opi":
+			if c.OpiConfig.Brc20Url == "" {
+				add("opi.brc20_url", "required by the opi brc20 source")
+			}
+		case "bis":
+			if c.BISConfig.APIKey == "" {
+				add("bis_config.api_key", "required by the bis brc20 source")
+			}
+		default:
+			add("brc20.sources", "%q should be opi or bis", source)
+		}
+	}
+	switch c.RunesConfig.UtxoSource {
+	case "":
+	case "opi":
+		if c.OpiConfig.RunesUrl == "" {
+			add("opi.runes_url", "required by the opi runes utxo source")
+		}
+	case "bis":
+		if c.BISConfig.APIKey == "" {
+			add("bis_config.api_key", "required by the bis runes utxo source")
+		}
+	default:
+		add("runes.utxo_source", "%q should be opi or bis", c.RunesConfig.UtxoSource)
+	}
+	for _, asset := range c.WatchConfig.Assets {
+		if !slices.Contains([]string{"btc", "brc20", "runes"}, asset) {
+			add("watch.assets", "%q should be btc, brc20 or runes", asset)
+		}
+	}
+	for _, sink := range c.WatchConfig.Sinks {
+		switch sink {
+		case "stdout":
+		case "webhook":
+			if len(c.WebhookConfig.Urls) == 0 {
+				add("webhook.urls", "required by the webhook sink")
+			}
+		case "queue":
+			if c.WatchConfig.QueueFile == "" {
+				add("watch.queue_file", "required by the queue sink")
+			}
+		default:
+			add("watch.sinks", "%q should be stdout, webhook or queue", sink)
+		}
+	}
+	for _, u := range c.WebhookConfig.Urls {
+		checkUrl("webhook.urls", u)
+	}
+	if len(c.WebhookConfig.Urls) > 0 && c.WebhookConfig.Secret == "" {
+		add("webhook.secret", "required to sign the events")
+	}
+	if c.WebhookConfig.MinBackoff > 0 && c.WebhookConfig.MaxBackoff > 0 && c.WebhookConfig.MaxBackoff < c.WebhookConfig.MinBackoff {
+		add("webhook.max_backoff", "%s is below webhook.min_backoff", c.WebhookConfig.MaxBackoff)
+	}
+	for _, key := range c.ApiConfig.ApiKeys {
+		if len(key) < 16 {
+			add("api.api_keys", "keys should be at least 16 characters")
+			break
+		}
+	}
+	if c.RunesConfig.MinConfirmations < 0 {
+		add("runes.min_confirmations", "can't be negative")
+	}
+	if sources := c.GetBrc20Sources(); c.Brc20Config.Quorum > len(sources) {
+		add("brc20.quorum", "%d is more than the %d brc20 sources", c.Brc20Config.Quorum, len(sources))
+	}
+	if c.FeeConfig.MaxFeeRate > 0 && c.FeeConfig.MaxFeeRate < c.FeeConfig.GetMinFeeRate() {
+		add("fee.max_fee_rate", "%d is below fee.min_fee_rate", c.FeeConfig.MaxFeeRate)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
@@ -0,0 +1,2199 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: btcservice.proto
+
+// gRPC api of btc-service, for the internal services. It serves the operations of the http api
+// (api/openapi.yaml) with the same validation & errors, plus a stream of the deposit events.
+//
+// Calls need one of api.api_keys in the x-api-key metadata, or as "authorization: Bearer KEY".
+// Failed calls carry a google.rpc.ErrorInfo detail, its reason being the error code of the http api
+// (e.g. insufficient_funds) and its "field" metadata the request field which failed validation.
+//
+// Amounts of tokens are decimal strings of base units, since they don't fit in 64 bits.
+
+package btcservicepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetFeesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFeesRequest) Reset() {
+	*x = GetFeesRequest{}
+	mi := &file_btcservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFeesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFeesRequest) ProtoMessage() {}
+
+func (x *GetFeesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFeesRequest.ProtoReflect.Descriptor instead.
+func (*GetFeesRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{0}
+}
+
+type Fees struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Fastest         uint64                 `protobuf:"varint,1,opt,name=fastest,proto3" json:"fastest,omitempty"`
+	Halfhour        uint64                 `protobuf:"varint,2,opt,name=halfhour,proto3" json:"halfhour,omitempty"`
+	Hour            uint64                 `protobuf:"varint,3,opt,name=hour,proto3" json:"hour,omitempty"`
+	Economy         uint64                 `protobuf:"varint,4,opt,name=economy,proto3" json:"economy,omitempty"`
+	DefaultPriority string                 `protobuf:"bytes,5,opt,name=default_priority,json=defaultPriority,proto3" json:"default_priority,omitempty"` // Priority of fee_rate auto
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Fees) Reset() {
+	*x = Fees{}
+	mi := &file_btcservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Fees) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Fees) ProtoMessage() {}
+
+func (x *Fees) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Fees.ProtoReflect.Descriptor instead.
+func (*Fees) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Fees) GetFastest() uint64 {
+	if x != nil {
+		return x.Fastest
+	}
+	return 0
+}
+
+func (x *Fees) GetHalfhour() uint64 {
+	if x != nil {
+		return x.Halfhour
+	}
+	return 0
+}
+
+func (x *Fees) GetHour() uint64 {
+	if x != nil {
+		return x.Hour
+	}
+	return 0
+}
+
+func (x *Fees) GetEconomy() uint64 {
+	if x != nil {
+		return x.Economy
+	}
+	return 0
+}
+
+func (x *Fees) GetDefaultPriority() string {
+	if x != nil {
+		return x.DefaultPriority
+	}
+	return ""
+}
+
+type GetUtxosRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUtxosRequest) Reset() {
+	*x = GetUtxosRequest{}
+	mi := &file_btcservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUtxosRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUtxosRequest) ProtoMessage() {}
+
+func (x *GetUtxosRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUtxosRequest.ProtoReflect.Descriptor instead.
+func (*GetUtxosRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetUtxosRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type Utxo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Vout          uint32                 `protobuf:"varint,2,opt,name=vout,proto3" json:"vout,omitempty"`
+	Value         uint64                 `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	Height        int64                  `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"` // 0 when unconfirmed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Utxo) Reset() {
+	*x = Utxo{}
+	mi := &file_btcservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Utxo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Utxo) ProtoMessage() {}
+
+func (x *Utxo) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Utxo.ProtoReflect.Descriptor instead.
+func (*Utxo) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Utxo) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *Utxo) GetVout() uint32 {
+	if x != nil {
+		return x.Vout
+	}
+	return 0
+}
+
+func (x *Utxo) GetValue() uint64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Utxo) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type GetUtxosResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Utxos         []*Utxo                `protobuf:"bytes,1,rep,name=utxos,proto3" json:"utxos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUtxosResponse) Reset() {
+	*x = GetUtxosResponse{}
+	mi := &file_btcservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUtxosResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUtxosResponse) ProtoMessage() {}
+
+func (x *GetUtxosResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUtxosResponse.ProtoReflect.Descriptor instead.
+func (*GetUtxosResponse) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetUtxosResponse) GetUtxos() []*Utxo {
+	if x != nil {
+		return x.Utxos
+	}
+	return nil
+}
+
+type GetBrc20BalanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Ticker        string                 `protobuf:"bytes,2,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBrc20BalanceRequest) Reset() {
+	*x = GetBrc20BalanceRequest{}
+	mi := &file_btcservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBrc20BalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBrc20BalanceRequest) ProtoMessage() {}
+
+func (x *GetBrc20BalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBrc20BalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetBrc20BalanceRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetBrc20BalanceRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *GetBrc20BalanceRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+type Brc20Balance struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Ticker           string                 `protobuf:"bytes,1,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	OverallBalance   string                 `protobuf:"bytes,2,opt,name=overall_balance,json=overallBalance,proto3" json:"overall_balance,omitempty"`       // Base units, 18 decimals
+	AvailableBalance string                 `protobuf:"bytes,3,opt,name=available_balance,json=availableBalance,proto3" json:"available_balance,omitempty"` // Base units, 18 decimals
+	BlockHeight      int64                  `protobuf:"varint,4,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Brc20Balance) Reset() {
+	*x = Brc20Balance{}
+	mi := &file_btcservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Brc20Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Brc20Balance) ProtoMessage() {}
+
+func (x *Brc20Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Brc20Balance.ProtoReflect.Descriptor instead.
+func (*Brc20Balance) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Brc20Balance) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Brc20Balance) GetOverallBalance() string {
+	if x != nil {
+		return x.OverallBalance
+	}
+	return ""
+}
+
+func (x *Brc20Balance) GetAvailableBalance() string {
+	if x != nil {
+		return x.AvailableBalance
+	}
+	return ""
+}
+
+func (x *Brc20Balance) GetBlockHeight() int64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+type GetRunesBalancesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunesBalancesRequest) Reset() {
+	*x = GetRunesBalancesRequest{}
+	mi := &file_btcservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunesBalancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunesBalancesRequest) ProtoMessage() {}
+
+func (x *GetRunesBalancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunesBalancesRequest.ProtoReflect.Descriptor instead.
+func (*GetRunesBalancesRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetRunesBalancesRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type RuneBalance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuneId        string                 `protobuf:"bytes,1,opt,name=rune_id,json=runeId,proto3" json:"rune_id,omitempty"`
+	RuneName      string                 `protobuf:"bytes,2,opt,name=rune_name,json=runeName,proto3" json:"rune_name,omitempty"`
+	Amount        string                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"` // Base units
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuneBalance) Reset() {
+	*x = RuneBalance{}
+	mi := &file_btcservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuneBalance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuneBalance) ProtoMessage() {}
+
+func (x *RuneBalance) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuneBalance.ProtoReflect.Descriptor instead.
+func (*RuneBalance) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RuneBalance) GetRuneId() string {
+	if x != nil {
+		return x.RuneId
+	}
+	return ""
+}
+
+func (x *RuneBalance) GetRuneName() string {
+	if x != nil {
+		return x.RuneName
+	}
+	return ""
+}
+
+func (x *RuneBalance) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+type GetRunesBalancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Balances      []*RuneBalance         `protobuf:"bytes,1,rep,name=balances,proto3" json:"balances,omitempty"` // Ordered by rune id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunesBalancesResponse) Reset() {
+	*x = GetRunesBalancesResponse{}
+	mi := &file_btcservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunesBalancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunesBalancesResponse) ProtoMessage() {}
+
+func (x *GetRunesBalancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunesBalancesResponse.ProtoReflect.Descriptor instead.
+func (*GetRunesBalancesResponse) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetRunesBalancesResponse) GetBalances() []*RuneBalance {
+	if x != nil {
+		return x.Balances
+	}
+	return nil
+}
+
+type Transfer struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	From  string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"` // Sender, funding the tx & getting the change
+	To    string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	// Sats/vB, or a priority estimated with the fee sources: auto, fast, slow, fastest, halfhour, hour
+	// or economy. Defaults to auto
+	FeeRate       string `protobuf:"bytes,3,opt,name=fee_rate,json=feeRate,proto3" json:"fee_rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transfer) Reset() {
+	*x = Transfer{}
+	mi := &file_btcservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transfer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transfer) ProtoMessage() {}
+
+func (x *Transfer) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transfer.ProtoReflect.Descriptor instead.
+func (*Transfer) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Transfer) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Transfer) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Transfer) GetFeeRate() string {
+	if x != nil {
+		return x.FeeRate
+	}
+	return ""
+}
+
+type BuildBtcSendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transfer      *Transfer              `protobuf:"bytes,1,opt,name=transfer,proto3" json:"transfer,omitempty"`
+	Amount        int64                  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"` // Sats
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildBtcSendRequest) Reset() {
+	*x = BuildBtcSendRequest{}
+	mi := &file_btcservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildBtcSendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildBtcSendRequest) ProtoMessage() {}
+
+func (x *BuildBtcSendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildBtcSendRequest.ProtoReflect.Descriptor instead.
+func (*BuildBtcSendRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BuildBtcSendRequest) GetTransfer() *Transfer {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+func (x *BuildBtcSendRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type BuildBrc20TransferRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transfer      *Transfer              `protobuf:"bytes,1,opt,name=transfer,proto3" json:"transfer,omitempty"`
+	InscriptionId string                 `protobuf:"bytes,2,opt,name=inscription_id,json=inscriptionId,proto3" json:"inscription_id,omitempty"` // Transfer inscription, in the output it was revealed into
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildBrc20TransferRequest) Reset() {
+	*x = BuildBrc20TransferRequest{}
+	mi := &file_btcservice_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildBrc20TransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildBrc20TransferRequest) ProtoMessage() {}
+
+func (x *BuildBrc20TransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildBrc20TransferRequest.ProtoReflect.Descriptor instead.
+func (*BuildBrc20TransferRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BuildBrc20TransferRequest) GetTransfer() *Transfer {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+func (x *BuildBrc20TransferRequest) GetInscriptionId() string {
+	if x != nil {
+		return x.InscriptionId
+	}
+	return ""
+}
+
+type BuildRunesTransferRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transfer      *Transfer              `protobuf:"bytes,1,opt,name=transfer,proto3" json:"transfer,omitempty"`
+	RuneId        string                 `protobuf:"bytes,2,opt,name=rune_id,json=runeId,proto3" json:"rune_id,omitempty"` // BLOCK:TX
+	Amount        string                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`               // Base units
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildRunesTransferRequest) Reset() {
+	*x = BuildRunesTransferRequest{}
+	mi := &file_btcservice_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildRunesTransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildRunesTransferRequest) ProtoMessage() {}
+
+func (x *BuildRunesTransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildRunesTransferRequest.ProtoReflect.Descriptor instead.
+func (*BuildRunesTransferRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BuildRunesTransferRequest) GetTransfer() *Transfer {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+func (x *BuildRunesTransferRequest) GetRuneId() string {
+	if x != nil {
+		return x.RuneId
+	}
+	return ""
+}
+
+func (x *BuildRunesTransferRequest) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+type Inscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Inscription) Reset() {
+	*x = Inscription{}
+	mi := &file_btcservice_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Inscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Inscription) ProtoMessage() {}
+
+func (x *Inscription) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Inscription.ProtoReflect.Descriptor instead.
+func (*Inscription) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Inscription) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Inscription) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type BuildInscriptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transfer      *Transfer              `protobuf:"bytes,1,opt,name=transfer,proto3" json:"transfer,omitempty"` // The sender has to be a P2WPKH or P2TR address
+	Inscriptions  []*Inscription         `protobuf:"bytes,2,rep,name=inscriptions,proto3" json:"inscriptions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildInscriptionRequest) Reset() {
+	*x = BuildInscriptionRequest{}
+	mi := &file_btcservice_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildInscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildInscriptionRequest) ProtoMessage() {}
+
+func (x *BuildInscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildInscriptionRequest.ProtoReflect.Descriptor instead.
+func (*BuildInscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *BuildInscriptionRequest) GetTransfer() *Transfer {
+	if x != nil {
+		return x.Transfer
+	}
+	return nil
+}
+
+func (x *BuildInscriptionRequest) GetInscriptions() []*Inscription {
+	if x != nil {
+		return x.Inscriptions
+	}
+	return nil
+}
+
+// Unsigned tx for the wallet of the sender to sign
+type Psbt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Psbt          string                 `protobuf:"bytes,1,opt,name=psbt,proto3" json:"psbt,omitempty"` // Base64
+	Fee           int64                  `protobuf:"varint,2,opt,name=fee,proto3" json:"fee,omitempty"`
+	Vsize         int64                  `protobuf:"varint,3,opt,name=vsize,proto3" json:"vsize,omitempty"` // Estimated, once signed
+	FeeRate       uint64                 `protobuf:"varint,4,opt,name=fee_rate,json=feeRate,proto3" json:"fee_rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Psbt) Reset() {
+	*x = Psbt{}
+	mi := &file_btcservice_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Psbt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Psbt) ProtoMessage() {}
+
+func (x *Psbt) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Psbt.ProtoReflect.Descriptor instead.
+func (*Psbt) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Psbt) GetPsbt() string {
+	if x != nil {
+		return x.Psbt
+	}
+	return ""
+}
+
+func (x *Psbt) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *Psbt) GetVsize() int64 {
+	if x != nil {
+		return x.Vsize
+	}
+	return 0
+}
+
+func (x *Psbt) GetFeeRate() uint64 {
+	if x != nil {
+		return x.FeeRate
+	}
+	return 0
+}
+
+type InscriptionPsbt struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Commit         *Psbt                  `protobuf:"bytes,1,opt,name=commit,proto3" json:"commit,omitempty"`
+	RevealTx       string                 `protobuf:"bytes,2,opt,name=reveal_tx,json=revealTx,proto3" json:"reveal_tx,omitempty"` // Hex, signed, submitted along with the signed commit
+	RevealTxId     string                 `protobuf:"bytes,3,opt,name=reveal_tx_id,json=revealTxId,proto3" json:"reveal_tx_id,omitempty"`
+	InscriptionIds []string               `protobuf:"bytes,4,rep,name=inscription_ids,json=inscriptionIds,proto3" json:"inscription_ids,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *InscriptionPsbt) Reset() {
+	*x = InscriptionPsbt{}
+	mi := &file_btcservice_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InscriptionPsbt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InscriptionPsbt) ProtoMessage() {}
+
+func (x *InscriptionPsbt) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InscriptionPsbt.ProtoReflect.Descriptor instead.
+func (*InscriptionPsbt) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *InscriptionPsbt) GetCommit() *Psbt {
+	if x != nil {
+		return x.Commit
+	}
+	return nil
+}
+
+func (x *InscriptionPsbt) GetRevealTx() string {
+	if x != nil {
+		return x.RevealTx
+	}
+	return ""
+}
+
+func (x *InscriptionPsbt) GetRevealTxId() string {
+	if x != nil {
+		return x.RevealTxId
+	}
+	return ""
+}
+
+func (x *InscriptionPsbt) GetInscriptionIds() []string {
+	if x != nil {
+		return x.InscriptionIds
+	}
+	return nil
+}
+
+type SubmitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Psbt          string                 `protobuf:"bytes,1,opt,name=psbt,proto3" json:"psbt,omitempty"`                         // Base64, every input signed
+	RevealTx      string                 `protobuf:"bytes,2,opt,name=reveal_tx,json=revealTx,proto3" json:"reveal_tx,omitempty"` // Hex reveal of BuildInscription
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitRequest) Reset() {
+	*x = SubmitRequest{}
+	mi := &file_btcservice_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitRequest) ProtoMessage() {}
+
+func (x *SubmitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitRequest.ProtoReflect.Descriptor instead.
+func (*SubmitRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SubmitRequest) GetPsbt() string {
+	if x != nil {
+		return x.Psbt
+	}
+	return ""
+}
+
+func (x *SubmitRequest) GetRevealTx() string {
+	if x != nil {
+		return x.RevealTx
+	}
+	return ""
+}
+
+type SubmitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxIds         []string               `protobuf:"bytes,1,rep,name=tx_ids,json=txIds,proto3" json:"tx_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitResponse) Reset() {
+	*x = SubmitResponse{}
+	mi := &file_btcservice_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResponse) ProtoMessage() {}
+
+func (x *SubmitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResponse.ProtoReflect.Descriptor instead.
+func (*SubmitResponse) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SubmitResponse) GetTxIds() []string {
+	if x != nil {
+		return x.TxIds
+	}
+	return nil
+}
+
+type VerifyBrc20DepositRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	InscriptionId    string                 `protobuf:"bytes,1,opt,name=inscription_id,json=inscriptionId,proto3" json:"inscription_id,omitempty"`
+	TxId             string                 `protobuf:"bytes,2,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Ticker           string                 `protobuf:"bytes,3,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	From             string                 `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	To               string                 `protobuf:"bytes,5,opt,name=to,proto3" json:"to,omitempty"`
+	Amount           string                 `protobuf:"bytes,6,opt,name=amount,proto3" json:"amount,omitempty"`                                              // Decimal, e.g. 1.5
+	MinConfirmations int64                  `protobuf:"varint,7,opt,name=min_confirmations,json=minConfirmations,proto3" json:"min_confirmations,omitempty"` // Defaults to 1
+	MinBlockHeight   int64                  `protobuf:"varint,8,opt,name=min_block_height,json=minBlockHeight,proto3" json:"min_block_height,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *VerifyBrc20DepositRequest) Reset() {
+	*x = VerifyBrc20DepositRequest{}
+	mi := &file_btcservice_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyBrc20DepositRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyBrc20DepositRequest) ProtoMessage() {}
+
+func (x *VerifyBrc20DepositRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyBrc20DepositRequest.ProtoReflect.Descriptor instead.
+func (*VerifyBrc20DepositRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *VerifyBrc20DepositRequest) GetInscriptionId() string {
+	if x != nil {
+		return x.InscriptionId
+	}
+	return ""
+}
+
+func (x *VerifyBrc20DepositRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *VerifyBrc20DepositRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *VerifyBrc20DepositRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *VerifyBrc20DepositRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *VerifyBrc20DepositRequest) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *VerifyBrc20DepositRequest) GetMinConfirmations() int64 {
+	if x != nil {
+		return x.MinConfirmations
+	}
+	return 0
+}
+
+func (x *VerifyBrc20DepositRequest) GetMinBlockHeight() int64 {
+	if x != nil {
+		return x.MinBlockHeight
+	}
+	return 0
+}
+
+type Brc20Deposit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InscriptionId string                 `protobuf:"bytes,1,opt,name=inscription_id,json=inscriptionId,proto3" json:"inscription_id,omitempty"`
+	TxId          string                 `protobuf:"bytes,2,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Ticker        string                 `protobuf:"bytes,3,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	Amount        string                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"` // Base units, 18 decimals
+	From          string                 `protobuf:"bytes,5,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,6,opt,name=to,proto3" json:"to,omitempty"`
+	BlockHeight   int64                  `protobuf:"varint,7,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Brc20Deposit) Reset() {
+	*x = Brc20Deposit{}
+	mi := &file_btcservice_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Brc20Deposit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Brc20Deposit) ProtoMessage() {}
+
+func (x *Brc20Deposit) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Brc20Deposit.ProtoReflect.Descriptor instead.
+func (*Brc20Deposit) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Brc20Deposit) GetInscriptionId() string {
+	if x != nil {
+		return x.InscriptionId
+	}
+	return ""
+}
+
+func (x *Brc20Deposit) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *Brc20Deposit) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Brc20Deposit) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *Brc20Deposit) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Brc20Deposit) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Brc20Deposit) GetBlockHeight() int64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+type VerifyRunesDepositRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	RuneId        string                 `protobuf:"bytes,2,opt,name=rune_id,json=runeId,proto3" json:"rune_id,omitempty"`
+	From          string                 `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"` // Any sender is accepted when empty
+	To            string                 `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	Amount        string                 `protobuf:"bytes,5,opt,name=amount,proto3" json:"amount,omitempty"`              // Decimal with up to divisibility decimals
+	Divisibility  uint32                 `protobuf:"varint,6,opt,name=divisibility,proto3" json:"divisibility,omitempty"` // Of the rune, amount is in base units by default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRunesDepositRequest) Reset() {
+	*x = VerifyRunesDepositRequest{}
+	mi := &file_btcservice_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRunesDepositRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRunesDepositRequest) ProtoMessage() {}
+
+func (x *VerifyRunesDepositRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRunesDepositRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRunesDepositRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *VerifyRunesDepositRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *VerifyRunesDepositRequest) GetRuneId() string {
+	if x != nil {
+		return x.RuneId
+	}
+	return ""
+}
+
+func (x *VerifyRunesDepositRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *VerifyRunesDepositRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *VerifyRunesDepositRequest) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *VerifyRunesDepositRequest) GetDivisibility() uint32 {
+	if x != nil {
+		return x.Divisibility
+	}
+	return 0
+}
+
+type RunesDeposit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	RuneId        string                 `protobuf:"bytes,2,opt,name=rune_id,json=runeId,proto3" json:"rune_id,omitempty"`
+	RuneName      string                 `protobuf:"bytes,3,opt,name=rune_name,json=runeName,proto3" json:"rune_name,omitempty"`
+	Amount        string                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"` // Base units
+	Outputs       []uint32               `protobuf:"varint,5,rep,packed,name=outputs,proto3" json:"outputs,omitempty"`
+	Senders       []string               `protobuf:"bytes,6,rep,name=senders,proto3" json:"senders,omitempty"`
+	Confirmations int64                  `protobuf:"varint,7,opt,name=confirmations,proto3" json:"confirmations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunesDeposit) Reset() {
+	*x = RunesDeposit{}
+	mi := &file_btcservice_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunesDeposit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunesDeposit) ProtoMessage() {}
+
+func (x *RunesDeposit) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunesDeposit.ProtoReflect.Descriptor instead.
+func (*RunesDeposit) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RunesDeposit) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *RunesDeposit) GetRuneId() string {
+	if x != nil {
+		return x.RuneId
+	}
+	return ""
+}
+
+func (x *RunesDeposit) GetRuneName() string {
+	if x != nil {
+		return x.RuneName
+	}
+	return ""
+}
+
+func (x *RunesDeposit) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *RunesDeposit) GetOutputs() []uint32 {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *RunesDeposit) GetSenders() []string {
+	if x != nil {
+		return x.Senders
+	}
+	return nil
+}
+
+func (x *RunesDeposit) GetConfirmations() int64 {
+	if x != nil {
+		return x.Confirmations
+	}
+	return 0
+}
+
+type WatchDepositsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addresses     []string               `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`                      // Of the deposits streamed, every watched address by default
+	Assets        []string               `protobuf:"bytes,2,rep,name=assets,proto3" json:"assets,omitempty"`                            // btc, brc20 or runes, every asset by default
+	TrackedTxs    bool                   `protobuf:"varint,3,opt,name=tracked_txs,json=trackedTxs,proto3" json:"tracked_txs,omitempty"` // Stream the tx.* events of the tracked txs too
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchDepositsRequest) Reset() {
+	*x = WatchDepositsRequest{}
+	mi := &file_btcservice_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDepositsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDepositsRequest) ProtoMessage() {}
+
+func (x *WatchDepositsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDepositsRequest.ProtoReflect.Descriptor instead.
+func (*WatchDepositsRequest) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *WatchDepositsRequest) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *WatchDepositsRequest) GetAssets() []string {
+	if x != nil {
+		return x.Assets
+	}
+	return nil
+}
+
+func (x *WatchDepositsRequest) GetTrackedTxs() bool {
+	if x != nil {
+		return x.TrackedTxs
+	}
+	return false
+}
+
+// Deposit or tracked tx changing status, see watch.Event
+type DepositEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`     // The same when an event is sent again
+	Type  string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // deposit.detected, deposit.confirmed, deposit.reorged, tx.broadcast, tx.confirmed or tx.replaced
+	Time  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	// Types that are valid to be assigned to Subject:
+	//
+	//	*DepositEvent_Deposit
+	//	*DepositEvent_Tx
+	Subject       isDepositEvent_Subject `protobuf_oneof:"subject"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DepositEvent) Reset() {
+	*x = DepositEvent{}
+	mi := &file_btcservice_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DepositEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DepositEvent) ProtoMessage() {}
+
+func (x *DepositEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DepositEvent.ProtoReflect.Descriptor instead.
+func (*DepositEvent) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DepositEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DepositEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DepositEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *DepositEvent) GetSubject() isDepositEvent_Subject {
+	if x != nil {
+		return x.Subject
+	}
+	return nil
+}
+
+func (x *DepositEvent) GetDeposit() *Deposit {
+	if x != nil {
+		if x, ok := x.Subject.(*DepositEvent_Deposit); ok {
+			return x.Deposit
+		}
+	}
+	return nil
+}
+
+func (x *DepositEvent) GetTx() *TrackedTx {
+	if x != nil {
+		if x, ok := x.Subject.(*DepositEvent_Tx); ok {
+			return x.Tx
+		}
+	}
+	return nil
+}
+
+type isDepositEvent_Subject interface {
+	isDepositEvent_Subject()
+}
+
+type DepositEvent_Deposit struct {
+	Deposit *Deposit `protobuf:"bytes,4,opt,name=deposit,proto3,oneof"`
+}
+
+type DepositEvent_Tx struct {
+	Tx *TrackedTx `protobuf:"bytes,5,opt,name=tx,proto3,oneof"`
+}
+
+func (*DepositEvent_Deposit) isDepositEvent_Subject() {}
+
+func (*DepositEvent_Tx) isDepositEvent_Subject() {}
+
+type Deposit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // Asset, tx, address & token
+	Asset         string                 `protobuf:"bytes,2,opt,name=asset,proto3" json:"asset,omitempty"`
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`                          // BRC-20 ticker or rune id
+	TokenName     string                 `protobuf:"bytes,4,opt,name=token_name,json=tokenName,proto3" json:"token_name,omitempty"` // Spaced name of the rune
+	Address       string                 `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	TxId          string                 `protobuf:"bytes,6,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Outputs       []uint32               `protobuf:"varint,7,rep,packed,name=outputs,proto3" json:"outputs,omitempty"`
+	InscriptionId string                 `protobuf:"bytes,8,opt,name=inscription_id,json=inscriptionId,proto3" json:"inscription_id,omitempty"`
+	Amount        string                 `protobuf:"bytes,9,opt,name=amount,proto3" json:"amount,omitempty"` // Base units
+	Decimals      uint32                 `protobuf:"varint,10,opt,name=decimals,proto3" json:"decimals,omitempty"`
+	Senders       []string               `protobuf:"bytes,11,rep,name=senders,proto3" json:"senders,omitempty"`
+	Height        int64                  `protobuf:"varint,12,opt,name=height,proto3" json:"height,omitempty"`
+	BlockHash     string                 `protobuf:"bytes,13,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Confirmations int64                  `protobuf:"varint,14,opt,name=confirmations,proto3" json:"confirmations,omitempty"`
+	Status        string                 `protobuf:"bytes,15,opt,name=status,proto3" json:"status,omitempty"` // pending, confirmed or reorged
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Deposit) Reset() {
+	*x = Deposit{}
+	mi := &file_btcservice_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Deposit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Deposit) ProtoMessage() {}
+
+func (x *Deposit) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Deposit.ProtoReflect.Descriptor instead.
+func (*Deposit) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Deposit) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Deposit) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *Deposit) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *Deposit) GetTokenName() string {
+	if x != nil {
+		return x.TokenName
+	}
+	return ""
+}
+
+func (x *Deposit) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Deposit) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *Deposit) GetOutputs() []uint32 {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *Deposit) GetInscriptionId() string {
+	if x != nil {
+		return x.InscriptionId
+	}
+	return ""
+}
+
+func (x *Deposit) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *Deposit) GetDecimals() uint32 {
+	if x != nil {
+		return x.Decimals
+	}
+	return 0
+}
+
+func (x *Deposit) GetSenders() []string {
+	if x != nil {
+		return x.Senders
+	}
+	return nil
+}
+
+func (x *Deposit) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Deposit) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *Deposit) GetConfirmations() int64 {
+	if x != nil {
+		return x.Confirmations
+	}
+	return 0
+}
+
+func (x *Deposit) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type TrackedTx struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	Replaces      string                 `protobuf:"bytes,2,opt,name=replaces,proto3" json:"replaces,omitempty"`
+	ReplacedBy    string                 `protobuf:"bytes,3,opt,name=replaced_by,json=replacedBy,proto3" json:"replaced_by,omitempty"`
+	Inputs        []string               `protobuf:"bytes,4,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	Height        int64                  `protobuf:"varint,5,opt,name=height,proto3" json:"height,omitempty"` // 0 until mined
+	BlockHash     string                 `protobuf:"bytes,6,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Confirmations int64                  `protobuf:"varint,7,opt,name=confirmations,proto3" json:"confirmations,omitempty"`
+	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"` // pending, confirmed or replaced
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrackedTx) Reset() {
+	*x = TrackedTx{}
+	mi := &file_btcservice_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrackedTx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrackedTx) ProtoMessage() {}
+
+func (x *TrackedTx) ProtoReflect() protoreflect.Message {
+	mi := &file_btcservice_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrackedTx.ProtoReflect.Descriptor instead.
+func (*TrackedTx) Descriptor() ([]byte, []int) {
+	return file_btcservice_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TrackedTx) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *TrackedTx) GetReplaces() string {
+	if x != nil {
+		return x.Replaces
+	}
+	return ""
+}
+
+func (x *TrackedTx) GetReplacedBy() string {
+	if x != nil {
+		return x.ReplacedBy
+	}
+	return ""
+}
+
+func (x *TrackedTx) GetInputs() []string {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *TrackedTx) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *TrackedTx) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *TrackedTx) GetConfirmations() int64 {
+	if x != nil {
+		return x.Confirmations
+	}
+	return 0
+}
+
+func (x *TrackedTx) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+var File_btcservice_proto protoreflect.FileDescriptor
+
+const file_btcservice_proto_rawDesc = "" +
+	"\n" +
+	"\x10btcservice.proto\x12\rbtcservice.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x10\n" +
+	"\x0eGetFeesRequest\"\x95\x01\n" +
+	"\x04Fees\x12\x18\n" +
+	"\afastest\x18\x01 \x01(\x04R\afastest\x12\x1a\n" +
+	"\bhalfhour\x18\x02 \x01(\x04R\bhalfhour\x12\x12\n" +
+	"\x04hour\x18\x03 \x01(\x04R\x04hour\x12\x18\n" +
+	"\aeconomy\x18\x04 \x01(\x04R\aeconomy\x12)\n" +
+	"\x10default_priority\x18\x05 \x01(\tR\x0fdefaultPriority\"+\n" +
+	"\x0fGetUtxosRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"]\n" +
+	"\x04Utxo\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\x12\x12\n" +
+	"\x04vout\x18\x02 \x01(\rR\x04vout\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x04R\x05value\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\x03R\x06height\"=\n" +
+	"\x10GetUtxosResponse\x12)\n" +
+	"\x05utxos\x18\x01 \x03(\v2\x13.btcservice.v1.UtxoR\x05utxos\"J\n" +
+	"\x16GetBrc20BalanceRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06ticker\x18\x02 \x01(\tR\x06ticker\"\x9f\x01\n" +
+	"\fBrc20Balance\x12\x16\n" +
+	"\x06ticker\x18\x01 \x01(\tR\x06ticker\x12'\n" +
+	"\x0foverall_balance\x18\x02 \x01(\tR\x0eoverallBalance\x12+\n" +
+	"\x11available_balance\x18\x03 \x01(\tR\x10availableBalance\x12!\n" +
+	"\fblock_height\x18\x04 \x01(\x03R\vblockHeight\"3\n" +
+	"\x17GetRunesBalancesRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"[\n" +
+	"\vRuneBalance\x12\x17\n" +
+	"\arune_id\x18\x01 \x01(\tR\x06runeId\x12\x1b\n" +
+	"\trune_name\x18\x02 \x01(\tR\bruneName\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\"R\n" +
+	"\x18GetRunesBalancesResponse\x126\n" +
+	"\bbalances\x18\x01 \x03(\v2\x1a.btcservice.v1.RuneBalanceR\bbalances\"I\n" +
+	"\bTransfer\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x19\n" +
+	"\bfee_rate\x18\x03 \x01(\tR\afeeRate\"b\n" +
+	"\x13BuildBtcSendRequest\x123\n" +
+	"\btransfer\x18\x01 \x01(\v2\x17.btcservice.v1.TransferR\btransfer\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\"w\n" +
+	"\x19BuildBrc20TransferRequest\x123\n" +
+	"\btransfer\x18\x01 \x01(\v2\x17.btcservice.v1.TransferR\btransfer\x12%\n" +
+	"\x0einscription_id\x18\x02 \x01(\tR\rinscriptionId\"\x81\x01\n" +
+	"\x19BuildRunesTransferRequest\x123\n" +
+	"\btransfer\x18\x01 \x01(\v2\x17.btcservice.v1.TransferR\btransfer\x12\x17\n" +
+	"\arune_id\x18\x02 \x01(\tR\x06runeId\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\"J\n" +
+	"\vInscription\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\"\x8e\x01\n" +
+	"\x17BuildInscriptionRequest\x123\n" +
+	"\btransfer\x18\x01 \x01(\v2\x17.btcservice.v1.TransferR\btransfer\x12>\n" +
+	"\finscriptions\x18\x02 \x03(\v2\x1a.btcservice.v1.InscriptionR\finscriptions\"]\n" +
+	"\x04Psbt\x12\x12\n" +
+	"\x04psbt\x18\x01 \x01(\tR\x04psbt\x12\x10\n" +
+	"\x03fee\x18\x02 \x01(\x03R\x03fee\x12\x14\n" +
+	"\x05vsize\x18\x03 \x01(\x03R\x05vsize\x12\x19\n" +
+	"\bfee_rate\x18\x04 \x01(\x04R\afeeRate\"\xa6\x01\n" +
+	"\x0fInscriptionPsbt\x12+\n" +
+	"\x06commit\x18\x01 \x01(\v2\x13.btcservice.v1.PsbtR\x06commit\x12\x1b\n" +
+	"\treveal_tx\x18\x02 \x01(\tR\brevealTx\x12 \n" +
+	"\freveal_tx_id\x18\x03 \x01(\tR\n" +
+	"revealTxId\x12'\n" +
+	"\x0finscription_ids\x18\x04 \x03(\tR\x0einscriptionIds\"@\n" +
+	"\rSubmitRequest\x12\x12\n" +
+	"\x04psbt\x18\x01 \x01(\tR\x04psbt\x12\x1b\n" +
+	"\treveal_tx\x18\x02 \x01(\tR\brevealTx\"'\n" +
+	"\x0eSubmitResponse\x12\x15\n" +
+	"\x06tx_ids\x18\x01 \x03(\tR\x05txIds\"\x82\x02\n" +
+	"\x19VerifyBrc20DepositRequest\x12%\n" +
+	"\x0einscription_id\x18\x01 \x01(\tR\rinscriptionId\x12\x13\n" +
+	"\x05tx_id\x18\x02 \x01(\tR\x04txId\x12\x16\n" +
+	"\x06ticker\x18\x03 \x01(\tR\x06ticker\x12\x12\n" +
+	"\x04from\x18\x04 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x05 \x01(\tR\x02to\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\tR\x06amount\x12+\n" +
+	"\x11min_confirmations\x18\a \x01(\x03R\x10minConfirmations\x12(\n" +
+	"\x10min_block_height\x18\b \x01(\x03R\x0eminBlockHeight\"\xc1\x01\n" +
+	"\fBrc20Deposit\x12%\n" +
+	"\x0einscription_id\x18\x01 \x01(\tR\rinscriptionId\x12\x13\n" +
+	"\x05tx_id\x18\x02 \x01(\tR\x04txId\x12\x16\n" +
+	"\x06ticker\x18\x03 \x01(\tR\x06ticker\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\tR\x06amount\x12\x12\n" +
+	"\x04from\x18\x05 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x06 \x01(\tR\x02to\x12!\n" +
+	"\fblock_height\x18\a \x01(\x03R\vblockHeight\"\xa9\x01\n" +
+	"\x19VerifyRunesDepositRequest\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\x12\x17\n" +
+	"\arune_id\x18\x02 \x01(\tR\x06runeId\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x01(\tR\x02to\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\tR\x06amount\x12\"\n" +
+	"\fdivisibility\x18\x06 \x01(\rR\fdivisibility\"\xcb\x01\n" +
+	"\fRunesDeposit\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\x12\x17\n" +
+	"\arune_id\x18\x02 \x01(\tR\x06runeId\x12\x1b\n" +
+	"\trune_name\x18\x03 \x01(\tR\bruneName\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\tR\x06amount\x12\x18\n" +
+	"\aoutputs\x18\x05 \x03(\rR\aoutputs\x12\x18\n" +
+	"\asenders\x18\x06 \x03(\tR\asenders\x12$\n" +
+	"\rconfirmations\x18\a \x01(\x03R\rconfirmations\"m\n" +
+	"\x14WatchDepositsRequest\x12\x1c\n" +
+	"\taddresses\x18\x01 \x03(\tR\taddresses\x12\x16\n" +
+	"\x06assets\x18\x02 \x03(\tR\x06assets\x12\x1f\n" +
+	"\vtracked_txs\x18\x03 \x01(\bR\n" +
+	"trackedTxs\"\xcd\x01\n" +
+	"\fDepositEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x122\n" +
+	"\adeposit\x18\x04 \x01(\v2\x16.btcservice.v1.DepositH\x00R\adeposit\x12*\n" +
+	"\x02tx\x18\x05 \x01(\v2\x18.btcservice.v1.TrackedTxH\x00R\x02txB\t\n" +
+	"\asubject\"\x97\x03\n" +
+	"\aDeposit\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05asset\x18\x02 \x01(\tR\x05asset\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\x12\x1d\n" +
+	"\n" +
+	"token_name\x18\x04 \x01(\tR\ttokenName\x12\x18\n" +
+	"\aaddress\x18\x05 \x01(\tR\aaddress\x12\x13\n" +
+	"\x05tx_id\x18\x06 \x01(\tR\x04txId\x12\x18\n" +
+	"\aoutputs\x18\a \x03(\rR\aoutputs\x12%\n" +
+	"\x0einscription_id\x18\b \x01(\tR\rinscriptionId\x12\x16\n" +
+	"\x06amount\x18\t \x01(\tR\x06amount\x12\x1a\n" +
+	"\bdecimals\x18\n" +
+	" \x01(\rR\bdecimals\x12\x18\n" +
+	"\asenders\x18\v \x03(\tR\asenders\x12\x16\n" +
+	"\x06height\x18\f \x01(\x03R\x06height\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\r \x01(\tR\tblockHash\x12$\n" +
+	"\rconfirmations\x18\x0e \x01(\x03R\rconfirmations\x12\x16\n" +
+	"\x06status\x18\x0f \x01(\tR\x06status\"\xea\x01\n" +
+	"\tTrackedTx\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\x12\x1a\n" +
+	"\breplaces\x18\x02 \x01(\tR\breplaces\x12\x1f\n" +
+	"\vreplaced_by\x18\x03 \x01(\tR\n" +
+	"replacedBy\x12\x16\n" +
+	"\x06inputs\x18\x04 \x03(\tR\x06inputs\x12\x16\n" +
+	"\x06height\x18\x05 \x01(\x03R\x06height\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\x06 \x01(\tR\tblockHash\x12$\n" +
+	"\rconfirmations\x18\a \x01(\x03R\rconfirmations\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status2\xf9\a\n" +
+	"\n" +
+	"BtcService\x12=\n" +
+	"\aGetFees\x12\x1d.btcservice.v1.GetFeesRequest\x1a\x13.btcservice.v1.Fees\x12K\n" +
+	"\bGetUtxos\x12\x1e.btcservice.v1.GetUtxosRequest\x1a\x1f.btcservice.v1.GetUtxosResponse\x12U\n" +
+	"\x0fGetBrc20Balance\x12%.btcservice.v1.GetBrc20BalanceRequest\x1a\x1b.btcservice.v1.Brc20Balance\x12c\n" +
+	"\x10GetRunesBalances\x12&.btcservice.v1.GetRunesBalancesRequest\x1a'.btcservice.v1.GetRunesBalancesResponse\x12G\n" +
+	"\fBuildBtcSend\x12\".btcservice.v1.BuildBtcSendRequest\x1a\x13.btcservice.v1.Psbt\x12S\n" +
+	"\x12BuildBrc20Transfer\x12(.btcservice.v1.BuildBrc20TransferRequest\x1a\x13.btcservice.v1.Psbt\x12S\n" +
+	"\x12BuildRunesTransfer\x12(.btcservice.v1.BuildRunesTransferRequest\x1a\x13.btcservice.v1.Psbt\x12Z\n" +
+	"\x10BuildInscription\x12&.btcservice.v1.BuildInscriptionRequest\x1a\x1e.btcservice.v1.InscriptionPsbt\x12E\n" +
+	"\x06Submit\x12\x1c.btcservice.v1.SubmitRequest\x1a\x1d.btcservice.v1.SubmitResponse\x12[\n" +
+	"\x12VerifyBrc20Deposit\x12(.btcservice.v1.VerifyBrc20DepositRequest\x1a\x1b.btcservice.v1.Brc20Deposit\x12[\n" +
+	"\x12VerifyRunesDeposit\x12(.btcservice.v1.VerifyRunesDepositRequest\x1a\x1b.btcservice.v1.RunesDeposit\x12S\n" +
+	"\rWatchDeposits\x12#.btcservice.v1.WatchDepositsRequest\x1a\x1b.btcservice.v1.DepositEvent0\x01B5Z3github.com/ordinox/btc-service/grpcapi/btcservicepbb\x06proto3"
+
+var (
+	file_btcservice_proto_rawDescOnce sync.Once
+	file_btcservice_proto_rawDescData []byte
+)
+
+func file_btcservice_proto_rawDescGZIP() []byte {
+	file_btcservice_proto_rawDescOnce.Do(func() {
+		file_btcservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_btcservice_proto_rawDesc), len(file_btcservice_proto_rawDesc)))
+	})
+	return file_btcservice_proto_rawDescData
+}
+
+var file_btcservice_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
+var file_btcservice_proto_goTypes = []any{
+	(*GetFeesRequest)(nil),            // 0: btcservice.v1.GetFeesRequest
+	(*Fees)(nil),                      // 1: btcservice.v1.Fees
+	(*GetUtxosRequest)(nil),           // 2: btcservice.v1.GetUtxosRequest
+	(*Utxo)(nil),                      // 3: btcservice.v1.Utxo
+	(*GetUtxosResponse)(nil),          // 4: btcservice.v1.GetUtxosResponse
+	(*GetBrc20BalanceRequest)(nil),    // 5: btcservice.v1.GetBrc20BalanceRequest
+	(*Brc20Balance)(nil),              // 6: btcservice.v1.Brc20Balance
+	(*GetRunesBalancesRequest)(nil),   // 7: btcservice.v1.GetRunesBalancesRequest
+	(*RuneBalance)(nil),               // 8: btcservice.v1.RuneBalance
+	(*GetRunesBalancesResponse)(nil),  // 9: btcservice.v1.GetRunesBalancesResponse
+	(*Transfer)(nil),                  // 10: btcservice.v1.Transfer
+	(*BuildBtcSendRequest)(nil),       // 11: btcservice.v1.BuildBtcSendRequest
+	(*BuildBrc20TransferRequest)(nil), // 12: btcservice.v1.BuildBrc20TransferRequest
+	(*BuildRunesTransferRequest)(nil), // 13: btcservice.v1.BuildRunesTransferRequest
+	(*Inscription)(nil),               // 14: btcservice.v1.Inscription
+	(*BuildInscriptionRequest)(nil),   // 15: btcservice.v1.BuildInscriptionRequest
+	(*Psbt)(nil),                      // 16: btcservice.v1.Psbt
+	(*InscriptionPsbt)(nil),           // 17: btcservice.v1.InscriptionPsbt
+	(*SubmitRequest)(nil),             // 18: btcservice.v1.SubmitRequest
+	(*SubmitResponse)(nil),            // 19: btcservice.v1.SubmitResponse
+	(*VerifyBrc20DepositRequest)(nil), // 20: btcservice.v1.VerifyBrc20DepositRequest
+	(*Brc20Deposit)(nil),              // 21: btcservice.v1.Brc20Deposit
+	(*VerifyRunesDepositRequest)(nil), // 22: btcservice.v1.VerifyRunesDepositRequest
+	(*RunesDeposit)(nil),              // 23: btcservice.v1.RunesDeposit
+	(*WatchDepositsRequest)(nil),      // 24: btcservice.v1.WatchDepositsRequest
+	(*DepositEvent)(nil),              // 25: btcservice.v1.DepositEvent
+	(*Deposit)(nil),                   // 26: btcservice.v1.Deposit
+	(*TrackedTx)(nil),                 // 27: btcservice.v1.TrackedTx
+	(*timestamppb.Timestamp)(nil),     // 28: google.protobuf.Timestamp
+}
+var file_btcservice_proto_depIdxs = []int32{
+	3,  // 0: btcservice.v1.GetUtxosResponse.utxos:type_name -> btcservice.v1.Utxo
+	8,  // 1: btcservice.v1.GetRunesBalancesResponse.balances:type_name -> btcservice.v1.RuneBalance
+	10, // 2: btcservice.v1.BuildBtcSendRequest.transfer:type_name -> btcservice.v1.Transfer
+	10, // 3: btcservice.v1.BuildBrc20TransferRequest.transfer:type_name -> btcservice.v1.Transfer
+	10, // 4: btcservice.v1.BuildRunesTransferRequest.transfer:type_name -> btcservice.v1.Transfer
+	10, // 5: btcservice.v1.BuildInscriptionRequest.transfer:type_name -> btcservice.v1.Transfer
+	14, // 6: btcservice.v1.BuildInscriptionRequest.inscriptions:type_name -> btcservice.v1.Inscription
+	16, // 7: btcservice.v1.InscriptionPsbt.commit:type_name -> btcservice.v1.Psbt
+	28, // 8: btcservice.v1.DepositEvent.time:type_name -> google.protobuf.Timestamp
+	26, // 9: btcservice.v1.DepositEvent.deposit:type_name -> btcservice.v1.Deposit
+	27, // 10: btcservice.v1.DepositEvent.tx:type_name -> btcservice.v1.TrackedTx
+	0,  // 11: btcservice.v1.BtcService.GetFees:input_type -> btcservice.v1.GetFeesRequest
+	2,  // 12: btcservice.v1.BtcService.GetUtxos:input_type -> btcservice.v1.GetUtxosRequest
+	5,  // 13: btcservice.v1.BtcService.GetBrc20Balance:input_type -> btcservice.v1.GetBrc20BalanceRequest
+	7,  // 14: btcservice.v1.BtcService.GetRunesBalances:input_type -> btcservice.v1.GetRunesBalancesRequest
+	11, // 15: btcservice.v1.BtcService.BuildBtcSend:input_type -> btcservice.v1.BuildBtcSendRequest
+	12, // 16: btcservice.v1.BtcService.BuildBrc20Transfer:input_type -> btcservice.v1.BuildBrc20TransferRequest
+	13, // 17: btcservice.v1.BtcService.BuildRunesTransfer:input_type -> btcservice.v1.BuildRunesTransferRequest
+	15, // 18: btcservice.v1.BtcService.BuildInscription:input_type -> btcservice.v1.BuildInscriptionRequest
+	18, // 19: btcservice.v1.BtcService.Submit:input_type -> btcservice.v1.SubmitRequest
+	20, // 20: btcservice.v1.BtcService.VerifyBrc20Deposit:input_type -> btcservice.v1.VerifyBrc20DepositRequest
+	22, // 21: btcservice.v1.BtcService.VerifyRunesDeposit:input_type -> btcservice.v1.VerifyRunesDepositRequest
+	24, // 22: btcservice.v1.BtcService.WatchDeposits:input_type -> btcservice.v1.WatchDepositsRequest
+	1,  // 23: btcservice.v1.BtcService.GetFees:output_type -> btcservice.v1.Fees
+	4,  // 24: btcservice.v1.BtcService.GetUtxos:output_type -> btcservice.v1.GetUtxosResponse
+	6,  // 25: btcservice.v1.BtcService.GetBrc20Balance:output_type -> btcservice.v1.Brc20Balance
+	9,  // 26: btcservice.v1.BtcService.GetRunesBalances:output_type -> btcservice.v1.GetRunesBalancesResponse
+	16, // 27: btcservice.v1.BtcService.BuildBtcSend:output_type -> btcservice.v1.Psbt
+	16, // 28: btcservice.v1.BtcService.BuildBrc20Transfer:output_type -> btcservice.v1.Psbt
+	16, // 29: btcservice.v1.BtcService.BuildRunesTransfer:output_type -> btcservice.v1.Psbt
+	17, // 30: btcservice.v1.BtcService.BuildInscription:output_type -> btcservice.v1.InscriptionPsbt
+	19, // 31: btcservice.v1.BtcService.Submit:output_type -> btcservice.v1.SubmitResponse
+	21, // 32: btcservice.v1.BtcService.VerifyBrc20Deposit:output_type -> btcservice.v1.Brc20Deposit
+	23, // 33: btcservice.v1.BtcService.VerifyRunesDeposit:output_type -> btcservice.v1.RunesDeposit
+	25, // 34: btcservice.v1.BtcService.WatchDeposits:output_type -> btcservice.v1.DepositEvent
+	23, // [23:35] is the sub-list for method output_type
+	11, // [11:23] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_btcservice_proto_init() }
+func file_btcservice_proto_init() {
+	if File_btcservice_proto != nil {
+		return
+	}
+	file_btcservice_proto_msgTypes[25].OneofWrappers = []any{
+		(*DepositEvent_Deposit)(nil),
+		(*DepositEvent_Tx)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_btcservice_proto_rawDesc), len(file_btcservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   28,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_btcservice_proto_goTypes,
+		DependencyIndexes: file_btcservice_proto_depIdxs,
+		MessageInfos:      file_btcservice_proto_msgTypes,
+	}.Build()
+	File_btcservice_proto = out.File
+	file_btcservice_proto_goTypes = nil
+	file_btcservice_proto_depIdxs = nil
+}
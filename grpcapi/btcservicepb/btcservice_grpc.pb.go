@@ -0,0 +1,580 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: btcservice.proto
+
+// gRPC api of btc-service, for the internal services. It serves the operations of the http api
+// (api/openapi.yaml) with the same validation & errors, plus a stream of the deposit events.
+//
+// Calls need one of api.api_keys in the x-api-key metadata, or as "authorization: Bearer KEY".
+// Failed calls carry a google.rpc.ErrorInfo detail, its reason being the error code of the http api
+// (e.g. insufficient_funds) and its "field" metadata the request field which failed validation.
+//
+// Amounts of tokens are decimal strings of base units, since they don't fit in 64 bits.
+
+package btcservicepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BtcService_GetFees_FullMethodName            = "/btcservice.v1.BtcService/GetFees"
+	BtcService_GetUtxos_FullMethodName           = "/btcservice.v1.BtcService/GetUtxos"
+	BtcService_GetBrc20Balance_FullMethodName    = "/btcservice.v1.BtcService/GetBrc20Balance"
+	BtcService_GetRunesBalances_FullMethodName   = "/btcservice.v1.BtcService/GetRunesBalances"
+	BtcService_BuildBtcSend_FullMethodName       = "/btcservice.v1.BtcService/BuildBtcSend"
+	BtcService_BuildBrc20Transfer_FullMethodName = "/btcservice.v1.BtcService/BuildBrc20Transfer"
+	BtcService_BuildRunesTransfer_FullMethodName = "/btcservice.v1.BtcService/BuildRunesTransfer"
+	BtcService_BuildInscription_FullMethodName   = "/btcservice.v1.BtcService/BuildInscription"
+	BtcService_Submit_FullMethodName             = "/btcservice.v1.BtcService/Submit"
+	BtcService_VerifyBrc20Deposit_FullMethodName = "/btcservice.v1.BtcService/VerifyBrc20Deposit"
+	BtcService_VerifyRunesDeposit_FullMethodName = "/btcservice.v1.BtcService/VerifyRunesDeposit"
+	BtcService_WatchDeposits_FullMethodName      = "/btcservice.v1.BtcService/WatchDeposits"
+)
+
+// BtcServiceClient is the client API for BtcService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BtcServiceClient interface {
+	// Fee rate estimates in sats/vB, from the configured fee sources
+	GetFees(ctx context.Context, in *GetFeesRequest, opts ...grpc.CallOption) (*Fees, error)
+	// Unspent outputs of an address, including the ones holding inscriptions or runes
+	GetUtxos(ctx context.Context, in *GetUtxosRequest, opts ...grpc.CallOption) (*GetUtxosResponse, error)
+	// BRC-20 balance of an address, agreed on by the configured brc20 sources
+	GetBrc20Balance(ctx context.Context, in *GetBrc20BalanceRequest, opts ...grpc.CallOption) (*Brc20Balance, error)
+	// Runes held by an address, from the configured runes utxo source
+	GetRunesBalances(ctx context.Context, in *GetRunesBalancesRequest, opts ...grpc.CallOption) (*GetRunesBalancesResponse, error)
+	// Unsigned BTC send, the change going back to the sender
+	BuildBtcSend(ctx context.Context, in *BuildBtcSendRequest, opts ...grpc.CallOption) (*Psbt, error)
+	// Unsigned BRC-20 transfer, sending a transfer inscription in the first output
+	BuildBrc20Transfer(ctx context.Context, in *BuildBrc20TransferRequest, opts ...grpc.CallOption) (*Psbt, error)
+	// Unsigned runes transfer: change & runes left, runes sent, runestone
+	BuildRunesTransfer(ctx context.Context, in *BuildRunesTransferRequest, opts ...grpc.CallOption) (*Psbt, error)
+	// Unsigned inscription commit, along with its reveal signed by a one-time key
+	BuildInscription(ctx context.Context, in *BuildInscriptionRequest, opts ...grpc.CallOption) (*InscriptionPsbt, error)
+	// Finalize & broadcast a signed psbt, then the reveal spending it if any
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error)
+	// Verify a BRC-20 deposit against the configured brc20 sources
+	VerifyBrc20Deposit(ctx context.Context, in *VerifyBrc20DepositRequest, opts ...grpc.CallOption) (*Brc20Deposit, error)
+	// Verify a runes deposit from the transaction and the runes its inputs held
+	VerifyRunesDeposit(ctx context.Context, in *VerifyRunesDepositRequest, opts ...grpc.CallOption) (*RunesDeposit, error)
+	// Events of the deposit watcher from now on, when serve runs it (serve --watch). Events sent while
+	// no stream is open are only delivered by the watch sinks, a stream falling behind is ended with
+	// RESOURCE_EXHAUSTED
+	WatchDeposits(ctx context.Context, in *WatchDepositsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DepositEvent], error)
+}
+
+type btcServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBtcServiceClient(cc grpc.ClientConnInterface) BtcServiceClient {
+	return &btcServiceClient{cc}
+}
+
+func (c *btcServiceClient) GetFees(ctx context.Context, in *GetFeesRequest, opts ...grpc.CallOption) (*Fees, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Fees)
+	err := c.cc.Invoke(ctx, BtcService_GetFees_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) GetUtxos(ctx context.Context, in *GetUtxosRequest, opts ...grpc.CallOption) (*GetUtxosResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUtxosResponse)
+	err := c.cc.Invoke(ctx, BtcService_GetUtxos_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) GetBrc20Balance(ctx context.Context, in *GetBrc20BalanceRequest, opts ...grpc.CallOption) (*Brc20Balance, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Brc20Balance)
+	err := c.cc.Invoke(ctx, BtcService_GetBrc20Balance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) GetRunesBalances(ctx context.Context, in *GetRunesBalancesRequest, opts ...grpc.CallOption) (*GetRunesBalancesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRunesBalancesResponse)
+	err := c.cc.Invoke(ctx, BtcService_GetRunesBalances_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) BuildBtcSend(ctx context.Context, in *BuildBtcSendRequest, opts ...grpc.CallOption) (*Psbt, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Psbt)
+	err := c.cc.Invoke(ctx, BtcService_BuildBtcSend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) BuildBrc20Transfer(ctx context.Context, in *BuildBrc20TransferRequest, opts ...grpc.CallOption) (*Psbt, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Psbt)
+	err := c.cc.Invoke(ctx, BtcService_BuildBrc20Transfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) BuildRunesTransfer(ctx context.Context, in *BuildRunesTransferRequest, opts ...grpc.CallOption) (*Psbt, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Psbt)
+	err := c.cc.Invoke(ctx, BtcService_BuildRunesTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) BuildInscription(ctx context.Context, in *BuildInscriptionRequest, opts ...grpc.CallOption) (*InscriptionPsbt, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InscriptionPsbt)
+	err := c.cc.Invoke(ctx, BtcService_BuildInscription_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitResponse)
+	err := c.cc.Invoke(ctx, BtcService_Submit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) VerifyBrc20Deposit(ctx context.Context, in *VerifyBrc20DepositRequest, opts ...grpc.CallOption) (*Brc20Deposit, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Brc20Deposit)
+	err := c.cc.Invoke(ctx, BtcService_VerifyBrc20Deposit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) VerifyRunesDeposit(ctx context.Context, in *VerifyRunesDepositRequest, opts ...grpc.CallOption) (*RunesDeposit, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunesDeposit)
+	err := c.cc.Invoke(ctx, BtcService_VerifyRunesDeposit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *btcServiceClient) WatchDeposits(ctx context.Context, in *WatchDepositsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DepositEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BtcService_ServiceDesc.Streams[0], BtcService_WatchDeposits_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchDepositsRequest, DepositEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BtcService_WatchDepositsClient = grpc.ServerStreamingClient[DepositEvent]
+
+// BtcServiceServer is the server API for BtcService service.
+// All implementations must embed UnimplementedBtcServiceServer
+// for forward compatibility.
+type BtcServiceServer interface {
+	// Fee rate estimates in sats/vB, from the configured fee sources
+	GetFees(context.Context, *GetFeesRequest) (*Fees, error)
+	// Unspent outputs of an address, including the ones holding inscriptions or runes
+	GetUtxos(context.Context, *GetUtxosRequest) (*GetUtxosResponse, error)
+	// BRC-20 balance of an address, agreed on by the configured brc20 sources
+	GetBrc20Balance(context.Context, *GetBrc20BalanceRequest) (*Brc20Balance, error)
+	// Runes held by an address, from the configured runes utxo source
+	GetRunesBalances(context.Context, *GetRunesBalancesRequest) (*GetRunesBalancesResponse, error)
+	// Unsigned BTC send, the change going back to the sender
+	BuildBtcSend(context.Context, *BuildBtcSendRequest) (*Psbt, error)
+	// Unsigned BRC-20 transfer, sending a transfer inscription in the first output
+	BuildBrc20Transfer(context.Context, *BuildBrc20TransferRequest) (*Psbt, error)
+	// Unsigned runes transfer: change & runes left, runes sent, runestone
+	BuildRunesTransfer(context.Context, *BuildRunesTransferRequest) (*Psbt, error)
+	// Unsigned inscription commit, along with its reveal signed by a one-time key
+	BuildInscription(context.Context, *BuildInscriptionRequest) (*InscriptionPsbt, error)
+	// Finalize & broadcast a signed psbt, then the reveal spending it if any
+	Submit(context.Context, *SubmitRequest) (*SubmitResponse, error)
+	// Verify a BRC-20 deposit against the configured brc20 sources
+	VerifyBrc20Deposit(context.Context, *VerifyBrc20DepositRequest) (*Brc20Deposit, error)
+	// Verify a runes deposit from the transaction and the runes its inputs held
+	VerifyRunesDeposit(context.Context, *VerifyRunesDepositRequest) (*RunesDeposit, error)
+	// Events of the deposit watcher from now on, when serve runs it (serve --watch). Events sent while
+	// no stream is open are only delivered by the watch sinks, a stream falling behind is ended with
+	// RESOURCE_EXHAUSTED
+	WatchDeposits(*WatchDepositsRequest, grpc.ServerStreamingServer[DepositEvent]) error
+	mustEmbedUnimplementedBtcServiceServer()
+}
+
+// UnimplementedBtcServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBtcServiceServer struct{}
+
+func (UnimplementedBtcServiceServer) GetFees(context.Context, *GetFeesRequest) (*Fees, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFees not implemented")
+}
+func (UnimplementedBtcServiceServer) GetUtxos(context.Context, *GetUtxosRequest) (*GetUtxosResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUtxos not implemented")
+}
+func (UnimplementedBtcServiceServer) GetBrc20Balance(context.Context, *GetBrc20BalanceRequest) (*Brc20Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBrc20Balance not implemented")
+}
+func (UnimplementedBtcServiceServer) GetRunesBalances(context.Context, *GetRunesBalancesRequest) (*GetRunesBalancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRunesBalances not implemented")
+}
+func (UnimplementedBtcServiceServer) BuildBtcSend(context.Context, *BuildBtcSendRequest) (*Psbt, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildBtcSend not implemented")
+}
+func (UnimplementedBtcServiceServer) BuildBrc20Transfer(context.Context, *BuildBrc20TransferRequest) (*Psbt, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildBrc20Transfer not implemented")
+}
+func (UnimplementedBtcServiceServer) BuildRunesTransfer(context.Context, *BuildRunesTransferRequest) (*Psbt, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildRunesTransfer not implemented")
+}
+func (UnimplementedBtcServiceServer) BuildInscription(context.Context, *BuildInscriptionRequest) (*InscriptionPsbt, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildInscription not implemented")
+}
+func (UnimplementedBtcServiceServer) Submit(context.Context, *SubmitRequest) (*SubmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedBtcServiceServer) VerifyBrc20Deposit(context.Context, *VerifyBrc20DepositRequest) (*Brc20Deposit, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyBrc20Deposit not implemented")
+}
+func (UnimplementedBtcServiceServer) VerifyRunesDeposit(context.Context, *VerifyRunesDepositRequest) (*RunesDeposit, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyRunesDeposit not implemented")
+}
+func (UnimplementedBtcServiceServer) WatchDeposits(*WatchDepositsRequest, grpc.ServerStreamingServer[DepositEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDeposits not implemented")
+}
+func (UnimplementedBtcServiceServer) mustEmbedUnimplementedBtcServiceServer() {}
+func (UnimplementedBtcServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeBtcServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BtcServiceServer will
+// result in compilation errors.
+type UnsafeBtcServiceServer interface {
+	mustEmbedUnimplementedBtcServiceServer()
+}
+
+func RegisterBtcServiceServer(s grpc.ServiceRegistrar, srv BtcServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBtcServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BtcService_ServiceDesc, srv)
+}
+
+func _BtcService_GetFees_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).GetFees(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_GetFees_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).GetFees(ctx, req.(*GetFeesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_GetUtxos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUtxosRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).GetUtxos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_GetUtxos_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).GetUtxos(ctx, req.(*GetUtxosRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_GetBrc20Balance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBrc20BalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).GetBrc20Balance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_GetBrc20Balance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).GetBrc20Balance(ctx, req.(*GetBrc20BalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_GetRunesBalances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunesBalancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).GetRunesBalances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_GetRunesBalances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).GetRunesBalances(ctx, req.(*GetRunesBalancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_BuildBtcSend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BuildBtcSendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).BuildBtcSend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_BuildBtcSend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).BuildBtcSend(ctx, req.(*BuildBtcSendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_BuildBrc20Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BuildBrc20TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).BuildBrc20Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_BuildBrc20Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).BuildBrc20Transfer(ctx, req.(*BuildBrc20TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_BuildRunesTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BuildRunesTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).BuildRunesTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_BuildRunesTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).BuildRunesTransfer(ctx, req.(*BuildRunesTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_BuildInscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BuildInscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).BuildInscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_BuildInscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).BuildInscription(ctx, req.(*BuildInscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_VerifyBrc20Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyBrc20DepositRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).VerifyBrc20Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_VerifyBrc20Deposit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).VerifyBrc20Deposit(ctx, req.(*VerifyBrc20DepositRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_VerifyRunesDeposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRunesDepositRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BtcServiceServer).VerifyRunesDeposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BtcService_VerifyRunesDeposit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BtcServiceServer).VerifyRunesDeposit(ctx, req.(*VerifyRunesDepositRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BtcService_WatchDeposits_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDepositsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BtcServiceServer).WatchDeposits(m, &grpc.GenericServerStream[WatchDepositsRequest, DepositEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BtcService_WatchDepositsServer = grpc.ServerStreamingServer[DepositEvent]
+
+// BtcService_ServiceDesc is the grpc.ServiceDesc for BtcService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BtcService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "btcservice.v1.BtcService",
+	HandlerType: (*BtcServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetFees",
+			Handler:    _BtcService_GetFees_Handler,
+		},
+		{
+			MethodName: "GetUtxos",
+			Handler:    _BtcService_GetUtxos_Handler,
+		},
+		{
+			MethodName: "GetBrc20Balance",
+			Handler:    _BtcService_GetBrc20Balance_Handler,
+		},
+		{
+			MethodName: "GetRunesBalances",
+			Handler:    _BtcService_GetRunesBalances_Handler,
+		},
+		{
+			MethodName: "BuildBtcSend",
+			Handler:    _BtcService_BuildBtcSend_Handler,
+		},
+		{
+			MethodName: "BuildBrc20Transfer",
+			Handler:    _BtcService_BuildBrc20Transfer_Handler,
+		},
+		{
+			MethodName: "BuildRunesTransfer",
+			Handler:    _BtcService_BuildRunesTransfer_Handler,
+		},
+		{
+			MethodName: "BuildInscription",
+			Handler:    _BtcService_BuildInscription_Handler,
+		},
+		{
+			MethodName: "Submit",
+			Handler:    _BtcService_Submit_Handler,
+		},
+		{
+			MethodName: "VerifyBrc20Deposit",
+			Handler:    _BtcService_VerifyBrc20Deposit_Handler,
+		},
+		{
+			MethodName: "VerifyRunesDeposit",
+			Handler:    _BtcService_VerifyRunesDeposit_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDeposits",
+			Handler:       _BtcService_WatchDeposits_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "btcservice.proto",
+}
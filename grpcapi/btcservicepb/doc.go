@@ -0,0 +1,5 @@
+// Messages & gRPC stubs of btcservice.proto, the gRPC api of the service. Clients dial the server of
+// serve --grpc and call NewBtcServiceClient
+package btcservicepb
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative btcservice.proto
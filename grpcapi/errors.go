@@ -0,0 +1,72 @@
+package grpcapi
+
+import (
+	"github.com/ordinox/btc-service/api"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain of the ErrorInfo details
+const errorDomain = "btc-service"
+
+// gRPC code of every error code of the http api
+var statusCodes = map[string]codes.Code{
+	api.CodeInvalidRequest:      codes.InvalidArgument,
+	api.CodeUnsupportedAddress:  codes.InvalidArgument,
+	api.CodeNotFinalized:        codes.InvalidArgument,
+	api.CodeUnauthorized:        codes.Unauthenticated,
+	api.CodeNotFound:            codes.NotFound,
+	api.CodeInsufficientFunds:   codes.FailedPrecondition,
+	api.CodeOutputSpent:         codes.FailedPrecondition,
+	api.CodeNotConfirmed:        codes.FailedPrecondition,
+	api.CodeDepositMismatch:     codes.FailedPrecondition,
+	api.CodeTransactionRejected: codes.FailedPrecondition,
+	api.CodeRequestTooLarge:     codes.ResourceExhausted,
+	api.CodeIndexersDisagree:    codes.Unavailable,
+	api.CodeIndexerBehind:       codes.Unavailable,
+	api.CodeUpstream:            codes.Unavailable,
+	api.CodeFeeUnavailable:      codes.Unavailable,
+	api.CodeTimeout:             codes.DeadlineExceeded,
+	api.CodeInternal:            codes.Internal,
+}
+
+// Status of an error, with the error code of the http api & the field in an ErrorInfo detail.
+// Statuses are kept as they are
+func toStatus(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	e := api.ErrorOf(err)
+	code, ok := statusCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	if code == codes.Internal || code == codes.Unknown {
+		log.Error().Err(err).Str("method", method).Msg("grpc call failed")
+	}
+	info := &errdetails.ErrorInfo{Reason: e.Code, Domain: errorDomain}
+	if e.Field != "" {
+		info.Metadata = map[string]string{"field": e.Field}
+	}
+	st, detailsErr := status.New(code, e.Error()).WithDetails(info)
+	if detailsErr != nil {
+		return status.Error(code, e.Error())
+	}
+	return st.Err()
+}
+
+func unauthenticated() error {
+	return toStatus("", &api.Error{Code: api.CodeUnauthorized, Message: "missing or invalid api key"})
+}
+
+// Error code of the http api of a failed call, empty when the status has none
+func ErrorCode(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == errorDomain {
+			return info.Reason
+		}
+	}
+	return ""
+}
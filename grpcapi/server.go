@@ -0,0 +1,358 @@
+package grpcapi
+
+// gRPC api of the service, for the internal services. The operations are the ones of the http api,
+// with the same validation, api keys & timeout, plus the WatchDeposits stream of the deposit watcher
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/ordinox/btc-service/api"
+	pb "github.com/ordinox/btc-service/grpcapi/btcservicepb"
+	"github.com/ordinox/btc-service/watch"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Events a WatchDeposits stream can fall behind by before it is ended
+const watchBuffer = 256
+
+type Server struct {
+	pb.UnimplementedBtcServiceServer
+	Api  *api.Server // Operations, api keys & timeout
+	Feed *watch.Feed // Events of WatchDeposits, nil when the watcher doesn't run in the process
+}
+
+var _ pb.BtcServiceServer = &Server{}
+
+func New(a *api.Server, feed *watch.Feed) *Server {
+	return &Server{Api: a, Feed: feed}
+}
+
+// gRPC server of the service, checking the api keys & logging the calls
+func (s *Server) GrpcServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(s.unary), grpc.ChainStreamInterceptor(s.stream))
+	srv := grpc.NewServer(opts...)
+	pb.RegisterBtcServiceServer(srv, s)
+	return srv
+}
+
+// Serve on the listener until the context is done, letting the calls in flight finish
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	srv := s.GrpcServer()
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		// Streams only end with their client
+		select {
+		case <-stopped:
+		case <-time.After(s.Api.Timeout):
+			srv.Stop()
+		}
+	}()
+	log.Info().Str("addr", lis.Addr().String()).Msg("grpc api listening")
+	if err := srv.Serve(lis); !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, lis)
+}
+
+func (s *Server) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	start := time.Now()
+	defer func() {
+		log.Info().Str("method", info.FullMethod).Str("code", status.Code(err).String()).Dur("took", time.Since(start)).Msg("grpc call")
+	}()
+	if !s.authorized(ctx) {
+		return nil, unauthenticated()
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Api.Timeout)
+	defer cancel()
+	res, err = handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(info.FullMethod, err)
+	}
+	return res, nil
+}
+
+func (s *Server) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		log.Info().Str("method", info.FullMethod).Str("code", status.Code(err).String()).Dur("took", time.Since(start)).Msg("grpc stream")
+	}()
+	if !s.authorized(ss.Context()) {
+		return unauthenticated()
+	}
+	if err = handler(srv, ss); err != nil {
+		return toStatus(info.FullMethod, err)
+	}
+	return nil
+}
+
+// Api key of the x-api-key metadata or of a bearer token
+func (s *Server) authorized(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var key string
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		key = keys[0]
+	} else if auth := md.Get("authorization"); len(auth) > 0 {
+		if bearer, ok := strings.CutPrefix(auth[0], "Bearer "); ok {
+			key = strings.TrimSpace(bearer)
+		}
+	}
+	return s.Api.ValidKey(key)
+}
+
+func (s *Server) GetFees(ctx context.Context, req *pb.GetFeesRequest) (*pb.Fees, error) {
+	fees, err := s.Api.FeeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Fees{Fastest: fees.Fastest, Halfhour: fees.HalfHour, Hour: fees.Hour, Economy: fees.Economy, DefaultPriority: fees.Default}, nil
+}
+
+func (s *Server) GetUtxos(ctx context.Context, req *pb.GetUtxosRequest) (*pb.GetUtxosResponse, error) {
+	utxos, err := s.Api.Utxos(ctx, req.Address)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetUtxosResponse{Utxos: make([]*pb.Utxo, len(utxos))}
+	for i, u := range utxos {
+		res.Utxos[i] = &pb.Utxo{TxId: u.TxId, Vout: u.Vout, Value: u.Value, Height: int64(u.Height)}
+	}
+	return res, nil
+}
+
+func (s *Server) GetBrc20Balance(ctx context.Context, req *pb.GetBrc20BalanceRequest) (*pb.Brc20Balance, error) {
+	balance, err := s.Api.Brc20Balance(ctx, req.Address, req.Ticker)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Brc20Balance{
+		Ticker:           balance.Ticker,
+		OverallBalance:   balance.OverallBalance,
+		AvailableBalance: balance.AvailableBalance,
+		BlockHeight:      int64(balance.BlockHeight),
+	}, nil
+}
+
+func (s *Server) GetRunesBalances(ctx context.Context, req *pb.GetRunesBalancesRequest) (*pb.GetRunesBalancesResponse, error) {
+	balances, err := s.Api.RunesBalances(ctx, req.Address)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetRunesBalancesResponse{Balances: make([]*pb.RuneBalance, len(balances))}
+	for i, b := range balances {
+		res.Balances[i] = &pb.RuneBalance{RuneId: b.RuneId, RuneName: b.RuneName, Amount: b.Amount.Int().String()}
+	}
+	return res, nil
+}
+
+func transfer(t *pb.Transfer) api.Transfer {
+	return api.Transfer{From: t.GetFrom(), To: t.GetTo(), FeeRate: api.FeeRate(t.GetFeeRate())}
+}
+
+func toPsbt(p *api.PsbtResponse) *pb.Psbt {
+	return &pb.Psbt{Psbt: p.Psbt, Fee: p.Fee, Vsize: p.VSize, FeeRate: p.FeeRate}
+}
+
+func (s *Server) BuildBtcSend(ctx context.Context, req *pb.BuildBtcSendRequest) (*pb.Psbt, error) {
+	res, err := s.Api.BuildBtcSend(ctx, api.BtcSendRequest{Transfer: transfer(req.Transfer), Amount: req.Amount})
+	if err != nil {
+		return nil, err
+	}
+	return toPsbt(res), nil
+}
+
+func (s *Server) BuildBrc20Transfer(ctx context.Context, req *pb.BuildBrc20TransferRequest) (*pb.Psbt, error) {
+	res, err := s.Api.BuildBrc20Transfer(ctx, api.Brc20TransferRequest{Transfer: transfer(req.Transfer), InscriptionId: req.InscriptionId})
+	if err != nil {
+		return nil, err
+	}
+	return toPsbt(res), nil
+}
+
+func (s *Server) BuildRunesTransfer(ctx context.Context, req *pb.BuildRunesTransferRequest) (*pb.Psbt, error) {
+	res, err := s.Api.BuildRunesTransfer(ctx, api.RunesTransferRequest{Transfer: transfer(req.Transfer), RuneId: req.RuneId, Amount: req.Amount})
+	if err != nil {
+		return nil, err
+	}
+	return toPsbt(res), nil
+}
+
+func (s *Server) BuildInscription(ctx context.Context, req *pb.BuildInscriptionRequest) (*pb.InscriptionPsbt, error) {
+	r := api.InscriptionRequest{Transfer: transfer(req.Transfer), Inscriptions: make([]api.Inscription, len(req.Inscriptions))}
+	for i, ins := range req.Inscriptions {
+		// Content as it is, whatever its encoding
+		r.Inscriptions[i] = api.Inscription{ContentType: ins.ContentType, ContentBase64: base64.StdEncoding.EncodeToString(ins.Content)}
+	}
+	res, err := s.Api.BuildInscription(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.InscriptionPsbt{Commit: toPsbt(&res.PsbtResponse), RevealTx: res.RevealTx, RevealTxId: res.RevealTxId, InscriptionIds: res.InscriptionIds}, nil
+}
+
+func (s *Server) Submit(ctx context.Context, req *pb.SubmitRequest) (*pb.SubmitResponse, error) {
+	res, err := s.Api.Submit(ctx, api.SubmitRequest{Psbt: req.Psbt, RevealTx: req.RevealTx})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SubmitResponse{TxIds: res.TxIds}, nil
+}
+
+func (s *Server) VerifyBrc20Deposit(ctx context.Context, req *pb.VerifyBrc20DepositRequest) (*pb.Brc20Deposit, error) {
+	res, err := s.Api.VerifyBrc20Deposit(ctx, api.Brc20DepositRequest{
+		InscriptionId:    req.InscriptionId,
+		TxId:             req.TxId,
+		Ticker:           req.Ticker,
+		From:             req.From,
+		To:               req.To,
+		Amount:           req.Amount,
+		MinConfirmations: req.MinConfirmations,
+		MinBlockHeight:   req.MinBlockHeight,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Brc20Deposit{
+		InscriptionId: res.InscriptionId,
+		TxId:          res.TxId,
+		Ticker:        res.Ticker,
+		Amount:        res.Amount.Int().String(),
+		From:          res.From,
+		To:            res.To,
+		BlockHeight:   res.BlockHeight,
+	}, nil
+}
+
+func (s *Server) VerifyRunesDeposit(ctx context.Context, req *pb.VerifyRunesDepositRequest) (*pb.RunesDeposit, error) {
+	if req.Divisibility > 38 {
+		return nil, &api.Error{Code: api.CodeInvalidRequest, Message: "should be at most 38", Field: "divisibility"}
+	}
+	res, err := s.Api.VerifyRunesDeposit(ctx, api.RunesDepositRequest{
+		TxId:         req.TxId,
+		RuneId:       req.RuneId,
+		From:         req.From,
+		To:           req.To,
+		Amount:       req.Amount,
+		Divisibility: uint8(req.Divisibility),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RunesDeposit{
+		TxId:          res.TxId,
+		RuneId:        res.RuneId,
+		RuneName:      res.RuneName,
+		Amount:        res.Amount.Int().String(),
+		Outputs:       res.Outputs,
+		Senders:       res.Senders,
+		Confirmations: res.Confirmations,
+	}, nil
+}
+
+func (s *Server) WatchDeposits(req *pb.WatchDepositsRequest, stream pb.BtcService_WatchDepositsServer) error {
+	if s.Feed == nil {
+		return status.Error(codes.FailedPrecondition, "the deposit watcher doesn't run in this server, see serve --watch")
+	}
+	addresses := make(map[string]bool, len(req.Addresses))
+	for _, a := range req.Addresses {
+		addr, err := btcutil.DecodeAddress(a, s.Api.Params)
+		if err != nil || !addr.IsForNet(s.Api.Params) {
+			return &api.Error{Code: api.CodeInvalidRequest, Message: a + " isn't a " + s.Api.Params.Name + " address", Field: "addresses"}
+		}
+		addresses[addr.EncodeAddress()] = true
+	}
+	assets := make(map[watch.Asset]bool, len(req.Assets))
+	for _, a := range req.Assets {
+		switch asset := watch.Asset(a); asset {
+		case watch.AssetBtc, watch.AssetBrc20, watch.AssetRunes:
+			assets[asset] = true
+		default:
+			return &api.Error{Code: api.CodeInvalidRequest, Message: a + " should be btc, brc20 or runes", Field: "assets"}
+		}
+	}
+
+	events, cancel := s.Feed.Subscribe(watchBuffer)
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "the stream fell behind the deposit events")
+			}
+			switch {
+			case event.Deposit != nil:
+				if len(addresses) > 0 && !addresses[event.Deposit.Address] || len(assets) > 0 && !assets[event.Deposit.Asset] {
+					continue
+				}
+			case event.Tx != nil:
+				if !req.TrackedTxs {
+					continue
+				}
+			}
+			if err := stream.Send(depositEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func depositEvent(e watch.Event) *pb.DepositEvent {
+	res := &pb.DepositEvent{Id: e.Id, Type: string(e.Type), Time: timestamppb.New(e.Time)}
+	if d := e.Deposit; d != nil {
+		res.Subject = &pb.DepositEvent_Deposit{Deposit: &pb.Deposit{
+			Id:            d.Id,
+			Asset:         string(d.Asset),
+			Token:         d.Token,
+			TokenName:     d.TokenName,
+			Address:       d.Address,
+			TxId:          d.TxId,
+			Outputs:       d.Outputs,
+			InscriptionId: d.InscriptionId,
+			Amount:        d.Amount.Int().String(),
+			Decimals:      uint32(d.Decimals),
+			Senders:       d.Senders,
+			Height:        d.Height,
+			BlockHash:     d.BlockHash,
+			Confirmations: d.Confirmations,
+			Status:        string(d.Status),
+		}}
+	}
+	if tx := e.Tx; tx != nil {
+		res.Subject = &pb.DepositEvent_Tx{Tx: &pb.TrackedTx{
+			TxId:          tx.TxId,
+			Replaces:      tx.Replaces,
+			ReplacedBy:    tx.ReplacedBy,
+			Inputs:        tx.Inputs,
+			Height:        tx.Height,
+			BlockHash:     tx.BlockHash,
+			Confirmations: tx.Confirmations,
+			Status:        string(tx.Status),
+		}}
+	}
+	return res
+}
@@ -0,0 +1,278 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ordinox/btc-service/api"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	pb "github.com/ordinox/btc-service/grpcapi/btcservicepb"
+	"github.com/ordinox/btc-service/internal/testutil"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/txbuilder"
+	"github.com/ordinox/btc-service/watch"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const testKey = "0123456789abcdef0123"
+
+type fixture struct {
+	server *Server
+	client pb.BtcServiceClient
+	ctx    context.Context // With the api key
+	from   string
+	to     string
+}
+
+// Server & client connected over bufconn
+func newFixture(t *testing.T, feed *watch.Feed) *fixture {
+	// One output of 20000 sats to the sender
+	w := testutil.NewWallet(t)
+	w.Chain.SendErr = &btcjson.RPCError{Code: btcjson.ErrRPCVerifyRejected, Message: "bad-txns"}
+	w.Fund(t, 20000)
+	// And 546 sats holding runes
+	held := testutil.Runes{
+		{Outpoint: w.AddOutput(t, 546).String(), Runes: []client.RuneBalance{{RuneId: "840000:3", RuneName: "DOG•GO•TO•THE•MOON", Amount: common.NewTokenAmountFromInt64(300, 0)}}},
+	}
+
+	cfg := config.Config{BtcConfig: config.BtcConfig{ChainConfig: string(config.NetworkRegtest)}}
+	s := New(&api.Server{
+		Builder:       &txbuilder.Builder{Chain: w.Chain, Utxos: w.Utxos, Runes: held, Params: &chaincfg.RegressionNetParams, Config: cfg},
+		Fees:          client.StaticFeeEstimator(3),
+		Brc20:         testutil.Brc20{"ordi": {OverallBalance: "1500000000000000000", AvailableBalance: "500000000000000000", BlockHeight: 100}},
+		Brc20Deposits: testutil.Brc20Deposits{BlockHeight: 90},
+		RunesDeposits: testutil.RunesDeposits{Err: fmt.Errorf("%w: 0 of 1", runes.ErrNotEnoughConfirmations)},
+		Params:        &chaincfg.RegressionNetParams,
+		ApiKeys:       []string{testKey},
+		Timeout:       5 * time.Second,
+		FeePriority:   client.FeePriorityHalfHour,
+	}, feed)
+
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, lis) }()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		require.NoError(t, <-served)
+	})
+	return &fixture{
+		server: s,
+		client: pb.NewBtcServiceClient(conn),
+		ctx:    metadata.AppendToOutgoingContext(context.Background(), "x-api-key", testKey),
+		from:   w.From.EncodeAddress(),
+		to:     w.To.EncodeAddress(),
+	}
+}
+
+// Code, api error code & field of a failed call
+func requireStatus(t *testing.T, err error, code codes.Code, errorCode, field string) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, code, status.Code(err), err.Error())
+	require.Equal(t, errorCode, ErrorCode(err))
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			require.Equal(t, field, info.Metadata["field"])
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	f := newFixture(t, nil)
+
+	_, err := f.client.GetFees(context.Background(), &pb.GetFeesRequest{})
+	requireStatus(t, err, codes.Unauthenticated, api.CodeUnauthorized, "")
+
+	_, err = f.client.GetFees(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testKey+"x"), &pb.GetFeesRequest{})
+	requireStatus(t, err, codes.Unauthenticated, api.CodeUnauthorized, "")
+
+	fees, err := f.client.GetFees(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testKey), &pb.GetFeesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), fees.Halfhour)
+	require.Equal(t, "halfhour", fees.DefaultPriority)
+
+	stream, err := f.client.WatchDeposits(context.Background(), &pb.WatchDepositsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireStatus(t, err, codes.Unauthenticated, api.CodeUnauthorized, "")
+}
+
+func TestQueries(t *testing.T) {
+	f := newFixture(t, nil)
+
+	utxos, err := f.client.GetUtxos(f.ctx, &pb.GetUtxosRequest{Address: f.from})
+	require.NoError(t, err)
+	require.Len(t, utxos.Utxos, 1)
+	require.Equal(t, uint64(20000), utxos.Utxos[0].Value)
+
+	_, err = f.client.GetUtxos(f.ctx, &pb.GetUtxosRequest{Address: "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"})
+	requireStatus(t, err, codes.InvalidArgument, api.CodeInvalidRequest, "address")
+
+	balance, err := f.client.GetBrc20Balance(f.ctx, &pb.GetBrc20BalanceRequest{Address: f.from, Ticker: "ORDI"})
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&pb.Brc20Balance{Ticker: "ordi", OverallBalance: "1500000000000000000", AvailableBalance: "500000000000000000", BlockHeight: 100}, balance), balance.String())
+
+	_, err = f.client.GetBrc20Balance(f.ctx, &pb.GetBrc20BalanceRequest{Address: f.from, Ticker: "sats"})
+	requireStatus(t, err, codes.NotFound, api.CodeNotFound, "")
+
+	runesBalances, err := f.client.GetRunesBalances(f.ctx, &pb.GetRunesBalancesRequest{Address: f.from})
+	require.NoError(t, err)
+	require.Len(t, runesBalances.Balances, 1)
+	require.Equal(t, "300", runesBalances.Balances[0].Amount)
+
+	deposit, err := f.client.VerifyBrc20Deposit(f.ctx, &pb.VerifyBrc20DepositRequest{
+		InscriptionId: chainhash.Hash{5}.String() + "i0",
+		TxId:          chainhash.Hash{6}.String(),
+		Ticker:        "ordi",
+		From:          f.from,
+		To:            f.to,
+		Amount:        "1.5",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1500000000000000000", deposit.Amount)
+	require.Equal(t, int64(90), deposit.BlockHeight)
+
+	_, err = f.client.VerifyRunesDeposit(f.ctx, &pb.VerifyRunesDepositRequest{TxId: chainhash.Hash{6}.String(), RuneId: "840000:3", To: f.to, Amount: "10"})
+	requireStatus(t, err, codes.FailedPrecondition, api.CodeNotConfirmed, "")
+}
+
+func TestBuild(t *testing.T) {
+	f := newFixture(t, nil)
+
+	res, err := f.client.BuildBtcSend(f.ctx, &pb.BuildBtcSendRequest{Transfer: &pb.Transfer{From: f.from, To: f.to, FeeRate: "5"}, Amount: 10000})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), res.FeeRate)
+	require.Equal(t, 5*res.Vsize, res.Fee)
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(res.Psbt), true)
+	require.NoError(t, err)
+	require.Len(t, packet.UnsignedTx.TxIn, 1)
+	require.Equal(t, int64(10000), packet.UnsignedTx.TxOut[0].Value)
+
+	_, err = f.client.BuildBtcSend(f.ctx, &pb.BuildBtcSendRequest{Transfer: &pb.Transfer{From: f.from, To: f.to}, Amount: 30000})
+	requireStatus(t, err, codes.FailedPrecondition, api.CodeInsufficientFunds, "")
+
+	_, err = f.client.BuildBtcSend(f.ctx, &pb.BuildBtcSendRequest{Amount: 1000})
+	requireStatus(t, err, codes.InvalidArgument, api.CodeInvalidRequest, "from")
+
+	runesRes, err := f.client.BuildRunesTransfer(f.ctx, &pb.BuildRunesTransferRequest{Transfer: &pb.Transfer{From: f.from, To: f.to}, RuneId: "840000:3", Amount: "100"})
+	require.NoError(t, err)
+	packet, err = psbt.NewFromRawBytes(strings.NewReader(runesRes.Psbt), true)
+	require.NoError(t, err)
+	require.Len(t, packet.UnsignedTx.TxIn, 2)
+	require.Equal(t, int64(546), packet.UnsignedTx.TxOut[1].Value)
+
+	ins, err := f.client.BuildInscription(f.ctx, &pb.BuildInscriptionRequest{
+		Transfer:     &pb.Transfer{From: f.from, To: f.from},
+		Inscriptions: []*pb.Inscription{{ContentType: "image/png", Content: []byte{0x89, 'P', 'N', 'G', 0xff}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{ins.RevealTxId + "i0"}, ins.InscriptionIds)
+	require.NotEmpty(t, ins.Commit.Psbt)
+
+	_, err = f.client.BuildInscription(f.ctx, &pb.BuildInscriptionRequest{
+		Transfer:     &pb.Transfer{From: f.from, To: f.from},
+		Inscriptions: []*pb.Inscription{{ContentType: "text/plain"}},
+	})
+	requireStatus(t, err, codes.InvalidArgument, api.CodeInvalidRequest, "inscriptions[0].content")
+
+	// The psbt isn't signed
+	_, err = f.client.Submit(f.ctx, &pb.SubmitRequest{Psbt: res.Psbt})
+	requireStatus(t, err, codes.InvalidArgument, api.CodeNotFinalized, "")
+}
+
+func TestWatchDeposits(t *testing.T) {
+	f := newFixture(t, nil)
+	stream, err := f.client.WatchDeposits(f.ctx, &pb.WatchDepositsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	feed := watch.NewFeed()
+	f = newFixture(t, feed)
+	ctx, cancel := context.WithCancel(f.ctx)
+	defer cancel()
+	stream, err = f.client.WatchDeposits(ctx, &pb.WatchDepositsRequest{Addresses: []string{f.to}, Assets: []string{"btc"}, TrackedTxs: true})
+	require.NoError(t, err)
+
+	// Events are only streamed once the server handles the call
+	ready := make(chan struct{})
+	pinged := make(chan struct{})
+	go func() {
+		defer close(pinged)
+		for {
+			_ = feed.Send(context.Background(), watch.Event{Id: "ready", Type: watch.EventTxBroadcast, Tx: &watch.Tx{}})
+			select {
+			case <-ready:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}()
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "ready", event.Id)
+	close(ready)
+	<-pinged
+
+	deposit := func(id string, asset watch.Asset, address string) watch.Event {
+		return watch.Event{Id: id, Type: watch.EventDetected, Time: time.Unix(1700000000, 0), Deposit: &watch.Deposit{
+			Id: id, Asset: asset, Address: address, TxId: chainhash.Hash{7}.String(), Outputs: []uint32{1},
+			Amount: common.NewTokenAmountFromInt64(15000, 0), Height: 100, Confirmations: 1, Status: watch.StatusPending,
+		}}
+	}
+	for _, e := range []watch.Event{
+		deposit("other address", watch.AssetBtc, f.from),
+		deposit("other asset", watch.AssetRunes, f.to),
+		{Id: "tracked tx", Type: watch.EventTxConfirmed, Tx: &watch.Tx{TxId: chainhash.Hash{8}.String(), Status: watch.StatusConfirmed}},
+		deposit("watched", watch.AssetBtc, f.to),
+	} {
+		require.NoError(t, feed.Send(context.Background(), e))
+	}
+	var received []*pb.DepositEvent
+	for len(received) < 2 {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		if event.Id != "ready" {
+			received = append(received, event)
+		}
+	}
+	require.Equal(t, "tracked tx", received[0].Id)
+	require.Equal(t, "confirmed", received[0].GetTx().Status)
+	event = received[1]
+	require.Equal(t, "watched", event.Id)
+	require.Equal(t, "deposit.detected", event.Type)
+	require.Equal(t, int64(1700000000), event.Time.AsTime().Unix())
+	require.Equal(t, "15000", event.GetDeposit().Amount)
+	require.Equal(t, []uint32{1}, event.GetDeposit().Outputs)
+	require.Equal(t, "pending", event.GetDeposit().Status)
+
+	// Invalid filters
+	stream, err = f.client.WatchDeposits(f.ctx, &pb.WatchDepositsRequest{Assets: []string{"eth"}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireStatus(t, err, codes.InvalidArgument, api.CodeInvalidRequest, "assets")
+}
@@ -0,0 +1,99 @@
+package inscriptions
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/btc"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/taproot"
+)
+
+// Size of a schnorr signature with SigHashDefault
+const schnorrSigLen = 64
+
+// Placeholder signature used while estimating the size of taproot spends
+var dummySchnorrSig = bytes.Repeat([]byte{0x00}, schnorrSigLen)
+
+// Values of the commit outputs. Every inscription gets exactly the postage, the last one also carries the reveal fee.
+// This keeps the first sat of each reveal input at the start of its reveal output
+func commitOutputValues(count int, postage, revealFee int64) []int64 {
+	values := make([]int64, count)
+	for i := range values {
+		values[i] = postage
+	}
+	values[count-1] += revealFee
+	return values
+}
+
+// Build the reveal tx spending every commit output through its inscription script path.
+// Input i carries inscription i and pays postage to output i.
+// The reveal is left unsigned if privateKey is nil, with placeholder signatures of the right size
+func buildRevealTx(commitHash *chainhash.Hash, commitTxOuts []*wire.TxOut, metas []*taproot.P2TRMetadata, receiverPkScript []byte, postage int64, privateKey *btcec.PrivateKey) (*wire.MsgTx, error) {
+	revealTx := btc.NewMsgTx(int32(btc.TxVersion))
+	revealTxOutputFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i := range metas {
+		commitOutpoint := btc.NewOutPoint(commitHash, uint32(i))
+		revealTx.AddTxIn(common.NewRbfTxIn(commitOutpoint, nil, nil))
+		revealTx.AddTxOut(btc.NewTxOut(postage, receiverPkScript))
+		revealTxOutputFetcher.AddPrevOut(*commitOutpoint, commitTxOuts[i])
+	}
+
+	sigHashes := txscript.NewTxSigHashes(revealTx, revealTxOutputFetcher)
+	for i, meta := range metas {
+		signature := dummySchnorrSig
+		if privateKey != nil {
+			witnessArray, err := txscript.CalcTapscriptSignaturehash(
+				sigHashes,
+				txscript.SigHashDefault,
+				revealTx,
+				i,
+				revealTxOutputFetcher,
+				txscript.NewBaseTapLeaf(meta.LockScript),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error constructing witness array, %s", err.Error())
+			}
+
+			sig, err := schnorr.Sign(privateKey, witnessArray)
+			if err != nil {
+				return nil, fmt.Errorf("error signing witness array, %s", err.Error())
+			}
+			signature = sig.Serialize()
+		}
+
+		revealTx.TxIn[i].Witness = wire.TxWitness{
+			signature,
+			meta.LockScript,
+			meta.ControlBlockWitness,
+		}
+	}
+	return revealTx, nil
+}
+
+// Virtual size of the reveal tx, computed from the real witnesses (signature, lock script & control block)
+func EstimateRevealVSize(metas []*taproot.P2TRMetadata, receiverPkScript []byte, postage int64) (int64, error) {
+	commitTxOuts := make([]*wire.TxOut, len(metas))
+	for i, meta := range metas {
+		commitTxOuts[i] = btc.NewTxOut(0, meta.PkScript)
+	}
+	revealTx, err := buildRevealTx(&chainhash.Hash{}, commitTxOuts, metas, receiverPkScript, postage, nil)
+	if err != nil {
+		return 0, err
+	}
+	return btc.GetTxVSize(btc.NewTx(revealTx)), nil
+}
+
+// Virtual size of a tx whose inputs are all P2TR key path spends
+func estimateKeySpendVSize(tx *wire.MsgTx) int64 {
+	sizeTx := tx.Copy()
+	for i := range sizeTx.TxIn {
+		sizeTx.TxIn[i].Witness = wire.TxWitness{dummySchnorrSig}
+	}
+	return btc.GetTxVSize(btc.NewTx(sizeTx))
+}
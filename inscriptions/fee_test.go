@@ -0,0 +1,48 @@
+package inscriptions
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/btc"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/taproot"
+	"github.com/stretchr/testify/require"
+)
+
+// The estimated reveal size should match the size of the signed reveal tx
+func TestRevealVSize(t *testing.T) {
+	cfg := config.Config{BtcConfig: config.BtcConfig{ChainConfig: string(config.NetworkRegtest)}}
+	pk := common.LoadPrivateKey(PK_HEX)
+	data := taproot.NewInscriptionData(`{"p":"brc-20","op":"transfer","tick":"opiz","amt":"100"}`, taproot.ContentTypeText)
+	meta, err := taproot.CreateP2TRInscriptionMetaData(data, pk.PubKey(), cfg)
+	require.NoError(t, err)
+
+	receiver, err := btc.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(pk.PubKey())), cfg.BtcConfig.GetChainConfigParams())
+	require.NoError(t, err)
+	receiverPkScript, err := btc.PayToAddrScript(receiver)
+	require.NoError(t, err)
+
+	for _, count := range []int{1, 3} {
+		metas := make([]*taproot.P2TRMetadata, count)
+		for i := range metas {
+			metas[i] = meta
+		}
+		estimated, err := EstimateRevealVSize(metas, receiverPkScript, 546)
+		require.NoError(t, err)
+
+		commitHash := chainhash.DoubleHashH([]byte("commit"))
+		commitTxOuts := make([]*wire.TxOut, count)
+		for i, v := range commitOutputValues(count, 546, estimated*10) {
+			commitTxOuts[i] = btc.NewTxOut(v, meta.PkScript)
+		}
+		revealTx, err := buildRevealTx(&commitHash, commitTxOuts, metas, receiverPkScript, 546, pk)
+		require.NoError(t, err)
+
+		require.Equal(t, btc.GetTxVSize(btc.NewTx(revealTx)), estimated)
+	}
+}
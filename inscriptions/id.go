@@ -0,0 +1,30 @@
+package inscriptions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var ErrInvalidInscriptionId = errors.New("invalid inscription id")
+
+// Outpoint an inscription was revealed into. Both backends put the nth inscription of a reveal in output n
+func GenesisOutPoint(inscriptionId string) (*wire.OutPoint, error) {
+	txId, index, ok := strings.Cut(inscriptionId, "i")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInscriptionId, inscriptionId)
+	}
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil || len(txId) != chainhash.MaxHashStringSize {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInscriptionId, inscriptionId)
+	}
+	vout, err := strconv.ParseUint(index, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInscriptionId, inscriptionId)
+	}
+	return wire.NewOutPoint(hash, uint32(vout)), nil
+}
@@ -1,43 +1,124 @@
 package inscriptions
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
-	"github.com/btcsuite/btcd/wire"
 	"github.com/ordinox/btc-service/btc"
 	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/taproot"
+	"github.com/rs/zerolog/log"
 )
 
-var defaultSequenceNum = btc.MaxTxInSequenceNum - 10
+// Change below this value is not worth an output and is added to the commit fee instead
+const (
+	minChangeValue = 546
 
+	// How long the reveal waits for the commit to reach the mempool
+	commitWaitTimeout = time.Minute
+)
+
+// Inscribes by building, signing and broadcasting the commit & reveal txs itself.
+// The commit is funded from the P2TR (key path) address of the request's private key
+type NativeInscriber struct {
+	config config.Config
+}
+
+func NewNativeInscriber(config config.Config) NativeInscriber {
+	return NativeInscriber{config: config}
+}
+
+// Inscribe a single inscription into the receiver
 func InscribeNative(
 	receiver btcutil.Address,
 	privateKey *btcec.PrivateKey,
 	inscriptionData taproot.InscriptionData,
 	feeRate uint64,
 	config config.Config,
-) (*SingleInscriptionResult, error) {
+) (*InscriptionResult, error) {
+	return NewNativeInscriber(config).Inscribe(InscribeRequest{
+		Inscriptions: []taproot.InscriptionData{inscriptionData},
+		Destination:  receiver,
+		PrivateKey:   privateKey,
+		FeeRate:      feeRate,
+	})
+}
+
+// Inscribe all the inscriptions with one commit & one reveal.
+// The commit has one output per inscription, the reveal spends them all and has one postage output per inscription
+func (n NativeInscriber) Inscribe(req InscribeRequest) (*InscriptionResult, error) {
+	if len(req.Inscriptions) == 0 {
+		return nil, ErrNoInscriptions
+	}
+	if req.PrivateKey == nil {
+		return nil, fmt.Errorf("native inscriber: %w", ErrPrivateKeyUnset)
+	}
+	var (
+		config        = n.config
+		privateKey    = req.PrivateKey
+		feeRate       = req.FeeRate
+		postage       = config.InscriptionConfig.GetPostage()
+		revealFeeRate = config.InscriptionConfig.GetRevealFeeRate(feeRate)
+	)
 	commitTx := btc.NewMsgTx(int32(btc.TxVersion))
-	client := client.NewBitcoinClient(config)
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
 	fromAddr, err := btc.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(privateKey.PubKey())), config.BtcConfig.GetChainConfigParams())
 	if err != nil {
 		return nil, fmt.Errorf("error deriving taproot addresss, %s", err.Error())
 	}
-	utxo, err := common.SelectOneUtxo(fromAddr.EncodeAddress(), 5000, config.BtcConfig)
+
+	inscriptionMetaData := make([]*taproot.P2TRMetadata, len(req.Inscriptions))
+	for i, inscriptionData := range req.Inscriptions {
+		inscriptionMetaData[i], err = taproot.CreateP2TRInscriptionMetaData(inscriptionData, privateKey.PubKey(), config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating inscription meta data, %s", err.Error())
+		}
+	}
+
+	recieverPkScript, err := btc.PayToAddrScript(req.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("error creating receiver pkscript, %s", err.Error())
+	}
+
+	// The reveal size doesn't depend on the commit, so the reveal fee is known upfront
+	revealVSize, err := EstimateRevealVSize(inscriptionMetaData, recieverPkScript, postage)
+	if err != nil {
+		return nil, fmt.Errorf("error estimating reveal size, %s", err.Error())
+	}
+	revealFee := revealVSize * int64(revealFeeRate)
+	commitValues := commitOutputValues(len(inscriptionMetaData), postage, revealFee)
+	payForward := int64(len(inscriptionMetaData))*postage + revealFee
+
+	fromPkScript, err := btc.PayToAddrScript(fromAddr)
+	if err != nil {
+		return nil, fmt.Errorf("error creating sender pkscript, %s", err.Error())
+	}
+	sizeTx := btc.NewMsgTx(int32(btc.TxVersion))
+	sizeTx.AddTxIn(btc.NewTxIn(btc.NewOutPoint(&chainhash.Hash{}, 0), nil, nil))
+	for i, meta := range inscriptionMetaData {
+		sizeTx.AddTxOut(btc.NewTxOut(commitValues[i], meta.PkScript))
+	}
+	sizeTx.AddTxOut(btc.NewTxOut(0, fromPkScript))
+	minCommitFee := estimateKeySpendVSize(sizeTx) * int64(feeRate)
+
+	utxo, err := common.SelectOneUtxo(fromAddr.EncodeAddress(), uint64(payForward+minCommitFee+minChangeValue), config.BtcConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error selecting utxo, %s", err.Error())
 	}
 
-	txData, err := GetTxData(client, utxo.TxID)
+	txData, err := GetTxData(rpc, utxo.TxID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting txdata, %s", err.Error())
 	}
@@ -56,37 +137,35 @@ func InscribeNative(
 
 	outPoint := btc.NewOutPoint(preVoutTxHash, utxo.Vout)
 
-	in := btc.NewTxIn(outPoint, nil, nil)
-	in.Sequence = defaultSequenceNum
+	in := common.NewRbfTxIn(outPoint, nil, nil)
 
 	commitTx.AddTxIn(in)
 
-	inscriptionMetaData, err := taproot.CreateP2TRInscriptionMetaData(inscriptionData, privateKey.PubKey(), config)
-	if err != nil {
-		return nil, fmt.Errorf("error creating inscription meta data, %s", err.Error())
+	// Inscription commit txouts, carry the postage and the reveal fee
+	for i, meta := range inscriptionMetaData {
+		commitTx.AddTxOut(btc.NewTxOut(commitValues[i], meta.PkScript))
 	}
 
-	// Inscription commit txout
-	commitTx.AddTxOut(btc.NewTxOut(546, inscriptionMetaData.PkScript))
-
-	totalSenderAmt := btc.Amount(utxo.Amount)
+	totalSenderAmt := int64(utxo.Amount)
 	// Change txout
 	commitTx.AddTxOut(btc.NewTxOut(0, prevVoutScriptPubkey))
 
-	fee := btc.Amount(btc.GetTxVSize(btc.NewTx(commitTx)) * int64(feeRate))
-	payForward := fee + 546
-	cost := fee + payForward + 100
-	changeAmount := totalSenderAmt - (cost) // 100 is the buffer amt to get the commit tx before the reveal tx
+	commitFee := estimateKeySpendVSize(commitTx) * int64(feeRate)
+	changeAmount := totalSenderAmt - payForward - commitFee
 
-	if changeAmount < 1 {
-		return nil, fmt.Errorf("selected UTXO has insufficient balance, %d", changeAmount)
+	if changeAmount < minChangeValue {
+		// Drop the change output, whatever is left goes to the miners
+		commitTx.TxOut = commitTx.TxOut[:len(inscriptionMetaData)]
+		commitFee = estimateKeySpendVSize(commitTx) * int64(feeRate)
+		if totalSenderAmt-payForward < commitFee {
+			return nil, fmt.Errorf("selected UTXO has insufficient balance, %d", totalSenderAmt-payForward-commitFee)
+		}
+		commitFee = totalSenderAmt - payForward
+	} else {
+		// Update the change value
+		commitTx.TxOut[len(commitTx.TxOut)-1].Value = changeAmount
 	}
 
-	// Update the inscription txout value to the payforward value
-	commitTx.TxOut[0].Value = int64(payForward)
-	// Update the change value
-	commitTx.TxOut[len(commitTx.TxOut)-1].Value = int64(changeAmount)
-
 	// Signing
 	prevOutputFetcher := txscript.NewMultiPrevOutFetcher(nil)
 	prevOutputFetcher.AddPrevOut(*outPoint, prevTxOut)
@@ -105,62 +184,77 @@ func InscribeNative(
 	}
 	commitTx.TxIn[0].Witness = witness
 
-	commitTxOut := btc.NewTxOut(
-		int64(payForward),
-		inscriptionMetaData.PkScript,
-	)
-
-	revealTx := btc.NewMsgTx(int32(btc.TxVersion))
-
 	commitTxHash := commitTx.TxHash()
-	commitOutpoint := btc.NewOutPoint(&commitTxHash, 0)
-
-	revealIn := btc.NewTxIn(commitOutpoint, nil, nil)
-	revealTx.AddTxIn(revealIn)
-
-	recieverPkScript, _ := btc.PayToAddrScript(receiver)
-	revealTx.AddTxOut(btc.NewTxOut(546, recieverPkScript))
-
-	revealTxOutputFetcher := txscript.NewMultiPrevOutFetcher(nil)
-	revealTxOutputFetcher.AddPrevOut(*commitOutpoint, commitTxOut)
-
-	witnessArray, err := txscript.CalcTapscriptSignaturehash(
-		txscript.NewTxSigHashes(revealTx, revealTxOutputFetcher),
-		txscript.SigHashDefault,
-		revealTx,
-		0,
-		revealTxOutputFetcher,
-		txscript.NewBaseTapLeaf(inscriptionMetaData.LockScript),
-	)
+	revealTx, err := buildRevealTx(&commitTxHash, commitTx.TxOut[:len(inscriptionMetaData)], inscriptionMetaData, recieverPkScript, postage, privateKey)
 	if err != nil {
-		return nil, fmt.Errorf("error constructing witness array, %s", err.Error())
+		return nil, err
 	}
 
-	signature, err := schnorr.Sign(privateKey, witnessArray)
+	// Relay policy caps how many unconfirmed txs can be chained, check it before broadcasting anything
+	fundingDepth, err := rpc.MempoolDepth(utxo.TxID)
 	if err != nil {
-		return nil, fmt.Errorf("error signing witness array, %s", err.Error())
+		return nil, fmt.Errorf("error getting the mempool depth of %s, %s", utxo.TxID, err.Error())
 	}
-
-	revealTx.TxIn[0].Witness = wire.TxWitness{
-		signature.Serialize(),
-		inscriptionMetaData.LockScript,
-		inscriptionMetaData.ControlBlockWitness,
+	commitDepth, err := common.SpendDepth(common.TxOutUtxo{Depth: fundingDepth})
+	if err != nil {
+		return nil, err
+	}
+	revealDepth, err := common.SpendDepth(common.TxOutUtxo{Depth: commitDepth})
+	if err != nil {
+		return nil, err
 	}
 
-	h1, err := client.SendRawTransaction(commitTx, true)
+	h1, err := rpc.SendRawTransaction(commitTx, true)
 	if err != nil {
 		return nil, fmt.Errorf("error sending commit tx, address=%s utxo_txid=%s utxo_vout=%d err=%s", fromAddr.String(), utxo.TxID, utxo.Vout, err.Error())
 	}
-	fmt.Println("Commit Tx:", (*h1).String())
+	log.Debug().Str("tx_id", h1.String()).Msg("commit tx sent")
 
-	h2, err := client.SendRawTransaction(revealTx, true)
+	// The reveal is rejected by nodes which haven't seen the commit yet
+	ctx, cancel := context.WithTimeout(context.Background(), commitWaitTimeout)
+	defer cancel()
+	if err := client.NewWaiter(rpc).WaitForMempool(ctx, h1.String()); err != nil {
+		return nil, fmt.Errorf("commit tx %s not in the mempool: %w", h1, err)
+	}
+
+	h2, err := rpc.SendRawTransaction(revealTx, true)
 	if err != nil {
 		return nil, fmt.Errorf("error sending reveal tx, %s", err.Error())
 	}
-	result := &SingleInscriptionResult{
-		TotalFeePaid: int64(fee) + int64(fee) - 546,
-		CommitTx:     (*h1).String(),
-		RevealTx:     (*h2).String(),
+
+	inscriptionIds := make([]string, len(inscriptionMetaData))
+	for i := range inscriptionIds {
+		inscriptionIds[i] = fmt.Sprintf("%si%d", (*h2).String(), i)
+	}
+	reveals := make([]common.TxOutUtxo, len(inscriptionMetaData))
+	for i := range reveals {
+		reveals[i] = common.TxOutUtxo{
+			TxHash:   h2.String(),
+			Vout:     uint32(i),
+			Value:    uint64(postage),
+			PkScript: recieverPkScript,
+			Depth:    revealDepth,
+		}
+	}
+	result := &InscriptionResult{
+		CommitTx:       (*h1).String(),
+		RevealTx:       (*h2).String(),
+		InscriptionIds: inscriptionIds,
+		CommitFee:      commitFee,
+		RevealFee:      revealFee,
+		Postage:        postage,
+		TotalFeePaid:   commitFee + revealFee,
+		Reveals:        reveals,
+	}
+	if len(commitTx.TxOut) > len(inscriptionMetaData) {
+		change := commitTx.TxOut[len(inscriptionMetaData)]
+		result.Change = &common.TxOutUtxo{
+			TxHash:   h1.String(),
+			Vout:     uint32(len(inscriptionMetaData)),
+			Value:    uint64(change.Value),
+			PkScript: change.PkScript,
+			Depth:    commitDepth,
+		}
 	}
 	return result, nil
 }
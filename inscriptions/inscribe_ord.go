@@ -0,0 +1,270 @@
+package inscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexellis/go-execute/v2"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultOrdTimeout = 2 * time.Minute
+	ordMaxAttempts    = 10
+)
+
+var (
+	ErrOrdDatabaseLocked    = errors.New("ord database is locked")
+	ErrOrdInsufficientFunds = errors.New("ord wallet has insufficient funds")
+	ErrOrdNotSynced         = errors.New("ord index is not synced with the wallet")
+	ErrOrdRpcUnavailable    = errors.New("ord can't connect to bitcoin core")
+	ErrOrdTimeout           = errors.New("ord timed out")
+	ErrOrdFailed            = errors.New("ord exited with a non-zero exit code")
+	ErrOrdContentType       = errors.New("content type not supported by ord")
+)
+
+// Ord infers the content type from the file extension, these are the types it knows of
+var ordExtensions = map[string]string{
+	"application/cbor":            "cbor",
+	"application/json":            "json",
+	"application/octet-stream":    "bin",
+	"application/pdf":             "pdf",
+	"application/pgp-signature":   "asc",
+	"application/protobuf":        "binpb",
+	"application/x-javascript":    "js",
+	"application/yaml":            "yaml",
+	"audio/flac":                  "flac",
+	"audio/mpeg":                  "mp3",
+	"audio/wav":                   "wav",
+	"font/otf":                    "otf",
+	"font/ttf":                    "ttf",
+	"font/woff":                   "woff",
+	"font/woff2":                  "woff2",
+	"image/apng":                  "apng",
+	"image/avif":                  "avif",
+	"image/gif":                   "gif",
+	"image/jpeg":                  "jpg",
+	"image/jxl":                   "jxl",
+	"image/png":                   "png",
+	"image/svg+xml":               "svg",
+	"image/webp":                  "webp",
+	"model/gltf+json":             "gltf",
+	"model/gltf-binary":           "glb",
+	"model/stl":                   "stl",
+	"text/css":                    "css",
+	"text/html;charset=utf-8":     "html",
+	"text/javascript":             "js",
+	"text/markdown;charset=utf-8": "md",
+	"text/plain;charset=utf-8":    "txt",
+	"text/x-python":               "py",
+	"video/mp4":                   "mp4",
+	"video/webm":                  "webm",
+}
+
+// Temp file pattern for the content type, so that ord inscribes it with that same type
+func ordFilePattern(contentType string) (string, error) {
+	ext, ok := ordExtensions[strings.ToLower(strings.ReplaceAll(contentType, " ", ""))]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrOrdContentType, contentType)
+	}
+	return "*." + ext, nil
+}
+
+// Inscribes by shelling out to `ord wallet inscribe` (or `ord wallet batch` for more than one inscription).
+// The commit is funded by ord's own wallet
+type OrdInscriber struct {
+	config config.Config
+}
+
+func NewOrdInscriber(config config.Config) OrdInscriber {
+	return OrdInscriber{config: config}
+}
+
+// Retry up to 10 times while the ord database is locked (Usually when ord server holds the lock)
+func (o OrdInscriber) Inscribe(req InscribeRequest) (res *InscriptionResult, err error) {
+	if len(req.Inscriptions) == 0 {
+		return nil, ErrNoInscriptions
+	}
+	for count := 1; ; count++ {
+		res, err = o.inscribe(req)
+		if err == nil || !errors.Is(err, ErrOrdDatabaseLocked) {
+			return res, err
+		}
+		if count >= ordMaxAttempts {
+			return nil, fmt.Errorf("exceeded retry attempts due to persistent error: %w", err)
+		}
+		log.Debug().Msg("Retrying inscribing")
+		time.Sleep(time.Second * 1)
+	}
+}
+
+func (o OrdInscriber) inscribe(req InscribeRequest) (*InscriptionResult, error) {
+	var (
+		btcConfig = o.config.BtcConfig
+		postage   = o.config.InscriptionConfig.GetPostage()
+		files     = make([]string, 0, len(req.Inscriptions))
+	)
+	defer func() {
+		for _, f := range files {
+			_ = os.Remove(f)
+		}
+	}()
+
+	patterns := make([]string, len(req.Inscriptions))
+	for i, inscription := range req.Inscriptions {
+		pattern, err := ordFilePattern(inscription.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		patterns[i] = pattern
+	}
+	for i, inscription := range req.Inscriptions {
+		f, err := writeTempFile(patterns[i], inscription.Data)
+		if f != "" {
+			files = append(files, f)
+		}
+		if err != nil {
+			log.Error().Msgf("Error writing inscription to the temp file - %s", err)
+			return nil, err
+		}
+	}
+
+	args := append(btcConfig.GetOrdChainArgs(), "--bitcoin-data-dir", btcConfig.BitcoinDataDir, "--data-dir", btcConfig.OrdDataDir, "wallet")
+	if len(files) == 1 {
+		args = append(args, "inscribe", "--fee-rate", fmt.Sprintf("%d", req.FeeRate), "--destination", req.Destination.EncodeAddress(), "--file", files[0], "--postage", fmt.Sprintf("%dsat", postage))
+	} else {
+		batchFile, err := writeTempFile("*.yaml", ordBatchFile(files, req.Destination.EncodeAddress(), postage))
+		if batchFile != "" {
+			files = append(files, batchFile)
+		}
+		if err != nil {
+			log.Error().Msgf("Error writing the batch file - %s", err)
+			return nil, err
+		}
+		args = append(args, "batch", "--fee-rate", fmt.Sprintf("%d", req.FeeRate), "--batch", batchFile)
+	}
+
+	timeout := o.config.InscriptionConfig.OrdTimeout
+	if timeout <= 0 {
+		timeout = defaultOrdTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := execute.ExecTask{
+		Command:     strings.TrimRight(btcConfig.OrdPath, "/") + "/ord",
+		Args:        args,
+		StreamStdio: false,
+	}
+	res, err := cmd.Execute(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w after %s", ErrOrdTimeout, timeout)
+	}
+	if err != nil {
+		log.Error().Msgf("Error sending inscription %s %v with err: %s", cmd.Command, cmd.Args, res.Stderr)
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		log.Error().Msgf("Non 0 exit code while sending inscription %s | %s", res.Stderr, res.Stdout)
+		return nil, classifyOrdError(res.Stderr)
+	}
+
+	data := &InscriptionResultRaw{}
+	if err := json.Unmarshal([]byte(res.Stdout), data); err != nil {
+		log.Err(errors.New(res.Stderr)).Msgf("Error packing raw inscription output")
+		return nil, err
+	}
+
+	inscriptionIds := make([]string, len(data.Inscriptions))
+	reveals := make([]common.TxOutUtxo, len(data.Inscriptions))
+	for i, inscription := range data.Inscriptions {
+		inscriptionIds[i] = inscription.Id
+		if reveals[i], err = ordRevealUtxo(inscription.Location, postage); err != nil {
+			return nil, err
+		}
+	}
+	return &InscriptionResult{
+		CommitTx:       data.Commit,
+		RevealTx:       data.Reveal,
+		InscriptionIds: inscriptionIds,
+		Postage:        postage,
+		TotalFeePaid:   data.TotalFees,
+		Reveals:        reveals,
+	}, nil
+}
+
+// Output holding an inscription from its ord location (txid:vout:offset).
+// Ord funds the commit from its own wallet, assumed to be confirmed, so the reveal is 2 deep
+func ordRevealUtxo(location string, postage int64) (common.TxOutUtxo, error) {
+	parts := strings.Split(location, ":")
+	if len(parts) != 3 {
+		return common.TxOutUtxo{}, fmt.Errorf("%w: unexpected location %q", ErrOrdFailed, location)
+	}
+	vout, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return common.TxOutUtxo{}, fmt.Errorf("%w: unexpected location %q", ErrOrdFailed, location)
+	}
+	return common.TxOutUtxo{TxHash: parts[0], Vout: uint32(vout), Value: uint64(postage), Depth: 2}, nil
+}
+
+// Map ord's stderr to one of the ErrOrd* errors
+func classifyOrdError(stderr string) error {
+	msg := strings.ToLower(stderr)
+	reason := strings.TrimSpace(stderr)
+	if i := strings.IndexByte(reason, '\n'); i > 0 {
+		reason = reason[:i]
+	}
+
+	switch {
+	case strings.Contains(msg, "database already open"),
+		strings.Contains(msg, "cannot acquire lock"),
+		strings.Contains(msg, "resource temporarily unavailable"):
+		return fmt.Errorf("%w: %s", ErrOrdDatabaseLocked, reason)
+	case strings.Contains(msg, "insufficient"),
+		strings.Contains(msg, "not enough cardinal"),
+		strings.Contains(msg, "no cardinal utxos"):
+		return fmt.Errorf("%w: %s", ErrOrdInsufficientFunds, reason)
+	case strings.Contains(msg, "synchronize"),
+		strings.Contains(msg, "not in ord server"),
+		strings.Contains(msg, "index is behind"):
+		return fmt.Errorf("%w: %s", ErrOrdNotSynced, reason)
+	case strings.Contains(msg, "connection refused"),
+		strings.Contains(msg, "failed to connect to bitcoin core"):
+		return fmt.Errorf("%w: %s", ErrOrdRpcUnavailable, reason)
+	default:
+		return fmt.Errorf("%w: %s", ErrOrdFailed, reason)
+	}
+}
+
+// Batch file for `ord wallet batch`, every inscription in its own output
+func ordBatchFile(files []string, destination string, postage int64) string {
+	var b strings.Builder
+	b.WriteString("mode: separate-outputs\n")
+	fmt.Fprintf(&b, "postage: %d\n", postage)
+	b.WriteString("inscriptions:\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "  - file: %q\n    destination: %q\n", f, destination)
+	}
+	return b.String()
+}
+
+// Returns the file name even on write errors, so that the caller can clean it up
+func writeTempFile(pattern, content string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		return file.Name(), err
+	}
+	return file.Name(), nil
+}
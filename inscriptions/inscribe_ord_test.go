@@ -0,0 +1,55 @@
+package inscriptions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyOrdError(t *testing.T) {
+	cases := map[string]error{
+		"error: Database already open. Cannot acquire lock.":                           ErrOrdDatabaseLocked,
+		"error: wallet contains no cardinal utxos":                                     ErrOrdInsufficientFunds,
+		"error: wallet failed to synchronize with `ord server` after 20 attempts":      ErrOrdNotSynced,
+		"error: failed to connect to Bitcoin Core RPC at `127.0.0.1:18443/wallet/ord`": ErrOrdRpcUnavailable,
+		"error: unexpected argument '--foo' found":                                     ErrOrdFailed,
+	}
+	for stderr, expected := range cases {
+		require.ErrorIs(t, classifyOrdError(stderr), expected, stderr)
+	}
+}
+
+func TestOrdRevealUtxo(t *testing.T) {
+	txId := "6fb976ab49dcec017f1e201e84395983204ae1a7c2abf7ced0a85d692e442799"
+	utxo, err := ordRevealUtxo(txId+":1:0", 546)
+	require.NoError(t, err)
+	require.Equal(t, txId, utxo.GetTxID())
+	require.Equal(t, uint32(1), utxo.GetVout())
+	require.Equal(t, uint64(546), utxo.GetValueInSats())
+	require.Equal(t, 2, utxo.Depth)
+
+	for _, location := range []string{txId, txId + ":x:0", ""} {
+		_, err := ordRevealUtxo(location, 546)
+		require.ErrorIs(t, err, ErrOrdFailed, location)
+	}
+}
+
+func TestOrdFilePattern(t *testing.T) {
+	cases := map[string]string{
+		"text/plain;charset=utf-8":  "*.txt",
+		"text/plain; charset=UTF-8": "*.txt",
+		"application/json":          "*.json",
+		"image/png":                 "*.png",
+		"text/html;charset=utf-8":   "*.html",
+	}
+	for contentType, expected := range cases {
+		pattern, err := ordFilePattern(contentType)
+		require.NoError(t, err, contentType)
+		require.Equal(t, expected, pattern, contentType)
+	}
+
+	for _, contentType := range []string{"", "text/plain", "image/bmp"} {
+		_, err := ordFilePattern(contentType)
+		require.ErrorIs(t, err, ErrOrdContentType, contentType)
+	}
+}
@@ -0,0 +1,41 @@
+package inscriptions
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ordinox/btc-service/config"
+)
+
+const (
+	BackendNative = "native"
+	BackendOrd    = "ord"
+)
+
+var (
+	ErrNoInscriptions  = errors.New("no inscriptions to inscribe")
+	ErrUnknownBackend  = errors.New("unknown inscriber backend")
+	ErrPrivateKeyUnset = errors.New("private key is required")
+)
+
+// Inscriber inscribes a batch of inscriptions into the destination with one commit & reveal
+type Inscriber interface {
+	Inscribe(req InscribeRequest) (*InscriptionResult, error)
+}
+
+var (
+	_ Inscriber = NativeInscriber{}
+	_ Inscriber = OrdInscriber{}
+)
+
+// Create the inscriber configured in `inscription.backend`, defaults to the native inscriber
+func NewInscriber(config config.Config) (Inscriber, error) {
+	switch config.InscriptionConfig.Backend {
+	case "", BackendNative:
+		return NewNativeInscriber(config), nil
+	case BackendOrd:
+		return NewOrdInscriber(config), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, config.InscriptionConfig.Backend)
+	}
+}
@@ -0,0 +1,57 @@
+package inscriptions
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/btc"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/taproot"
+)
+
+// Reveal signed by a one-time key, for commits funded & signed by someone else, e.g. through a PSBT.
+// The commit has to pay CommitOutputs first, and its inputs have to be segwit so that its txid is
+// known before it is signed
+type PresignedReveal struct {
+	CommitOutputs []*wire.TxOut
+	RevealFee     int64
+	Postage       int64
+	metas         []*taproot.P2TRMetadata
+	receiver      []byte
+	key           *btcec.PrivateKey
+}
+
+// Inscription scripts locked to a fresh key, which only ever signs the reveal
+func NewPresignedReveal(inscriptions []taproot.InscriptionData, receiverPkScript []byte, revealFeeRate uint64, config config.Config) (*PresignedReveal, error) {
+	if len(inscriptions) == 0 {
+		return nil, ErrNoInscriptions
+	}
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	r := &PresignedReveal{Postage: config.InscriptionConfig.GetPostage(), receiver: receiverPkScript, key: key}
+	for _, inscription := range inscriptions {
+		meta, err := taproot.CreateP2TRInscriptionMetaData(inscription, key.PubKey(), config)
+		if err != nil {
+			return nil, fmt.Errorf("error creating inscription meta data, %s", err.Error())
+		}
+		r.metas = append(r.metas, meta)
+	}
+	revealVSize, err := EstimateRevealVSize(r.metas, receiverPkScript, r.Postage)
+	if err != nil {
+		return nil, err
+	}
+	r.RevealFee = revealVSize * int64(revealFeeRate)
+	for i, value := range commitOutputValues(len(r.metas), r.Postage, r.RevealFee) {
+		r.CommitOutputs = append(r.CommitOutputs, btc.NewTxOut(value, r.metas[i].PkScript))
+	}
+	return r, nil
+}
+
+// Reveal spending the commit outputs of the commit tx
+func (r *PresignedReveal) Sign(commitHash *chainhash.Hash) (*wire.MsgTx, error) {
+	return buildRevealTx(commitHash, r.CommitOutputs, r.metas, r.receiver, r.Postage, r.key)
+}
@@ -0,0 +1,129 @@
+package tracker
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	tagContentType = 1
+	tagParent      = 3
+
+	// First byte of the taproot annex
+	annexTag = 0x50
+)
+
+var protocolId = []byte("ord")
+
+// Inscription envelope found in a tapscript
+type Envelope struct {
+	ContentType string
+	Parent      string // Inscription id of the parent, not validated
+	Body        []byte
+}
+
+// Parse all the envelopes revealed in an input witness.
+// Only script path spends can reveal inscriptions, the script being the second to last witness element
+func ParseEnvelopes(witness wire.TxWitness) []Envelope {
+	if len(witness) > 1 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == annexTag {
+		witness = witness[:len(witness)-1]
+	}
+	if len(witness) < 2 {
+		return nil
+	}
+	return parseScript(witness[len(witness)-2])
+}
+
+// Envelopes are `OP_FALSE OP_IF "ord" [tag value]... [OP_0 body...] OP_ENDIF`
+func parseScript(script []byte) []Envelope {
+	envelopes := make([]Envelope, 0)
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	var prevOp1, prevOp2 byte = 0xff, 0xff
+
+	for tokenizer.Next() {
+		op := tokenizer.Opcode()
+		data := tokenizer.Data()
+		if prevOp2 == txscript.OP_FALSE && prevOp1 == txscript.OP_IF && isPush(op) && bytes.Equal(data, protocolId) {
+			if envelope, ok := parseEnvelopeBody(&tokenizer); ok {
+				envelopes = append(envelopes, envelope)
+			}
+			prevOp1, prevOp2 = 0xff, 0xff
+			continue
+		}
+		prevOp2, prevOp1 = prevOp1, op
+	}
+	return envelopes
+}
+
+// Read the fields & body of an envelope up to OP_ENDIF
+func parseEnvelopeBody(tokenizer *txscript.ScriptTokenizer) (Envelope, bool) {
+	envelope := Envelope{}
+	inBody := false
+	for tokenizer.Next() {
+		op := tokenizer.Opcode()
+		if op == txscript.OP_ENDIF {
+			return envelope, true
+		}
+		if !isPush(op) {
+			return envelope, false
+		}
+		if inBody {
+			envelope.Body = append(envelope.Body, tokenizer.Data()...)
+			continue
+		}
+
+		tag, ok := pushValue(op, tokenizer.Data())
+		if !ok {
+			return envelope, false
+		}
+		if tag == 0 {
+			inBody = true
+			continue
+		}
+		if !tokenizer.Next() || !isPush(tokenizer.Opcode()) {
+			return envelope, false
+		}
+		switch tag {
+		case tagContentType:
+			envelope.ContentType = string(tokenizer.Data())
+		case tagParent:
+			envelope.Parent = parseInscriptionId(tokenizer.Data())
+		}
+	}
+	return envelope, false
+}
+
+func isPush(op byte) bool {
+	return op <= txscript.OP_PUSHDATA4 || (op >= txscript.OP_1 && op <= txscript.OP_16) || op == txscript.OP_1NEGATE
+}
+
+// Numeric value of a tag push, either a small int opcode or a single byte push
+func pushValue(op byte, data []byte) (int, bool) {
+	switch {
+	case op == txscript.OP_0:
+		return 0, true
+	case op >= txscript.OP_1 && op <= txscript.OP_16:
+		return int(op-txscript.OP_1) + 1, true
+	case len(data) == 1:
+		return int(data[0]), true
+	default:
+		return 0, false
+	}
+}
+
+// Inscription ids are serialized as the txid followed by the little endian index without trailing zeroes
+func parseInscriptionId(data []byte) string {
+	if len(data) < chainhash.HashSize || len(data) > chainhash.HashSize+4 {
+		return ""
+	}
+	hash, _ := chainhash.NewHash(data[:chainhash.HashSize])
+	var index uint32
+	for i, b := range data[chainhash.HashSize:] {
+		index |= uint32(b) << (8 * i)
+	}
+	return fmt.Sprintf("%si%d", hash.String(), index)
+}
@@ -0,0 +1,259 @@
+package tracker
+
+// Minimal ord-style inscription tracker.
+// It finds inscriptions revealed in a block and follows them through transfers using ord's first-in-first-out sat flow.
+// Simplifications compared to ord:
+// 1. Inscriptions always land on the first sat of the input revealing them (pointers are ignored)
+// 2. Cursed and reinscription rules are not applied, every envelope is an inscription
+// 3. Inscriptions spent as fee are marked as such and stop being tracked
+// 4. A parent is valid when the parent inscription is spent by the same tx
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+)
+
+var (
+	ErrPrevOutNotFound = errors.New("previous output not found")
+	ErrUnexpectedBlock = errors.New("unexpected block height")
+)
+
+// Location of a sat, the offset being within the output
+type SatPoint struct {
+	OutPoint wire.OutPoint
+	Offset   uint64
+}
+
+func (s SatPoint) String() string {
+	return fmt.Sprintf("%s:%d", s.OutPoint.String(), s.Offset)
+}
+
+type Inscription struct {
+	Id            string
+	Number        int64
+	ContentType   string
+	Body          []byte
+	Parent        string // Only set when the parent was spent by the genesis tx
+	GenesisHeight int64
+	GenesisTxId   string
+	Location      SatPoint
+	PkScript      []byte // Script of the output currently holding the inscription
+}
+
+type EventKind int
+
+const (
+	EventInscribed EventKind = iota
+	EventTransferred
+)
+
+type Event struct {
+	Kind        EventKind
+	Inscription Inscription // Snapshot after the event
+	TxId        string
+	Height      int64
+	From        []byte // Previous owner's script, nil when inscribed
+	To          []byte // New owner's script, nil when spent as fee
+	SpentAsFee  bool
+}
+
+// Resolve outputs spent by a block. Outputs created in the same block are resolved by the tracker itself
+type PrevOutFetcher interface {
+	FetchPrevOut(outpoint wire.OutPoint) (*wire.TxOut, error)
+}
+
+// Fetch previous outputs from bitcoin core
+type RpcPrevOutFetcher struct {
+	Client *client.BtcRpcClient
+}
+
+func (f RpcPrevOutFetcher) FetchPrevOut(outpoint wire.OutPoint) (*wire.TxOut, error) {
+	tx, err := f.Client.GetRawTransaction(&outpoint.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if int(outpoint.Index) >= len(tx.MsgTx().TxOut) {
+		return nil, fmt.Errorf("%w: %s", ErrPrevOutNotFound, outpoint.String())
+	}
+	return tx.MsgTx().TxOut[outpoint.Index], nil
+}
+
+type Tracker struct {
+	fetcher PrevOutFetcher
+	height  int64
+	next    int64
+
+	byId      map[string]*Inscription
+	locations map[wire.OutPoint][]*Inscription // Inscriptions sitting on each unspent output
+}
+
+// Create a tracker which expects startHeight to be the next block indexed
+func New(fetcher PrevOutFetcher, startHeight int64) *Tracker {
+	return &Tracker{
+		fetcher:   fetcher,
+		height:    startHeight - 1,
+		byId:      make(map[string]*Inscription),
+		locations: make(map[wire.OutPoint][]*Inscription),
+	}
+}
+
+// Height of the last indexed block
+func (t *Tracker) Height() int64 {
+	return t.height
+}
+
+func (t *Tracker) Inscription(id string) (*Inscription, bool) {
+	i, ok := t.byId[id]
+	if !ok {
+		return nil, false
+	}
+	copy := *i
+	return &copy, true
+}
+
+// Index all the transactions in the block, returning inscribe & transfer events in order
+func (t *Tracker) IndexBlock(height int64, block *wire.MsgBlock) ([]Event, error) {
+	if height != t.height+1 {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedBlock, t.height+1, height)
+	}
+	// Every output spent by the block is resolved before anything changes, a failed fetch leaves the
+	// tracker as it was
+	prevOuts := make(map[wire.OutPoint]*wire.TxOut)
+	for _, tx := range block.Transactions {
+		txHash := tx.TxHash()
+		for i, out := range tx.TxOut {
+			prevOuts[wire.OutPoint{Hash: txHash, Index: uint32(i)}] = out
+		}
+	}
+	for _, tx := range block.Transactions {
+		if blockchain.IsCoinBaseTx(tx) {
+			continue
+		}
+		for _, in := range tx.TxIn {
+			if _, ok := prevOuts[in.PreviousOutPoint]; ok {
+				continue
+			}
+			prevOut, err := t.fetcher.FetchPrevOut(in.PreviousOutPoint)
+			if err != nil {
+				return nil, fmt.Errorf("error indexing tx %s: %w", tx.TxHash().String(), err)
+			}
+			prevOuts[in.PreviousOutPoint] = prevOut
+		}
+	}
+
+	events := make([]Event, 0)
+	for _, tx := range block.Transactions {
+		if blockchain.IsCoinBaseTx(tx) {
+			continue
+		}
+		events = append(events, t.indexTx(height, tx, prevOuts)...)
+	}
+	t.height = height
+	return events, nil
+}
+
+type floating struct {
+	inscription *Inscription
+	offset      uint64 // Offset within all the inputs of the tx
+	from        []byte
+	isNew       bool
+	parent      string
+}
+
+func (t *Tracker) indexTx(height int64, tx *wire.MsgTx, prevOuts map[wire.OutPoint]*wire.TxOut) []Event {
+	txHash := tx.TxHash()
+	moving := make([]floating, 0)
+
+	var inputOffset uint64
+	var newIndex int
+	spent := make(map[string]bool)
+	for _, in := range tx.TxIn {
+		prevOut := prevOuts[in.PreviousOutPoint]
+
+		for _, inscription := range t.locations[in.PreviousOutPoint] {
+			spent[inscription.Id] = true
+			moving = append(moving, floating{
+				inscription: inscription,
+				offset:      inputOffset + inscription.Location.Offset,
+				from:        inscription.PkScript,
+			})
+		}
+		delete(t.locations, in.PreviousOutPoint)
+
+		for _, envelope := range ParseEnvelopes(in.Witness) {
+			inscription := &Inscription{
+				Id:            fmt.Sprintf("%si%d", txHash.String(), newIndex),
+				Number:        t.next,
+				ContentType:   envelope.ContentType,
+				Body:          envelope.Body,
+				GenesisHeight: height,
+				GenesisTxId:   txHash.String(),
+			}
+			t.next++
+			newIndex++
+			moving = append(moving, floating{inscription: inscription, offset: inputOffset, isNew: true, parent: envelope.Parent})
+		}
+		inputOffset += uint64(prevOut.Value)
+	}
+	for _, m := range moving {
+		if m.isNew && spent[m.parent] {
+			m.inscription.Parent = m.parent
+		}
+	}
+
+	sort.SliceStable(moving, func(i, j int) bool { return moving[i].offset < moving[j].offset })
+
+	events := make([]Event, 0, len(moving))
+	for _, m := range moving {
+		event := Event{
+			Kind:   EventTransferred,
+			TxId:   txHash.String(),
+			Height: height,
+			From:   m.from,
+		}
+		if m.isNew {
+			event.Kind = EventInscribed
+			t.byId[m.inscription.Id] = m.inscription
+		}
+
+		vout, offset, ok := locateOffset(tx, m.offset)
+		if !ok {
+			// The sat went to the miner
+			event.SpentAsFee = true
+			m.inscription.PkScript = nil
+			m.inscription.Location = SatPoint{}
+		} else {
+			outpoint := wire.OutPoint{Hash: txHash, Index: vout}
+			m.inscription.Location = SatPoint{OutPoint: outpoint, Offset: offset}
+			m.inscription.PkScript = tx.TxOut[vout].PkScript
+			t.locations[outpoint] = append(t.locations[outpoint], m.inscription)
+			event.To = m.inscription.PkScript
+		}
+		event.Inscription = *m.inscription
+		events = append(events, event)
+	}
+	return events
+}
+
+// Find the output holding the sat at the given offset of the inputs
+func locateOffset(tx *wire.MsgTx, offset uint64) (uint32, uint64, bool) {
+	var start uint64
+	for i, out := range tx.TxOut {
+		end := start + uint64(out.Value)
+		if offset < end {
+			return uint32(i), offset - start, true
+		}
+		start = end
+	}
+	return 0, 0, false
+}
+
+// Convenience wrapper for btcutil blocks
+func (t *Tracker) IndexUtilBlock(height int64, block *btcutil.Block) ([]Event, error) {
+	return t.IndexBlock(height, block.MsgBlock())
+}
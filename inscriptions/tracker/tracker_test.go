@@ -0,0 +1,110 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+type mapFetcher map[wire.OutPoint]*wire.TxOut
+
+func (m mapFetcher) FetchPrevOut(outpoint wire.OutPoint) (*wire.TxOut, error) {
+	out, ok := m[outpoint]
+	if !ok {
+		return nil, ErrPrevOutNotFound
+	}
+	return out, nil
+}
+
+func revealWitness(t *testing.T, contentType, body string, parent []byte) wire.TxWitness {
+	builder := txscript.NewScriptBuilder().
+		AddData(make([]byte, 32)).
+		AddOp(txscript.OP_CHECKSIG).
+		AddOp(txscript.OP_FALSE).
+		AddOp(txscript.OP_IF).
+		AddData([]byte("ord")).
+		AddData([]byte{tagContentType}).
+		AddData([]byte(contentType))
+	if parent != nil {
+		builder.AddData([]byte{tagParent}).AddData(parent)
+	}
+	script, err := builder.
+		AddOp(txscript.OP_0).
+		AddData([]byte(body)).
+		AddOp(txscript.OP_ENDIF).
+		Script()
+	require.NoError(t, err)
+	return wire.TxWitness{make([]byte, 64), script, make([]byte, 33)}
+}
+
+func TestParseEnvelopes(t *testing.T) {
+	parentHash := chainhash.DoubleHashH([]byte("parent"))
+	envelopes := ParseEnvelopes(revealWitness(t, "text/plain", "hello", append(parentHash[:], 1)))
+	require.Len(t, envelopes, 1)
+	require.Equal(t, "text/plain", envelopes[0].ContentType)
+	require.Equal(t, "hello", string(envelopes[0].Body))
+	require.Equal(t, parentHash.String()+"i1", envelopes[0].Parent)
+
+	// Key path spends can't reveal anything
+	require.Empty(t, ParseEnvelopes(wire.TxWitness{make([]byte, 64)}))
+}
+
+// Inscriptions follow the first in first out order of sats, the ones past the last output go to the miner
+func TestSatFlow(t *testing.T) {
+	funding := wire.OutPoint{Hash: chainhash.DoubleHashH([]byte("funding")), Index: 0}
+	tracker := New(mapFetcher{funding: wire.NewTxOut(10_000, []byte{txscript.OP_TRUE})}, 1)
+
+	reveal := wire.NewMsgTx(2)
+	reveal.AddTxIn(&wire.TxIn{PreviousOutPoint: funding, Witness: revealWitness(t, "text/plain", "a", nil)})
+	reveal.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_1}))
+	revealHash := reveal.TxHash()
+
+	// Spend the inscription together with another input, moving it to the second output
+	other := wire.OutPoint{Hash: chainhash.DoubleHashH([]byte("other")), Index: 0}
+	tracker.fetcher.(mapFetcher)[other] = wire.NewTxOut(600, []byte{txscript.OP_TRUE})
+	transfer := wire.NewMsgTx(2)
+	transfer.AddTxIn(&wire.TxIn{PreviousOutPoint: other})
+	transfer.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Hash: revealHash, Index: 0}})
+	transfer.AddTxOut(wire.NewTxOut(500, []byte{txscript.OP_2}))
+	transfer.AddTxOut(wire.NewTxOut(500, []byte{txscript.OP_3}))
+
+	events, err := tracker.IndexBlock(1, &wire.MsgBlock{Transactions: []*wire.MsgTx{reveal, transfer}})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, EventInscribed, events[0].Kind)
+	require.Equal(t, revealHash.String()+"i0", events[0].Inscription.Id)
+	require.Equal(t, EventTransferred, events[1].Kind)
+	require.Equal(t, []byte{txscript.OP_3}, events[1].To)
+	require.Equal(t, uint64(100), events[1].Inscription.Location.Offset)
+
+	// A tx with a single small output sends the inscription to the miner
+	fee := wire.NewMsgTx(2)
+	fee.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Hash: transfer.TxHash(), Index: 0}})
+	fee.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Hash: transfer.TxHash(), Index: 1}})
+	fee.AddTxOut(wire.NewTxOut(550, []byte{txscript.OP_4}))
+	fetcher := tracker.fetcher.(mapFetcher)
+	fetcher[wire.OutPoint{Hash: transfer.TxHash(), Index: 0}] = transfer.TxOut[0]
+	fetcher[wire.OutPoint{Hash: transfer.TxHash(), Index: 1}] = transfer.TxOut[1]
+
+	// A block spending an output which can't be fetched changes nothing
+	unknown := wire.NewMsgTx(2)
+	unknown.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Hash: transfer.TxHash(), Index: 1}})
+	unknown.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Hash: chainhash.DoubleHashH([]byte("unknown"))}})
+	unknown.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_4}))
+	_, err = tracker.IndexBlock(2, &wire.MsgBlock{Transactions: []*wire.MsgTx{fee, unknown}})
+	require.ErrorIs(t, err, ErrPrevOutNotFound)
+	require.Equal(t, int64(1), tracker.Height())
+	inscription, ok := tracker.Inscription(events[0].Inscription.Id)
+	require.True(t, ok)
+	require.Equal(t, events[1].Inscription.Location, inscription.Location)
+
+	_, err = tracker.IndexBlock(3, &wire.MsgBlock{Transactions: []*wire.MsgTx{fee}})
+	require.ErrorIs(t, err, ErrUnexpectedBlock)
+	events, err = tracker.IndexBlock(2, &wire.MsgBlock{Transactions: []*wire.MsgTx{fee}})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.True(t, events[0].SpentAsFee)
+}
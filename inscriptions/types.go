@@ -1,21 +1,40 @@
 package inscriptions
 
+import (
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/taproot"
+)
+
+type InscribeRequest struct {
+	Inscriptions []taproot.InscriptionData
+	Destination  btcutil.Address
+	PrivateKey   *btcec.PrivateKey // Funds and signs the native commit/reveal. The ord backend uses its own wallet
+	FeeRate      uint64
+}
+
+// Result of inscribing one or more inscriptions in a single commit/reveal pair
 type InscriptionResult struct {
-	InscriptionId string
-	Tx            string
-	Fees          int64
-	ServiceFees   int64
+	CommitTx       string
+	RevealTx       string
+	InscriptionIds []string
+	CommitFee      int64 // Fee paid by the commit tx
+	RevealFee      int64 // Fee paid by the reveal tx, funded through the commit outputs
+	Postage        int64 // Value of each inscription output
+	TotalFeePaid   int64 // CommitFee + RevealFee
+
+	Reveals []common.TxOutUtxo // Inscription outputs, in the order of InscriptionIds
+	Change  *common.TxOutUtxo  // Change of the commit, nil when there is none
 }
 
+// Raw output of `ord wallet inscribe` & `ord wallet batch`
 type InscriptionResultRaw struct {
 	Commit       string `json:"commit"`
+	Reveal       string `json:"reveal"`
+	TotalFees    int64  `json:"total_fees"`
 	Inscriptions []struct {
-		Id string `json:"id"`
+		Id       string `json:"id"`
+		Location string `json:"location"`
 	} `json:"inscriptions"`
 }
-
-type SingleInscriptionResult struct {
-	CommitTx     string
-	RevealTx     string
-	TotalFeePaid int64
-}
@@ -0,0 +1,155 @@
+package testutil
+
+// Fakes of the node & the indexers, shared by the tests of the tx builder and of the apis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/brc20"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/stretchr/testify/require"
+)
+
+// Node knowing the txs in Txs, outputs listed in Spent are gone.
+// Sent txs are recorded, or rejected with SendErr when it is set
+type Chain struct {
+	Txs     map[chainhash.Hash]*wire.MsgTx
+	Spent   map[wire.OutPoint]bool
+	Sent    []*wire.MsgTx
+	SendErr error
+}
+
+func NewChain() *Chain {
+	return &Chain{Txs: make(map[chainhash.Hash]*wire.MsgTx), Spent: make(map[wire.OutPoint]bool)}
+}
+
+func (c *Chain) GetRawTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error) {
+	tx, ok := c.Txs[*txHash]
+	if !ok {
+		return nil, client.ErrNotFound
+	}
+	return btcutil.NewTx(tx), nil
+}
+
+func (c *Chain) GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error) {
+	if _, ok := c.Txs[*txHash]; !ok || c.Spent[*wire.NewOutPoint(txHash, index)] {
+		return nil, nil
+	}
+	return &btcjson.GetTxOutResult{}, nil
+}
+
+func (c *Chain) SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
+	if c.SendErr != nil {
+		return nil, c.SendErr
+	}
+	c.Sent = append(c.Sent, tx)
+	hash := tx.TxHash()
+	return &hash, nil
+}
+
+// Utxo api, by address
+type Utxos map[string][]common.WebUtxo
+
+func (u Utxos) GetUtxos(ctx context.Context, address string) ([]common.WebUtxo, error) {
+	return u[address], nil
+}
+
+// Outpoints holding runes, whatever the address
+type Runes []client.RunesUtxo
+
+func (r Runes) GetRunesUtxos(ctx context.Context, address string) ([]client.RunesUtxo, error) {
+	return r, nil
+}
+
+// Outpoints holding inscriptions, whatever the address
+type Inscriptions []string
+
+func (i Inscriptions) GetInscriptionOutpoints(ctx context.Context, address string) ([]string, error) {
+	return i, nil
+}
+
+// Brc20 balances by ticker, whatever the address
+type Brc20 map[string]client.Brc20Balance
+
+func (b Brc20) GetBrc20Balance(ctx context.Context, address, ticker string) (*client.Brc20Balance, error) {
+	balance, ok := b[ticker]
+	if !ok {
+		return nil, client.ErrNotFound
+	}
+	return &balance, nil
+}
+
+// Brc20 deposits failing with Err, or found at BlockHeight as described
+type Brc20Deposits struct {
+	Err         error
+	BlockHeight int64
+}
+
+func (v Brc20Deposits) Verify(ctx context.Context, data brc20.VerifyBrc20DepositData) (*client.Brc20Transfer, error) {
+	if v.Err != nil {
+		return nil, v.Err
+	}
+	return &client.Brc20Transfer{InscriptionId: data.InscriptionId, TxId: data.TxId, Tick: data.Tick, Amount: data.Amount.WithDecimals(18), SourceWallet: data.FromWalletAddr, SpentWallet: data.ToWalletAddr, BlockHeight: v.BlockHeight}, nil
+}
+
+// Runes deposits always failing with Err
+type RunesDeposits struct {
+	Err error
+}
+
+func (v RunesDeposits) Verify(ctx context.Context, deposit runes.RunesDeposit) (*runes.RunesDepositReceipt, error) {
+	return nil, v.Err
+}
+
+// Regtest taproot sender & p2pkh recipient, the outputs of the sender living in Chain & Utxos
+type Wallet struct {
+	Key   *btcec.PrivateKey
+	From  btcutil.Address
+	To    btcutil.Address
+	Chain *Chain
+	Utxos Utxos
+}
+
+func NewWallet(t *testing.T) *Wallet {
+	key, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	from, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(key.PubKey())), &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	to, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	return &Wallet{Key: key, From: from, To: to, Chain: NewChain(), Utxos: make(Utxos)}
+}
+
+// Output of the value to the sender, in a tx of its own known to the node only
+func (w *Wallet) AddOutput(t *testing.T, value int64) wire.OutPoint {
+	script, err := txscript.PayToAddrScript(w.From)
+	require.NoError(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(len(w.Chain.Txs) + 1)}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(value, script))
+	w.Chain.Txs[tx.TxHash()] = tx
+	return *wire.NewOutPoint(ptr(tx.TxHash()), 0)
+}
+
+// Fund the sender with an output of the value, listed by the utxo api as well
+func (w *Wallet) Fund(t *testing.T, value int64) wire.OutPoint {
+	outpoint := w.AddOutput(t, value)
+	address := w.From.EncodeAddress()
+	w.Utxos[address] = append(w.Utxos[address], common.WebUtxo{TxHash: outpoint.Hash.String(), Vout: outpoint.Index, Value: uint64(value), Height: 1})
+	return outpoint
+}
+
+func ptr(hash chainhash.Hash) *chainhash.Hash {
+	return &hash
+}
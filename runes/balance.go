@@ -1 +1,29 @@
 package runes
+
+import (
+	"sort"
+
+	"github.com/ordinox/btc-service/client"
+)
+
+// Balance of every rune held by the outpoints, ordered by rune id
+func SumBalances(utxos []client.RunesUtxo) []client.RuneBalance {
+	var balances []client.RuneBalance
+	index := make(map[string]int)
+	for _, utxo := range utxos {
+		for _, r := range utxo.Runes {
+			i, ok := index[r.RuneId]
+			if !ok {
+				index[r.RuneId] = len(balances)
+				balances = append(balances, r)
+				continue
+			}
+			balances[i].Amount = balances[i].Amount.Add(r.Amount)
+			if balances[i].RuneName == "" {
+				balances[i].RuneName = r.RuneName
+			}
+		}
+	}
+	sort.SliceStable(balances, func(a, b int) bool { return balances[a].RuneId < balances[b].RuneId })
+	return balances
+}
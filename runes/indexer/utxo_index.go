@@ -0,0 +1,107 @@
+package indexer
+
+// Local index of the outpoints holding runes, following the transactions sent by the service
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/runes/runestone"
+)
+
+var _ client.RunesUtxoSource = &UtxoIndex{}
+var _ runes.TxRecorder = &UtxoIndex{}
+
+// Rune balances of the transactions sent by the service, kept until the indexers see them. Serves the
+// outpoints of the remote source without the ones spent locally, along with the ones created locally,
+// so transfers sent one after the other don't select an outpoint which is already spent.
+// Outpoints spent locally are remembered for the life of the index
+type UtxoIndex struct {
+	sync.Mutex
+	remote  client.RunesUtxoSource // Nil to only serve the local outpoints
+	params  *chaincfg.Params
+	created map[string]client.RunesUtxo // Outpoint -> runes, until the remote source reports it
+	spent   map[string]bool
+}
+
+func NewUtxoIndex(remote client.RunesUtxoSource, params *chaincfg.Params) *UtxoIndex {
+	return &UtxoIndex{
+		remote:  remote,
+		params:  params,
+		created: make(map[string]client.RunesUtxo),
+		spent:   make(map[string]bool),
+	}
+}
+
+func (i *UtxoIndex) GetRunesUtxos(ctx context.Context, address string) ([]client.RunesUtxo, error) {
+	remote := make([]client.RunesUtxo, 0)
+	if i.remote != nil {
+		var err error
+		if remote, err = i.remote.GetRunesUtxos(ctx, address); err != nil {
+			return nil, err
+		}
+	}
+	i.Lock()
+	defer i.Unlock()
+	res := make([]client.RunesUtxo, 0, len(remote))
+	for _, utxo := range remote {
+		// Mined, the remote source is authoritative from now on
+		delete(i.created, utxo.Outpoint)
+		if !i.spent[utxo.Outpoint] {
+			res = append(res, utxo)
+		}
+	}
+	local := make([]client.RunesUtxo, 0)
+	for _, utxo := range i.created {
+		if utxo.WalletAddr == address {
+			local = append(local, utxo)
+		}
+	}
+	sort.Slice(local, func(a, b int) bool { return local[a].Outpoint < local[b].Outpoint })
+	return append(res, local...), nil
+}
+
+// Record a transaction sent by the service, inputs being the outpoints holding runes it spends as the
+// source reported them. Runes move the way ord moves them, see runestone.Allocate
+func (i *UtxoIndex) Apply(tx *wire.MsgTx, inputs []client.RunesUtxo) {
+	i.Lock()
+	defer i.Unlock()
+	known := make(map[string]client.RunesUtxo, len(inputs))
+	for _, utxo := range inputs {
+		known[utxo.Outpoint] = utxo
+	}
+
+	balances := make([][]client.RuneBalance, len(tx.TxIn))
+	for n, in := range tx.TxIn {
+		outpoint := in.PreviousOutPoint.String()
+		balances[n] = known[outpoint].Runes
+		if local, isLocal := i.created[outpoint]; isLocal {
+			balances[n] = local.Runes
+		}
+		i.spent[outpoint] = true
+		delete(i.created, outpoint)
+	}
+
+	hash := tx.TxHash()
+	for n, runes := range runestone.Allocate(tx, balances).Outputs {
+		if len(runes) == 0 {
+			continue
+		}
+		utxo := client.RunesUtxo{
+			Outpoint: wire.NewOutPoint(&hash, uint32(n)).String(),
+			PkScript: hex.EncodeToString(tx.TxOut[n].PkScript),
+			Runes:    runes,
+		}
+		if _, addrs, _, err := txscript.ExtractPkScriptAddrs(tx.TxOut[n].PkScript, i.params); err == nil && len(addrs) == 1 {
+			utxo.WalletAddr = addrs[0].EncodeAddress()
+		}
+		i.created[utxo.Outpoint] = utxo
+	}
+}
@@ -0,0 +1,99 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/stretchr/testify/require"
+)
+
+type staticUtxos []client.RunesUtxo
+
+func (s staticUtxos) GetRunesUtxos(ctx context.Context, address string) ([]client.RunesUtxo, error) {
+	return s, nil
+}
+
+func payTo(t *testing.T, addr string) []byte {
+	decoded, err := btcutil.DecodeAddress(addr, &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	script, err := txscript.PayToAddrScript(decoded)
+	require.NoError(t, err)
+	return script
+}
+
+func TestUtxoIndex(t *testing.T) {
+	ctx := context.Background()
+	sender, receiver := "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "msTNoMYXynujefraNmpeKKuUsAsSYMZ8C2"
+	held := client.RunesUtxo{
+		Outpoint:   "1111111111111111111111111111111111111111111111111111111111111111:0",
+		WalletAddr: sender,
+		Runes: []client.RuneBalance{
+			{RuneId: "840000:1", RuneName: "UNCOMMON•GOODS", Amount: common.NewTokenAmountFromInt64(1000, 2)},
+			{RuneId: "840000:2", Amount: common.NewTokenAmountFromInt64(7, 0)},
+		},
+	}
+	index := NewUtxoIndex(staticUtxos{held}, &chaincfg.RegressionNetParams)
+
+	// Shaped like runes.TransferRuneFrom: change, destination & runestone, paid by an output without runes
+	rune := runes.Rune{BlockNumber: 840000, TxIndex: 1}
+	utxos, err := index.GetRunesUtxos(ctx, sender)
+	require.NoError(t, err)
+	selected, err := runes.SelectRunesUnspentOutput(&rune, big.NewInt(300), utxos)
+	require.NoError(t, err)
+	outpoint, err := selected.OutPoint()
+	require.NoError(t, err)
+	transferScript, err := runes.CreateTransferScript(rune, big.NewInt(300), 1, true)
+	require.NoError(t, err)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 5}, nil, nil))
+	tx.AddTxIn(wire.NewTxIn(outpoint, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(10000, payTo(t, sender)))
+	tx.AddTxOut(wire.NewTxOut(546, payTo(t, receiver)))
+	tx.AddTxOut(wire.NewTxOut(0, transferScript))
+	index.Apply(tx, []client.RunesUtxo{*selected})
+
+	// The spent outpoint is hidden, the change holds the rest of the runes
+	utxos, err = index.GetRunesUtxos(ctx, sender)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, tx.TxHash().String()+":0", utxos[0].Outpoint)
+	require.Equal(t, "7", utxos[0].Balance("840000:1").String())
+	require.Equal(t, "7", utxos[0].Balance("840000:2").String())
+	require.Equal(t, "UNCOMMON•GOODS", utxos[0].Runes[0].RuneName)
+	_, err = runes.SelectRunesUnspentOutput(&rune, big.NewInt(701), utxos)
+	require.ErrorIs(t, err, runes.ErrRunesUtxoNotFound)
+
+	utxos, err = index.GetRunesUtxos(ctx, receiver)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, "3", utxos[0].Balance("840000:1").String())
+	require.Zero(t, utxos[0].Balance("840000:2").Int().Sign())
+
+	// Spending the local change before it's mined, with a cenotaph burning everything
+	change, err := utxos[0].OutPoint()
+	require.NoError(t, err)
+	burn := wire.NewMsgTx(2)
+	burn.AddTxIn(wire.NewTxIn(change, nil, nil))
+	burn.AddTxOut(wire.NewTxOut(546, payTo(t, receiver)))
+	burn.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN, txscript.OP_13, txscript.OP_NOP}))
+	index.Apply(burn, nil)
+	utxos, err = index.GetRunesUtxos(ctx, receiver)
+	require.NoError(t, err)
+	require.Empty(t, utxos)
+
+	// Once mined, the remote source reports the change itself
+	mined := client.RunesUtxo{Outpoint: tx.TxHash().String() + ":0", WalletAddr: sender, Runes: held.Runes[1:]}
+	index.remote = staticUtxos{held, mined}
+	utxos, err = index.GetRunesUtxos(ctx, sender)
+	require.NoError(t, err)
+	require.Equal(t, []client.RunesUtxo{mined}, utxos)
+	require.Empty(t, index.created[mined.Outpoint].Runes)
+}
@@ -31,7 +31,7 @@ func MintRunes(rune Rune, addr btc.Address, privateKey btc.PrivateKey, feeRate u
 	outTxId, err := btc.NewHashFromStr(utxo.TxID)
 
 	outPoint := btc.NewOutPoint(outTxId, utxo.Vout)
-	txIn0 := btc.NewTxIn(outPoint, btc.DummySig, nil) // Dummy Sig used for gas estimation
+	txIn0 := common.NewRbfTxIn(outPoint, btc.DummySig, nil) // Dummy Sig used for gas estimation
 
 	tx.AddTxIn(txIn0)
 
@@ -60,8 +60,11 @@ func MintRunes(rune Rune, addr btc.Address, privateKey btc.PrivateKey, feeRate u
 		return nil, err
 	}
 
-	client := client.NewBitcoinClient(config)
-	h, err := client.SendRawTransaction(tx.MsgTx, true)
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
+	h, err := rpc.SendRawTransaction(tx.MsgTx, true)
 
 	if err != nil {
 		return nil, err
@@ -1,16 +1,13 @@
 package runes_test
 
 import (
-	"fmt"
+	"context"
 	"math/big"
-	"strconv"
-	"strings"
 	"testing"
 	"time"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/ordinox/btc-service/btc"
 	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/cmd"
 	"github.com/ordinox/btc-service/common"
@@ -30,16 +27,18 @@ func testTransfer(t *testing.T, amt int64, shouldVerify bool) {
 		senderRuneBalance   *client.RunesBalance
 		receiverRuneBalance *client.RunesBalance
 
+		ctx         = context.Background()
 		config      = config.GetDefaultConfig()
-		opiClient   = client.NewOpiClient(config.OpiConfig)
 		pk          = common.LoadPrivateKey(TEST_PRIVATE_KEY)
 		addr, err   = common.GetP2PKHAddress(pk.PubKey().SerializeCompressed(), &chaincfg.RegressionNetParams)
 		destAddr, _ = btcutil.DecodeAddress("msTNoMYXynujefraNmpeKKuUsAsSYMZ8C2", &chaincfg.RegressionNetParams)
 	)
 	require.Nil(t, err)
+	opiClient, err := client.NewOpiClient(config.OpiConfig)
+	require.Nil(t, err)
 
 	// Check Balance - Store balance
-	balances, err := opiClient.GetRunesBalance(addr.EncodeAddress())
+	balances, err := opiClient.GetRunesBalance(ctx, addr.EncodeAddress())
 	require.Nil(t, err)
 	for _, i := range balances {
 		if i.RuneID == TEST_RUNE_ID {
@@ -48,10 +47,10 @@ func testTransfer(t *testing.T, amt int64, shouldVerify bool) {
 		}
 	}
 	require.NotNil(t, senderRuneBalance)
-	senderPrevBalanceStr := senderRuneBalance.TotalBalance
+	senderPrevBalance := senderRuneBalance.TotalBalance
 
 	// Receiver prev address
-	balances, err = opiClient.GetRunesBalance(destAddr.EncodeAddress())
+	balances, err = opiClient.GetRunesBalance(ctx, destAddr.EncodeAddress())
 	require.Nil(t, err)
 	for _, i := range balances {
 		if i.RuneID == TEST_RUNE_ID {
@@ -60,13 +59,13 @@ func testTransfer(t *testing.T, amt int64, shouldVerify bool) {
 		}
 	}
 	require.NotNil(t, receiverRuneBalance)
-	receiverPrevBalanceStr := receiverRuneBalance.TotalBalance
+	receiverPrevBalance := receiverRuneBalance.TotalBalance
 
 	// Do Transfer
 	rune, err := runes.ParseRune(senderRuneBalance.RuneID)
 	require.Nil(t, err)
 
-	hash, err := runes.TransferRune(*rune, big.NewInt(amt), addr, destAddr, pk, 22, config)
+	hash, err := runes.TransferRune(ctx, *rune, big.NewInt(amt), addr, destAddr, pk, 22, config)
 	require.Nil(t, err)
 
 	if !shouldVerify {
@@ -84,7 +83,7 @@ func testTransfer(t *testing.T, amt int64, shouldVerify bool) {
 	time.Sleep(3 * time.Second)
 
 	// Get new sender balances
-	balances, err = opiClient.GetRunesBalance(addr.EncodeAddress())
+	balances, err = opiClient.GetRunesBalance(ctx, addr.EncodeAddress())
 	require.Nil(t, err)
 	for _, i := range balances {
 		if i.RuneID == TEST_RUNE_ID {
@@ -92,10 +91,10 @@ func testTransfer(t *testing.T, amt int64, shouldVerify bool) {
 			break
 		}
 	}
-	senderNewBalanceStr := senderRuneBalance.TotalBalance
+	senderNewBalance := senderRuneBalance.TotalBalance
 
 	// Get new receiver balances
-	balances, err = opiClient.GetRunesBalance(destAddr.EncodeAddress())
+	balances, err = opiClient.GetRunesBalance(ctx, destAddr.EncodeAddress())
 	require.Nil(t, err)
 	for _, i := range balances {
 		if i.RuneID == TEST_RUNE_ID {
@@ -103,30 +102,29 @@ func testTransfer(t *testing.T, amt int64, shouldVerify bool) {
 			break
 		}
 	}
-	receiverNewBalanceStr := receiverRuneBalance.TotalBalance
-
-	senderPrevBalance, ok := big.NewInt(0).SetString(senderPrevBalanceStr, 10)
-	require.True(t, ok)
-
-	receiverPrevBalance, ok := big.NewInt(0).SetString(receiverPrevBalanceStr, 10)
-	require.True(t, ok)
-
-	senderNewBalance, ok := big.NewInt(0).SetString(senderNewBalanceStr, 10)
-	require.True(t, ok)
-
-	receiverNewBalance, ok := big.NewInt(0).SetString(receiverNewBalanceStr, 10)
-	require.True(t, ok)
+	receiverNewBalance := receiverRuneBalance.TotalBalance
 
 	// Check if runes are deducted from the sender
-	require.Equal(t, big.NewInt(0).Sub(senderPrevBalance, senderNewBalance).String(), big.NewInt(amt).String())
+	sent, err := senderPrevBalance.Sub(senderNewBalance)
+	require.NoError(t, err)
+	require.Equal(t, sent.Int().String(), big.NewInt(amt).String())
 
 	// Check if runes are added to the receiver
-	require.Equal(t, big.NewInt(0).Sub(receiverNewBalance, receiverPrevBalance).String(), big.NewInt(amt).String())
-
-	require.NotNil(t, big.NewInt(0).Sub(receiverNewBalance, receiverPrevBalance).String(), big.NewInt(amt).String())
-
-	err = runes.VerifyRunesDeposit(config, (*hash).String(), addr.EncodeAddress(), destAddr.EncodeAddress(), fmt.Sprintf("%d", amt))
-	require.Nil(t, err)
+	received, err := receiverNewBalance.Sub(receiverPrevBalance)
+	require.NoError(t, err)
+	require.Equal(t, received.Int().String(), big.NewInt(amt).String())
+
+	info, err := opiClient.GetRunesTickerInfo(ctx, TEST_RUNE_ID)
+	require.NoError(t, err)
+	receipt, err := runes.VerifyRunesDeposit(ctx, config, runes.RunesDeposit{
+		TxId:     (*hash).String(),
+		FromAddr: addr.EncodeAddress(),
+		ToAddr:   destAddr.EncodeAddress(),
+		RuneId:   TEST_RUNE_ID,
+		Amount:   common.NewTokenAmountFromInt64(amt, info.Divisibility),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{addr.EncodeAddress()}, receipt.Senders)
 }
 
 // Outputs given by OPI should be valid outpoints
@@ -135,25 +133,23 @@ func TestRunesOutpointFetch(t *testing.T) {
 	config := config.GetDefaultConfig()
 	pk := common.LoadPrivateKey(TEST_PRIVATE_KEY)
 	addr, _ := common.GetP2PKHAddress(pk.PubKey().SerializeCompressed(), &chaincfg.RegressionNetParams)
-	opiClient := client.NewOpiClient(config.OpiConfig)
-	btcClient := client.NewBitcoinClient(config)
+	opiClient, err := client.NewOpiClient(config.OpiConfig)
+	require.Nil(t, err)
+	btcClient, err := client.NewBitcoinClient(config)
+	require.Nil(t, err)
 
 	rune, _ := runes.ParseRune("310:1")
 
-	utxos, err := opiClient.GetRunesUnspentOutpoints(addr.EncodeAddress())
-	require.Nil(t, err)
-
-	outpoint, err := runes.SelectRunesUnspentOutput(rune, big.NewInt(100), utxos)
+	utxos, err := opiClient.GetRunesUtxos(context.Background(), addr.EncodeAddress())
 	require.Nil(t, err)
 
-	txId := strings.Split(outpoint.Outpoint, ":")[0]
-	_, err = strconv.Atoi(strings.Split(outpoint.Outpoint, ":")[1])
+	utxo, err := runes.SelectRunesUnspentOutput(rune, big.NewInt(100), utxos)
 	require.Nil(t, err)
 
-	hash, err := btc.NewHashFromStr(txId)
+	outpoint, err := utxo.OutPoint()
 	require.Nil(t, err)
 
-	_, err = btcClient.GetRawTransactionVerbose(hash)
+	_, err = btcClient.GetRawTransactionVerbose(&outpoint.Hash)
 	require.Nil(t, err)
 }
 
@@ -0,0 +1,167 @@
+package runestone
+
+import (
+	"math/big"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+)
+
+// Runes held by the outputs of a transaction once applied
+type Allocation struct {
+	Outputs  [][]client.RuneBalance // By output, in the order the runes came in. Empty for OP_RETURNs
+	Burned   []client.RuneBalance
+	Cenotaph bool
+}
+
+// Amount of a rune held by an output
+func (a Allocation) Balance(output int, runeId string) common.TokenAmount {
+	for _, r := range a.Outputs[output] {
+		if r.RuneId == runeId {
+			return r.Amount
+		}
+	}
+	return common.NewTokenAmountFromInt64(0, 0)
+}
+
+// Move the runes of the inputs, given in the order of tx.TxIn, the way ord moves them: edicts first, then
+// the rest to the pointer or the first output which isn't an OP_RETURN. A cenotaph burns them all, and so
+// does an OP_RETURN output. Runes minted or etched by the transaction aren't accounted for
+func Allocate(tx *wire.MsgTx, inputs [][]client.RuneBalance) Allocation {
+	unallocated := make(map[string]*big.Int)
+	var order []string // Runes in the order they came in, so outputs list them deterministically
+	info := make(map[string]client.RuneBalance)
+	for _, runes := range inputs {
+		for _, r := range runes {
+			if _, ok := unallocated[r.RuneId]; !ok {
+				unallocated[r.RuneId] = new(big.Int)
+				order = append(order, r.RuneId)
+				info[r.RuneId] = r
+			}
+			unallocated[r.RuneId].Add(unallocated[r.RuneId], r.Amount.Int())
+		}
+	}
+
+	allocated := make([]map[string]*big.Int, len(tx.TxOut))
+	allocate := func(output int, runeId string, amount *big.Int) {
+		if amount.Sign() == 0 {
+			return
+		}
+		if allocated[output] == nil {
+			allocated[output] = make(map[string]*big.Int)
+		}
+		if allocated[output][runeId] == nil {
+			allocated[output][runeId] = new(big.Int)
+		}
+		allocated[output][runeId].Add(allocated[output][runeId], amount)
+		unallocated[runeId].Sub(unallocated[runeId], amount)
+	}
+	balance := func(runes map[string]*big.Int) []client.RuneBalance {
+		var res []client.RuneBalance
+		for _, runeId := range order {
+			if amount, ok := runes[runeId]; ok && amount.Sign() > 0 {
+				r := info[runeId]
+				r.Amount = common.NewTokenAmount(amount, r.Amount.Decimals())
+				res = append(res, r)
+			}
+		}
+		return res
+	}
+
+	res := Allocation{Outputs: make([][]client.RuneBalance, len(tx.TxOut))}
+	var pointer *int
+	if HasRunestone(tx) {
+		artifact := DecipherRunestone(tx)
+		if artifact.Runestone == nil {
+			res.Cenotaph = true
+			res.Burned = balance(unallocated)
+			return res
+		}
+		var destinations []int
+		for n, out := range tx.TxOut {
+			if !IsOpReturn(out.PkScript) {
+				destinations = append(destinations, n)
+			}
+		}
+		for _, edict := range artifact.Runestone.Edicts {
+			runeId := edict.Id.String()
+			if _, ok := unallocated[runeId]; !ok {
+				continue
+			}
+			output := int(edict.Output)
+			switch {
+			case output < len(tx.TxOut) && edict.Amount.Sign() == 0:
+				allocate(output, runeId, new(big.Int).Set(unallocated[runeId]))
+			case output < len(tx.TxOut):
+				allocate(output, runeId, minInt(edict.Amount, unallocated[runeId]))
+			case len(destinations) > 0 && edict.Amount.Sign() == 0:
+				// Split evenly, the first outputs getting the remainder
+				share, remainder := new(big.Int).QuoRem(unallocated[runeId], big.NewInt(int64(len(destinations))), new(big.Int))
+				for i, n := range destinations {
+					amount := new(big.Int).Set(share)
+					if int64(i) < remainder.Int64() {
+						amount.Add(amount, big.NewInt(1))
+					}
+					allocate(n, runeId, amount)
+				}
+			default:
+				for _, n := range destinations {
+					allocate(n, runeId, minInt(edict.Amount, unallocated[runeId]))
+				}
+			}
+		}
+		if artifact.Runestone.Pointer != nil {
+			p := int(*artifact.Runestone.Pointer)
+			pointer = &p
+		}
+	}
+	if pointer == nil {
+		for n, out := range tx.TxOut {
+			if !IsOpReturn(out.PkScript) {
+				pointer = &n
+				break
+			}
+		}
+	}
+	if pointer != nil {
+		for _, runeId := range order {
+			allocate(*pointer, runeId, new(big.Int).Set(unallocated[runeId]))
+		}
+	}
+
+	burned := unallocated
+	for n, runes := range allocated {
+		if !IsOpReturn(tx.TxOut[n].PkScript) {
+			res.Outputs[n] = balance(runes)
+			continue
+		}
+		for runeId, amount := range runes {
+			burned[runeId].Add(burned[runeId], amount)
+		}
+	}
+	res.Burned = balance(burned)
+	return res
+}
+
+func minInt(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
+
+// Whether an output carries a runestone, cenotaphs included
+func HasRunestone(tx *wire.MsgTx) bool {
+	for _, out := range tx.TxOut {
+		if len(out.PkScript) >= 2 && out.PkScript[0] == txscript.OP_RETURN && out.PkScript[1] == MAGIC_NUMBER {
+			return true
+		}
+	}
+	return false
+}
+
+func IsOpReturn(pkScript []byte) bool {
+	return len(pkScript) > 0 && pkScript[0] == txscript.OP_RETURN
+}
@@ -32,8 +32,9 @@ func NewEdictFromIntegers(tx *wire.MsgTx, id RuneId, amount *big.Int, output *bi
 		return EmptyEdict, fmt.Errorf("output value %v is out of range for uint64", output)
 	}
 
+	// An edict to the output past the last one splits the runes between all the outputs
 	o := int(output.Uint64())
-	if o >= len(tx.TxOut) { // Changed to >= to handle out-of-bounds correctly
+	if o > len(tx.TxOut) {
 		return EmptyEdict, fmt.Errorf("%w: output index %d out of range", ErrInvalidOutput, o)
 	}
 
@@ -42,8 +42,13 @@ func NewRuneId(block uint64, tx uint32) RuneId {
 	return RuneId{Block: Uint64(block), Tx: Uint32(tx)}
 }
 
+// Encoding of next relative to r, the way edicts carry their ids. Next can't come before r
 func (r RuneId) Delta(next RuneId) (*big.Int, *big.Int) {
-	return new(big.Int), new(big.Int)
+	block := new(big.Int).SetUint64(uint64(next.Block - r.Block))
+	if block.Sign() == 0 {
+		return block, new(big.Int).SetUint64(uint64(next.Tx - r.Tx))
+	}
+	return block, new(big.Int).SetUint64(uint64(next.Tx))
 }
 
 func (r RuneId) Next(block *big.Int, tx *big.Int) (RuneId, error) {
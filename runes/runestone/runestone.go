@@ -75,7 +75,10 @@ func DecipherRunestone(tx *wire.MsgTx) Artifact {
 	})
 
 	flaw := None
-	if flags != nil && flags.Uint64() != 0 {
+	if msg.Flaw != None {
+		flaw = int(msg.Flaw)
+	}
+	if flaw == None && flags != nil && flags.Uint64() != 0 {
 		flaw = UnrecognizedFlag
 	}
 
@@ -127,9 +130,12 @@ func EncipherRunestone(runeStone Runestone) *txscript.ScriptBuilder {
 			}
 			return edicts[i].Id.Block < edicts[j].Id.Block
 		})
+		previous := RuneId{}
 		for _, e := range edicts {
-			payload = encodeToVec(e.Id.Block.To64(), payload)
-			payload = encodeToVec(e.Id.Tx.To64(), payload)
+			block, tx := previous.Delta(e.Id)
+			previous = e.Id
+			payload = encodeToVec(block, payload)
+			payload = encodeToVec(tx, payload)
 			payload = encodeToVec(e.Amount, payload)
 			payload = encodeToVec(e.Output.To64(), payload)
 		}
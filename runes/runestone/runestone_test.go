@@ -1,4 +1,4 @@
-package runestone
+package runestone_test
 
 import (
 	"fmt"
@@ -13,10 +13,11 @@ import (
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
 	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/runes/runestone"
 )
 
 type RuneTx struct {
-	Edict   Edict
+	Edict   runestone.Edict
 	Tx      *btcutil.Tx
 	Senders []btcutil.Address
 }
@@ -47,7 +48,7 @@ func TestEdicts(t *testing.T) {
 	}
 	runes := make([]RuneTx, 0)
 	for _, tx := range txs {
-		artifact := DecipherRunestone(tx.MsgTx())
+		artifact := runestone.DecipherRunestone(tx.MsgTx())
 		if artifact.Runestone != nil {
 			for _, e := range artifact.Runestone.Edicts {
 				output := tx.MsgTx().TxOut[e.Output]
@@ -90,13 +91,13 @@ func TestEdicts(t *testing.T) {
 
 func TestEncipherRune(t *testing.T) {
 	script1, _ := runes.CreateTransferScript(runes.Rune{BlockNumber: 100, TxIndex: 100}, big.NewInt(100), 0, true)
-	edict := Edict{Id: NewRuneId(100, 100), Output: 0, Amount: big.NewInt(100)}
-	runeStone := Runestone{Edicts: []Edict{edict}}
-	runeScript, _ := EncipherRunestone(runeStone).Script()
+	edict := runestone.Edict{Id: runestone.NewRuneId(100, 100), Output: 0, Amount: big.NewInt(100)}
+	runeStone := runestone.Runestone{Edicts: []runestone.Edict{edict}}
+	runeScript, _ := runestone.EncipherRunestone(runeStone).Script()
 	fmt.Println((script1), (runeScript))
 	tx := wire.NewMsgTx(wire.TxVersion)
 	tx.AddTxOut(wire.NewTxOut(0, script1))
-	for _, i := range DecipherRunestone(tx).Runestone.Edicts {
+	for _, i := range runestone.DecipherRunestone(tx).Runestone.Edicts {
 		fmt.Printf("%v", i)
 	}
 }
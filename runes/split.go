@@ -1,6 +1,7 @@
 package runes
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/btcsuite/btcd/btcec/v2"
@@ -9,9 +10,12 @@ import (
 	"github.com/ordinox/btc-service/client"
 	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
+	"github.com/rs/zerolog/log"
 )
 
-func Split(addr btc.Address, privateKey *btcec.PrivateKey, utxoIn common.Utxo, outCount, outValue uint64, feeRate uint64, config config.Config) (*chainhash.Hash, error) {
+// Split an output holding no runes into outputs of outValue, to pay the fees of later transfers.
+// The outpoints holding runes are listed by the configured runes utxo source
+func Split(ctx context.Context, addr btc.Address, privateKey *btcec.PrivateKey, outCount, outValue uint64, feeRate uint64, config config.Config) (*chainhash.Hash, error) {
 	addr, pubkeyData, err := common.VerifyPrivateKey(privateKey, addr, config.BtcConfig.GetChainConfigParams())
 	if err != nil {
 		return nil, err
@@ -20,19 +24,26 @@ func Split(addr btc.Address, privateKey *btcec.PrivateKey, utxoIn common.Utxo, o
 	if err != nil {
 		return nil, err
 	}
-	totalSatsReq := outValue * outCount
-	utxo, err := common.SelectOneUtxo(addr.EncodeAddress(), totalSatsReq, config.BtcConfig)
+	source, err := NewRunesUtxoSource(config)
 	if err != nil {
-		fmt.Printf("Err: Not enough sats: Total Sats Required: %d\n", totalSatsReq)
 		return nil, err
 	}
+	runeUtxos, err := source.GetRunesUtxos(ctx, addr.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	totalSatsReq := outValue * outCount
+	utxo, err := common.SelectOneUtxoExcept(addr.EncodeAddress(), totalSatsReq, runesOutpoints(runeUtxos), config.BtcConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%d sats required: %w", totalSatsReq, err)
+	}
 
 	rawTx := btc.NewMsgTx(int32(btc.TxVersion))
 	tx := common.NewWrappedTx(rawTx, senderScript)
 
 	outTxId, err := btc.NewHashFromStr(utxo.TxID)
 	outPoint := btc.NewOutPoint(outTxId, utxo.Vout)
-	txIn0 := btc.NewTxIn(outPoint, btc.DummySig, nil) // Dummy Sig used for gas estimation
+	txIn0 := common.NewRbfTxIn(outPoint, btc.DummySig, nil) // Dummy Sig used for gas estimation
 	tx.AddTxIn(txIn0)
 
 	count := 0
@@ -60,7 +71,7 @@ func Split(addr btc.Address, privateKey *btcec.PrivateKey, utxoIn common.Utxo, o
 	if len(tx.TxOut) >= int(pruneCount) {
 		tx.TxOut = tx.TxOut[:len(tx.TxOut)-int(pruneCount)]
 	} else {
-		panic("UTXOs less than prune count")
+		return nil, fmt.Errorf("%d outputs can't pay the fee of %d sats", len(tx.TxOut), fee)
 	}
 	changeTxOut := btc.NewTxOut(int64(change), senderScript)
 	tx.AddTxOut(changeTxOut)
@@ -69,16 +80,17 @@ func Split(addr btc.Address, privateKey *btcec.PrivateKey, utxoIn common.Utxo, o
 		return nil, err
 	}
 
-	client := client.NewBitcoinClient(config)
-	h, err := client.SendRawTransaction(tx.MsgTx, true)
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
+	h, err := rpc.SendRawTransaction(tx.MsgTx, true)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println("Count:", count)
-	fmt.Println("Change:", change)
-	fmt.Println("Fee", fee)
+	log.Debug().Str("tx_id", h.String()).Int("outputs", count).Int64("change", change).Uint64("fee", fee).Msg("split sent")
 
 	return h, nil
 }
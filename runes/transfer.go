@@ -1,10 +1,10 @@
 package runes
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/big"
-	"strconv"
-	"strings"
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/ordinox/btc-service/btc"
@@ -13,30 +13,76 @@ import (
 	"github.com/ordinox/btc-service/config"
 )
 
+var ErrRunesUtxoNotFound = errors.New("runes utxo not found")
+
+// Source keeping track of the transactions sent with its outpoints, e.g. the local index
+type TxRecorder interface {
+	Apply(tx *wire.MsgTx, inputs []client.RunesUtxo)
+}
+
+// Indexer serving both the outpoints holding runes and the ones a transaction spent
+type runesIndexer interface {
+	client.RunesUtxoSource
+	client.RunesInputSource
+}
+
+// The indexer set in runes.utxo_source, BIS by default on mainnet and OPI everywhere else
+func NewRunesUtxoSource(config config.Config) (client.RunesUtxoSource, error) {
+	return newRunesIndexer(config)
+}
+
+func newRunesIndexer(config config.Config) (runesIndexer, error) {
+	switch source := config.GetRunesUtxoSource(); source {
+	case "opi":
+		opi, err := client.NewOpiClient(config.OpiConfig)
+		if err != nil {
+			return nil, err
+		}
+		return opi, nil
+	case "bis":
+		bis, err := client.NewBISClient(config.BISConfig)
+		if err != nil {
+			return nil, err
+		}
+		return bis, nil
+	default:
+		return nil, fmt.Errorf("unknown runes utxo source %q", source)
+	}
+}
+
 // Given a list of rune utxos, get the most appropirate utxo for a transfer
-func SelectRunesUnspentOutput(rune *Rune, amt *big.Int, utxos []client.OPIRunesUnspentOutput) (*client.OPIRunesUnspentOutput, error) {
+func SelectRunesUnspentOutput(rune *Rune, amt *big.Int, utxos []client.RunesUtxo) (*client.RunesUtxo, error) {
 	runeStr := rune.String()
-
-	for i, utxo := range utxos {
-		for j, rune := range utxo.RuneIds {
-			// Check if the rune
-			if rune == runeStr && utxos[i].Balances[j].Cmp(amt) >= 0 {
-				return &utxo, nil
-			}
+	for i := range utxos {
+		if utxos[i].Balance(runeStr).Int().Cmp(amt) >= 0 {
+			return &utxos[i], nil
 		}
 	}
-	return nil, fmt.Errorf("Runes UTXO not found")
+	return nil, fmt.Errorf("%w: no outpoint holds %s of %s", ErrRunesUtxoNotFound, amt, runeStr)
 }
 
-// Transfer one specific rune from one address to another
-// TODO: Port this into a batch transfer function to save on fees
-func TransferRune(rune Rune, amount *big.Int, addr btc.Address, toAddr btc.Address, privateKey btc.PrivateKey, feeRate uint64, config config.Config) (btc.Hash, error) {
-	var (
-		// Flag to identify if the transaction has 2 inputs (FEE UTXO + RUNE UTXO) or 1 (RUNE UTXO)
-		includesFeeUtxo = false
-	)
+// Outpoints holding runes, spending them to pay fees would move the runes
+func runesOutpoints(utxos []client.RunesUtxo) map[string]bool {
+	outpoints := make(map[string]bool, len(utxos))
+	for _, utxo := range utxos {
+		outpoints[utxo.Outpoint] = true
+	}
+	return outpoints
+}
 
-	opiClient := client.NewOpiClient(config.OpiConfig)
+// Transfer one specific rune from one address to another, see TransferRuneFrom
+func TransferRune(ctx context.Context, rune Rune, amount *big.Int, addr btc.Address, toAddr btc.Address, privateKey btc.PrivateKey, feeRate uint64, config config.Config) (btc.Hash, error) {
+	source, err := NewRunesUtxoSource(config)
+	if err != nil {
+		return nil, err
+	}
+	return TransferRuneFrom(ctx, source, rune, amount, addr, toAddr, privateKey, feeRate, config)
+}
+
+// Transfer one specific rune from one address to another, with the outpoints of the source. The fees are
+// paid by an output holding no runes, and a TxRecorder source records the transaction once it's sent
+// TODO: Port this into a batch transfer function to save on fees
+func TransferRuneFrom(ctx context.Context, source client.RunesUtxoSource, rune Rune, amount *big.Int, addr btc.Address, toAddr btc.Address, privateKey btc.PrivateKey, feeRate uint64, config config.Config) (btc.Hash, error) {
 	addr, pubkeyData, err := common.VerifyPrivateKey(privateKey, addr, config.BtcConfig.GetChainConfigParams())
 	if err != nil {
 		return nil, err
@@ -54,55 +100,40 @@ func TransferRune(rune Rune, amount *big.Int, addr btc.Address, toAddr btc.Addre
 		return nil, err
 	}
 
-	// Get a utxo for fee payment
-	utxo, err := common.SelectOneUtxo(addr.EncodeAddress(), 1000, config.BtcConfig)
+	// Get the rune inputs
+	runeUtxos, err := source.GetRunesUtxos(ctx, addr.EncodeAddress())
 	if err != nil {
 		return nil, err
 	}
 
-	rawTx := btc.NewMsgTx(int32(btc.TxVersion))
-	tx := common.NewWrappedTx(rawTx, senderScript)
-
-	outTxId, _ := btc.NewHashFromStr(utxo.TxID)
-
-	outPoint := btc.NewOutPoint(outTxId, utxo.Vout)
-	txIn0 := btc.NewTxIn(outPoint, btc.DummySig, nil) // Dummy Sig used for gas estimation
-
-	// This is the fee utxo but, can be also the rune utxo
-	tx.AddTxIn(txIn0)
-
-	// Get the rune inputs
-	runeUtxos, err := opiClient.GetRunesUnspentOutpoints(addr.EncodeAddress())
+	// Get the Rune UTXO for the given Rune
+	runeUtxo, err := SelectRunesUnspentOutput(&rune, amount, runeUtxos)
 	if err != nil {
 		return nil, err
 	}
-
-	// Get the Rune UTXO for the given Rune
-	runeOutpoint, err := SelectRunesUnspentOutput(&rune, amount, runeUtxos)
+	runeOutPoint, err := runeUtxo.OutPoint()
 	if err != nil {
 		return nil, err
 	}
 
-	runeOutTxIdStr := strings.Split(runeOutpoint.Outpoint, ":")[0]
-	vout, err := strconv.Atoi(strings.Split(runeOutpoint.Outpoint, ":")[1])
+	// Get a utxo for fee payment
+	utxo, err := common.SelectOneUtxoExcept(addr.EncodeAddress(), 1000, runesOutpoints(runeUtxos), config.BtcConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	// If the Rune UTXO and the cardinal UTXOs are different, then the tx needs to have 2 txins
-	if runeOutTxIdStr != utxo.TxID {
-		runeOutTxId, err := btc.NewHashFromStr(runeOutTxIdStr)
-		if err != nil {
-			return nil, err
-		}
-
-		runeOutPoint := btc.NewOutPoint(runeOutTxId, uint32(vout))
-		txIn1 := btc.NewTxIn(runeOutPoint, btc.DummySig, nil)
-
-		tx.AddTxIn(txIn1)
+	rawTx := btc.NewMsgTx(int32(btc.TxVersion))
+	tx := common.NewWrappedTx(rawTx, senderScript)
 
-		includesFeeUtxo = true // Set the fee flag
+	outTxId, err := btc.NewHashFromStr(utxo.TxID)
+	if err != nil {
+		return nil, err
 	}
+	outPoint := btc.NewOutPoint(outTxId, utxo.Vout)
+
+	// Dummy Sigs used for gas estimation
+	tx.AddTxIn(common.NewRbfTxIn(outPoint, btc.DummySig, nil))
+	tx.AddTxIn(common.NewRbfTxIn(runeOutPoint, btc.DummySig, nil))
 
 	// Runestone script
 	transferScript, err := CreateTransferScript(rune, amount, 1, true)
@@ -126,6 +157,7 @@ func TransferRune(rune Rune, amount *big.Int, addr btc.Address, toAddr btc.Addre
 		return nil, fmt.Errorf("UTXO Amount is lower than what's needed: change=%d fee=%d amt=%d totalUtxoAmt=%f", change, fee, 546, utxo.Amount)
 	}
 
+	// The runes left on the rune input go back to the change, the first output
 	changeTxOut := btc.NewTxOut(change, senderScript)
 
 	// Reset TxOut
@@ -134,20 +166,24 @@ func TransferRune(rune Rune, amount *big.Int, addr btc.Address, toAddr btc.Addre
 	tx.AddTxOut(destTxOut)
 	tx.AddTxOut(transferTxOut)
 
-	if err := tx.SignP2PKH(privateKey, pubkeyData, 0); err != nil {
-		return nil, err
-	}
-
-	// If there is a seperate rune txin, sign the second txin as well
-	if includesFeeUtxo {
-		if err := tx.SignP2PKH(privateKey, pubkeyData, 1); err != nil {
+	for i := range tx.TxIn {
+		if err := tx.SignP2PKH(privateKey, pubkeyData, i); err != nil {
 			return nil, err
 		}
 	}
 
-	client := client.NewBitcoinClient(config)
-	h, err := client.SendRawTransaction(tx.MsgTx, true)
-	return h, err
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := rpc.SendRawTransaction(tx.MsgTx, true)
+	if err != nil {
+		return nil, err
+	}
+	if recorder, ok := source.(TxRecorder); ok {
+		recorder.Apply(tx.MsgTx, []client.RunesUtxo{*runeUtxo})
+	}
+	return hash, nil
 }
 
 // Create txout script which contains the runestone
@@ -1,50 +1,280 @@
 package runes
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
 	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/runes/runestone"
 )
 
-// Since "/event" api returns a UTXO and UTXOs can have multiple outputs, it's impossible to extract one receiver
-// Hence, this verifier requires sender, receiver & amountToVerify to ensure that the runes transfer has actually taken place
-// TODO: Check if the blockheight of txid and the blockheight of the scan
-func VerifyRunesDeposit(config config.Config, txId string, sender, receiver, amountToVerify string) error {
-	var (
-		client           = client.NewOpiClient(config.OpiConfig)
-		senderVerified   = false
-		receiverVerified = false
-	)
+var (
+	ErrNotEnoughConfirmations = errors.New("runes deposit doesn't have enough confirmations")
+	ErrRunesInputsNotFound    = errors.New("no input of the deposit holds the rune")
+	ErrRunesBurned            = errors.New("runes deposit is a cenotaph, its runes are burned")
+	ErrRunesNotReceived       = errors.New("receiver didn't get the rune")
+	ErrRunesAmountMismatch    = errors.New("runes deposit amount doesn't match")
+	ErrRunesSenderMismatch    = errors.New("runes deposit wasn't sent by the sender")
+)
+
+// Looks up a transaction along with its confirmations
+type TxLookup interface {
+	GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error)
+}
+
+var _ TxLookup = &client.BtcRpcClient{}
+
+// Verifies runes deposits from the transaction itself: the runes its inputs held, as reported by an
+// indexer, are moved along its runestone the way ord moves them
+type RunesDepositVerifier struct {
+	Chain             TxLookup
+	Inputs            client.RunesInputSource
+	Params            *chaincfg.Params
+	MinConfirmations  int64 // Defaults to 1
+	AllowOtherSenders bool  // Inputs of other addresses may spend the rune too, as long as one of the sender's does
+}
+
+type RunesDeposit struct {
+	TxId             string
+	FromAddr, ToAddr string             // No sender accepts runes from anyone
+	RuneId           string             // block:tx
+	Amount           common.TokenAmount // Received over all the outputs of the receiver, compared in base units
+}
+
+// Runes received by an address in a transaction
+type RunesDepositReceipt struct {
+	TxId          string             `json:"tx_id"`
+	RuneId        string             `json:"rune_id"`
+	RuneName      string             `json:"rune_name"` // Empty when the indexer doesn't report it
+	Amount        common.TokenAmount `json:"amount"`
+	Outputs       []uint32           `json:"outputs"` // Outputs of the receiver holding the rune
+	Senders       []string           `json:"senders"` // Addresses of the inputs which held the rune
+	Confirmations int64              `json:"confirmations"`
+}
+
+// The configured runes indexer, see runes.utxo_source, with bitcoin core for the transaction
+func NewRunesDepositVerifier(config config.Config) (RunesDepositVerifier, error) {
+	rpc, err := client.NewBitcoinClient(config)
+	if err != nil {
+		return RunesDepositVerifier{}, err
+	}
+	indexer, err := newRunesIndexer(config)
+	if err != nil {
+		return RunesDepositVerifier{}, err
+	}
+	return RunesDepositVerifier{
+		Chain:             rpc,
+		Inputs:            indexer,
+		Params:            config.BtcConfig.GetChainConfigParams(),
+		MinConfirmations:  config.RunesConfig.GetMinConfirmations(),
+		AllowOtherSenders: config.RunesConfig.AllowOtherSenders,
+	}, nil
+}
+
+func VerifyRunesDeposit(ctx context.Context, config config.Config, deposit RunesDeposit) (*RunesDepositReceipt, error) {
+	verifier, err := NewRunesDepositVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+	return verifier.Verify(ctx, deposit)
+}
 
-	events, err := client.GetRunesEventsByTxID(txId)
+// Work out the runes the receiver got in the deposit and check them against it. A deposit which
+// isn't deep enough yet is returned along with ErrNotEnoughConfirmations
+func (v RunesDepositVerifier) Verify(ctx context.Context, deposit RunesDeposit) (*RunesDepositReceipt, error) {
+	hash, err := chainhash.NewHashFromStr(deposit.TxId)
+	if err != nil {
+		return nil, err
+	}
+	res, err := v.Chain.GetRawTransactionVerbose(hash)
+	if err != nil {
+		return nil, err
+	}
+	minConfirmations := v.MinConfirmations
+	if minConfirmations < 1 {
+		minConfirmations = 1
+	}
+	// Indexers only know about mined transactions
+	if res.Confirmations < 1 {
+		return nil, fmt.Errorf("%w: %s is unconfirmed", ErrNotEnoughConfirmations, deposit.TxId)
+	}
+	raw, err := hex.DecodeString(res.Hex)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
 	}
 
-	if len(events) == 0 {
-		return fmt.Errorf("transaction not found")
+	receipt, err := v.receipt(ctx, tx, deposit)
+	if err != nil {
+		return nil, err
 	}
+	receipt.Confirmations = int64(res.Confirmations)
+	if receipt.Confirmations < minConfirmations {
+		return receipt, fmt.Errorf("%w: %d of %d", ErrNotEnoughConfirmations, receipt.Confirmations, minConfirmations)
+	}
+	return receipt, nil
+}
 
-	// Loop through all the outputs and check if Sender, Receiver & Amount matches the inputs
-	for _, evt := range events {
-		if evt.EventType == "input" && evt.WalletAddr != nil {
-			senderVerified = true
-		} else if evt.EventType == "output" && evt.WalletAddr != nil && evt.Amount == amountToVerify && evt.WalletAddr.(string) == receiver {
-			receiverVerified = true
+// Runes moved by a transaction
+type resolvedTx struct {
+	allocation runestone.Allocation
+	senders    map[string][]string // Rune id -> addresses of the inputs which held it
+	names      map[string]string   // Rune id -> spaced name
+}
+
+func (v RunesDepositVerifier) resolve(ctx context.Context, tx *wire.MsgTx) (*resolvedTx, error) {
+	inputs, err := v.Inputs.GetRunesInputs(ctx, tx.TxHash().String())
+	if err != nil {
+		return nil, err
+	}
+	spent := make(map[string]client.RunesUtxo, len(inputs))
+	for _, utxo := range inputs {
+		spent[utxo.Outpoint] = utxo
+	}
+	res := &resolvedTx{senders: make(map[string][]string), names: make(map[string]string)}
+	balances := make([][]client.RuneBalance, len(tx.TxIn))
+	for n, in := range tx.TxIn {
+		utxo, ok := spent[in.PreviousOutPoint.String()]
+		if !ok {
+			continue
 		}
-		if senderVerified && receiverVerified {
-			return nil
+		balances[n] = utxo.Runes
+		for _, r := range utxo.Runes {
+			if r.Amount.Sign() == 0 {
+				continue
+			}
+			if res.names[r.RuneId] == "" {
+				res.names[r.RuneId] = r.RuneName
+			}
+			if sender := v.inputAddress(utxo); !slices.Contains(res.senders[r.RuneId], sender) {
+				res.senders[r.RuneId] = append(res.senders[r.RuneId], sender)
+			}
 		}
 	}
+	res.allocation = runestone.Allocate(tx, balances)
+	return res, nil
+}
 
-	if !senderVerified {
-		return fmt.Errorf("[Runes Verifier] Could not verify sender")
+// Runes of the outputs paying the script, in the order the inputs held them
+func (r resolvedTx) received(tx *wire.MsgTx, script []byte) []RunesDepositReceipt {
+	var res []RunesDepositReceipt
+	for n, out := range tx.TxOut {
+		if !bytes.Equal(out.PkScript, script) {
+			continue
+		}
+		for _, balance := range r.allocation.Outputs[n] {
+			i := slices.IndexFunc(res, func(receipt RunesDepositReceipt) bool { return receipt.RuneId == balance.RuneId })
+			if i < 0 {
+				res = append(res, RunesDepositReceipt{
+					TxId:     tx.TxHash().String(),
+					RuneId:   balance.RuneId,
+					RuneName: r.names[balance.RuneId],
+					Amount:   common.NewTokenAmountFromInt64(0, balance.Amount.Decimals()),
+					Outputs:  make([]uint32, 0),
+					Senders:  r.senders[balance.RuneId],
+				})
+				i = len(res) - 1
+			}
+			res[i].Amount = res[i].Amount.Add(balance.Amount)
+			res[i].Outputs = append(res[i].Outputs, uint32(n))
+		}
 	}
+	return res
+}
 
-	if !receiverVerified {
-		return fmt.Errorf("[Runes Verifier] Could not verify receiver")
+// Runes received by an address in a mined transaction, one receipt per rune. Confirmations are left
+// out, and so is a cenotaph since it burns everything
+func (v RunesDepositVerifier) Received(ctx context.Context, tx *wire.MsgTx, address string) ([]RunesDepositReceipt, error) {
+	script, err := v.addressScript(address)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := v.resolve(ctx, tx)
+	if err != nil {
+		return nil, err
 	}
+	return resolved.received(tx, script), nil
+}
 
-	return nil
+func (v RunesDepositVerifier) receipt(ctx context.Context, tx *wire.MsgTx, deposit RunesDeposit) (*RunesDepositReceipt, error) {
+	script, err := v.addressScript(deposit.ToAddr)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := v.resolve(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	senders := resolved.senders[deposit.RuneId]
+	if len(senders) == 0 {
+		return nil, fmt.Errorf("%w: %s in %s, the indexer may not have processed it yet", ErrRunesInputsNotFound, deposit.RuneId, deposit.TxId)
+	}
+	if resolved.allocation.Cenotaph {
+		return nil, fmt.Errorf("%w: %s", ErrRunesBurned, deposit.TxId)
+	}
+	received := resolved.received(tx, script)
+	i := slices.IndexFunc(received, func(r RunesDepositReceipt) bool { return r.RuneId == deposit.RuneId })
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no output of %s holds %s", ErrRunesNotReceived, deposit.ToAddr, deposit.RuneId)
+	}
+	receipt := received[i]
+	if receipt.Amount.Int().Cmp(deposit.Amount.Int()) != 0 {
+		return nil, fmt.Errorf("%w: received %s instead of %s base units", ErrRunesAmountMismatch, receipt.Amount.Int(), deposit.Amount.Int())
+	}
+	if deposit.FromAddr != "" {
+		sender := v.normalizeAddress(deposit.FromAddr)
+		if !slices.Contains(senders, sender) {
+			return nil, fmt.Errorf("%w: sent by %v instead of %s", ErrRunesSenderMismatch, senders, deposit.FromAddr)
+		}
+		if len(senders) > 1 && !v.AllowOtherSenders {
+			return nil, fmt.Errorf("%w: inputs of %v also sent %s", ErrRunesSenderMismatch, senders, deposit.RuneId)
+		}
+	}
+	return &receipt, nil
+}
+
+func (v RunesDepositVerifier) addressScript(address string) ([]byte, error) {
+	addr, err := btcutil.DecodeAddress(address, v.Params)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// Address of a spent outpoint, from its pkScript when the indexer doesn't report it
+func (v RunesDepositVerifier) inputAddress(utxo client.RunesUtxo) string {
+	if utxo.WalletAddr != "" {
+		return v.normalizeAddress(utxo.WalletAddr)
+	}
+	pkScript, err := hex.DecodeString(utxo.PkScript)
+	if err != nil {
+		return utxo.PkScript
+	}
+	if _, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, v.Params); err == nil && len(addrs) == 1 {
+		return addrs[0].EncodeAddress()
+	}
+	return utxo.PkScript
+}
+
+// Addresses as bitcoin encodes them, so bech32 ones compare whatever their case
+func (v RunesDepositVerifier) normalizeAddress(address string) string {
+	if addr, err := btcutil.DecodeAddress(address, v.Params); err == nil {
+		return addr.EncodeAddress()
+	}
+	return address
 }
@@ -0,0 +1,168 @@
+package runes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/runes/runestone"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChain map[string]*btcjson.TxRawResult
+
+func (c fakeChain) GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	if res, ok := c[txHash.String()]; ok {
+		return res, nil
+	}
+	return nil, fmt.Errorf("no transaction %s", txHash)
+}
+
+func (c fakeChain) add(t *testing.T, tx *wire.MsgTx, confirmations uint64) string {
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+	c[tx.TxHash().String()] = &btcjson.TxRawResult{Hex: hex.EncodeToString(buf.Bytes()), Confirmations: confirmations}
+	return tx.TxHash().String()
+}
+
+type staticInputs []client.RunesUtxo
+
+func (s staticInputs) GetRunesInputs(ctx context.Context, txId string) ([]client.RunesUtxo, error) {
+	return s, nil
+}
+
+func addressScript(t *testing.T, addr string) []byte {
+	decoded, err := btcutil.DecodeAddress(addr, &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	script, err := txscript.PayToAddrScript(decoded)
+	require.NoError(t, err)
+	return script
+}
+
+func runestoneScript(t *testing.T, stone runestone.Runestone) []byte {
+	script, err := runestone.EncipherRunestone(stone).Script()
+	require.NoError(t, err)
+	return script
+}
+
+func TestVerifyRunesDeposit(t *testing.T) {
+	ctx := context.Background()
+	sender, receiver, other := "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "msTNoMYXynujefraNmpeKKuUsAsSYMZ8C2", "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"
+	held := func(index uint32, wallet string, amount int64) client.RunesUtxo {
+		return client.RunesUtxo{
+			Outpoint:   wire.NewOutPoint(&chainhash.Hash{1}, index).String(),
+			WalletAddr: wallet,
+			Runes:      []client.RuneBalance{{RuneId: "840000:1", RuneName: "UNCOMMON•GOODS", Amount: common.NewTokenAmountFromInt64(amount, 2)}},
+		}
+	}
+	spend := func(tx *wire.MsgTx, utxos ...client.RunesUtxo) {
+		for _, u := range utxos {
+			outpoint, err := u.OutPoint()
+			require.NoError(t, err)
+			tx.AddTxIn(wire.NewTxIn(outpoint, nil, nil))
+		}
+	}
+	chain := fakeChain{}
+	verifier := runes.RunesDepositVerifier{Chain: chain, Params: &chaincfg.RegressionNetParams, MinConfirmations: 2}
+	id := runestone.NewRuneId(840000, 1)
+	pointer := runestone.Uint32(2)
+
+	// Two edicts to the receiver, the rest to the sender's change through the pointer
+	tx := wire.NewMsgTx(2)
+	spend(tx, held(0, sender, 1000))
+	tx.AddTxOut(wire.NewTxOut(546, addressScript(t, receiver)))
+	tx.AddTxOut(wire.NewTxOut(546, addressScript(t, receiver)))
+	tx.AddTxOut(wire.NewTxOut(546, addressScript(t, sender)))
+	tx.AddTxOut(wire.NewTxOut(0, runestoneScript(t, runestone.Runestone{Pointer: &pointer, Edicts: []runestone.Edict{
+		{Id: id, Amount: big.NewInt(300), Output: 0},
+		{Id: id, Amount: big.NewInt(200), Output: 1},
+	}})))
+	deposit := runes.RunesDeposit{
+		TxId:     chain.add(t, tx, 3),
+		FromAddr: sender,
+		ToAddr:   receiver,
+		RuneId:   "840000:1",
+		Amount:   common.NewTokenAmountFromInt64(500, 0),
+	}
+	verifier.Inputs = staticInputs{held(0, sender, 1000)}
+	receipt, err := verifier.Verify(ctx, deposit)
+	require.NoError(t, err)
+	require.Equal(t, "5", receipt.Amount.String())
+	require.Equal(t, "UNCOMMON•GOODS", receipt.RuneName)
+	require.Equal(t, []uint32{0, 1}, receipt.Outputs)
+	require.Equal(t, []string{sender}, receipt.Senders)
+	require.Equal(t, int64(3), receipt.Confirmations)
+
+	deposit.Amount = common.NewTokenAmountFromInt64(5, 0)
+	_, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrRunesAmountMismatch)
+	deposit.Amount = common.NewTokenAmountFromInt64(500, 2)
+
+	// Pending deposits come with their receipt
+	verifier.MinConfirmations = 6
+	receipt, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrNotEnoughConfirmations)
+	require.Equal(t, int64(3), receipt.Confirmations)
+	verifier.MinConfirmations = 2
+
+	// Nothing to check until the indexer has processed the deposit
+	verifier.Inputs = staticInputs{}
+	_, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrRunesInputsNotFound)
+
+	// No edict, everything goes to the first output. Another address spending the rune is only
+	// accepted when allowed
+	tx = wire.NewMsgTx(2)
+	spend(tx, held(0, sender, 1000), held(1, other, 250))
+	tx.AddTxOut(wire.NewTxOut(546, addressScript(t, receiver)))
+	deposit.TxId, deposit.Amount = chain.add(t, tx, 2), common.NewTokenAmountFromInt64(1250, 2)
+	verifier.Inputs = staticInputs{held(0, sender, 1000), held(1, other, 250)}
+	_, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrRunesSenderMismatch)
+	verifier.AllowOtherSenders = true
+	receipt, err = verifier.Verify(ctx, deposit)
+	require.NoError(t, err)
+	require.Equal(t, []string{sender, other}, receipt.Senders)
+	deposit.FromAddr = "BCRT1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KYGT080"
+	_, err = verifier.Verify(ctx, deposit)
+	require.NoError(t, err)
+	deposit.FromAddr = receiver
+	_, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrRunesSenderMismatch)
+	deposit.FromAddr = sender
+
+	// An edict past the last output splits the runes evenly
+	tx = wire.NewMsgTx(2)
+	spend(tx, held(0, sender, 1001))
+	tx.AddTxOut(wire.NewTxOut(546, addressScript(t, sender)))
+	tx.AddTxOut(wire.NewTxOut(546, addressScript(t, receiver)))
+	tx.AddTxOut(wire.NewTxOut(0, runestoneScript(t, runestone.Runestone{Edicts: []runestone.Edict{{Id: id, Amount: big.NewInt(0), Output: 3}}})))
+	deposit.TxId, deposit.Amount = chain.add(t, tx, 2), common.NewTokenAmountFromInt64(500, 2)
+	verifier.Inputs = staticInputs{held(0, sender, 1001)}
+	receipt, err = verifier.Verify(ctx, deposit)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1}, receipt.Outputs)
+
+	// A cenotaph burns the runes, whatever the edicts say
+	tx.TxOut[2].PkScript = []byte{txscript.OP_RETURN, runestone.MAGIC_NUMBER, txscript.OP_VERIFY}
+	deposit.TxId = chain.add(t, tx, 2)
+	_, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrRunesBurned)
+
+	tx.TxOut[2].PkScript = addressScript(t, sender)
+	deposit.TxId = chain.add(t, tx, 0)
+	_, err = verifier.Verify(ctx, deposit)
+	require.ErrorIs(t, err, runes.ErrNotEnoughConfirmations)
+}
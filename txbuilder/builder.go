@@ -0,0 +1,430 @@
+package txbuilder
+
+// Unsigned transactions as PSBTs, for wallets holding their own keys. Inputs come with the outputs they
+// spend, the whole previous tx for legacy ones, and the fee is estimated from the size of the inputs
+// once signed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/inscriptions"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/taproot"
+)
+
+// Outputs below it are dust
+const dustLimit = 546
+
+var (
+	ErrInsufficientFunds = errors.New("not enough funds")
+	ErrUnsupportedInput  = errors.New("unsupported input script")
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrOutputSpent       = errors.New("output not found or already spent")
+	ErrNotFinalized      = errors.New("psbt isn't fully signed")
+)
+
+// Chain queries of the builder, satisfied by BtcRpcClient
+type Chain interface {
+	GetRawTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error)
+	GetTxOut(txHash *chainhash.Hash, index uint32, mempool bool) (*btcjson.GetTxOutResult, error)
+	SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error)
+}
+
+var _ Chain = &client.BtcRpcClient{}
+
+// Spendable outputs of an address
+type UtxoSource interface {
+	GetUtxos(ctx context.Context, address string) ([]common.WebUtxo, error)
+}
+
+// The utxo api of the btc config, Sandshrew or the electrum proxy
+type WebUtxoSource config.BtcConfig
+
+func (s WebUtxoSource) GetUtxos(ctx context.Context, address string) ([]common.WebUtxo, error) {
+	res, err := common.GetUtxos(address, config.BtcConfig(s))
+	if err != nil {
+		return nil, err
+	}
+	return res.Result, nil
+}
+
+type Builder struct {
+	Chain        Chain
+	Utxos        UtxoSource
+	Runes        client.RunesUtxoSource       // Outpoints holding runes are never spent to pay fees, optional
+	Inscriptions client.InscriptionUtxoSource // Outpoints holding inscriptions are never spent to pay fees, optional
+	Params       *chaincfg.Params
+	Config       config.Config // Postage & reveal fee rate of inscriptions
+}
+
+func New(c config.Config) (*Builder, error) {
+	rpc, err := client.NewBitcoinClient(c)
+	if err != nil {
+		return nil, err
+	}
+	source, err := runes.NewRunesUtxoSource(c)
+	if err != nil {
+		return nil, err
+	}
+	b := &Builder{Chain: rpc, Utxos: WebUtxoSource(c.BtcConfig), Runes: source, Params: c.BtcConfig.GetChainConfigParams(), Config: c}
+	// Without a BIS key only the utxos up to the postage are assumed to carry inscriptions
+	if c.BISConfig.APIKey != "" {
+		bis, err := client.NewBISClient(c.BISConfig)
+		if err != nil {
+			return nil, err
+		}
+		b.Inscriptions = bis
+	}
+	return b, nil
+}
+
+// Unsigned tx, along with the outputs its inputs spend
+type Unsigned struct {
+	Packet *psbt.Packet
+	Fee    int64
+	VSize  int64 // Estimated, once signed
+}
+
+func (u *Unsigned) Base64() (string, error) {
+	return u.Packet.B64Encode()
+}
+
+// Output spent by the tx being built
+type input struct {
+	outpoint wire.OutPoint
+	prevTx   *wire.MsgTx
+	prevOut  *wire.TxOut
+}
+
+func (b *Builder) input(txId string, vout uint32) (input, error) {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return input{}, err
+	}
+	tx, err := b.Chain.GetRawTransaction(hash)
+	if err != nil {
+		return input{}, err
+	}
+	if int(vout) >= len(tx.MsgTx().TxOut) {
+		return input{}, fmt.Errorf("%w: %s:%d", ErrOutputSpent, txId, vout)
+	}
+	return input{outpoint: *wire.NewOutPoint(hash, vout), prevTx: tx.MsgTx(), prevOut: tx.MsgTx().TxOut[vout]}, nil
+}
+
+// Unspent output, failing if it was spent, in the mempool as well
+func (b *Builder) unspent(outpoint wire.OutPoint) (input, error) {
+	out, err := b.Chain.GetTxOut(&outpoint.Hash, outpoint.Index, true)
+	if err != nil {
+		return input{}, err
+	}
+	if out == nil {
+		return input{}, fmt.Errorf("%w: %s", ErrOutputSpent, outpoint)
+	}
+	return b.input(outpoint.Hash.String(), outpoint.Index)
+}
+
+// Virtual size of an input spending the script once signed, rounded up
+func inputVSize(pkScript []byte) (int64, error) {
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyHashTy:
+		return 148, nil
+	case txscript.WitnessV0PubKeyHashTy:
+		return 68, nil
+	case txscript.ScriptHashTy:
+		// P2SH-P2WPKH, the only nested script spent with a single key
+		return 91, nil
+	case txscript.WitnessV1TaprootTy:
+		return 58, nil
+	}
+	return 0, fmt.Errorf("%w: %x", ErrUnsupportedInput, pkScript)
+}
+
+func estimateVSize(tx *wire.MsgTx, inputs []input) (int64, error) {
+	vsize := int64(11)
+	for _, in := range inputs {
+		size, err := inputVSize(in.prevOut.PkScript)
+		if err != nil {
+			return 0, err
+		}
+		vsize += size
+	}
+	for _, out := range tx.TxOut {
+		vsize += int64(out.SerializeSize())
+	}
+	return vsize, nil
+}
+
+// Add utxos of the address, largest first, until the inputs pay the outputs & the fee. The change goes to
+// the output at changeIdx, or to a new last output when it isn't dust and changeIdx is negative.
+// Utxos holding runes or inscriptions are skipped, and so are the ones up to the postage as they may
+// carry an inscription the indexer hasn't seen yet
+func (b *Builder) fund(ctx context.Context, tx *wire.MsgTx, inputs []input, from btcutil.Address, feeRate uint64, changeIdx int) ([]input, int64, error) {
+	utxos, err := b.Utxos.GetUtxos(ctx, from.EncodeAddress())
+	if err != nil {
+		return nil, 0, err
+	}
+	excluded := make(map[string]bool)
+	for _, in := range inputs {
+		excluded[in.outpoint.String()] = true
+	}
+	if b.Runes != nil {
+		held, err := b.Runes.GetRunesUtxos(ctx, from.EncodeAddress())
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, utxo := range held {
+			excluded[utxo.Outpoint] = true
+		}
+	}
+	if b.Inscriptions != nil {
+		held, err := b.Inscriptions.GetInscriptionOutpoints(ctx, from.EncodeAddress())
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, outpoint := range held {
+			excluded[outpoint] = true
+		}
+	}
+	minValue := max(uint64(dustLimit), uint64(b.Config.InscriptionConfig.GetPostage()))
+	sort.SliceStable(utxos, func(i, j int) bool { return utxos[i].Value > utxos[j].Value })
+
+	changeScript, err := txscript.PayToAddrScript(from)
+	if err != nil {
+		return nil, 0, err
+	}
+	var outputs int64
+	for _, out := range tx.TxOut {
+		outputs += out.Value
+	}
+	next := 0
+	for {
+		var in int64
+		for _, i := range inputs {
+			in += i.prevOut.Value
+		}
+		sizeTx := tx.Copy()
+		if changeIdx < 0 {
+			sizeTx.AddTxOut(wire.NewTxOut(0, changeScript))
+		}
+		vsize, err := estimateVSize(sizeTx, inputs)
+		if err != nil {
+			return nil, 0, err
+		}
+		fee := vsize * int64(feeRate)
+		if change := in - outputs - fee; change >= 0 && len(inputs) > 0 {
+			switch {
+			case changeIdx >= 0:
+				tx.TxOut[changeIdx].Value += change
+			case change >= dustLimit:
+				tx.AddTxOut(wire.NewTxOut(change, changeScript))
+			default:
+				// Whatever is left goes to the miners
+				fee = in - outputs
+			}
+			return inputs, fee, nil
+		}
+
+		for ; next < len(utxos); next++ {
+			utxo := utxos[next]
+			if utxo.Value > minValue && !excluded[fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Vout)] {
+				break
+			}
+		}
+		if next == len(utxos) {
+			return nil, 0, fmt.Errorf("%w: %s has %d sats spendable, %d needed", ErrInsufficientFunds, from.EncodeAddress(), in, outputs+fee)
+		}
+		added, err := b.input(utxos[next].TxHash, utxos[next].Vout)
+		if err != nil {
+			return nil, 0, err
+		}
+		next++
+		inputs = append(inputs, added)
+		tx.AddTxIn(common.NewRbfTxIn(&added.outpoint, nil, nil))
+	}
+}
+
+func (b *Builder) packet(tx *wire.MsgTx, inputs []input, fee int64) (*Unsigned, error) {
+	vsize, err := estimateVSize(tx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	p, err := psbt.NewFromUnsignedTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range inputs {
+		if txscript.IsWitnessProgram(in.prevOut.PkScript) {
+			p.Inputs[i].WitnessUtxo = in.prevOut
+		} else {
+			p.Inputs[i].NonWitnessUtxo = in.prevTx
+		}
+	}
+	return &Unsigned{Packet: p, Fee: fee, VSize: vsize}, nil
+}
+
+// Send sats to an address, the change going back to the sender
+func (b *Builder) SendBtc(ctx context.Context, from, to btcutil.Address, amount int64, feeRate uint64) (*Unsigned, error) {
+	if amount < dustLimit {
+		return nil, fmt.Errorf("%w: %d sats is below the dust limit", ErrInvalidAmount, amount)
+	}
+	toScript, err := txscript.PayToAddrScript(to)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(amount, toScript))
+	inputs, fee, err := b.fund(ctx, tx, nil, from, feeRate, -1)
+	if err != nil {
+		return nil, err
+	}
+	return b.packet(tx, inputs, fee)
+}
+
+// Send the output an inscription was revealed into, e.g. a brc20 transfer inscription. The inscription
+// stays on the first sat of the first output, the fee is paid by other utxos of the sender
+func (b *Builder) TransferInscription(ctx context.Context, from, to btcutil.Address, inscription wire.OutPoint, feeRate uint64) (*Unsigned, error) {
+	toScript, err := txscript.PayToAddrScript(to)
+	if err != nil {
+		return nil, err
+	}
+	held, err := b.unspent(inscription)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(common.NewRbfTxIn(&held.outpoint, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(held.prevOut.Value, toScript))
+	inputs, fee, err := b.fund(ctx, tx, []input{held}, from, feeRate, -1)
+	if err != nil {
+		return nil, err
+	}
+	return b.packet(tx, inputs, fee)
+}
+
+// Send an amount of a rune in base units. The outpoint holding the runes is spent along with fee utxos,
+// the first output gets the change and the runes left, the second one the runes sent
+func (b *Builder) TransferRune(ctx context.Context, from, to btcutil.Address, rune runes.Rune, amount *big.Int, feeRate uint64) (*Unsigned, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: nothing to send", ErrInvalidAmount)
+	}
+	if b.Runes == nil {
+		return nil, fmt.Errorf("%w: no runes utxo source", runes.ErrRunesUtxoNotFound)
+	}
+	fromScript, err := txscript.PayToAddrScript(from)
+	if err != nil {
+		return nil, err
+	}
+	toScript, err := txscript.PayToAddrScript(to)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := b.Runes.GetRunesUtxos(ctx, from.EncodeAddress())
+	if err != nil {
+		return nil, err
+	}
+	utxo, err := runes.SelectRunesUnspentOutput(&rune, amount, utxos)
+	if err != nil {
+		return nil, err
+	}
+	outpoint, err := utxo.OutPoint()
+	if err != nil {
+		return nil, err
+	}
+	held, err := b.unspent(*outpoint)
+	if err != nil {
+		return nil, err
+	}
+	runestone, err := runes.CreateTransferScript(rune, amount, 1, true)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(common.NewRbfTxIn(&held.outpoint, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(dustLimit, fromScript))
+	tx.AddTxOut(wire.NewTxOut(dustLimit, toScript))
+	tx.AddTxOut(wire.NewTxOut(0, runestone))
+	inputs, fee, err := b.fund(ctx, tx, []input{held}, from, feeRate, 0)
+	if err != nil {
+		return nil, err
+	}
+	return b.packet(tx, inputs, fee)
+}
+
+// Submit a signed PSBT, finalizing its inputs, then the txs spending its outputs. Returns their ids
+func (b *Builder) Submit(p *psbt.Packet, then ...*wire.MsgTx) ([]string, error) {
+	if err := psbt.MaybeFinalizeAll(p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotFinalized, err)
+	}
+	tx, err := psbt.Extract(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotFinalized, err)
+	}
+	txIds := make([]string, 0, 1+len(then))
+	for _, tx := range append([]*wire.MsgTx{tx}, then...) {
+		hash, err := b.Chain.SendRawTransaction(tx, false)
+		if err != nil {
+			return txIds, err
+		}
+		txIds = append(txIds, hash.String())
+	}
+	return txIds, nil
+}
+
+// Commit funded by the sender, along with the reveal spending it, signed already. The reveal sends the
+// inscriptions to the receiver
+type Inscription struct {
+	Commit *Unsigned
+	Reveal *wire.MsgTx
+}
+
+// Inscribe from an address, the commit being a PSBT for its wallet to sign. Only native segwit senders
+// are supported, the reveal being signed for the txid of the commit before it is
+func (b *Builder) Inscribe(ctx context.Context, from, to btcutil.Address, data []taproot.InscriptionData, feeRate uint64) (*Inscription, error) {
+	fromScript, err := txscript.PayToAddrScript(from)
+	if err != nil {
+		return nil, err
+	}
+	if !txscript.IsPayToWitnessPubKeyHash(fromScript) && !txscript.IsPayToTaproot(fromScript) {
+		return nil, fmt.Errorf("%w: %s isn't a native segwit address", ErrUnsupportedInput, from.EncodeAddress())
+	}
+	toScript, err := txscript.PayToAddrScript(to)
+	if err != nil {
+		return nil, err
+	}
+	reveal, err := inscriptions.NewPresignedReveal(data, toScript, b.Config.InscriptionConfig.GetRevealFeeRate(feeRate), b.Config)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for _, out := range reveal.CommitOutputs {
+		tx.AddTxOut(out)
+	}
+	inputs, fee, err := b.fund(ctx, tx, nil, from, feeRate, -1)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := b.packet(tx, inputs, fee)
+	if err != nil {
+		return nil, err
+	}
+	commitHash := tx.TxHash()
+	signed, err := reveal.Sign(&commitHash)
+	if err != nil {
+		return nil, err
+	}
+	return &Inscription{Commit: commit, Reveal: signed}, nil
+}
@@ -0,0 +1,212 @@
+package txbuilder
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/internal/testutil"
+	"github.com/ordinox/btc-service/runes"
+	"github.com/ordinox/btc-service/taproot"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	*testutil.Wallet
+	builder *Builder
+}
+
+func newFixture(t *testing.T) *fixture {
+	w := testutil.NewWallet(t)
+	cfg := config.Config{BtcConfig: config.BtcConfig{ChainConfig: string(config.NetworkRegtest)}}
+	return &fixture{Wallet: w, builder: &Builder{Chain: w.Chain, Utxos: w.Utxos, Params: &chaincfg.RegressionNetParams, Config: cfg}}
+}
+
+func ptr(hash chainhash.Hash) *chainhash.Hash {
+	return &hash
+}
+
+// Sign the PSBT as a wallet would, leaving the finalization to Submit
+func (f *fixture) sign(t *testing.T, u *Unsigned) {
+	tx := u.Packet.UnsignedTx.Copy()
+	prevOuts := make([]*wire.TxOut, len(u.Packet.Inputs))
+	for i, in := range u.Packet.Inputs {
+		require.NotNil(t, in.WitnessUtxo)
+		prevOuts[i] = in.WitnessUtxo
+	}
+	require.NoError(t, common.SignInputs(tx, prevOuts, f.Key))
+	for i, in := range tx.TxIn {
+		u.Packet.Inputs[i].TaprootKeySpendSig = in.Witness[0]
+	}
+}
+
+func (f *fixture) submit(t *testing.T, u *Unsigned, then ...*wire.MsgTx) *wire.MsgTx {
+	f.sign(t, u)
+	txIds, err := f.builder.Submit(u.Packet, then...)
+	require.NoError(t, err)
+	require.Len(t, txIds, 1+len(then))
+	signed := f.Chain.Sent[len(f.Chain.Sent)-1-len(then)]
+	require.LessOrEqual(t, mempool.GetTxVirtualSize(btcutil.NewTx(signed)), u.VSize)
+	return signed
+}
+
+func balance(u *Unsigned) (in, out int64) {
+	for _, i := range u.Packet.Inputs {
+		in += i.WitnessUtxo.Value
+	}
+	for _, o := range u.Packet.UnsignedTx.TxOut {
+		out += o.Value
+	}
+	return in, out
+}
+
+func TestBuilder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("send btc", func(t *testing.T) {
+		f := newFixture(t)
+		f.Fund(t, 3000)
+		f.Fund(t, 10000)
+
+		u, err := f.builder.SendBtc(ctx, f.From, f.To, 5000, 2)
+		require.NoError(t, err)
+		// The largest utxo pays it all, the change goes back
+		require.Len(t, u.Packet.UnsignedTx.TxIn, 1)
+		require.Len(t, u.Packet.UnsignedTx.TxOut, 2)
+		require.Equal(t, int64(5000), u.Packet.UnsignedTx.TxOut[0].Value)
+		in, out := balance(u)
+		require.Equal(t, in-out, u.Fee)
+		require.Equal(t, u.VSize*2, u.Fee)
+
+		b64, err := u.Base64()
+		require.NoError(t, err)
+		decoded, err := psbt.NewFromRawBytes(strings.NewReader(b64), true)
+		require.NoError(t, err)
+		require.Equal(t, u.Packet.UnsignedTx.TxHash(), decoded.UnsignedTx.TxHash())
+
+		f.submit(t, u)
+	})
+
+	t.Run("inscription utxos aren't spent", func(t *testing.T) {
+		f := newFixture(t)
+		inscription := f.Fund(t, 20000)
+		f.Fund(t, 10000)
+		f.builder.Inscriptions = testutil.Inscriptions{inscription.String()}
+
+		u, err := f.builder.SendBtc(ctx, f.From, f.To, 5000, 2)
+		require.NoError(t, err)
+		require.Len(t, u.Packet.UnsignedTx.TxIn, 1)
+		require.NotEqual(t, inscription, u.Packet.UnsignedTx.TxIn[0].PreviousOutPoint)
+
+		_, err = f.builder.SendBtc(ctx, f.From, f.To, 15000, 2)
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("utxos up to the postage aren't spent", func(t *testing.T) {
+		f := newFixture(t)
+		f.builder.Config.InscriptionConfig.Postage = 10000
+		f.Fund(t, 10000)
+		f.Fund(t, 3000)
+		_, err := f.builder.SendBtc(ctx, f.From, f.To, 5000, 2)
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("not enough funds", func(t *testing.T) {
+		f := newFixture(t)
+		f.Fund(t, 3000)
+		_, err := f.builder.SendBtc(ctx, f.From, f.To, 5000, 2)
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+		_, err = f.builder.SendBtc(ctx, f.From, f.To, 100, 2)
+		require.ErrorIs(t, err, ErrInvalidAmount)
+	})
+
+	t.Run("transfer inscription", func(t *testing.T) {
+		f := newFixture(t)
+		inscription := f.Fund(t, 546)
+		f.Fund(t, 5000)
+
+		u, err := f.builder.TransferInscription(ctx, f.From, f.To, inscription, 2)
+		require.NoError(t, err)
+		require.Equal(t, inscription, u.Packet.UnsignedTx.TxIn[0].PreviousOutPoint)
+		require.Equal(t, int64(546), u.Packet.UnsignedTx.TxOut[0].Value)
+		f.submit(t, u)
+
+		f.Chain.Spent[inscription] = true
+		_, err = f.builder.TransferInscription(ctx, f.From, f.To, inscription, 2)
+		require.ErrorIs(t, err, ErrOutputSpent)
+	})
+
+	t.Run("transfer rune", func(t *testing.T) {
+		f := newFixture(t)
+		held := f.Fund(t, 546)
+		other := f.Fund(t, 10000)
+		fee := f.Fund(t, 4000)
+		f.builder.Runes = testutil.Runes{
+			{Outpoint: held.String(), Runes: []client.RuneBalance{{RuneId: "840000:3", Amount: common.NewTokenAmountFromInt64(1000, 0)}}},
+			{Outpoint: other.String(), Runes: []client.RuneBalance{{RuneId: "840000:7", Amount: common.NewTokenAmountFromInt64(5, 0)}}},
+		}
+		rune := runes.Rune{BlockNumber: 840000, TxIndex: 3}
+
+		u, err := f.builder.TransferRune(ctx, f.From, f.To, rune, big.NewInt(400), 2)
+		require.NoError(t, err)
+		// The fees are paid by the utxo holding no runes
+		tx := u.Packet.UnsignedTx
+		require.Len(t, tx.TxIn, 2)
+		require.Equal(t, held, tx.TxIn[0].PreviousOutPoint)
+		require.Equal(t, fee, tx.TxIn[1].PreviousOutPoint)
+		require.Len(t, tx.TxOut, 3)
+		require.Equal(t, int64(546), tx.TxOut[1].Value)
+		runestone, err := runes.CreateTransferScript(rune, big.NewInt(400), 1, true)
+		require.NoError(t, err)
+		require.Equal(t, runestone, tx.TxOut[2].PkScript)
+		in, out := balance(u)
+		require.Equal(t, in-out, u.Fee)
+		f.submit(t, u)
+
+		_, err = f.builder.TransferRune(ctx, f.From, f.To, rune, big.NewInt(2000), 2)
+		require.ErrorIs(t, err, runes.ErrRunesUtxoNotFound)
+	})
+
+	t.Run("inscribe", func(t *testing.T) {
+		f := newFixture(t)
+		f.Fund(t, 20000)
+		data := []taproot.InscriptionData{
+			taproot.NewInscriptionData(`{"p":"brc-20","op":"transfer","tick":"opiz","amt":"100"}`, taproot.ContentTypeText),
+			taproot.NewInscriptionData("hello", taproot.ContentTypeText),
+		}
+
+		_, err := f.builder.Inscribe(ctx, f.To, f.From, data, 2)
+		require.ErrorIs(t, err, ErrUnsupportedInput)
+
+		ins, err := f.builder.Inscribe(ctx, f.From, f.To, data, 2)
+		require.NoError(t, err)
+		require.Len(t, ins.Reveal.TxIn, 2)
+		commit := f.submit(t, ins.Commit, ins.Reveal)
+		// The commit keeps its txid once signed, the reveal spends it
+		for i, in := range ins.Reveal.TxIn {
+			require.Equal(t, *wire.NewOutPoint(ptr(commit.TxHash()), uint32(i)), in.PreviousOutPoint)
+		}
+		require.Equal(t, ins.Reveal, f.Chain.Sent[1])
+
+		fetcher := txscript.NewMultiPrevOutFetcher(nil)
+		for i, in := range ins.Reveal.TxIn {
+			fetcher.AddPrevOut(in.PreviousOutPoint, commit.TxOut[i])
+		}
+		for i, in := range ins.Reveal.TxIn {
+			engine, err := txscript.NewEngine(commit.TxOut[i].PkScript, ins.Reveal, i, txscript.StandardVerifyFlags, nil, txscript.NewTxSigHashes(ins.Reveal, fetcher), commit.TxOut[i].Value, fetcher)
+			require.NoError(t, err)
+			require.NoError(t, engine.Execute(), "reveal input %d spending %s", i, in.PreviousOutPoint)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/common"
+	"github.com/ordinox/btc-service/runes"
+)
+
+type Asset string
+
+const (
+	AssetBtc   Asset = "btc"
+	AssetBrc20 Asset = "brc20"
+	AssetRunes Asset = "runes"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusConfirmed Status = "confirmed"
+	StatusReorged   Status = "reorged"  // The block of the deposit left the chain
+	StatusReplaced  Status = "replaced" // Another tx spent the inputs of a tracked tx
+)
+
+// Funds received by a watched address in a transaction
+type Deposit struct {
+	Id            string             `json:"id"` // Asset, tx, address & token, the same whichever block the tx ends up in
+	Asset         Asset              `json:"asset"`
+	Token         string             `json:"token,omitempty"`      // BRC20 ticker or rune id
+	TokenName     string             `json:"token_name,omitempty"` // Spaced name of the rune
+	Address       string             `json:"address"`
+	TxId          string             `json:"tx_id"`
+	Outputs       []uint32           `json:"outputs,omitempty"`        // Outputs paying the address, unknown for brc20
+	InscriptionId string             `json:"inscription_id,omitempty"` // Transfer inscription of a brc20 deposit
+	Amount        common.TokenAmount `json:"amount"`                   // Base units: sats, brc20 amounts with 18 decimals or runes with their divisibility
+	Decimals      uint8              `json:"decimals"`
+	Senders       []string           `json:"senders,omitempty"` // Unknown for btc
+	Height        int64              `json:"height"`
+	BlockHash     string             `json:"block_hash"`
+	Confirmations int64              `json:"confirmations"`
+	Status        Status             `json:"status"`
+}
+
+// The amount keeps its decimals, which the json form of an amount leaves out
+func (d *Deposit) UnmarshalJSON(data []byte) error {
+	type deposit Deposit
+	if err := json.Unmarshal(data, (*deposit)(d)); err != nil {
+		return err
+	}
+	d.Amount = d.Amount.WithDecimals(d.Decimals)
+	return nil
+}
+
+func newDeposit(asset Asset, txId, address, token string, amount common.TokenAmount) Deposit {
+	id := fmt.Sprintf("%s:%s:%s", asset, txId, address)
+	if token != "" {
+		id += ":" + token
+	}
+	return Deposit{Id: id, Asset: asset, Token: token, Address: address, TxId: txId, Amount: amount, Decimals: amount.Decimals()}
+}
+
+type EventType string
+
+const (
+	EventDetected    EventType = "deposit.detected"
+	EventConfirmed   EventType = "deposit.confirmed"
+	EventReorged     EventType = "deposit.reorged"
+	EventTxBroadcast EventType = "tx.broadcast"
+	EventTxConfirmed EventType = "tx.confirmed"
+	EventTxReplaced  EventType = "tx.replaced"
+)
+
+// A deposit or tracked transaction changing status
+type Event struct {
+	Id      string    `json:"id"` // Unique, the same when the event is delivered again
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	Deposit *Deposit  `json:"deposit,omitempty"`
+	Tx      *Tx       `json:"tx,omitempty"`
+}
+
+func newEvent(eventType EventType, deposit Deposit) Event {
+	return Event{
+		Id:      fmt.Sprintf("%s:%s:%s", eventType, deposit.Id, deposit.BlockHash),
+		Type:    eventType,
+		Time:    time.Now().UTC(),
+		Deposit: &deposit,
+	}
+}
+
+func newTxEvent(eventType EventType, tx Tx) Event {
+	id := fmt.Sprintf("%s:%s", eventType, tx.TxId)
+	switch eventType {
+	case EventTxConfirmed:
+		id += ":" + tx.BlockHash
+	case EventTxReplaced:
+		id += ":" + tx.ReplacedBy
+	}
+	return Event{Id: id, Type: eventType, Time: time.Now().UTC(), Tx: &tx}
+}
+
+// Transaction of a block paying watched addresses
+type Candidate struct {
+	Tx      *wire.MsgTx
+	Outputs map[string][]uint32 // Watched address -> outputs paying it
+}
+
+// Block being scanned, with the transactions paying watched addresses
+type Block struct {
+	Height     int64
+	Hash       string
+	Candidates []Candidate
+	addresses  map[string]bool
+	params     *chaincfg.Params
+}
+
+// The address as it is watched, bech32 addresses being watched whatever their case
+func (b Block) Watched(address string) (string, bool) {
+	if addr, err := btcutil.DecodeAddress(address, b.params); err == nil {
+		address = addr.EncodeAddress()
+	}
+	return address, b.addresses[address]
+}
+
+// Finds the deposits of one asset in a block. Fails with client.ErrIndexerBehind while the indexer
+// it relies on hasn't processed the block, the block is scanned again later
+type Detector interface {
+	Detect(ctx context.Context, block Block) ([]Deposit, error)
+}
+
+// BTC paid to the watched addresses, summed by transaction
+type BtcDetector struct {
+	MinValue int64 // Deposits below it are ignored
+}
+
+func (d BtcDetector) Detect(ctx context.Context, block Block) ([]Deposit, error) {
+	deposits := make([]Deposit, 0)
+	for _, c := range block.Candidates {
+		for address, outputs := range c.Outputs {
+			var value int64
+			for _, n := range outputs {
+				value += c.Tx.TxOut[n].Value
+			}
+			if value == 0 || value < d.MinValue {
+				continue
+			}
+			deposit := newDeposit(AssetBtc, c.Tx.TxHash().String(), address, "", common.NewTokenAmountFromInt64(value, 8))
+			deposit.Outputs = outputs
+			deposits = append(deposits, deposit)
+		}
+	}
+	return deposits, nil
+}
+
+// Runes received by the watched addresses, resolved from the inputs the indexer reports
+type RunesDetector struct {
+	Inputs func(height int64) client.RunesInputSource // Indexer failing with client.ErrIndexerBehind below the height
+	Params *chaincfg.Params
+}
+
+func (d RunesDetector) Detect(ctx context.Context, block Block) ([]Deposit, error) {
+	verifier := runes.RunesDepositVerifier{Inputs: d.Inputs(block.Height), Params: d.Params}
+	deposits := make([]Deposit, 0)
+	for _, c := range block.Candidates {
+		for address := range c.Outputs {
+			receipts, err := verifier.Received(ctx, c.Tx, address)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range receipts {
+				deposit := newDeposit(AssetRunes, r.TxId, address, r.RuneId, r.Amount)
+				deposit.TokenName, deposit.Outputs, deposit.Senders = r.RuneName, r.Outputs, r.Senders
+				deposits = append(deposits, deposit)
+			}
+		}
+	}
+	return deposits, nil
+}
+
+// Indexer listing the brc20 events of a whole block, OPI can't look transfers up by tx
+type Brc20BlockSource interface {
+	GetBrc20ActivityOnBlock(ctx context.Context, height int64) ([]client.Brc20BlockEvent, error)
+}
+
+var _ Brc20BlockSource = client.OpiClient{}
+
+// BRC20 transfer inscriptions sent to the watched addresses
+type Brc20Detector struct {
+	Source func(height int64) client.Brc20EventSource // Indexer failing with client.ErrIndexerBehind below the height
+}
+
+func (d Brc20Detector) Detect(ctx context.Context, block Block) ([]Deposit, error) {
+	source := d.Source(block.Height)
+	if blocks, ok := source.(Brc20BlockSource); ok {
+		return d.detectInBlock(ctx, blocks, block)
+	}
+	deposits := make([]Deposit, 0)
+	for _, c := range block.Candidates {
+		res, err := source.GetBrc20Transfers(ctx, c.Tx.TxHash().String(), "")
+		if err != nil && !errors.Is(err, client.ErrNotFound) {
+			return nil, err
+		}
+		if res == nil {
+			continue
+		}
+		for _, t := range res.Transfers {
+			if address, ok := block.Watched(t.SpentWallet); ok {
+				t.SpentWallet = address
+				deposits = append(deposits, brc20Deposit(t))
+			}
+		}
+	}
+	return deposits, nil
+}
+
+func (d Brc20Detector) detectInBlock(ctx context.Context, source Brc20BlockSource, block Block) ([]Deposit, error) {
+	events, err := source.GetBrc20ActivityOnBlock(ctx, block.Height)
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		return nil, err
+	}
+	deposits := make([]Deposit, 0)
+	for _, e := range events {
+		address, ok := block.Watched(e.SpentWallet)
+		if e.EventType != "transfer-transfer" || !ok {
+			continue
+		}
+		amount, err := common.ParseTokenAmount(e.Amount, 0)
+		if err != nil {
+			return nil, fmt.Errorf("transfer of %s: %w", e.InscriptionId, err)
+		}
+		deposits = append(deposits, brc20Deposit(client.Brc20Transfer{
+			InscriptionId: e.InscriptionId,
+			TxId:          e.UsingTxID,
+			Tick:          e.Tick,
+			Amount:        amount.WithDecimals(18),
+			SourceWallet:  e.SourceWallet,
+			SpentWallet:   address,
+		}))
+	}
+	return deposits, nil
+}
+
+func brc20Deposit(t client.Brc20Transfer) Deposit {
+	deposit := newDeposit(AssetBrc20, t.TxId, t.SpentWallet, strings.ToLower(t.Tick), t.Amount)
+	// A tx can move several transfer inscriptions of the same ticker
+	deposit.Id += ":" + t.InscriptionId
+	deposit.InscriptionId, deposit.Senders = t.InscriptionId, []string{t.SourceWallet}
+	return deposit
+}
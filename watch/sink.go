@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ordinox/btc-service/webhook"
+)
+
+// Destination of the deposit events. Events are delivered at least once: a block is scanned again when
+// a sink fails or the watcher stops before saving its progress, consumers dedupe on Event.Id
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// A json line per event, e.g. on stdout
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+func (s *JSONSink) Send(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.w).Encode(event)
+}
+
+// Json lines appended to a file, a local queue for another process to consume
+type QueueSink struct {
+	mu   sync.Mutex
+	Path string
+}
+
+func NewQueueSink(path string) *QueueSink {
+	return &QueueSink{Path: path}
+}
+
+func (s *QueueSink) Send(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Events queued in the outbox of a webhook dispatcher, which signs & delivers them
+type WebhookSink struct {
+	Dispatcher *webhook.Dispatcher
+}
+
+func NewWebhookSink(d *webhook.Dispatcher) *WebhookSink {
+	return &WebhookSink{Dispatcher: d}
+}
+
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	return s.Dispatcher.Enqueue(event.Id, string(event.Type), event)
+}
+
+// Events fanned out in memory to the subscribers, e.g. the WatchDeposits streams of the gRPC api.
+// Subscribers only get the events sent while subscribed, the sinks above are the ones which deliver
+// every event. A subscriber falling behind by more than its buffer is dropped
+type Feed struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewFeed() *Feed {
+	return &Feed{subs: make(map[chan Event]struct{})}
+}
+
+// Never fails nor blocks the watcher
+func (f *Feed) Send(ctx context.Context, event Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Events sent from now on, until cancel is called. The channel is closed when the subscriber is dropped
+func (f *Feed) Subscribe(buffer int) (events <-chan Event, cancel func()) {
+	ch := make(chan Event, buffer)
+	f.mu.Lock()
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Block scanned by the watcher
+type BlockRef struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// Progress of the watcher, saved after every block
+type State struct {
+	Height  int64      `json:"height"` // Last block scanned
+	Blocks  []BlockRef `json:"blocks"` // Last blocks scanned, oldest first, to find where a reorg forked
+	Pending []Deposit  `json:"pending"`
+	Txs     []Tx       `json:"txs"` // Tracked txs, until confirmed or replaced
+}
+
+// Persists the state of the watcher
+type Store interface {
+	Load() (*State, error) // Nil without a saved state
+	Save(state *State) error
+}
+
+// State kept in a json file, replaced at once so a crash never leaves half of it
+type FileStore struct {
+	Path string
+}
+
+var _ Store = FileStore{}
+
+func (s FileStore) Load() (*State, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid watch state %s: %w", s.Path, err)
+	}
+	return state, nil
+}
+
+func (s FileStore) Save(state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
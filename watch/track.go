@@ -0,0 +1,226 @@
+package watch
+
+// Tracked transactions. Txs broadcast by the service are handed to the watcher through a directory,
+// a file each, so that any process can add one while the watcher runs. The watcher follows them until
+// they are deep enough or another tx spends their inputs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/rs/zerolog/log"
+)
+
+// Transaction followed by the watcher
+type Tx struct {
+	TxId          string   `json:"tx_id"`
+	Replaces      string   `json:"replaces,omitempty"`    // Tx it replaced, e.g. with a fee bump
+	ReplacedBy    string   `json:"replaced_by,omitempty"` // Tx which spent its inputs instead
+	Inputs        []string `json:"inputs"`
+	Height        int64    `json:"height,omitempty"` // 0 until mined
+	BlockHash     string   `json:"block_hash,omitempty"`
+	Confirmations int64    `json:"confirmations"`
+	Status        Status   `json:"status"`
+}
+
+type trackRequest struct {
+	TxId     string `json:"tx_id"`
+	Replaces string `json:"replaces,omitempty"`
+}
+
+// Ask the watcher tracking dir to follow a broadcast tx, optionally replacing another tracked tx
+func TrackTx(dir, txId, replaces string) error {
+	if _, err := chainhash.NewHashFromStr(txId); err != nil {
+		return fmt.Errorf("invalid tx id %s: %w", txId, err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(trackRequest{TxId: txId, Replaces: replaces})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, txId+".json"))
+}
+
+// Transactions being followed
+func (w *Watcher) Tracked() []Tx {
+	if w.state == nil {
+		return nil
+	}
+	return append([]Tx{}, w.state.Txs...)
+}
+
+func (w *Watcher) trackedIndex(txId string) int {
+	for i, tx := range w.state.Txs {
+		if tx.TxId == txId {
+			return i
+		}
+	}
+	return -1
+}
+
+// Take the txs added to the tracking dir. A tx the node doesn't know yet stays in the dir until it does
+func (w *Watcher) adoptTracked(ctx context.Context, tip int64) error {
+	if w.TrackDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(w.TrackDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(w.TrackDir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var req trackRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("invalid tracked tx %s: %w", path, err)
+		}
+		if w.trackedIndex(req.TxId) < 0 {
+			tx, err := w.fetchTracked(req, tip)
+			if err != nil {
+				log.Warn().Err(err).Str("tx", req.TxId).Msg("can't track tx yet")
+				continue
+			}
+			if err := w.emit(ctx, newTxEvent(EventTxBroadcast, *tx)); err != nil {
+				return err
+			}
+			if i := w.trackedIndex(req.Replaces); i >= 0 && w.state.Txs[i].Height == 0 {
+				if err := w.emitReplaced(ctx, w.state.Txs[i], req.TxId); err != nil {
+					return err
+				}
+				w.state.Txs = append(w.state.Txs[:i], w.state.Txs[i+1:]...)
+			}
+			w.state.Txs = append(w.state.Txs, *tx)
+			if err := w.Store.Save(w.state); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) fetchTracked(req trackRequest, tip int64) (*Tx, error) {
+	hash, err := chainhash.NewHashFromStr(req.TxId)
+	if err != nil {
+		return nil, err
+	}
+	res, err := w.Chain.GetRawTransactionVerbose(hash)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Tx{TxId: req.TxId, Replaces: req.Replaces, Inputs: make([]string, 0, len(res.Vin)), Status: StatusPending}
+	for _, in := range res.Vin {
+		tx.Inputs = append(tx.Inputs, fmt.Sprintf("%s:%d", in.Txid, in.Vout))
+	}
+	// Already mined, in a block the watcher has scanned or not
+	if res.Confirmations > 0 && res.BlockHash != "" {
+		tx.Height, tx.BlockHash = tip-int64(res.Confirmations)+1, res.BlockHash
+	}
+	return tx, nil
+}
+
+// Tracked txs once the block is applied: the ones mined in the block get its height, the ones a tx of
+// the block replaced by spending their inputs are dropped
+func (w *Watcher) scanTracked(ctx context.Context, height int64, hash string, block []*wire.MsgTx) ([]Tx, error) {
+	txs := append([]Tx{}, w.state.Txs...)
+	if len(txs) == 0 {
+		return txs, nil
+	}
+	spent := make(map[string]string)
+	mined := make(map[string]bool)
+	for _, msg := range block {
+		txId := msg.TxHash().String()
+		mined[txId] = true
+		for _, in := range msg.TxIn {
+			spent[in.PreviousOutPoint.String()] = txId
+		}
+	}
+	for i := 0; i < len(txs); i++ {
+		tx := &txs[i]
+		if tx.Height != 0 {
+			continue
+		}
+		if mined[tx.TxId] {
+			tx.Height, tx.BlockHash = height, hash
+			continue
+		}
+		for _, input := range tx.Inputs {
+			if by, ok := spent[input]; ok {
+				if err := w.emitReplaced(ctx, *tx, by); err != nil {
+					return nil, err
+				}
+				txs = append(txs[:i], txs[i+1:]...)
+				i--
+				break
+			}
+		}
+	}
+	return txs, nil
+}
+
+// Emit the replacement of a tracked tx, the caller stops following it
+func (w *Watcher) emitReplaced(ctx context.Context, tx Tx, by string) error {
+	tx.Status, tx.ReplacedBy = StatusReplaced, by
+	return w.emit(ctx, newTxEvent(EventTxReplaced, tx))
+}
+
+func (w *Watcher) confirmTracked(ctx context.Context) error {
+	txs := make([]Tx, 0, len(w.state.Txs))
+	for _, tx := range w.state.Txs {
+		if tx.Height == 0 || tx.Height > w.state.Height {
+			txs = append(txs, tx)
+			continue
+		}
+		tx.Confirmations = w.state.Height - tx.Height + 1
+		if tx.Confirmations < w.confirmations() {
+			txs = append(txs, tx)
+			continue
+		}
+		tx.Status = StatusConfirmed
+		if err := w.emit(ctx, newTxEvent(EventTxConfirmed, tx)); err != nil {
+			return err
+		}
+	}
+	w.state.Txs = txs
+	return nil
+}
+
+// Tracked txs mined above the fork are back in the mempool, or replaced once another block says so
+func (w *Watcher) unwindTracked(forkHeight int64) {
+	for i := range w.state.Txs {
+		if tx := &w.state.Txs[i]; tx.Height > forkHeight {
+			tx.Height, tx.BlockHash, tx.Confirmations = 0, "", 0
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package watch
+
+// Deposit watcher. Scans every block for transactions paying the watched addresses, asks the detectors
+// which deposits they carry and follows the deposits until they are deep enough, or until a reorg
+// takes their block out of the chain. Transactions added to the tracking dir are followed the same
+// way. Progress is saved after every block
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/ordinox/btc-service/config"
+	"github.com/ordinox/btc-service/webhook"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrReorgTooDeep = errors.New("the chain forked below the blocks the watcher remembers")
+	ErrNoAddresses  = errors.New("no address to watch")
+)
+
+// Blocks remembered past the confirmation depth, to find where a reorg forked
+const reorgMargin = 6
+
+// Chain queries of the watcher, satisfied by BtcRpcClient
+type Chain interface {
+	GetBlockCount() (int64, error)
+	GetBlockHash(height int64) (*chainhash.Hash, error)
+	GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error)
+	GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error)
+}
+
+var _ Chain = &client.BtcRpcClient{}
+
+type Watcher struct {
+	Chain         Chain
+	Params        *chaincfg.Params
+	Detectors     []Detector
+	Sinks         []Sink
+	Store         Store
+	Confirmations int64 // Defaults to 1
+	StartHeight   int64 // First block scanned without a saved state, the tip by default
+	PollInterval  time.Duration
+	TrackDir      string              // Txs to follow, see TrackTx
+	Webhooks      *webhook.Dispatcher // Delivers the events of the webhook sink while running
+
+	addresses map[string]bool
+	state     *State
+}
+
+// Watcher of the addresses in watch.addresses, see config.WatchConfig
+func New(c config.Config) (*Watcher, error) {
+	rpc, err := client.NewBitcoinClient(c)
+	if err != nil {
+		return nil, err
+	}
+	params := c.BtcConfig.GetChainConfigParams()
+	w := &Watcher{
+		Chain:         rpc,
+		Params:        params,
+		Store:         FileStore{Path: c.WatchConfig.GetStateFile()},
+		Confirmations: c.WatchConfig.GetConfirmations(),
+		StartHeight:   c.WatchConfig.StartHeight,
+		PollInterval:  c.WatchConfig.GetPollInterval(),
+		TrackDir:      c.WatchConfig.GetTrackDir(),
+	}
+	if err := w.Watch(c.WatchConfig.Addresses...); err != nil {
+		return nil, err
+	}
+	for _, asset := range c.WatchConfig.GetAssets() {
+		detector, err := newDetector(Asset(asset), c)
+		if err != nil {
+			return nil, err
+		}
+		w.Detectors = append(w.Detectors, detector)
+	}
+	for _, sink := range c.WatchConfig.GetSinks() {
+		switch sink {
+		case "stdout":
+			w.Sinks = append(w.Sinks, NewJSONSink(os.Stdout))
+		case "webhook":
+			if w.Webhooks, err = webhook.NewDispatcher(c.WebhookConfig); err != nil {
+				return nil, err
+			}
+			w.Sinks = append(w.Sinks, NewWebhookSink(w.Webhooks))
+		case "queue":
+			w.Sinks = append(w.Sinks, NewQueueSink(c.WatchConfig.QueueFile))
+		default:
+			return nil, fmt.Errorf("unknown watch sink %q", sink)
+		}
+	}
+	return w, nil
+}
+
+func newDetector(asset Asset, c config.Config) (Detector, error) {
+	switch asset {
+	case AssetBtc:
+		return BtcDetector{MinValue: c.WatchConfig.MinBtcValue}, nil
+	case AssetRunes:
+		switch source := c.GetRunesUtxoSource(); source {
+		case "opi":
+			opi, err := client.NewOpiClient(c.OpiConfig)
+			if err != nil {
+				return nil, err
+			}
+			return RunesDetector{Inputs: func(height int64) client.RunesInputSource { return opi.WithMinHeight(height) }, Params: c.BtcConfig.GetChainConfigParams()}, nil
+		case "bis":
+			bis, err := client.NewBISClient(c.BISConfig)
+			if err != nil {
+				return nil, err
+			}
+			return RunesDetector{Inputs: func(height int64) client.RunesInputSource { return bis.WithMinHeight(height) }, Params: c.BtcConfig.GetChainConfigParams()}, nil
+		default:
+			return nil, fmt.Errorf("unknown runes utxo source %q", source)
+		}
+	case AssetBrc20:
+		switch source := c.GetBrc20Sources()[0]; source {
+		case "opi":
+			opi, err := client.NewOpiClient(c.OpiConfig)
+			if err != nil {
+				return nil, err
+			}
+			return Brc20Detector{Source: func(height int64) client.Brc20EventSource { return opi.WithMinHeight(height) }}, nil
+		case "bis":
+			bis, err := client.NewBISClient(c.BISConfig)
+			if err != nil {
+				return nil, err
+			}
+			return Brc20Detector{Source: func(height int64) client.Brc20EventSource { return bis.WithMinHeight(height) }}, nil
+		default:
+			return nil, fmt.Errorf("unknown brc20 source %q", source)
+		}
+	}
+	return nil, fmt.Errorf("unknown asset %q", asset)
+}
+
+// Add addresses to watch
+func (w *Watcher) Watch(addresses ...string) error {
+	if w.addresses == nil {
+		w.addresses = make(map[string]bool)
+	}
+	for _, address := range addresses {
+		addr, err := btcutil.DecodeAddress(address, w.Params)
+		if err != nil {
+			return fmt.Errorf("invalid watch address %s: %w", address, err)
+		}
+		w.addresses[addr.EncodeAddress()] = true
+	}
+	return nil
+}
+
+// Deposits waiting for confirmations
+func (w *Watcher) Pending() []Deposit {
+	if w.state == nil {
+		return nil
+	}
+	return append([]Deposit{}, w.state.Pending...)
+}
+
+// Poll until the context is done. Failed polls are logged and tried again after the interval
+func (w *Watcher) Run(ctx context.Context) error {
+	if len(w.addresses) == 0 {
+		return ErrNoAddresses
+	}
+	if w.Webhooks != nil {
+		go func() { _ = w.Webhooks.Run(ctx, time.Second) }()
+	}
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Err(err).Msg("watch poll failed")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// Scan the blocks up to the tip and update the confirmations of the pending deposits. Scanning stops
+// at the first block an indexer hasn't processed yet. After a failure the state is loaded again from
+// the store, so that the next poll starts over from the progress saved
+func (w *Watcher) Poll(ctx context.Context) (err error) {
+	if len(w.addresses) == 0 {
+		return ErrNoAddresses
+	}
+	defer func() {
+		if err != nil {
+			w.state = nil
+		}
+	}()
+	tip, err := w.Chain.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	if err := w.load(tip); err != nil {
+		return err
+	}
+	if err := w.unwindReorg(ctx, tip); err != nil {
+		return err
+	}
+	if err := w.adoptTracked(ctx, tip); err != nil {
+		return err
+	}
+	for height := w.state.Height + 1; height <= tip; height++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := w.scan(ctx, height)
+		if errors.Is(err, client.ErrIndexerBehind) {
+			log.Debug().Err(err).Int64("height", height).Msg("waiting for the indexers")
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.confirm(ctx); err != nil {
+		return err
+	}
+	if err := w.confirmTracked(ctx); err != nil {
+		return err
+	}
+	return w.Store.Save(w.state)
+}
+
+func (w *Watcher) load(tip int64) error {
+	if w.state != nil {
+		return nil
+	}
+	state, err := w.Store.Load()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &State{Height: tip - 1, Pending: make([]Deposit, 0), Txs: make([]Tx, 0)}
+		if w.StartHeight > 0 {
+			state.Height = w.StartHeight - 1
+		}
+	}
+	w.state = state
+	return nil
+}
+
+// Drop the blocks which left the chain, along with their pending deposits
+func (w *Watcher) unwindReorg(ctx context.Context, tip int64) error {
+	blocks := w.state.Blocks
+	for len(blocks) > 0 {
+		last := blocks[len(blocks)-1]
+		if last.Height <= tip {
+			hash, err := w.Chain.GetBlockHash(last.Height)
+			if err != nil {
+				return err
+			}
+			if hash.String() == last.Hash {
+				break
+			}
+		}
+		blocks = blocks[:len(blocks)-1]
+	}
+	if len(blocks) == len(w.state.Blocks) {
+		return nil
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("%w: %d blocks", ErrReorgTooDeep, len(w.state.Blocks))
+	}
+	forkHeight := blocks[len(blocks)-1].Height
+	pending := make([]Deposit, 0, len(w.state.Pending))
+	for _, d := range w.state.Pending {
+		if d.Height <= forkHeight {
+			pending = append(pending, d)
+			continue
+		}
+		d.Status, d.Confirmations = StatusReorged, 0
+		if err := w.emit(ctx, newEvent(EventReorged, d)); err != nil {
+			return err
+		}
+	}
+	w.unwindTracked(forkHeight)
+	log.Warn().Int64("height", forkHeight).Int("blocks", len(w.state.Blocks)-len(blocks)).Msg("chain reorganized")
+	w.state.Blocks, w.state.Pending, w.state.Height = blocks, pending, forkHeight
+	return w.Store.Save(w.state)
+}
+
+func (w *Watcher) scan(ctx context.Context, height int64) error {
+	hash, err := w.Chain.GetBlockHash(height)
+	if err != nil {
+		return err
+	}
+	msgBlock, err := w.Chain.GetBlock(hash)
+	if err != nil {
+		return err
+	}
+	block := Block{Height: height, Hash: hash.String(), addresses: w.addresses, params: w.Params}
+	for _, tx := range msgBlock.Transactions {
+		candidate := Candidate{Tx: tx, Outputs: make(map[string][]uint32)}
+		for n, out := range tx.TxOut {
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(out.PkScript, w.Params)
+			if err != nil || len(addrs) != 1 || !w.addresses[addrs[0].EncodeAddress()] {
+				continue
+			}
+			candidate.Outputs[addrs[0].EncodeAddress()] = append(candidate.Outputs[addrs[0].EncodeAddress()], uint32(n))
+		}
+		if len(candidate.Outputs) > 0 {
+			block.Candidates = append(block.Candidates, candidate)
+		}
+	}
+
+	// Every detector has to succeed before the deposits of the block go out
+	var deposits []Deposit
+	if len(block.Candidates) > 0 {
+		for _, detector := range w.Detectors {
+			found, err := detector.Detect(ctx, block)
+			if err != nil {
+				return fmt.Errorf("block %d: %w", height, err)
+			}
+			deposits = append(deposits, found...)
+		}
+	}
+	// The state only changes once the whole block went through
+	for i := range deposits {
+		d := &deposits[i]
+		d.Height, d.BlockHash, d.Confirmations, d.Status = height, block.Hash, 1, StatusPending
+		if err := w.emit(ctx, newEvent(EventDetected, *d)); err != nil {
+			return err
+		}
+	}
+	txs, err := w.scanTracked(ctx, height, block.Hash, msgBlock.Transactions)
+	if err != nil {
+		return err
+	}
+
+	w.state.Pending = append(w.state.Pending, deposits...)
+	w.state.Txs = txs
+	w.state.Height = height
+	w.state.Blocks = append(w.state.Blocks, BlockRef{Height: height, Hash: block.Hash})
+	if keep := int(w.confirmations()) + reorgMargin; len(w.state.Blocks) > keep {
+		w.state.Blocks = w.state.Blocks[len(w.state.Blocks)-keep:]
+	}
+	return w.Store.Save(w.state)
+}
+
+// Count the confirmations up to the last block scanned, the only ones checked for reorgs
+func (w *Watcher) confirm(ctx context.Context) error {
+	pending := make([]Deposit, 0, len(w.state.Pending))
+	for _, d := range w.state.Pending {
+		d.Confirmations = w.state.Height - d.Height + 1
+		if d.Confirmations < w.confirmations() {
+			pending = append(pending, d)
+			continue
+		}
+		d.Status = StatusConfirmed
+		if err := w.emit(ctx, newEvent(EventConfirmed, d)); err != nil {
+			return err
+		}
+	}
+	w.state.Pending = pending
+	return nil
+}
+
+func (w *Watcher) confirmations() int64 {
+	if w.Confirmations < 1 {
+		return 1
+	}
+	return w.Confirmations
+}
+
+func (w *Watcher) emit(ctx context.Context, event Event) error {
+	for _, sink := range w.Sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			return fmt.Errorf("%s of %s: %w", event.Type, event.Deposit.Id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ordinox/btc-service/client"
+	"github.com/stretchr/testify/require"
+)
+
+// Chain of blocks indexed by height, a fork replaces the blocks above a height. Txs are looked up in
+// the mempool
+type fakeChain struct {
+	blocks  []*wire.MsgBlock
+	mempool map[string]*wire.MsgTx
+}
+
+func (c *fakeChain) GetBlockCount() (int64, error) {
+	return int64(len(c.blocks) - 1), nil
+}
+
+func (c *fakeChain) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	hash := c.blocks[height].BlockHash()
+	return &hash, nil
+}
+
+func (c *fakeChain) GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error) {
+	for _, b := range c.blocks {
+		if b.BlockHash() == *blockHash {
+			return b, nil
+		}
+	}
+	return nil, client.ErrNotFound
+}
+
+func (c *fakeChain) GetRawTransactionVerbose(txHash *chainhash.Hash) (*btcjson.TxRawResult, error) {
+	tx, ok := c.mempool[txHash.String()]
+	if !ok {
+		return nil, client.ErrNotFound
+	}
+	res := &btcjson.TxRawResult{Txid: txHash.String()}
+	for _, in := range tx.TxIn {
+		res.Vin = append(res.Vin, btcjson.Vin{Txid: in.PreviousOutPoint.Hash.String(), Vout: in.PreviousOutPoint.Index})
+	}
+	return res, nil
+}
+
+func (c *fakeChain) mine(fork uint32, txs ...*wire.MsgTx) {
+	header := wire.BlockHeader{Nonce: fork}
+	if len(c.blocks) > 0 {
+		header.PrevBlock = c.blocks[len(c.blocks)-1].BlockHash()
+	}
+	c.blocks = append(c.blocks, &wire.MsgBlock{Header: header, Transactions: txs})
+}
+
+// Indexer which has processed the blocks up to a height
+type laggingDetector struct {
+	height int64
+}
+
+func (d *laggingDetector) Detect(ctx context.Context, block Block) ([]Deposit, error) {
+	if block.Height > d.height {
+		return nil, client.ErrIndexerBehind
+	}
+	return nil, nil
+}
+
+func payTo(t *testing.T, address string, value int64) *wire.MsgTx {
+	addr, err := btcutil.DecodeAddress(address, &chaincfg.RegressionNetParams)
+	require.NoError(t, err)
+	script, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(value)}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(value, script))
+	return tx
+}
+
+func events(t *testing.T, out *bytes.Buffer) []Event {
+	var events []Event
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var e Event
+		require.NoError(t, dec.Decode(&e))
+		events = append(events, e)
+	}
+	out.Reset()
+	return events
+}
+
+func TestWatcher(t *testing.T) {
+	ctx := context.Background()
+	deposit, other := "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", "msTNoMYXynujefraNmpeKKuUsAsSYMZ8C2"
+	chain := &fakeChain{}
+	var out bytes.Buffer
+	store := FileStore{Path: filepath.Join(t.TempDir(), "state.json")}
+	indexer := &laggingDetector{height: 100}
+	newWatcher := func() *Watcher {
+		w := &Watcher{
+			Chain:         chain,
+			Params:        &chaincfg.RegressionNetParams,
+			Detectors:     []Detector{BtcDetector{MinValue: 1000}, indexer},
+			Sinks:         []Sink{NewJSONSink(&out)},
+			Store:         store,
+			Confirmations: 3,
+			StartHeight:   1,
+		}
+		require.NoError(t, w.Watch(deposit))
+		return w
+	}
+	w := newWatcher()
+	require.ErrorIs(t, (&Watcher{}).Poll(ctx), ErrNoAddresses)
+
+	chain.mine(0)
+	chain.mine(0, payTo(t, other, 5000))
+	chain.mine(0, payTo(t, deposit, 999), payTo(t, deposit, 2000))
+	require.NoError(t, w.Poll(ctx))
+	detected := events(t, &out)
+	require.Len(t, detected, 1)
+	require.Equal(t, EventDetected, detected[0].Type)
+	require.Equal(t, AssetBtc, detected[0].Deposit.Asset)
+	require.Equal(t, "0.00002", detected[0].Deposit.Amount.String())
+	require.Equal(t, int64(2), detected[0].Deposit.Height)
+	require.Equal(t, StatusPending, detected[0].Deposit.Status)
+
+	// Nothing is sent again, progress survives a restart
+	require.NoError(t, w.Poll(ctx))
+	require.Empty(t, events(t, &out))
+	w = newWatcher()
+	chain.mine(0)
+	require.NoError(t, w.Poll(ctx))
+	require.Empty(t, events(t, &out))
+	require.Equal(t, int64(2), w.Pending()[0].Confirmations)
+	require.Equal(t, "0.00002", w.Pending()[0].Amount.String())
+
+	// Scanning waits for the indexers
+	indexer.height = 3
+	chain.mine(0, payTo(t, deposit, 500))
+	chain.mine(0)
+	require.NoError(t, w.Poll(ctx))
+	require.Empty(t, events(t, &out))
+	state, err := store.Load()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), state.Height)
+	indexer.height = 100
+	require.NoError(t, w.Poll(ctx))
+	confirmed := events(t, &out)
+	require.Len(t, confirmed, 1)
+	require.Equal(t, EventConfirmed, confirmed[0].Type)
+	require.Equal(t, int64(4), confirmed[0].Deposit.Confirmations)
+	require.Equal(t, detected[0].Deposit.Id, confirmed[0].Deposit.Id)
+	state, err = store.Load()
+	require.NoError(t, err)
+	require.Equal(t, int64(5), state.Height)
+	require.Empty(t, state.Pending)
+
+	// A deposit dropped by a reorg is detected again in its new block
+	chain.mine(0, payTo(t, deposit, 3000))
+	require.NoError(t, w.Poll(ctx))
+	detected = events(t, &out)
+	require.Len(t, detected, 1)
+	chain.blocks = chain.blocks[:6]
+	chain.mine(1)
+	chain.mine(1, payTo(t, deposit, 3000))
+	require.NoError(t, w.Poll(ctx))
+	reorged := events(t, &out)
+	require.Len(t, reorged, 2)
+	require.Equal(t, EventReorged, reorged[0].Type)
+	require.Equal(t, StatusReorged, reorged[0].Deposit.Status)
+	require.Equal(t, EventDetected, reorged[1].Type)
+	require.Equal(t, detected[0].Deposit.Id, reorged[1].Deposit.Id)
+	require.NotEqual(t, detected[0].Id, reorged[1].Id)
+	require.Equal(t, int64(7), reorged[1].Deposit.Height)
+}
+
+// Sink failing its nth event
+type failingSink struct {
+	Sink
+	fail int
+}
+
+func (s *failingSink) Send(ctx context.Context, event Event) error {
+	if s.fail--; s.fail == 0 {
+		return errors.New("sink down")
+	}
+	return s.Sink.Send(ctx, event)
+}
+
+// Store failing its nth save
+type failingStore struct {
+	Store
+	fail int
+}
+
+func (s *failingStore) Save(state *State) error {
+	if s.fail--; s.fail == 0 {
+		return errors.New("disk full")
+	}
+	return s.Store.Save(state)
+}
+
+func TestWatcherRetry(t *testing.T) {
+	ctx := context.Background()
+	deposit := "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"
+	chain := &fakeChain{}
+	var out bytes.Buffer
+	sink := &failingSink{Sink: NewJSONSink(&out)}
+	store := &failingStore{Store: FileStore{Path: filepath.Join(t.TempDir(), "state.json")}}
+	w := &Watcher{
+		Chain:         chain,
+		Params:        &chaincfg.RegressionNetParams,
+		Detectors:     []Detector{BtcDetector{MinValue: 1000}},
+		Sinks:         []Sink{sink},
+		Store:         store,
+		Confirmations: 3,
+		StartHeight:   1,
+	}
+	require.NoError(t, w.Watch(deposit))
+	// Deposit ids of the events, an event sent again has the same id
+	sent := func() map[string]string {
+		ids := map[string]string{}
+		for _, e := range events(t, &out) {
+			ids[e.Id] = e.Deposit.Id
+		}
+		return ids
+	}
+
+	// The block is scanned again once the sink is back
+	chain.mine(0)
+	chain.mine(0, payTo(t, deposit, 2000), payTo(t, deposit, 3000))
+	sink.fail = 2
+	require.Error(t, w.Poll(ctx))
+	require.Len(t, sent(), 1)
+	require.Empty(t, w.Pending())
+	require.NoError(t, w.Poll(ctx))
+	require.Len(t, sent(), 2)
+	require.Len(t, w.Pending(), 2)
+
+	// The same when the state couldn't be saved
+	chain.mine(0, payTo(t, deposit, 4000))
+	store.fail = 1
+	require.Error(t, w.Poll(ctx))
+	detected := sent()
+	require.Len(t, detected, 1)
+	require.NoError(t, w.Poll(ctx))
+	require.Equal(t, detected, sent())
+	require.Len(t, w.Pending(), 3)
+	state, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, state.Pending, 3)
+	require.Equal(t, int64(2), state.Pending[0].Confirmations)
+}
+
+func TestWatcherTrackedTxs(t *testing.T) {
+	ctx := context.Background()
+	chain := &fakeChain{mempool: map[string]*wire.MsgTx{}}
+	var out bytes.Buffer
+	dir := t.TempDir()
+	w := &Watcher{
+		Chain:         chain,
+		Params:        &chaincfg.RegressionNetParams,
+		Sinks:         []Sink{NewJSONSink(&out)},
+		Store:         FileStore{Path: filepath.Join(dir, "state.json")},
+		Confirmations: 2,
+		TrackDir:      filepath.Join(dir, "track"),
+	}
+	require.NoError(t, w.Watch("msTNoMYXynujefraNmpeKKuUsAsSYMZ8C2"))
+	send := func(value int64) *wire.MsgTx {
+		tx := payTo(t, "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", value)
+		chain.mempool[tx.TxHash().String()] = tx
+		return tx
+	}
+	chain.mine(0)
+
+	transfer, bump := send(1000), send(900)
+	require.NoError(t, TrackTx(w.TrackDir, transfer.TxHash().String(), ""))
+	require.NoError(t, TrackTx(w.TrackDir, chainhash.DoubleHashH([]byte("unknown")).String(), ""))
+	require.ErrorContains(t, TrackTx(w.TrackDir, "not a tx", ""), "invalid tx id")
+	require.NoError(t, w.Poll(ctx))
+	broadcast := events(t, &out)
+	require.Len(t, broadcast, 1)
+	require.Equal(t, EventTxBroadcast, broadcast[0].Type)
+	require.Equal(t, transfer.TxHash().String(), broadcast[0].Tx.TxId)
+	require.Nil(t, broadcast[0].Deposit)
+
+	// A bump replaces the tx it was made from
+	bump.TxIn[0].PreviousOutPoint = transfer.TxIn[0].PreviousOutPoint
+	delete(chain.mempool, bump.TxHash().String())
+	chain.mempool[bump.TxHash().String()] = bump
+	require.NoError(t, TrackTx(w.TrackDir, bump.TxHash().String(), transfer.TxHash().String()))
+	require.NoError(t, w.Poll(ctx))
+	replaced := events(t, &out)
+	require.Len(t, replaced, 2)
+	require.Equal(t, EventTxBroadcast, replaced[0].Type)
+	require.Equal(t, EventTxReplaced, replaced[1].Type)
+	require.Equal(t, transfer.TxHash().String(), replaced[1].Tx.TxId)
+	require.Equal(t, bump.TxHash().String(), replaced[1].Tx.ReplacedBy)
+
+	// Another tx spending the inputs in a block replaces it too, a mined tx is confirmed at depth
+	other, conflict := send(800), send(700)
+	conflict.TxIn[0].PreviousOutPoint = other.TxIn[0].PreviousOutPoint
+	require.NoError(t, TrackTx(w.TrackDir, other.TxHash().String(), ""))
+	require.NoError(t, w.Poll(ctx))
+	require.Len(t, events(t, &out), 1)
+	chain.mine(0, bump, conflict)
+	require.NoError(t, w.Poll(ctx))
+	replaced = events(t, &out)
+	require.Len(t, replaced, 1)
+	require.Equal(t, other.TxHash().String(), replaced[0].Tx.TxId)
+	require.Equal(t, conflict.TxHash().String(), replaced[0].Tx.ReplacedBy)
+	require.Equal(t, int64(1), w.Tracked()[0].Height)
+
+	chain.mine(0)
+	require.NoError(t, w.Poll(ctx))
+	confirmed := events(t, &out)
+	require.Len(t, confirmed, 1)
+	require.Equal(t, EventTxConfirmed, confirmed[0].Type)
+	require.Equal(t, bump.TxHash().String(), confirmed[0].Tx.TxId)
+	require.Equal(t, int64(2), confirmed[0].Tx.Confirmations)
+	require.Empty(t, w.Tracked())
+
+	// Txs unknown to the node wait in the tracking dir
+	entries, err := os.ReadDir(w.TrackDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
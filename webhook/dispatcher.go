@@ -0,0 +1,235 @@
+package webhook
+
+// Signed webhook delivery. Events are queued in an outbox, one delivery per url, and POSTed until the
+// receiver answers with a 2xx. Failed attempts are retried with an exponential backoff and deliveries
+// out of attempts wait in the outbox for a replay. Delivery is at least once, receivers dedupe on the
+// X-Webhook-Id header
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ordinox/btc-service/config"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	HeaderId        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderAttempt   = "X-Webhook-Attempt"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature" // sha256=HEX of the HMAC-SHA256 of "TIMESTAMP.BODY"
+)
+
+var (
+	ErrNoWebhookUrl     = errors.New("no webhook url")
+	ErrNoWebhookSecret  = errors.New("no webhook secret")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature too old")
+)
+
+// Sign the body sent at the timestamp, the X-Webhook-Signature header
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Check the signature of a request received by a webhook, rejecting the ones signed more than
+// tolerance ago to prevent replays. A zero tolerance accepts any timestamp
+func Verify(secret []byte, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSignature, header.Get(HeaderTimestamp))
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(header.Get(HeaderSignature))) {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return fmt.Errorf("%w: signed %s ago", ErrSignatureExpired, age.Round(time.Second))
+	}
+	return nil
+}
+
+type Dispatcher struct {
+	Urls        []string
+	Secret      []byte
+	Outbox      *Outbox
+	MaxAttempts int
+	MinBackoff  time.Duration // Before the first retry, doubled for every other one
+	MaxBackoff  time.Duration
+	Retention   time.Duration // Delivered deliveries are kept this long, for replays & to skip events emitted again
+	http        *http.Client
+	now         func() time.Time
+}
+
+// Dispatcher of the webhook section, see config.WebhookConfig
+func NewDispatcher(c config.WebhookConfig) (*Dispatcher, error) {
+	if len(c.Urls) == 0 {
+		return nil, ErrNoWebhookUrl
+	}
+	if c.Secret == "" {
+		return nil, ErrNoWebhookSecret
+	}
+	outbox, err := NewOutbox(c.GetOutboxDir())
+	if err != nil {
+		return nil, err
+	}
+	return &Dispatcher{
+		Urls:        c.Urls,
+		Secret:      []byte(c.Secret),
+		Outbox:      outbox,
+		MaxAttempts: c.GetMaxAttempts(),
+		MinBackoff:  c.GetMinBackoff(),
+		MaxBackoff:  c.GetMaxBackoff(),
+		Retention:   c.GetRetention(),
+		http:        &http.Client{Timeout: c.GetTimeout()},
+		now:         time.Now,
+	}, nil
+}
+
+// Queue an event for every url, it is sent as json. Events already queued are left as they are
+func (d *Dispatcher) Enqueue(eventId, eventType string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := d.now().UTC()
+	for _, url := range d.Urls {
+		_, err := d.Outbox.Add(Delivery{
+			Id:          deliveryId(eventId, url),
+			EventId:     eventId,
+			EventType:   eventType,
+			Url:         url,
+			Body:        body,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attempt the due deliveries once, returning how many were delivered
+func (d *Dispatcher) Deliver(ctx context.Context) (int, error) {
+	due, err := d.Outbox.Due(d.now())
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		delivery.Attempts++
+		err := d.post(ctx, delivery)
+		now := d.now().UTC()
+		switch {
+		case err == nil:
+			delivery.Status, delivery.DeliveredAt, delivery.LastError = StatusDelivered, &now, ""
+			delivered++
+		case delivery.Attempts >= d.MaxAttempts:
+			delivery.Status, delivery.LastError = StatusFailed, err.Error()
+			log.Error().Err(err).Str("event", delivery.EventId).Str("url", delivery.Url).Int("attempts", delivery.Attempts).Msg("webhook delivery failed")
+		default:
+			delivery.NextAttempt, delivery.LastError = now.Add(d.backoff(delivery.Attempts)), err.Error()
+			log.Warn().Err(err).Str("event", delivery.EventId).Str("url", delivery.Url).Time("retry", delivery.NextAttempt).Msg("webhook delivery failed")
+		}
+		if err := d.Outbox.Update(delivery); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// Remove the deliveries delivered longer than the retention ago, returning how many were removed.
+// A zero retention keeps them all
+func (d *Dispatcher) Prune() (int, error) {
+	if d.Retention <= 0 {
+		return 0, nil
+	}
+	return d.Outbox.Prune(d.now().Add(-d.Retention))
+}
+
+// Deliver the queued events until the context is done, checking the outbox every interval
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		if _, err := d.Deliver(ctx); err != nil && ctx.Err() == nil {
+			log.Err(err).Msg("webhook delivery")
+		}
+		if _, err := d.Prune(); err != nil {
+			log.Err(err).Msg("webhook outbox pruning")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Queue delivered or failed deliveries again, with their attempts reset. Returns the ones replayed
+func (d *Dispatcher) Replay(ids ...string) ([]Delivery, error) {
+	replayed := make([]Delivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := d.Outbox.Get(id)
+		if err != nil {
+			return replayed, err
+		}
+		if delivery.Status == StatusPending {
+			continue
+		}
+		delivery.Status, delivery.Attempts, delivery.NextAttempt, delivery.DeliveredAt = StatusPending, 0, d.now().UTC(), nil
+		if err := d.Outbox.Update(*delivery); err != nil {
+			return replayed, err
+		}
+		replayed = append(replayed, *delivery)
+	}
+	return replayed, nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.MinBackoff
+	for i := 1; i < attempts && backoff < d.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.MaxBackoff)
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery Delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderId, delivery.EventId)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(delivery.Attempts))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Secret, timestamp, delivery.Body))
+	res, err := d.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s: %s", delivery.Url, res.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
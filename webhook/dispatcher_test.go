@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ordinox/btc-service/config"
+	"github.com/stretchr/testify/require"
+)
+
+// Receiver checking the signatures, failing the first requests
+type receiver struct {
+	mu       sync.Mutex
+	secret   []byte
+	failures int
+	received []http.Header
+	bodies   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if err := Verify(r.secret, req.Header, body, time.Minute); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+		return
+	}
+	r.received = append(r.received, req.Header.Clone())
+	r.bodies = append(r.bodies, string(body))
+}
+
+func TestSignature(t *testing.T) {
+	secret, body := []byte("whsec"), []byte(`{"id":"1"}`)
+	now := time.Now().Unix()
+	header := http.Header{}
+	header.Set(HeaderTimestamp, strconv.FormatInt(now, 10))
+	header.Set(HeaderSignature, Sign(secret, now, body))
+	require.NoError(t, Verify(secret, header, body, time.Minute))
+	require.ErrorIs(t, Verify([]byte("other"), header, body, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, header, []byte(`{"id":"2"}`), time.Minute), ErrInvalidSignature)
+
+	// Replayed later
+	old := now - 3600
+	header.Set(HeaderTimestamp, strconv.FormatInt(old, 10))
+	header.Set(HeaderSignature, Sign(secret, old, body))
+	require.ErrorIs(t, Verify(secret, header, body, time.Minute), ErrSignatureExpired)
+	require.NoError(t, Verify(secret, header, body, 0))
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	recv := &receiver{secret: []byte("whsec"), failures: 2}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	dir := t.TempDir()
+
+	_, err := NewDispatcher(config.WebhookConfig{Urls: []string{server.URL}})
+	require.ErrorIs(t, err, ErrNoWebhookSecret)
+	d, err := NewDispatcher(config.WebhookConfig{Urls: []string{server.URL}, Secret: "whsec", OutboxDir: dir, MaxAttempts: 4, MinBackoff: time.Second, MaxBackoff: 3 * time.Second})
+	require.NoError(t, err)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	event := map[string]string{"id": "deposit.detected:btc:abc", "type": "deposit.detected"}
+	require.NoError(t, d.Enqueue(event["id"], event["type"], event))
+	require.NoError(t, d.Enqueue(event["id"], event["type"], event))
+	pending, err := d.Outbox.List(StatusPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	// Retried with a backoff, nothing is attempted before it
+	delivered, err := d.Deliver(ctx)
+	require.NoError(t, err)
+	require.Zero(t, delivered)
+	delivery, err := d.Outbox.Get(pending[0].Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivery.Attempts)
+	require.Equal(t, now.Add(time.Second).UTC(), delivery.NextAttempt)
+	require.Contains(t, delivery.LastError, "503 Service Unavailable: busy")
+	delivered, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	require.Zero(t, delivered)
+	require.Empty(t, recv.received)
+
+	now = now.Add(time.Second)
+	_, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	delivery, err = d.Outbox.Get(pending[0].Id)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(2*time.Second).UTC(), delivery.NextAttempt)
+
+	// Survives a restart
+	d, err = NewDispatcher(config.WebhookConfig{Urls: []string{server.URL}, Secret: "whsec", OutboxDir: dir})
+	require.NoError(t, err)
+	now = now.Add(2 * time.Second)
+	d.now = func() time.Time { return now }
+	delivered, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.Len(t, recv.received, 1)
+	require.Equal(t, event["id"], recv.received[0].Get(HeaderId))
+	require.Equal(t, "deposit.detected", recv.received[0].Get(HeaderEvent))
+	require.Equal(t, "3", recv.received[0].Get(HeaderAttempt))
+	require.JSONEq(t, `{"id":"deposit.detected:btc:abc","type":"deposit.detected"}`, recv.bodies[0])
+
+	// Delivered again on a replay only
+	_, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	require.Len(t, recv.received, 1)
+	replayed, err := d.Replay(pending[0].Id)
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+	delivered, err = d.Deliver(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.Len(t, recv.received, 2)
+	require.Equal(t, "1", recv.received[1].Get(HeaderAttempt))
+	_, err = d.Replay("missing")
+	require.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	// Out of attempts, a wrong secret never gets through
+	d.Secret, d.MaxAttempts = []byte("wrong"), 2
+	require.NoError(t, d.Enqueue("tx.broadcast:def", "tx.broadcast", map[string]string{"id": "tx.broadcast:def"}))
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Hour)
+		_, err = d.Deliver(ctx)
+		require.NoError(t, err)
+	}
+	failed, err := d.Outbox.List(StatusFailed)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	require.Equal(t, 2, failed[0].Attempts)
+	require.Contains(t, failed[0].LastError, "401 Unauthorized")
+	pending, err = d.Outbox.List(StatusPending)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	// Delivered events are removed past the retention, failed ones wait for a replay
+	d.Retention = 24 * time.Hour
+	removed, err := d.Prune()
+	require.NoError(t, err)
+	require.Zero(t, removed)
+	now = now.Add(24 * time.Hour)
+	removed, err = d.Prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	kept, err := d.Outbox.List(StatusDelivered)
+	require.NoError(t, err)
+	require.Empty(t, kept)
+	failed, err = d.Outbox.List(StatusFailed)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+}
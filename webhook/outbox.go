@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var ErrDeliveryNotFound = errors.New("no such delivery")
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed" // Out of attempts, waiting for a replay
+)
+
+var statuses = []Status{StatusPending, StatusDelivered, StatusFailed}
+
+// An event to POST to one url
+type Delivery struct {
+	Id          string          `json:"id"` // Of the event and the url
+	EventId     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Url         string          `json:"url"`
+	Body        json.RawMessage `json:"body"`
+	Status      Status          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DeliveredAt *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func deliveryId(eventId, url string) string {
+	sum := sha256.Sum256([]byte(eventId + "\n" + url))
+	return hex.EncodeToString(sum[:12])
+}
+
+// Deliveries kept as a json file each, in a directory per status. Files are replaced at once, so the
+// outbox survives crashes and can be shared by the processes emitting events
+type Outbox struct {
+	Dir string
+}
+
+func NewOutbox(dir string) (*Outbox, error) {
+	for _, status := range statuses {
+		if err := os.MkdirAll(filepath.Join(dir, string(status)), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &Outbox{Dir: dir}, nil
+}
+
+func (o *Outbox) path(status Status, id string) string {
+	return filepath.Join(o.Dir, string(status), id+".json")
+}
+
+// Queue a delivery. An event already in the outbox, whatever its status, isn't queued again so that
+// events emitted twice are only delivered once
+func (o *Outbox) Add(d Delivery) (bool, error) {
+	if _, err := o.Get(d.Id); err == nil {
+		return false, nil
+	} else if !errors.Is(err, ErrDeliveryNotFound) {
+		return false, err
+	}
+	d.Status = StatusPending
+	return true, o.write(d)
+}
+
+func (o *Outbox) Get(id string) (*Delivery, error) {
+	for _, status := range statuses {
+		d, err := o.read(o.path(status, id))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return d, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+}
+
+// Save a delivery under its status, removing it from the others
+func (o *Outbox) Update(d Delivery) error {
+	if err := o.write(d); err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status == d.Status {
+			continue
+		}
+		if err := os.Remove(o.path(status, d.Id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliveries with the status, oldest first
+func (o *Outbox) List(status Status) ([]Delivery, error) {
+	entries, err := os.ReadDir(filepath.Join(o.Dir, string(status)))
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]Delivery, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		d, err := o.read(filepath.Join(o.Dir, string(status), e.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			// Moved to another status meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt) })
+	return deliveries, nil
+}
+
+// Deliveries with the id, or of the event with the id to every url
+func (o *Outbox) Lookup(id string) ([]Delivery, error) {
+	if d, err := o.Get(id); err == nil {
+		return []Delivery{*d}, nil
+	} else if !errors.Is(err, ErrDeliveryNotFound) {
+		return nil, err
+	}
+	var found []Delivery
+	for _, status := range statuses {
+		deliveries, err := o.List(status)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range deliveries {
+			if d.EventId == id {
+				found = append(found, d)
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+	return found, nil
+}
+
+// Pending deliveries whose next attempt is due
+func (o *Outbox) Due(now time.Time) ([]Delivery, error) {
+	pending, err := o.List(StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	due := make([]Delivery, 0, len(pending))
+	for _, d := range pending {
+		if !d.NextAttempt.After(now) {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+// Remove the delivered deliveries delivered before the time, returning how many were removed
+func (o *Outbox) Prune(before time.Time) (int, error) {
+	delivered, err := o.List(StatusDelivered)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, d := range delivered {
+		if d.DeliveredAt != nil && !d.DeliveredAt.Before(before) {
+			continue
+		}
+		if err := os.Remove(o.path(StatusDelivered, d.Id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (o *Outbox) read(path string) (*Delivery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Delivery{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("invalid delivery %s: %w", path, err)
+	}
+	return d, nil
+}
+
+func (o *Outbox) write(d Delivery) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(o.Dir, string(d.Status))
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), o.path(d.Status, d.Id))
+}